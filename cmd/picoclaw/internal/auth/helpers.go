@@ -15,17 +15,20 @@ import (
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
-const supportedProvidersMsg = "supported providers: openai, anthropic, google-antigravity"
+const supportedProvidersMsg = "supported providers: openai, anthropic, azure, google-antigravity, email-<account>"
 
 func authLoginCmd(provider string, useDeviceCode bool) error {
 	switch provider {
 	case "openai":
 		return authLoginOpenAI(useDeviceCode)
-	case "anthropic":
+	case "anthropic", "azure":
 		return authLoginPasteToken(provider)
 	case "google-antigravity", "antigravity":
 		return authLoginGoogleAntigravity()
 	default:
+		if strings.HasPrefix(provider, "email-") {
+			return authLoginPasteToken(provider)
+		}
 		return fmt.Errorf("unsupported provider: %s (%s)", provider, supportedProvidersMsg)
 	}
 }
@@ -244,6 +247,18 @@ func authLoginPasteToken(provider string) error {
 			}
 			// Update default model
 			appCfg.Agents.Defaults.ModelName = "gpt-5.2"
+		case "azure":
+			// Azure entries need api_base and a deployment, so only existing entries are switched over.
+			found := false
+			for i := range appCfg.ModelList {
+				if isAzureModel(appCfg.ModelList[i].Model) {
+					appCfg.ModelList[i].AuthMethod = "token"
+					found = true
+				}
+			}
+			if !found {
+				fmt.Println("No azure/ entries in model_list; add one with api_base and \"auth_method\": \"token\".")
+			}
 		}
 		if err := config.SaveConfig(internal.GetConfigPath(), appCfg); err != nil {
 			return fmt.Errorf("could not update config: %w", err)
@@ -282,6 +297,10 @@ func authLogoutCmd(provider string) error {
 					if isAntigravityModel(appCfg.ModelList[i].Model) {
 						appCfg.ModelList[i].AuthMethod = ""
 					}
+				case "azure":
+					if isAzureModel(appCfg.ModelList[i].Model) {
+						appCfg.ModelList[i].AuthMethod = ""
+					}
 				}
 			}
 			// Clear AuthMethod in Providers (legacy)
@@ -435,3 +454,10 @@ func isAnthropicModel(model string) bool {
 	return model == "anthropic" ||
 		strings.HasPrefix(model, "anthropic/")
 }
+
+// isAzureModel checks if a model string belongs to azure provider
+func isAzureModel(model string) bool {
+	return model == "azure" ||
+		strings.HasPrefix(model, "azure/") ||
+		strings.HasPrefix(model, "azure-openai/")
+}
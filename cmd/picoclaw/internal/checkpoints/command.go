@@ -0,0 +1,54 @@
+package checkpoints
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func NewCheckpointsCommand() *cobra.Command {
+	var (
+		store   *checkpoint.Store
+		agentID string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "checkpoints",
+		Aliases: []string{"cp"},
+		Short:   "Inspect and restore file snapshots taken before agent edits",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			workspace, err := agent.AgentWorkspace(cfg, agentID)
+			if err != nil {
+				return err
+			}
+			store = checkpoint.NewStore(workspace)
+			store.SetMaxBytes(int64(cfg.Tools.Checkpoints.MaxSizeMB) << 20)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&agentID, "agent", "a", "",
+		"Agent whose workspace to use (default: the default workspace)")
+
+	storeFn := func() *checkpoint.Store { return store }
+	cmd.AddCommand(
+		newListCommand(storeFn),
+		newDiffCommand(storeFn),
+		newRestoreCommand(storeFn),
+		newPruneCommand(storeFn),
+	)
+
+	return cmd
+}
@@ -0,0 +1,21 @@
+package checkpoints
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func newDiffCommand(store func() *checkpoint.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff <checkpoint> [path...]",
+		Short:   "Show how files changed since a checkpoint",
+		Args:    cobra.MinimumNArgs(1),
+		Example: `picoclaw checkpoints diff 20260101-120000 AGENTS.md`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return checkpointsDiffCmd(store(), args[0], args[1:])
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,165 @@
+package checkpoints
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func checkpointsListCmd(store *checkpoint.Store, session string) error {
+	turns, err := store.Turns()
+	if err != nil {
+		return err
+	}
+	turns = slices.DeleteFunc(turns, func(t *checkpoint.Turn) bool {
+		return session != "" && t.SessionKey != session
+	})
+	if len(turns) == 0 {
+		fmt.Println("No checkpoints.")
+		return nil
+	}
+
+	fmt.Println("\nCheckpoints (newest first):")
+	fmt.Println("---------------------------")
+	for i := len(turns) - 1; i >= 0; i-- {
+		t := turns[i]
+		fmt.Printf("  %s  %s", t.ID, t.Time.Local().Format("2006-01-02 15:04:05"))
+		if t.SessionKey != "" {
+			fmt.Printf("  %s", t.SessionKey)
+		}
+		switch {
+		case t.RestoreOf != "":
+			fmt.Printf("  (before restoring %s)", t.RestoreOf)
+		case t.Undone:
+			fmt.Print("  (undone)")
+		}
+		fmt.Println()
+		for _, f := range t.Files {
+			state := "modified"
+			if f.Hash == "" {
+				state = "created"
+			}
+			fmt.Printf("    %-8s %s\n", state, store.Rel(f.Path))
+		}
+	}
+	return nil
+}
+
+func checkpointsDiffCmd(store *checkpoint.Store, id string, paths []string) error {
+	turn, err := store.Turn(id)
+	if err != nil {
+		return err
+	}
+	files, err := selectFiles(store, turn, paths)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		var before []byte
+		if f.Hash != "" {
+			if before, err = store.Load(f.Hash); err != nil {
+				return fmt.Errorf("snapshot of %s is missing: %w", store.Rel(f.Path), err)
+			}
+		}
+		after, err := store.ReadFile(f.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		rel := filepath.ToSlash(store.Rel(f.Path))
+		if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+			if !bytes.Equal(before, after) {
+				fmt.Printf("Binary file %s differs\n", rel)
+			}
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(before),
+			B:        splitLines(after),
+			FromFile: "a/" + rel + " (" + turn.ID + ")",
+			ToFile:   "b/" + rel + " (current)",
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Print(diff)
+	}
+	return nil
+}
+
+func checkpointsRestoreCmd(store *checkpoint.Store, id string, paths []string) error {
+	turn, err := store.Turn(id)
+	if err != nil {
+		return err
+	}
+	restored, err := store.Restore(turn.ID, paths...)
+	for _, path := range restored {
+		fmt.Printf("✓ Restored %s\n", store.Rel(path))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Files are back to their state before %s. The replaced versions were saved as a new checkpoint.\n",
+		turn.ID)
+	return nil
+}
+
+func checkpointsPruneCmd(store *checkpoint.Store, maxSizeMB int) error {
+	if maxSizeMB > 0 {
+		store.SetMaxBytes(int64(maxSizeMB) << 20)
+	}
+	before, err := store.Turns()
+	if err != nil {
+		return err
+	}
+	if err := store.Prune(); err != nil {
+		return err
+	}
+	after, err := store.Turns()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Pruned %d checkpoint(s), %d left\n", len(before)-len(after), len(after))
+	return nil
+}
+
+// selectFiles returns the files of turn named by paths, or all of them.
+func selectFiles(store *checkpoint.Store, turn *checkpoint.Turn, paths []string) ([]checkpoint.FileSnapshot, error) {
+	if len(paths) == 0 {
+		return turn.Files, nil
+	}
+	var files []checkpoint.FileSnapshot
+	for _, p := range paths {
+		i := slices.IndexFunc(turn.Files, func(f checkpoint.FileSnapshot) bool {
+			return f.Path == store.Rel(p)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("checkpoint %s did not change %s", turn.ID, p)
+		}
+		files = append(files, turn.Files[i])
+	}
+	return files, nil
+}
+
+// splitLines splits content into lines that keep their newline, without
+// the empty last line difflib.SplitLines adds.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
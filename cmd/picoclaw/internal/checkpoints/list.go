@@ -0,0 +1,24 @@
+package checkpoints
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func newListCommand(store func() *checkpoint.Store) *cobra.Command {
+	var session string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List checkpoints and the files each one changed",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return checkpointsListCmd(store(), session)
+		},
+	}
+
+	cmd.Flags().StringVarP(&session, "session", "s", "", "Only show checkpoints of this session key")
+
+	return cmd
+}
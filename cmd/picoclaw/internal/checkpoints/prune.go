@@ -0,0 +1,24 @@
+package checkpoints
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func newPruneCommand(store func() *checkpoint.Store) *cobra.Command {
+	var maxSizeMB int
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Drop the oldest checkpoints until the store fits its size limit",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return checkpointsPruneCmd(store(), maxSizeMB)
+		},
+	}
+
+	cmd.Flags().IntVar(&maxSizeMB, "max-size-mb", 0, "Size limit (default: tools.checkpoints.max_size_mb)")
+
+	return cmd
+}
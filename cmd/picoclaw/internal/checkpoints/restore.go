@@ -0,0 +1,21 @@
+package checkpoints
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
+)
+
+func newRestoreCommand(store func() *checkpoint.Store) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "restore <checkpoint> [path...]",
+		Short:   "Restore files to their state before a checkpoint's turn",
+		Args:    cobra.MinimumNArgs(1),
+		Example: `picoclaw checkpoints restore 20260101-120000 skills/weather/SKILL.md`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return checkpointsRestoreCmd(store(), args[0], args[1:])
+		},
+	}
+
+	return cmd
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/health"
 )
 
 func TestNewGatewayCommand(t *testing.T) {
@@ -29,3 +31,23 @@ func TestNewGatewayCommand(t *testing.T) {
 	assert.True(t, cmd.HasFlags())
 	assert.NotNil(t, cmd.Flags().Lookup("debug"))
 }
+
+func TestModelHealth(t *testing.T) {
+	status := newModelHealth(health.NewServer("127.0.0.1", 0))
+
+	status.set("gpt", false, "openai/gpt-4o rate_limit: too many requests")
+	ok, detail := status.check("gpt")
+	assert.False(t, ok)
+	assert.Equal(t, "gpt: openai/gpt-4o rate_limit: too many requests", detail)
+
+	// One healthy load-balanced entry keeps the model ready.
+	status.set("gpt#2", true, "openai/gpt-4o ok in 120ms")
+	ok, detail = status.check("gpt")
+	assert.True(t, ok)
+	assert.Equal(t, "gpt#2: openai/gpt-4o ok in 120ms; gpt: openai/gpt-4o rate_limit: too many requests", detail)
+
+	status.set("claude", false, "missing api_key")
+	ok, _ = status.check("claude")
+	assert.False(t, ok, "a broken entry of one model must not affect another")
+	assert.Equal(t, "c#sharp", probeModelName("c#sharp"))
+}
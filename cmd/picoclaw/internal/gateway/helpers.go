@@ -8,7 +8,10 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
@@ -122,40 +125,24 @@ func gatewayCmd(debug bool) error {
 	// Inject channel manager into agent loop for command handling
 	agentLoop.SetChannelManager(channelManager)
 
-	var transcriber *voice.GroqTranscriber
-	groqAPIKey := cfg.Providers.Groq.APIKey
-	if groqAPIKey == "" {
-		for _, mc := range cfg.ModelList {
-			if strings.HasPrefix(mc.Model, "groq/") && mc.APIKey != "" {
-				groqAPIKey = mc.APIKey
-				break
-			}
-		}
+	transcriber, err := newTranscriber(cfg)
+	if err != nil {
+		return fmt.Errorf("error creating transcriber: %w", err)
 	}
-	if groqAPIKey != "" {
-		transcriber = voice.NewGroqTranscriber(groqAPIKey)
-		logger.InfoC("voice", "Groq voice transcription enabled")
+	if transcriber != nil {
+		channelManager.SetTranscriber(transcriber)
 	}
 
-	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
-		}
-		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
-			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
-				sc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Slack channel")
-			}
-		}
+	synthesizer, err := voice.NewSynthesizer(cfg.Voice.TTS)
+	if err != nil {
+		return fmt.Errorf("error creating speech synthesizer: %w", err)
+	}
+	if synthesizer != nil {
+		channelManager.SetSynthesizer(synthesizer, cfg.Voice.TTS.MaxChars)
+		logger.InfoCF("voice", "Voice replies enabled", map[string]any{
+			"provider":  cfg.Voice.TTS.Provider,
+			"available": synthesizer.IsAvailable(),
+		})
 	}
 
 	enabledChannels := channelManager.GetEnabledChannels()
@@ -205,6 +192,8 @@ func gatewayCmd(debug bool) error {
 	}()
 	fmt.Printf("✓ Health endpoints available at http://%s:%d/health and /ready\n", cfg.Gateway.Host, cfg.Gateway.Port)
 
+	probeMonitor := setupProviderProbes(ctx, cfg, agentLoop, healthServer)
+
 	go agentLoop.Run(ctx)
 
 	sigChan := make(chan os.Signal, 1)
@@ -216,17 +205,130 @@ func gatewayCmd(debug bool) error {
 		cp.Close()
 	}
 	cancel()
+	if probeMonitor != nil {
+		probeMonitor.Close()
+	}
 	healthServer.Stop(context.Background())
 	deviceService.Stop()
 	heartbeatService.Stop()
 	cronService.Stop()
 	agentLoop.Stop()
+	agentLoop.Close()
 	channelManager.StopAll(ctx)
 	fmt.Println("✓ Gateway stopped")
 
 	return nil
 }
 
+// setupProviderProbes periodically probes the models referenced by agents,
+// feeding results into the agent loop's cooldown tracker and registering
+// one readiness check per model on the health server.
+func setupProviderProbes(
+	ctx context.Context,
+	cfg *config.Config,
+	agentLoop *agent.AgentLoop,
+	healthServer *health.Server,
+) *providers.ProbeMonitor {
+	if cfg.Gateway.ProbeInterval <= 0 {
+		return nil
+	}
+
+	targets, errs := providers.BuildProbeTargets(cfg, cfg.ReferencedModelNames()...)
+	status := newModelHealth(healthServer)
+	for name, err := range errs {
+		status.set(name, false, err.Error())
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	monitor := providers.NewProbeMonitor(
+		targets,
+		agentLoop.CooldownTracker(),
+		time.Duration(cfg.Gateway.ProbeInterval)*time.Minute,
+	)
+	monitor.SetResultHandler(func(target providers.ProbeTarget, result providers.ProbeResult) {
+		if result.OK {
+			status.set(target.Name, true, fmt.Sprintf("%s/%s ok in %s", target.Protocol, target.Model,
+				result.Latency.Round(time.Millisecond)))
+			return
+		}
+		status.set(target.Name, false, fmt.Sprintf("%s/%s %s: %v", target.Protocol, target.Model, result.Reason, result.Err))
+	})
+	monitor.Start(ctx)
+
+	fmt.Printf("✓ Provider probes every %d min for %d model(s)\n", cfg.Gateway.ProbeInterval, len(targets))
+	return monitor
+}
+
+// modelHealth reports one readiness check per model_name. Load-balanced
+// model_list entries share a model_name (probe targets number the extras,
+// e.g. "gpt#2"), and the fallback chain can use any of them, so a model is
+// ready when at least one of its entries is healthy.
+type modelHealth struct {
+	server *health.Server
+
+	mu      sync.Mutex
+	entries map[string]map[string]modelEntryStatus // model_name -> entry name -> status
+}
+
+type modelEntryStatus struct {
+	ok     bool
+	detail string
+}
+
+func newModelHealth(server *health.Server) *modelHealth {
+	return &modelHealth{server: server, entries: make(map[string]map[string]modelEntryStatus)}
+}
+
+// set records the status of entry and updates its model's check.
+func (h *modelHealth) set(entry string, ok bool, detail string) {
+	model := probeModelName(entry)
+	h.mu.Lock()
+	if h.entries[model] == nil {
+		h.entries[model] = make(map[string]modelEntryStatus)
+	}
+	h.entries[model][entry] = modelEntryStatus{ok: ok, detail: detail}
+	h.mu.Unlock()
+
+	h.server.RegisterCheck("provider:"+model, func() (bool, string) {
+		return h.check(model)
+	})
+}
+
+// check reports whether model has a healthy entry, with the status of
+// each entry, healthy ones first.
+func (h *modelHealth) check(model string) (bool, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	names := make([]string, 0, len(h.entries[model]))
+	for name := range h.entries[model] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var healthy, failing []string
+	for _, name := range names {
+		st := h.entries[model][name]
+		if st.ok {
+			healthy = append(healthy, name+": "+st.detail)
+		} else {
+			failing = append(failing, name+": "+st.detail)
+		}
+	}
+	return len(healthy) > 0, strings.Join(append(healthy, failing...), "; ")
+}
+
+// probeModelName strips the "#n" suffix BuildProbeTargets adds to extra
+// load-balanced entries.
+func probeModelName(entry string) string {
+	if i := strings.LastIndexByte(entry, '#'); i > 0 {
+		if n, err := strconv.Atoi(entry[i+1:]); err == nil && n > 1 {
+			return entry[:i]
+		}
+	}
+	return entry
+}
+
 func setupCronTool(
 	agentLoop *agent.AgentLoop,
 	msgBus *bus.MessageBus,
@@ -252,3 +354,33 @@ func setupCronTool(
 
 	return cronService
 }
+
+// newTranscriber creates the transcriber configured in voice.transcription.
+// Without a provider it falls back to Groq when a Groq API key is set in
+// providers or model_list, and returns nil otherwise.
+func newTranscriber(cfg *config.Config) (voice.Transcriber, error) {
+	if cfg.Voice.Transcription.Provider != "" {
+		transcriber, err := voice.NewTranscriber(cfg.Voice.Transcription)
+		if err == nil {
+			logger.InfoCF("voice", "Voice transcription enabled", map[string]any{
+				"provider": cfg.Voice.Transcription.Provider,
+			})
+		}
+		return transcriber, err
+	}
+
+	groqAPIKey := cfg.Providers.Groq.APIKey
+	if groqAPIKey == "" {
+		for _, mc := range cfg.ModelList {
+			if strings.HasPrefix(mc.Model, "groq/") && mc.APIKey != "" {
+				groqAPIKey = mc.APIKey
+				break
+			}
+		}
+	}
+	if groqAPIKey == "" {
+		return nil, nil
+	}
+	logger.InfoC("voice", "Groq voice transcription enabled")
+	return voice.NewGroqTranscriber(groqAPIKey), nil
+}
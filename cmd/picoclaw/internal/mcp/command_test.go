@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestNewMCPCommand(t *testing.T) {
+	cmd := NewMCPCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Model Context Protocol integration", cmd.Short)
+	assert.False(t, cmd.HasFlags())
+	assert.NotNil(t, cmd.RunE)
+	assert.True(t, cmd.HasSubCommands())
+
+	allowedCommands := []string{
+		"serve",
+	}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+
+	for _, subcmd := range subcommands {
+		found := slices.Contains(allowedCommands, subcmd.Name())
+		assert.True(t, found, "unexpected subcommand %q", subcmd.Name())
+
+		assert.False(t, subcmd.Hidden)
+		assert.Nil(t, subcmd.Run)
+		assert.NotNil(t, subcmd.RunE)
+	}
+
+	serve, _, err := cmd.Find([]string{"serve"})
+	require.NoError(t, err)
+	for _, flag := range []string{"http", "host", "port", "debug"} {
+		assert.NotNil(t, serve.Flags().Lookup(flag), "missing --%s", flag)
+	}
+}
+
+type noopRunner struct{}
+
+func (noopRunner) ProcessDirect(context.Context, string, string) (string, error) { return "", nil }
+
+func TestExportRegistry(t *testing.T) {
+	source := tools.NewToolRegistry()
+	source.Register(tools.NewReadFileTool(t.TempDir(), true))
+	source.Register(tools.NewMessageTool())
+
+	registry, missing := exportRegistry(source, []string{"ask_agent", "read_file", "web_fetch"}, noopRunner{})
+
+	assert.Equal(t, []string{"ask_agent", "read_file"}, registry.List())
+	assert.Equal(t, []string{"web_fetch"}, missing)
+
+	readFile, _ := source.Get("read_file")
+	exported, _ := registry.Get("read_file")
+	assert.Same(t, readFile, exported, "exported tools should be the agent's own instances")
+}
+
+func TestExportedToolNames(t *testing.T) {
+	assert.Equal(t, defaultExportedTools, exportedToolNames(config.MCPServeConfig{}))
+	assert.Equal(t, []string{"exec"}, exportedToolNames(config.MCPServeConfig{Tools: []string{"exec"}}))
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:18791", "[::1]:18791", "localhost:9100"} {
+		assert.True(t, isLoopbackAddr(addr), addr)
+	}
+	for _, addr := range []string{"0.0.0.0:9000", ":9000", "[::]:9000", "192.168.1.10:9000", "example.com:9000"} {
+		assert.False(t, isLoopbackAddr(addr), addr)
+	}
+}
+
+func TestServeHTTPRequiresTokenOffLoopback(t *testing.T) {
+	err := serveHTTP(context.Background(), http.NotFoundHandler(), "0.0.0.0:0", false, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mcp.serve.token")
+}
+
+func TestServeAddr(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:18791", serveAddr(config.MCPServeConfig{}, serveOptions{}))
+	assert.Equal(t, "0.0.0.0:9000", serveAddr(config.MCPServeConfig{Host: "0.0.0.0", Port: 9000}, serveOptions{}))
+	assert.Equal(t, "localhost:9100", serveAddr(
+		config.MCPServeConfig{Host: "0.0.0.0", Port: 9000},
+		serveOptions{Host: "localhost", Port: 9100},
+	))
+}
@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/channels"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/cron"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+const (
+	defaultServeHost = "127.0.0.1"
+	defaultServePort = 18791
+	askAgentTool     = "ask_agent"
+)
+
+// defaultExportedTools is what serve publishes when mcp.serve.tools is
+// unset: the agent itself plus read-only tools. exec, cron and message
+// must be opted into.
+var defaultExportedTools = []string{askAgentTool, "read_file", "web_fetch"}
+
+type serveOptions struct {
+	HTTP  bool
+	Host  string
+	Port  int
+	Debug bool
+}
+
+func mcpServeCmd(opts serveOptions) error {
+	if opts.Debug {
+		logger.SetLevel(logger.DEBUG)
+	}
+
+	// In stdio mode stdout carries the protocol; keep stray prints off it.
+	stdout := os.Stdout
+	if !opts.HTTP {
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	}
+
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	provider, modelID, err := providers.CreateProvider(cfg)
+	if err != nil {
+		return fmt.Errorf("error creating provider: %w", err)
+	}
+	if modelID != "" {
+		cfg.Agents.Defaults.ModelName = modelID
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	msgBus := bus.NewMessageBus()
+	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	defer agentLoop.Close()
+
+	// Jobs are saved to the shared store and run by the gateway's cron
+	// service, the same as "picoclaw cron add".
+	execTimeout := time.Duration(cfg.Tools.Cron.ExecTimeoutMinutes) * time.Minute
+	cronService := cron.NewCronService(filepath.Join(cfg.WorkspacePath(), "cron", "jobs.json"), nil)
+	agentLoop.RegisterTool(tools.NewCronTool(
+		cronService, agentLoop, msgBus, cfg.WorkspacePath(),
+		cfg.Agents.Defaults.RestrictToWorkspace, execTimeout, cfg,
+	))
+
+	names := exportedToolNames(cfg.MCP.Serve)
+	agentLoop.WaitForMCP(ctx)
+	registry, missing := exportRegistry(agentLoop.DefaultAgentTools(), names, agentLoop)
+	for _, name := range missing {
+		fmt.Fprintf(os.Stderr, "⚠ mcp.serve.tools: %q is not an available tool, skipping\n", name)
+	}
+
+	// The message tool needs running channels to deliver anything, which
+	// makes this process a gateway; don't run both on the same bot tokens.
+	if slices.Contains(names, "message") {
+		channelManager, err := channels.NewManager(cfg, msgBus)
+		if err != nil {
+			return fmt.Errorf("error creating channel manager: %w", err)
+		}
+		agentLoop.SetChannelManager(channelManager)
+		if err := channelManager.StartAll(ctx); err != nil {
+			return fmt.Errorf("error starting channels: %w", err)
+		}
+		defer channelManager.StopAll(context.Background())
+		go agentLoop.Run(ctx)
+		defer agentLoop.Stop()
+	}
+
+	server := mcp.NewServer(mcp.Implementation{Name: "picoclaw", Version: internal.GetVersion()}, registry)
+	server.SetContext(cfg.MCP.Serve.Channel, cfg.MCP.Serve.ChatID)
+	server.SetToken(cfg.MCP.Serve.Token)
+
+	if !opts.HTTP {
+		logger.InfoCF("mcp", "Serving MCP over stdio", map[string]any{"tools": registry.List()})
+		return server.ServeStdio(ctx, os.Stdin, stdout)
+	}
+	return serveHTTP(ctx, server, serveAddr(cfg.MCP.Serve, opts), cfg.MCP.Serve.Token != "", registry.List())
+}
+
+func serveHTTP(ctx context.Context, handler http.Handler, addr string, authenticated bool, toolNames []string) error {
+	if !authenticated && !isLoopbackAddr(addr) {
+		return fmt.Errorf("refusing to serve MCP on %s without mcp.serve.token; set a token or use a loopback host", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", handler)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	fmt.Printf("✓ MCP server listening on http://%s/mcp (%d tools)\n", addr, len(toolNames))
+	if !authenticated {
+		fmt.Println("⚠ No mcp.serve.token set; any local process can call the exported tools")
+	}
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("mcp server: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// exportedToolNames returns the configured allowlist or the default one.
+func exportedToolNames(cfg config.MCPServeConfig) []string {
+	if len(cfg.Tools) > 0 {
+		return cfg.Tools
+	}
+	return defaultExportedTools
+}
+
+// exportRegistry builds a registry holding only the allowlisted tools,
+// taken from the agent's own registry so they keep the agent's workspace
+// restrictions. Names that are not available are returned as missing.
+func exportRegistry(
+	source *tools.ToolRegistry,
+	names []string,
+	runner mcp.AgentRunner,
+) (*tools.ToolRegistry, []string) {
+	registry := tools.NewToolRegistry()
+	var missing []string
+	for _, name := range names {
+		if name == askAgentTool {
+			registry.Register(mcp.NewAskAgentTool(runner))
+			continue
+		}
+		tool, ok := source.Get(name)
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		registry.Register(tool)
+	}
+	return registry, missing
+}
+
+// isLoopbackAddr reports whether addr only accepts connections from this
+// machine. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func serveAddr(cfg config.MCPServeConfig, opts serveOptions) string {
+	host := opts.Host
+	if host == "" {
+		host = cfg.Host
+	}
+	if host == "" {
+		host = defaultServeHost
+	}
+	port := opts.Port
+	if port == 0 {
+		port = cfg.Port
+	}
+	if port == 0 {
+		port = defaultServePort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
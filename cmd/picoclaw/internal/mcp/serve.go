@@ -0,0 +1,30 @@
+package mcp
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	var (
+		httpMode bool
+		host     string
+		port     int
+		debug    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve PicoClaw's tools and agent to MCP clients (stdio by default)",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return mcpServeCmd(serveOptions{HTTP: httpMode, Host: host, Port: port, Debug: debug})
+		},
+	}
+
+	cmd.Flags().BoolVar(&httpMode, "http", false, "Serve streamable HTTP instead of stdio")
+	cmd.Flags().StringVar(&host, "host", "", "HTTP listen host (default from config, else 127.0.0.1)")
+	cmd.Flags().IntVar(&port, "port", 0, "HTTP listen port (default from config, else 18791)")
+	cmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug logging")
+
+	return cmd
+}
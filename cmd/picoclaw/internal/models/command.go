@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewModelsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "Inspect and test configured models",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		newTestCommand(),
+		newStatusCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/state"
+)
+
+func modelsTestCmd(modelName string, checkTools bool, timeout time.Duration) error {
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	var names []string
+	if modelName != "" {
+		names = append(names, modelName)
+	}
+	targets, errs := providers.BuildProbeTargets(cfg, names...)
+	if len(targets) == 0 && len(errs) == 0 {
+		if modelName != "" {
+			return fmt.Errorf("model %q not found in model_list", modelName)
+		}
+		return fmt.Errorf("model_list is empty")
+	}
+
+	ctx := context.Background()
+	opts := providers.ProbeOptions{Timeout: timeout, Completion: true, Tools: checkTools}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPROVIDER\tSTATUS\tLATENCY\tAUTH\tTOOLS\tDETAIL")
+	for _, target := range targets {
+		fmt.Fprintf(os.Stderr, "Testing %s...\n", target.Name)
+		result := providers.ProbeModel(ctx, target.Provider, target.Protocol, target.Model, opts)
+		if sp, ok := target.Provider.(providers.StatefulProvider); ok {
+			sp.Close()
+		}
+		fmt.Fprintln(w, formatProbeRow(target, result))
+		if !result.OK {
+			failed++
+		}
+	}
+
+	// Entries that could not be constructed (missing keys, unknown protocol).
+	errNames := make([]string, 0, len(errs))
+	for name := range errs {
+		errNames = append(errNames, name)
+	}
+	sort.Strings(errNames)
+	for _, name := range errNames {
+		fmt.Fprintf(w, "%s\t-\tconfig error\t-\t-\t-\t%v\n", name, errs[name])
+		failed++
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d model(s) failed", failed, len(targets)+len(errs))
+	}
+	return nil
+}
+
+func modelsStatusCmd() error {
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	path := state.CooldownPath(cfg.WorkspacePath())
+	snapshot, err := providers.LoadCooldownSnapshot(path)
+	if err != nil {
+		return fmt.Errorf("error loading cooldown state: %w", err)
+	}
+	byProvider := make(map[string]providers.CooldownStatus, len(snapshot))
+	for _, s := range snapshot {
+		byProvider[s.Provider] = s
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CANDIDATE\tSTATE\tREMAINING\tERRORS\tLAST ERROR")
+	seen := make(map[string]bool)
+	for _, c := range defaultCandidates(cfg) {
+		seen[c.Provider] = true
+		status, ok := byProvider[c.Provider]
+		if !ok {
+			status = providers.CooldownStatus{Provider: c.Provider, State: providers.CircuitClosed}
+		}
+		fmt.Fprintln(w, formatStatusRow(providers.ModelKey(c.Provider, c.Model), status))
+	}
+	for _, s := range snapshot {
+		if !seen[s.Provider] {
+			fmt.Fprintln(w, formatStatusRow(s.Provider, s))
+		}
+	}
+	w.Flush()
+	return nil
+}
+
+// defaultCandidates resolves the default agent's fallback candidates the same
+// way the agent loop does.
+func defaultCandidates(cfg *config.Config) []providers.FallbackCandidate {
+	defaults := cfg.Agents.Defaults
+	return providers.ResolveCandidates(providers.ModelConfig{
+		Primary:   defaults.GetModelName(),
+		Fallbacks: defaults.ModelFallbacks,
+	}, defaults.Provider)
+}
+
+func formatStatusRow(name string, status providers.CooldownStatus) string {
+	remaining := "-"
+	if status.Remaining > 0 {
+		remaining = status.Remaining.Round(time.Second).String()
+	}
+	last := "-"
+	if status.LastReason != "" {
+		last = string(status.LastReason)
+		if status.LastError != "" {
+			last = fmt.Sprintf("%s: %s", status.LastReason, firstLine(status.LastError))
+		}
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%d\t%s", name, status.State, remaining, status.ErrorCount, last)
+}
+
+func formatProbeRow(target providers.ProbeTarget, result providers.ProbeResult) string {
+	status := "ok"
+	detail := ""
+	if !result.OK {
+		status = "fail"
+		detail = string(result.Reason)
+		if result.Err != nil {
+			detail = fmt.Sprintf("%s: %s", result.Reason, firstLine(result.Err.Error()))
+		}
+	}
+	tools := result.ToolCalls
+	if tools == "" {
+		tools = "-"
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		target.Name,
+		target.Protocol,
+		status,
+		result.Latency.Round(time.Millisecond),
+		result.AuthStatus,
+		tools,
+		detail,
+	)
+}
+
+func firstLine(s string) string {
+	for i, r := range s {
+		if r == '\n' {
+			return s[:i]
+		}
+	}
+	if len(s) > 120 {
+		return s[:120] + "..."
+	}
+	return s
+}
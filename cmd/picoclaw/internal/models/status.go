@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show provider circuit-breaker state and remaining cooldowns",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return modelsStatusCmd()
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newTestCommand() *cobra.Command {
+	var (
+		noTools bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:     "test [model_name]",
+		Short:   "Probe model_list entries for latency, auth and tool-call support",
+		Args:    cobra.MaximumNArgs(1),
+		Example: "picoclaw models test\npicoclaw models test gpt-5.2",
+		RunE: func(_ *cobra.Command, args []string) error {
+			modelName := ""
+			if len(args) > 0 {
+				modelName = args[0]
+			}
+			return modelsTestCmd(modelName, !noTools, timeout)
+		},
+	}
+
+	cmd.Flags().BoolVar(&noTools, "no-tools", false, "Skip the tool-call support check")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Per-request timeout")
+
+	return cmd
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestNewTestSubcommand(t *testing.T) {
+	cmd := newTestCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Probe model_list entries for latency, auth and tool-call support", cmd.Short)
+	assert.NotNil(t, cmd.Flags().Lookup("no-tools"))
+	assert.NotNil(t, cmd.Flags().Lookup("timeout"))
+}
+
+func TestFormatProbeRow(t *testing.T) {
+	target := providers.ProbeTarget{Name: "gpt", Protocol: "openai", ProviderKey: "openai", Model: "gpt-4o"}
+
+	row := formatProbeRow(target, providers.ProbeResult{
+		OK: true, Latency: 1234 * time.Millisecond, AuthStatus: providers.AuthStatusOK,
+		ToolCalls: providers.ToolCallsSupported,
+	})
+	assert.Equal(t, "gpt\topenai\tok\t1.234s\tok\tsupported\t", row)
+
+	row = formatProbeRow(target, providers.ProbeResult{
+		Reason: providers.FailoverAuth, AuthStatus: providers.AuthStatusFailed,
+		Err: errors.New("API request failed:\n  Status: 401"),
+	})
+	assert.True(t, strings.HasSuffix(row, "\tfailed\t-\tauth: API request failed:"), row)
+}
+
+func TestFormatStatusRow(t *testing.T) {
+	row := formatStatusRow("openai/gpt-4o", providers.CooldownStatus{
+		Provider: "openai", State: providers.CircuitOpen, Remaining: 90 * time.Second,
+		ErrorCount: 2, LastReason: providers.FailoverRateLimit, LastError: "429\nretry later",
+	})
+	assert.Equal(t, "openai/gpt-4o\topen\t1m30s\t2\trate_limit: 429", row)
+
+	row = formatStatusRow("anthropic/claude", providers.CooldownStatus{State: providers.CircuitClosed})
+	assert.Equal(t, "anthropic/claude\tclosed\t-\t0\t-", row)
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func NewSecretsCommand() *cobra.Command {
+	var vault *secrets.Vault
+
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage encrypted secrets for tools and skills",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			var err error
+			vault, err = secrets.Open(secrets.DefaultPath(), secrets.DefaultKeyPath())
+			if err != nil {
+				return fmt.Errorf("error opening secrets vault: %w", err)
+			}
+			return nil
+		},
+	}
+
+	vaultFn := func() *secrets.Vault { return vault }
+	cmd.AddCommand(
+		newSetCommand(vaultFn),
+		newListCommand(vaultFn),
+		newRmCommand(vaultFn),
+		newImportCommand(vaultFn),
+	)
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func TestNewSecretsCommand(t *testing.T) {
+	cmd := NewSecretsCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Manage encrypted secrets for tools and skills", cmd.Short)
+
+	assert.Len(t, cmd.Aliases, 0)
+	assert.False(t, cmd.HasFlags())
+
+	assert.Nil(t, cmd.Run)
+	assert.NotNil(t, cmd.RunE)
+
+	assert.NotNil(t, cmd.PersistentPreRunE)
+	assert.Nil(t, cmd.PersistentPreRun)
+	assert.Nil(t, cmd.PersistentPostRun)
+
+	assert.True(t, cmd.HasSubCommands())
+
+	allowedCommands := []string{
+		"set",
+		"list",
+		"rm",
+		"import",
+	}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+
+	for _, subcmd := range subcommands {
+		found := slices.Contains(allowedCommands, subcmd.Name())
+		assert.True(t, found, "unexpected subcommand %q", subcmd.Name())
+
+		assert.False(t, subcmd.Hidden)
+		assert.False(t, subcmd.HasSubCommands())
+
+		assert.Nil(t, subcmd.Run)
+		assert.NotNil(t, subcmd.RunE)
+	}
+}
+
+func openTestVault(t *testing.T) *secrets.Vault {
+	t.Helper()
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	require.NoError(t, err)
+	return vault
+}
+
+func TestSecretsSetKeepsAccessOnUpdate(t *testing.T) {
+	vault := openTestVault(t)
+
+	access := accessFlags{tools: []string{"exec"}, skills: []string{"todoist-manager"}}
+	require.NoError(t, secretsSetCmd(vault, "TODOIST_API_TOKEN", "", false, access, strings.NewReader("tok-1\n")))
+	require.NoError(t, secretsSetCmd(vault, "TODOIST_API_TOKEN", "tok-2", true, accessFlags{}, nil))
+
+	s, ok := vault.Get("TODOIST_API_TOKEN")
+	require.True(t, ok)
+	assert.Equal(t, "tok-2", s.Value)
+	assert.Equal(t, []string{"exec"}, s.Tools)
+	assert.Equal(t, []string{"todoist-manager"}, s.Skills)
+
+	err := secretsSetCmd(vault, "WEATHER_KEY", "k", true, accessFlags{tools: []string{"http_request"}}, nil)
+	assert.ErrorContains(t, err, "--tool http_request needs at least one --host")
+	_, ok = vault.Get("WEATHER_KEY")
+	assert.False(t, ok)
+
+	require.NoError(t, secretsRmCmd(vault, []string{"TODOIST_API_TOKEN"}))
+	assert.ErrorContains(t, secretsRmCmd(vault, []string{"TODOIST_API_TOKEN"}), "not found")
+}
+
+func TestSecretsImport(t *testing.T) {
+	vault := openTestVault(t)
+	envFile := filepath.Join(t.TempDir(), ".env.picoclaw")
+	require.NoError(t, os.WriteFile(envFile, []byte("TODOIST_API_TOKEN=tok\nGIT_REPOS=~/src\nEMPTY=\n"), 0o600))
+
+	err := secretsImportCmd(vault, importOptions{envFile: envFile, names: []string{"MISSING"}})
+	assert.ErrorContains(t, err, "MISSING is not defined")
+
+	require.NoError(t, secretsImportCmd(vault, importOptions{
+		envFile: envFile,
+		names:   []string{"TODOIST_API_TOKEN"},
+		remove:  true,
+		access:  accessFlags{tools: []string{"http_request"}, hosts: []string{"api.todoist.com"}},
+	}))
+	s, ok := vault.Get("TODOIST_API_TOKEN")
+	require.True(t, ok)
+	assert.Equal(t, "tok", s.Value)
+	assert.Equal(t, []string{"api.todoist.com"}, s.Hosts)
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, "GIT_REPOS=~/src\nEMPTY=\n", string(data))
+
+	t.Setenv("PC_IMPORT_TEST", "from-env")
+	require.NoError(t, secretsImportCmd(vault, importOptions{fromEnv: true, names: []string{"PC_IMPORT_TEST"}}))
+	_, ok = vault.Get("PC_IMPORT_TEST")
+	assert.True(t, ok)
+	assert.ErrorContains(t, secretsImportCmd(vault, importOptions{fromEnv: true}), "needs the names")
+}
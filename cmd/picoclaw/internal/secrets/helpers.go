@@ -0,0 +1,205 @@
+package secrets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+// accessFlags select the tools, skills and hosts a secret is granted to.
+type accessFlags struct {
+	tools  []string
+	skills []string
+	hosts  []string
+}
+
+func (a *accessFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&a.tools, "tool", nil, "Tool that receives the secret (exec, http_request, email); repeatable")
+	cmd.Flags().StringSliceVar(&a.skills, "skill", nil, "Skill whose own scripts receive the secret when run by exec; repeatable")
+	cmd.Flags().StringSliceVar(&a.hosts, "host", nil, "Host http_request may send the secret to; repeatable")
+}
+
+func (a accessFlags) empty() bool {
+	return len(a.tools) == 0 && len(a.skills) == 0 && len(a.hosts) == 0
+}
+
+// apply grants s the selected access. Without flags, an existing secret
+// keeps the access it had. A secret for http_request must name its hosts.
+func (a accessFlags) apply(s *secrets.Secret, prev secrets.Secret, existed bool) error {
+	if a.empty() && existed {
+		s.Tools, s.Skills, s.Hosts = prev.Tools, prev.Skills, prev.Hosts
+	} else {
+		s.Tools, s.Skills, s.Hosts = a.tools, a.skills, a.hosts
+	}
+	if slices.Contains(s.Tools, "http_request") && len(s.Hosts) == 0 {
+		return fmt.Errorf("%s: --tool http_request needs at least one --host", s.Name)
+	}
+	return nil
+}
+
+func secretsSetCmd(
+	vault *secrets.Vault,
+	name, value string,
+	hasValue bool,
+	access accessFlags,
+	stdin io.Reader,
+) error {
+	if !hasValue {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read value: %w", err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	s := secrets.Secret{Name: name, Value: value}
+	prev, existed := vault.Get(name)
+	if err := access.apply(&s, prev, existed); err != nil {
+		return err
+	}
+	if err := vault.Set(s); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Saved secret %s\n", name)
+	printUnusedHint(s)
+	return nil
+}
+
+func secretsListCmd(vault *secrets.Vault) {
+	list := vault.List()
+	if len(list) == 0 {
+		fmt.Println("No secrets. Add one with: picoclaw secrets set NAME --tool exec")
+		return
+	}
+
+	fmt.Println("\nSecrets:")
+	fmt.Println("--------")
+	for _, s := range list {
+		fmt.Printf("  %s  (updated %s)\n", s.Name, s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		if len(s.Tools) > 0 {
+			fmt.Printf("    tools:  %s\n", strings.Join(s.Tools, ", "))
+		}
+		if len(s.Skills) > 0 {
+			fmt.Printf("    skills: %s\n", strings.Join(s.Skills, ", "))
+		}
+		if len(s.Hosts) > 0 {
+			fmt.Printf("    hosts:  %s\n", strings.Join(s.Hosts, ", "))
+		}
+	}
+}
+
+func secretsRmCmd(vault *secrets.Vault, names []string) error {
+	for _, name := range names {
+		removed, err := vault.Remove(name)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("secret %s not found", name)
+		}
+		fmt.Printf("✓ Removed secret %s\n", name)
+	}
+	return nil
+}
+
+type importOptions struct {
+	envFile string
+	fromEnv bool
+	remove  bool
+	names   []string
+	access  accessFlags
+}
+
+func secretsImportCmd(vault *secrets.Vault, opts importOptions) error {
+	values := make(map[string]string)
+	if opts.fromEnv {
+		if len(opts.names) == 0 {
+			return fmt.Errorf("--from-env needs the names of the variables to import")
+		}
+		for _, name := range opts.names {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return fmt.Errorf("%s is not set", name)
+			}
+			values[name] = value
+		}
+	} else {
+		vars, err := config.ReadEnvFile(opts.envFile)
+		if err != nil {
+			return fmt.Errorf("read env file: %w", err)
+		}
+		if len(opts.names) == 0 {
+			values = vars
+		}
+		for _, name := range opts.names {
+			value, ok := vars[name]
+			if !ok {
+				return fmt.Errorf("%s is not defined in %s", name, opts.envFile)
+			}
+			values[name] = value
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var imported []string
+	for _, name := range names {
+		if values[name] == "" {
+			fmt.Printf("  skipped %s (empty)\n", name)
+			continue
+		}
+		s := secrets.Secret{Name: name, Value: values[name]}
+		prev, existed := vault.Get(name)
+		if err := opts.access.apply(&s, prev, existed); err != nil {
+			return err
+		}
+		if err := vault.Set(s); err != nil {
+			return err
+		}
+		imported = append(imported, name)
+		fmt.Printf("✓ Imported %s\n", name)
+		printUnusedHint(s)
+	}
+	if len(imported) == 0 {
+		fmt.Println("Nothing to import.")
+		return nil
+	}
+
+	if opts.remove && !opts.fromEnv {
+		if err := config.RemoveEnvFileKeys(opts.envFile, imported); err != nil {
+			return fmt.Errorf("remove from env file: %w", err)
+		}
+		fmt.Printf("✓ Removed %d variable(s) from %s\n", len(imported), opts.envFile)
+	} else if !opts.fromEnv {
+		fmt.Printf("Remove them from %s (or rerun with --remove) so that commands no longer inherit them.\n",
+			opts.envFile)
+	}
+	return nil
+}
+
+func printUnusedHint(s secrets.Secret) {
+	if len(s.Tools) == 0 && len(s.Skills) == 0 {
+		fmt.Printf("  %s is not granted to any tool or skill yet; it is only masked in output. "+
+			"Use --tool or --skill to grant it.\n", s.Name)
+	}
+}
+
+func defaultEnvFile(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), ".env.picoclaw")
+}
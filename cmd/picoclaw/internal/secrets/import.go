@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func newImportCommand(vault func() *secrets.Vault) *cobra.Command {
+	var (
+		opts   importOptions
+		access accessFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [NAME...]",
+		Short: "Import secrets from an env file or the environment",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if opts.envFile == "" && !opts.fromEnv {
+				opts.envFile = defaultEnvFile(internal.GetConfigPath())
+			}
+			opts.names = args
+			opts.access = access
+			return secretsImportCmd(vault(), opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.envFile, "env-file", "", "Env file to read (default: ~/.picoclaw/.env.picoclaw)")
+	cmd.Flags().BoolVar(&opts.fromEnv, "from-env", false, "Read the named variables from the environment instead")
+	cmd.Flags().BoolVar(&opts.remove, "remove", false, "Remove imported variables from the env file")
+	access.register(cmd)
+
+	return cmd
+}
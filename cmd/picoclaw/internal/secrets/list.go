@@ -0,0 +1,19 @@
+package secrets
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func newListCommand(vault func() *secrets.Vault) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List secrets and who may use them (values are never shown)",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			secretsListCmd(vault())
+			return nil
+		},
+	}
+}
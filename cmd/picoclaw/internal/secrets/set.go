@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func newSetCommand(vault func() *secrets.Vault) *cobra.Command {
+	var (
+		value  string
+		access accessFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Add or replace a secret (the value is read from stdin unless --value is given)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return secretsSetCmd(vault(), args[0], value, cmd.Flags().Changed("value"), access, os.Stdin)
+		},
+	}
+
+	cmd.Flags().StringVar(&value, "value", "", "Secret value (visible in shell history; prefer stdin)")
+	access.register(cmd)
+
+	return cmd
+}
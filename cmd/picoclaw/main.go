@@ -15,13 +15,18 @@ import (
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/agent"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/auth"
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/checkpoints"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/cron"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/gateway"
+	mcpcmd "github.com/sipeed/picoclaw/cmd/picoclaw/internal/mcp"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/migrate"
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/models"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/onboard"
+	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/secrets"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/skills"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/status"
 	"github.com/sipeed/picoclaw/cmd/picoclaw/internal/version"
+	"github.com/sipeed/picoclaw/pkg/mcp"
 )
 
 func NewPicoclawCommand() *cobra.Command {
@@ -38,9 +43,13 @@ func NewPicoclawCommand() *cobra.Command {
 		agent.NewAgentCommand(),
 		auth.NewAuthCommand(),
 		gateway.NewGatewayCommand(),
+		mcpcmd.NewMCPCommand(),
 		status.NewStatusCommand(),
 		cron.NewCronCommand(),
+		checkpoints.NewCheckpointsCommand(),
 		migrate.NewMigrateCommand(),
+		models.NewModelsCommand(),
+		secrets.NewSecretsCommand(),
 		skills.NewSkillsCommand(),
 		version.NewVersionCommand(),
 	)
@@ -49,6 +58,7 @@ func NewPicoclawCommand() *cobra.Command {
 }
 
 func main() {
+	mcp.ClientVersion = internal.GetVersion()
 	cmd := NewPicoclawCommand()
 	if err := cmd.Execute(); err != nil {
 		os.Exit(1)
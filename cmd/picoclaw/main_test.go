@@ -35,10 +35,14 @@ func TestNewPicoclawCommand(t *testing.T) {
 	allowedCommands := []string{
 		"agent",
 		"auth",
+		"checkpoints",
 		"cron",
 		"gateway",
+		"mcp",
 		"migrate",
+		"models",
 		"onboard",
+		"secrets",
 		"skills",
 		"status",
 		"version",
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// handleApproveCommand handles /approve, which lists the git commits and
+// checkouts waiting for approval in the session, and /approve <code>,
+// which runs one. The git tool only queues these changes, so they need a
+// message from the user: fromChannel is false for messages that cron jobs,
+// the CLI or MCP clients pass to the loop, which the agent could have
+// written itself.
+func handleApproveCommand(
+	ctx context.Context,
+	agent *AgentInstance,
+	sessionKey, content string,
+	fromChannel bool,
+) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] != "/approve" {
+		return "", false
+	}
+	if !fromChannel {
+		return "/approve only works in a message you send in a chat; scheduled jobs, the CLI and MCP clients " +
+			"cannot approve changes.", true
+	}
+
+	var gitTool *tools.GitTool
+	if tool, ok := agent.Tools.Get("git"); ok {
+		gitTool, _ = tool.(*tools.GitTool)
+	}
+	if gitTool == nil {
+		return "Nothing to approve: the git tool is not enabled.", true
+	}
+
+	if len(fields) == 1 {
+		pending := gitTool.Pending(sessionKey)
+		if pending == "" {
+			return "Nothing to approve.", true
+		}
+		return "Waiting for approval (send /approve <code>):\n" + pending, true
+	}
+	if len(fields) > 2 {
+		return "Usage: /approve [code]", true
+	}
+
+	output, err := gitTool.Approve(ctx, sessionKey, fields[1])
+	if err != nil {
+		return fmt.Sprintf("Failed to approve: %v", err), true
+	}
+	return output, true
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestHandleApproveCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+	ctx := context.Background()
+
+	if _, handled := handleApproveCommand(ctx, agent, "chat-1", "/approved", true); handled {
+		t.Error("expected /approved not to be handled")
+	}
+	if response, _ := handleApproveCommand(ctx, agent, "chat-1", "/approve", true); !strings.Contains(response, "not enabled") {
+		t.Errorf("unexpected response without the git tool: %q", response)
+	}
+
+	gitTool, err := tools.NewGitTool("", []string{t.TempDir()}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.Tools.Register(gitTool)
+	if response, _ := handleApproveCommand(ctx, agent, "chat-1", "/approve", true); response != "Nothing to approve." {
+		t.Errorf("unexpected /approve response %q", response)
+	}
+	if response, _ := handleApproveCommand(ctx, agent, "chat-1", "/approve 1234", true); !strings.HasPrefix(response, "Failed to approve: ") {
+		t.Errorf("unexpected response for an unknown code: %q", response)
+	}
+
+	queueCheckout(t, gitTool, "chat-1")
+	if response, _ := handleApproveCommand(ctx, agent, "chat-2", "/approve", true); response != "Nothing to approve." {
+		t.Errorf("another session sees the pending change: %q", response)
+	}
+	if response, _ := handleApproveCommand(ctx, agent, "chat-1", "/approve", false); !strings.Contains(response, "only works") {
+		t.Errorf("unexpected response outside a channel: %q", response)
+	}
+}
+
+func TestProcessDirectCannotApprove(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Tools: config.ToolsConfig{Git: config.GitToolConfig{Repos: []string{t.TempDir()}, AllowWrite: true}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	agent := al.registry.GetDefaultAgent()
+	tool, ok := agent.Tools.Get("git")
+	if !ok {
+		t.Fatal("git tool not registered")
+	}
+	gitTool := tool.(*tools.GitTool)
+
+	// A cron job with deliver=false runs its message through the loop.
+	msg := bus.InboundMessage{Channel: "telegram", ChatID: "chat1", SessionKey: "cron-job"}
+	_, sessionKey, _ := al.routeMessage(msg)
+	code := queueCheckout(t, gitTool, sessionKey)
+	response, err := al.ProcessDirectWithChannel(context.Background(), "/approve "+code, "cron-job", "telegram", "chat1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "only works") {
+		t.Errorf("cron-originated /approve was not refused: %q", response)
+	}
+	if !strings.HasPrefix(gitTool.Pending(sessionKey), code+": checkout") {
+		t.Error("the pending change was consumed")
+	}
+}
+
+// queueCheckout queues a branch switch in session and returns its code.
+func queueCheckout(t *testing.T, gitTool *tools.GitTool, session string) string {
+	t.Helper()
+	ctx := tools.WithSessionKey(context.Background(), session)
+	result := gitTool.Execute(ctx, map[string]any{"action": "checkout", "branch": "next"})
+	if result.IsError {
+		t.Fatalf("checkout was not queued: %s", result.ForLLM)
+	}
+	_, code, _ := strings.Cut(result.ForLLM, "/approve ")
+	return strings.Fields(code)[0]
+}
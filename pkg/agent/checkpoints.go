@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleCheckpointCommand handles /undo and /changes, which act on the
+// file snapshots of the routed agent and session.
+func handleCheckpointCommand(agent *AgentInstance, sessionKey, content string) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || (fields[0] != "/undo" && fields[0] != "/changes") {
+		return "", false
+	}
+	if agent.Checkpoints == nil {
+		return "Checkpoints are disabled (tools.checkpoints.enabled).", true
+	}
+
+	if fields[0] == "/undo" {
+		turn, restored, err := agent.Checkpoints.Undo(sessionKey)
+		if err != nil {
+			return fmt.Sprintf("Undo failed: %v", err), true
+		}
+		if turn == nil {
+			return "Nothing to undo: no file changes recorded in this session.", true
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Reverted %d file(s) changed at %s (checkpoint %s):\n",
+			len(restored), turn.Time.Format("15:04:05"), turn.ID)
+		for _, path := range restored {
+			sb.WriteString("  " + agent.Checkpoints.Rel(path) + "\n")
+		}
+		return strings.TrimRight(sb.String(), "\n"), true
+	}
+
+	changes, err := agent.Checkpoints.Changes(sessionKey)
+	if err != nil {
+		return fmt.Sprintf("Failed to list changes: %v", err), true
+	}
+	if len(changes) == 0 {
+		return "No files changed in this session.", true
+	}
+	var sb strings.Builder
+	sb.WriteString("Files changed in this session:\n")
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "  %s (%s", agent.Checkpoints.Rel(c.Path), c.Status)
+		if c.Turns > 1 {
+			fmt.Fprintf(&sb, ", %d turns", c.Turns)
+		}
+		sb.WriteString(")\n")
+	}
+	sb.WriteString("Use /undo to revert the last turn's changes.")
+	return sb.String(), true
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHandleCheckpointCommand_UndoAndChanges(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = workspace
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+	if agent.Checkpoints == nil {
+		t.Fatal("checkpoints should be enabled by default")
+	}
+
+	notes := filepath.Join(workspace, "notes.md")
+	if err := os.WriteFile(notes, []byte("original\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := agent.Checkpoints.BeginTurn("chat-1")
+	turnTools := agent.Tools.WithSnapshotter(recorder)
+	for name, args := range map[string]map[string]any{
+		"edit_file":  {"path": "notes.md", "old_text": "original", "new_text": "edited"},
+		"write_file": {"path": "todo.txt", "content": "buy milk\n"},
+	} {
+		if result := turnTools.Execute(context.Background(), name, args); result.IsError {
+			t.Fatalf("%s failed: %s", name, result.ForLLM)
+		}
+	}
+	if err := recorder.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	response, handled := handleCheckpointCommand(agent, "chat-1", "/changes")
+	if !handled {
+		t.Fatal("/changes was not handled")
+	}
+	for _, want := range []string{"notes.md (modified)", "todo.txt (created)"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("/changes response %q does not contain %q", response, want)
+		}
+	}
+
+	response, _ = handleCheckpointCommand(agent, "chat-1", "/undo")
+	if !strings.HasPrefix(response, "Reverted 2 file(s)") {
+		t.Errorf("unexpected /undo response %q", response)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "original\n" {
+		t.Errorf("notes.md = %q after undo", data)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "todo.txt")); !os.IsNotExist(err) {
+		t.Errorf("todo.txt should have been removed by undo, stat error: %v", err)
+	}
+
+	response, _ = handleCheckpointCommand(agent, "chat-1", "/undo")
+	if !strings.HasPrefix(response, "Nothing to undo") {
+		t.Errorf("unexpected second /undo response %q", response)
+	}
+	if _, handled := handleCheckpointCommand(agent, "chat-1", "/undone is not a command"); handled {
+		t.Error("only /undo and /changes should be handled")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/plan"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/skills"
 )
@@ -21,6 +22,7 @@ type ContextBuilder struct {
 	workspace    string
 	skillsLoader *skills.SkillsLoader
 	memory       *MemoryStore
+	plans        *plan.Store // nil when plans are not tracked
 
 	// Cache for system prompt to avoid rebuilding on every call.
 	// This fixes issue #607: repeated reprocessing of the entire context.
@@ -376,6 +378,58 @@ func (cb *ContextBuilder) buildDynamicContext(channel, chatID string) string {
 	return sb.String()
 }
 
+// planHeader starts the system prompt block that shows the open plan.
+const planHeader = "## Current Plan"
+
+// SetPlanStore makes BuildMessages show the open plan of the store's
+// current session.
+func (cb *ContextBuilder) SetPlanStore(store *plan.Store) {
+	cb.plans = store
+}
+
+// buildPlanContext returns the open plan of sessionKey, or "" if there is
+// none or it has been completed.
+func (cb *ContextBuilder) buildPlanContext(sessionKey string) string {
+	if cb.plans == nil {
+		return ""
+	}
+	p, err := cb.plans.Get(sessionKey)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to load plan", map[string]any{"error": err.Error()})
+		return ""
+	}
+	if p == nil || p.Completed {
+		return ""
+	}
+	return fmt.Sprintf("%s\n%s\n\nKeep this plan current with the plan tool as you work, "+
+		"and complete it when every item is done.", planHeader, p.Format())
+}
+
+// UpdatePlanContext returns the system message with its plan block replaced
+// by the current plan of sessionKey, so that plan changes made by tool calls
+// are visible in the next iteration.
+func (cb *ContextBuilder) UpdatePlanContext(system providers.Message, sessionKey string) providers.Message {
+	if cb.plans == nil || system.Role != "system" {
+		return system
+	}
+	parts := make([]providers.ContentBlock, 0, len(system.SystemParts)+1)
+	for _, block := range system.SystemParts {
+		if !strings.HasPrefix(block.Text, planHeader) {
+			parts = append(parts, block)
+		}
+	}
+	if planText := cb.buildPlanContext(sessionKey); planText != "" {
+		parts = append(parts, providers.ContentBlock{Type: "text", Text: planText})
+	}
+	texts := make([]string, len(parts))
+	for i, block := range parts {
+		texts[i] = block.Text
+	}
+	system.SystemParts = parts
+	system.Content = strings.Join(texts, "\n\n---\n\n")
+	return system
+}
+
 func (cb *ContextBuilder) extractRequestedSkills(currentMessage string) []string {
 	msg := strings.ToLower(strings.TrimSpace(currentMessage))
 	if msg == "" {
@@ -544,6 +598,14 @@ func (cb *ContextBuilder) BuildMessages(
 		contentBlocks = append(contentBlocks, providers.ContentBlock{Type: "text", Text: summaryText})
 	}
 
+	// The open plan goes last; UpdatePlanContext replaces it between iterations.
+	if cb.plans != nil {
+		if planText := cb.buildPlanContext(cb.plans.Session()); planText != "" {
+			stringParts = append(stringParts, planText)
+			contentBlocks = append(contentBlocks, providers.ContentBlock{Type: "text", Text: planText})
+		}
+	}
+
 	fullSystemPrompt := strings.Join(stringParts, "\n\n---\n\n")
 
 	// Log system prompt summary for debugging (debug mode only).
@@ -688,6 +750,11 @@ func (cb *ContextBuilder) AddAssistantMessage(
 	return messages
 }
 
+// ListSkills returns the skills available to the agent.
+func (cb *ContextBuilder) ListSkills() []skills.SkillInfo {
+	return cb.skillsLoader.ListSkills()
+}
+
 // GetSkillsInfo returns information about loaded skills.
 func (cb *ContextBuilder) GetSkillsInfo() map[string]any {
 	allSkills := cb.skillsLoader.ListSkills()
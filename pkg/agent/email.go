@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/email"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// emailOAuthScopes are sent when refreshing XOAUTH2 tokens for the common
+// providers if the account config names no scopes.
+var emailOAuthScopes = map[string]string{
+	"googleapis.com":        "https://mail.google.com/",
+	"login.microsoftonline": "https://outlook.office.com/IMAP.AccessAsUser.All https://outlook.office.com/SMTP.Send offline_access",
+}
+
+// emailAccounts builds the accounts of the email tools. It returns all
+// accounts and the ones email_send may use.
+func emailAccounts(cfg *config.Config, vault *secrets.Vault) (all, sending []*email.Account, err error) {
+	seen := make(map[string]bool)
+	for _, ac := range cfg.Tools.Email.Accounts {
+		if ac.Name == "" || ac.Address == "" {
+			return nil, nil, fmt.Errorf("email accounts need a name and an address")
+		}
+		if seen[ac.Name] {
+			return nil, nil, fmt.Errorf("duplicate email account %q", ac.Name)
+		}
+		seen[ac.Name] = true
+
+		account := &email.Account{Name: ac.Name, Address: ac.Address, Username: ac.Username}
+		if account.IMAP, err = emailServer(ac.IMAP); err != nil {
+			return nil, nil, fmt.Errorf("email account %s imap: %w", ac.Name, err)
+		}
+		if account.SMTP, err = emailServer(ac.SMTP); err != nil {
+			return nil, nil, fmt.Errorf("email account %s smtp: %w", ac.Name, err)
+		}
+
+		switch ac.Auth {
+		case "", "password":
+			account.Password = emailPassword(ac, vault)
+			if account.Password == "" {
+				return nil, nil, fmt.Errorf("email account %s has no password (set password or password_env)", ac.Name)
+			}
+		case "xoauth2":
+			account.Token = emailTokenSource(ac)
+		default:
+			return nil, nil, fmt.Errorf("email account %s: unknown auth %q (use password or xoauth2)", ac.Name, ac.Auth)
+		}
+
+		all = append(all, account)
+		if !ac.ReadOnly && account.SMTP.Host != "" {
+			sending = append(sending, account)
+		}
+	}
+	return all, sending, nil
+}
+
+func emailServer(sc config.EmailServerConfig) (email.Server, error) {
+	server := email.Server{Host: sc.Host, Port: sc.Port, Security: email.Security(sc.Security)}
+	switch server.Security {
+	case "":
+		server.Security = email.SecurityTLS
+	case email.SecurityTLS, email.SecurityStartTLS, email.SecurityNone:
+	default:
+		return server, fmt.Errorf("unknown security %q (use tls, starttls or none)", sc.Security)
+	}
+	return server, nil
+}
+
+// emailPassword resolves an account's app password. password_env is looked
+// up in the environment first, then in the vault if the secret is granted
+// to the email tools.
+func emailPassword(ac config.EmailAccountConfig, vault *secrets.Vault) string {
+	if ac.PasswordEnv == "" {
+		return ac.Password
+	}
+	if value := os.Getenv(ac.PasswordEnv); value != "" {
+		return value
+	}
+	if vault != nil {
+		if s, ok := vault.Get(ac.PasswordEnv); ok && s.AllowsTool("email") {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// emailTokenSource returns the XOAUTH2 access token of an account, stored
+// by "picoclaw auth login --provider email-<name>" and refreshed through
+// the account's OAuth client.
+func emailTokenSource(ac config.EmailAccountConfig) func(context.Context) (string, error) {
+	provider := "email-" + ac.Name
+	oauthCfg := auth.OAuthProviderConfig{
+		TokenURL:     ac.OAuth.TokenURL,
+		ClientID:     ac.OAuth.ClientID,
+		ClientSecret: ac.OAuth.ClientSecret,
+		RefreshScope: ac.OAuth.Scopes,
+	}
+	if oauthCfg.RefreshScope == "" {
+		for host, scopes := range emailOAuthScopes {
+			if strings.Contains(ac.OAuth.TokenURL, host) {
+				oauthCfg.RefreshScope = scopes
+			}
+		}
+	}
+	refreshToken := ac.OAuth.RefreshToken
+	if ac.OAuth.RefreshTokenEnv != "" {
+		refreshToken = os.Getenv(ac.OAuth.RefreshTokenEnv)
+	}
+	return func(context.Context) (string, error) {
+		return auth.AccessToken(provider, oauthCfg, refreshToken)
+	}
+}
+
+// registerEmailTools gives the agent the email tools when accounts are
+// configured.
+func registerEmailTools(cfg *config.Config, agent *AgentInstance, all, sending []*email.Account) {
+	if len(all) == 0 {
+		return
+	}
+	restrict := cfg.Agents.Defaults.RestrictToWorkspace
+	agent.Tools.Register(tools.NewEmailSearchTool(all))
+	agent.Tools.Register(tools.NewEmailReadTool(all, agent.Workspace, restrict, cfg.Tools.Email.AttachmentsDir))
+	if len(sending) > 0 {
+		agent.Tools.Register(tools.NewEmailSendTool(sending, agent.Workspace, restrict))
+	}
+}
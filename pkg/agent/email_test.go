@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/email"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+)
+
+func TestEmailAccounts(t *testing.T) {
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Set(secrets.Secret{Name: "WORK_MAIL", Value: "vault-pass", Tools: []string{"email"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Set(secrets.Secret{Name: "OTHER_MAIL", Value: "not-for-email", Tools: []string{"exec"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Tools.Email.Accounts = []config.EmailAccountConfig{
+		{
+			Name:        "work",
+			Address:     "Bob <bob@example.com>",
+			PasswordEnv: "WORK_MAIL",
+			IMAP:        config.EmailServerConfig{Host: "imap.example.com"},
+			SMTP:        config.EmailServerConfig{Host: "smtp.example.com", Port: 587, Security: "starttls"},
+		},
+		{
+			Name:     "gmail",
+			Address:  "bob@gmail.com",
+			Auth:     "xoauth2",
+			IMAP:     config.EmailServerConfig{Host: "imap.gmail.com"},
+			SMTP:     config.EmailServerConfig{Host: "smtp.gmail.com"},
+			ReadOnly: true,
+		},
+	}
+	all, sending, err := emailAccounts(cfg, vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || len(sending) != 1 || sending[0].Name != "work" {
+		t.Fatalf("all = %d, sending = %v", len(all), sending)
+	}
+	work := all[0]
+	if work.Password != "vault-pass" || work.Token != nil {
+		t.Errorf("work password = %q", work.Password)
+	}
+	if work.IMAP.Security != email.SecurityTLS || work.SMTP.Security != email.SecurityStartTLS {
+		t.Errorf("security = %s / %s", work.IMAP.Security, work.SMTP.Security)
+	}
+	if all[1].Token == nil || all[1].Password != "" {
+		t.Error("xoauth2 account should authenticate with a token")
+	}
+
+	cfg.Tools.Email.Accounts[0].PasswordEnv = "OTHER_MAIL"
+	if _, _, err := emailAccounts(cfg, vault); err == nil || !strings.Contains(err.Error(), "no password") {
+		t.Errorf("vault secret not granted to email: err = %v", err)
+	}
+
+	cfg.Tools.Email.Accounts[0].PasswordEnv = ""
+	cfg.Tools.Email.Accounts[0].Password = "inline"
+	cfg.Tools.Email.Accounts[1].Name = "work"
+	if _, _, err := emailAccounts(cfg, vault); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("duplicate names: err = %v", err)
+	}
+}
+
+func TestRegisterSharedTools_Email(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Tools.Email.Accounts = []config.EmailAccountConfig{{
+		Name:     "work",
+		Address:  "bob@example.com",
+		Password: "app-password",
+		IMAP:     config.EmailServerConfig{Host: "imap.example.com"},
+		ReadOnly: true,
+	}}
+	registry := NewAgentRegistry(cfg, &mockProvider{})
+	all, sending, err := emailAccounts(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := registry.GetDefaultAgent()
+	registerEmailTools(cfg, agent, all, sending)
+
+	for _, name := range []string{"email_search", "email_read"} {
+		if _, ok := agent.Tools.Get(name); !ok {
+			t.Errorf("%s not registered", name)
+		}
+	}
+	if _, ok := agent.Tools.Get("email_send"); ok {
+		t.Error("email_send registered for a read-only account")
+	}
+}
@@ -1,11 +1,14 @@
 package agent
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/sipeed/picoclaw/pkg/checkpoint"
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/plan"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/session"
@@ -28,7 +31,12 @@ type AgentInstance struct {
 	Sessions       *session.SessionManager
 	ContextBuilder *ContextBuilder
 	Tools          *tools.ToolRegistry
+	Processes      *tools.ProcessManager
+	Checkpoints    *checkpoint.Store // nil when checkpoints are disabled
+	Plans          *plan.Store
+	SubagentTasks  *tools.SubagentManager // Runs this agent's spawn and subagent calls
 	Subagents      *config.SubagentsConfig
+	Egress         *config.EgressRules
 	SkillsFilter   []string
 	Candidates     []providers.FallbackCandidate
 }
@@ -51,24 +59,47 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewReadFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewWriteFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewListDirTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewSearchFilesTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewGlobFilesTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewExecToolWithConfig(workspace, restrict, cfg))
 	toolsRegistry.Register(tools.NewEditFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict))
+	toolsRegistry.Register(tools.NewApplyPatchTool(workspace, restrict))
+
+	var checkpoints *checkpoint.Store
+	if cfg == nil || cfg.Tools.Checkpoints.Enabled {
+		checkpoints = checkpoint.NewStore(workspace)
+		if cfg != nil {
+			checkpoints.SetMaxBytes(int64(cfg.Tools.Checkpoints.MaxSizeMB) << 20)
+		}
+		for _, name := range toolsRegistry.List() {
+			tool, _ := toolsRegistry.Get(name)
+			if st, ok := tool.(tools.SnapshottingTool); ok {
+				st.SetSnapshotter(checkpoints)
+			}
+		}
+	}
 
 	sessionsDir := filepath.Join(workspace, "sessions")
 	sessionsManager := session.NewSessionManager(sessionsDir)
 
+	plans := plan.NewStore(workspace)
+	toolsRegistry.Register(tools.NewPlanTool(plans))
+
 	contextBuilder := NewContextBuilder(workspace)
+	contextBuilder.SetPlanStore(plans)
 
 	agentID := routing.DefaultAgentID
 	agentName := ""
 	var subagents *config.SubagentsConfig
+	var egress *config.EgressRules
 	var skillsFilter []string
 
 	if agentCfg != nil {
 		agentID = routing.NormalizeAgentID(agentCfg.ID)
 		agentName = agentCfg.Name
 		subagents = agentCfg.Subagents
+		egress = agentCfg.Egress
 		skillsFilter = agentCfg.Skills
 	}
 
@@ -108,12 +139,42 @@ func NewAgentInstance(
 		Sessions:       sessionsManager,
 		ContextBuilder: contextBuilder,
 		Tools:          toolsRegistry,
+		Checkpoints:    checkpoints,
+		Plans:          plans,
 		Subagents:      subagents,
+		Egress:         egress,
 		SkillsFilter:   skillsFilter,
 		Candidates:     candidates,
 	}
 }
 
+// withTools returns a copy of the agent that uses registry, for a turn that
+// must not share per-turn tool state with the agent's other turns.
+func (a *AgentInstance) withTools(registry *tools.ToolRegistry) *AgentInstance {
+	c := *a
+	c.Tools = registry
+	return &c
+}
+
+// AgentWorkspace returns the workspace of the configured agent agentID,
+// resolved the way the agent registry does. An empty ID selects the
+// default workspace.
+func AgentWorkspace(cfg *config.Config, agentID string) (string, error) {
+	if strings.TrimSpace(agentID) == "" {
+		return cfg.WorkspacePath(), nil
+	}
+	id := routing.NormalizeAgentID(agentID)
+	for i := range cfg.Agents.List {
+		if routing.NormalizeAgentID(cfg.Agents.List[i].ID) == id {
+			return resolveAgentWorkspace(&cfg.Agents.List[i], &cfg.Agents.Defaults), nil
+		}
+	}
+	if len(cfg.Agents.List) == 0 && id == routing.DefaultAgentID {
+		return cfg.WorkspacePath(), nil
+	}
+	return "", fmt.Errorf("agent %q is not configured", agentID)
+}
+
 // resolveAgentWorkspace determines the workspace directory for an agent.
 func resolveAgentWorkspace(agentCfg *config.AgentConfig, defaults *config.AgentDefaults) string {
 	if agentCfg != nil && strings.TrimSpace(agentCfg.Workspace) != "" {
@@ -93,3 +93,25 @@ func TestNewAgentInstance_DefaultsTemperatureWhenUnset(t *testing.T) {
 		t.Fatalf("Temperature = %f, want %f", agent.Temperature, 0.7)
 	}
 }
+
+func TestAgentWorkspace(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: "/srv/main"},
+			List: []config.AgentConfig{
+				{ID: "main", Default: true},
+				{ID: "Research", Workspace: "/srv/research"},
+			},
+		},
+	}
+
+	for id, want := range map[string]string{"": "/srv/main", "main": "/srv/main", "research": "/srv/research"} {
+		got, err := AgentWorkspace(cfg, id)
+		if err != nil || got != want {
+			t.Errorf("AgentWorkspace(%q) = %q, %v; want %q", id, got, err, want)
+		}
+	}
+	if _, err := AgentWorkspace(cfg, "missing"); err == nil {
+		t.Error("expected an error for an agent that is not configured")
+	}
+}
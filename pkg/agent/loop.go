@@ -10,6 +10,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,8 +23,10 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/mcp"
 	"github.com/sipeed/picoclaw/pkg/providers"
 	"github.com/sipeed/picoclaw/pkg/routing"
+	"github.com/sipeed/picoclaw/pkg/secrets"
 	"github.com/sipeed/picoclaw/pkg/skills"
 	"github.com/sipeed/picoclaw/pkg/state"
 	"github.com/sipeed/picoclaw/pkg/tools"
@@ -37,7 +41,9 @@ type AgentLoop struct {
 	running        atomic.Bool
 	summarizing    sync.Map
 	fallback       *providers.FallbackChain
+	cooldown       *providers.CooldownTracker
 	channelManager *channels.Manager
+	mcp            *mcp.Manager
 }
 
 // processOptions configures how a message is processed
@@ -50,17 +56,16 @@ type processOptions struct {
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SystemPrompt    string // Added to the system prompt, e.g. for subagent turns
 }
 
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
 	registry := NewAgentRegistry(cfg, provider)
 
 	// Register shared tools to all agents
-	registerSharedTools(cfg, msgBus, registry, provider)
-
-	// Set up shared fallback chain
-	cooldown := providers.NewCooldownTracker()
-	fallbackChain := providers.NewFallbackChain(cooldown)
+	vault := openVault()
+	registerSharedTools(cfg, msgBus, registry, provider, vault)
+	protectSecrets(cfg, msgBus, registry, vault)
 
 	// Create state manager using default agent's workspace for channel recording
 	defaultAgent := registry.GetDefaultAgent()
@@ -69,23 +74,112 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		stateManager = state.NewManager(defaultAgent.Workspace)
 	}
 
-	return &AgentLoop{
+	// Set up shared fallback chain; cooldowns persist across restarts so a
+	// provider that was rate limited or out of credit is not retried at once.
+	var cooldown *providers.CooldownTracker
+	if defaultAgent != nil {
+		cooldown = providers.NewCooldownTrackerWithStore(state.CooldownPath(defaultAgent.Workspace))
+	} else {
+		cooldown = providers.NewCooldownTracker()
+	}
+	fallbackChain := providers.NewFallbackChain(cooldown)
+
+	al := &AgentLoop{
 		bus:         msgBus,
 		cfg:         cfg,
 		registry:    registry,
 		state:       stateManager,
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
+		cooldown:    cooldown,
+		mcp:         startMCP(cfg, registry),
+	}
+
+	// Subagents run as turns of registered agents, so they need the loop.
+	for _, agentID := range registry.ListAgentIDs() {
+		if agent, ok := registry.GetAgent(agentID); ok && agent.SubagentTasks != nil {
+			agent.SubagentTasks.SetRunner(agent.ID, al.subagentRunner(agent.ID))
+		}
+	}
+
+	return al
+}
+
+// startMCP connects to the configured MCP servers in the background and
+// registers their tools with each agent. It does not wait for them; the
+// first turn does, through WaitForMCP. Servers that are unreachable keep
+// retrying and register their tools once they connect.
+func startMCP(cfg *config.Config, registry *AgentRegistry) *mcp.Manager {
+	if len(cfg.MCP.Servers) == 0 {
+		return nil
+	}
+	manager := mcp.NewManager(cfg.MCP)
+	for _, agentID := range registry.ListAgentIDs() {
+		if agent, ok := registry.GetAgent(agentID); ok {
+			manager.AddRegistry(agentID, agent.Tools)
+		}
+	}
+	manager.Start(context.Background())
+	return manager
+}
+
+// WaitForMCP waits until every MCP server has connected or failed its
+// first attempt, bounded by ctx and a startup timeout. Once startup is over
+// it returns immediately.
+func (al *AgentLoop) WaitForMCP(ctx context.Context) {
+	if al.mcp != nil {
+		al.mcp.WaitReady(ctx)
 	}
 }
 
+// newEgressGuard builds the egress guard of an agent's HTTP tool from the
+// global, per-tool and per-agent rules.
+func newEgressGuard(cfg *config.Config, agent *AgentInstance, tool string) (*tools.EgressGuard, error) {
+	rules := cfg.Tools.Egress.RulesFor(tool, agent.Egress)
+	return tools.NewEgressGuard(tools.EgressPolicy{
+		AllowPrivate: cfg.Tools.Egress.AllowPrivate,
+		Allow:        rules.Allow,
+		Deny:         rules.Deny,
+	})
+}
+
+// gitRepos returns the repositories of the git tool: tools.git.repos, or
+// else the entries of GIT_REPOS that are local directories. GIT_REPOS may
+// also name GitHub owner/repo references, which the tool cannot read.
+func gitRepos(cfg *config.Config) []string {
+	if len(cfg.Tools.Git.Repos) > 0 {
+		return cfg.Tools.Git.Repos
+	}
+	var repos []string
+	for _, entry := range strings.Split(os.Getenv("GIT_REPOS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		path := entry
+		if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+			path = filepath.Join(home, path[2:])
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			repos = append(repos, path)
+		}
+	}
+	return repos
+}
+
 // registerSharedTools registers tools that are shared across all agents (web, message, spawn).
 func registerSharedTools(
 	cfg *config.Config,
 	msgBus *bus.MessageBus,
 	registry *AgentRegistry,
 	provider providers.LLMProvider,
+	vault *secrets.Vault,
 ) {
+	emailAll, emailSending, err := emailAccounts(cfg, vault)
+	if err != nil {
+		logger.ErrorCF("agent", "Invalid email config, email tools disabled", map[string]any{"error": err.Error()})
+	}
+
 	for _, agentID := range registry.ListAgentIDs() {
 		agent, ok := registry.GetAgent(agentID)
 		if !ok {
@@ -110,7 +204,33 @@ func registerSharedTools(
 		}); searchTool != nil {
 			agent.Tools.Register(searchTool)
 		}
-		agent.Tools.Register(tools.NewWebFetchToolWithProxy(50000, cfg.Tools.Web.Proxy))
+		fetchTool := tools.NewWebFetchToolWithProxy(50000, cfg.Tools.Web.Proxy)
+		if guard, err := newEgressGuard(cfg, agent, fetchTool.Name()); err != nil {
+			logger.ErrorCF("agent", "Invalid egress policy, tool disabled",
+				map[string]any{"agent_id": agentID, "tool": fetchTool.Name(), "error": err.Error()})
+		} else {
+			fetchTool.SetEgressGuard(guard)
+			agent.Tools.Register(fetchTool)
+		}
+		if httpTool, err := newHTTPRequestTool(cfg, vault); err != nil {
+			logger.ErrorCF("agent", "Invalid http_request config, tool disabled",
+				map[string]any{"agent_id": agentID, "error": err.Error()})
+		} else if guard, err := newEgressGuard(cfg, agent, httpTool.Name()); err != nil {
+			logger.ErrorCF("agent", "Invalid egress policy, tool disabled",
+				map[string]any{"agent_id": agentID, "tool": httpTool.Name(), "error": err.Error()})
+		} else {
+			httpTool.SetEgressGuard(guard)
+			agent.Tools.Register(httpTool)
+		}
+		registerEmailTools(cfg, agent, emailAll, emailSending)
+		if repos := gitRepos(cfg); len(repos) > 0 {
+			if gitTool, err := tools.NewGitTool(agent.Workspace, repos, cfg.Tools.Git.AllowWrite); err != nil {
+				logger.ErrorCF("agent", "Invalid git config, tool disabled",
+					map[string]any{"agent_id": agentID, "error": err.Error()})
+			} else {
+				agent.Tools.Register(gitTool)
+			}
+		}
 
 		// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
 		agent.Tools.Register(tools.NewI2CTool())
@@ -128,6 +248,15 @@ func registerSharedTools(
 		})
 		agent.Tools.Register(messageTool)
 
+		// Background processes share the exec tool's policy and sandbox
+		if tool, ok := agent.Tools.Get("exec"); ok {
+			if execTool, ok := tool.(*tools.ExecTool); ok {
+				agent.Processes = tools.NewProcessManager(agent.ID, execTool, msgBus)
+				agent.Processes.SetLimits(cfg.Tools.Process.MaxProcesses, int64(cfg.Tools.Process.OutputBufferKB)<<10)
+				agent.Tools.Register(tools.NewProcessTool(agent.Processes))
+			}
+		}
+
 		// Skill discovery and installation tools
 		registryMgr := skills.NewRegistryManagerFromConfig(skills.RegistryConfig{
 			MaxConcurrentSearches: cfg.Tools.Skills.MaxConcurrentSearches,
@@ -140,20 +269,37 @@ func registerSharedTools(
 		agent.Tools.Register(tools.NewFindSkillsTool(registryMgr, searchCache))
 		agent.Tools.Register(tools.NewInstallSkillTool(registryMgr, agent.Workspace))
 
-		// Spawn tool with allowlist checker
+		// Spawn and subagent tools with allowlist checker
 		subagentManager := tools.NewSubagentManager(provider, agent.Model, agent.Workspace, msgBus)
 		subagentManager.SetLLMOptions(agent.MaxTokens, agent.Temperature)
-		spawnTool := tools.NewSpawnTool(subagentManager)
+		if agent.Subagents != nil {
+			subagentManager.SetLimits(agent.Subagents.MaxDepth, agent.Subagents.MaxConcurrent)
+			subagentManager.SetTimeout(time.Duration(agent.Subagents.TimeoutMinutes) * time.Minute)
+		}
+		if err := subagentManager.EnablePersistence(); err != nil {
+			logger.WarnCF("agent", "Failed to load subagent tasks",
+				map[string]any{"agent_id": agentID, "error": err.Error()})
+		}
+		agent.SubagentTasks = subagentManager
 		currentAgentID := agentID
-		spawnTool.SetAllowlistChecker(func(targetAgentID string) bool {
-			return registry.CanSpawnSubagent(currentAgentID, targetAgentID)
-		})
+		allowlistCheck := func(targetAgentID string) bool {
+			return routing.NormalizeAgentID(targetAgentID) == currentAgentID ||
+				registry.CanSpawnSubagent(currentAgentID, targetAgentID)
+		}
+		spawnTool := tools.NewSpawnTool(subagentManager)
+		spawnTool.SetAllowlistChecker(allowlistCheck)
 		agent.Tools.Register(spawnTool)
+		subagentTool := tools.NewSubagentTool(subagentManager)
+		subagentTool.SetAllowlistChecker(allowlistCheck)
+		agent.Tools.Register(subagentTool)
+		agent.Tools.Register(tools.NewSubagentStatusTool(subagentManager))
+		agent.Tools.Register(tools.NewSubagentCancelTool(subagentManager))
 	}
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
+	al.reportOrphanedSubagents()
 
 	for al.running.Load() {
 		select {
@@ -165,7 +311,7 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
+			response, voice, err := al.processMessage(ctx, msg, true)
 			if err != nil {
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
@@ -189,6 +335,7 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 						Channel: msg.Channel,
 						ChatID:  msg.ChatID,
 						Content: response,
+						Voice:   voice,
 					})
 				}
 			}
@@ -202,6 +349,20 @@ func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 }
 
+// Close releases resources held by the loop, such as MCP server
+// connections and background processes. It should be called once the loop
+// is no longer used.
+func (al *AgentLoop) Close() {
+	if al.mcp != nil {
+		al.mcp.Close()
+	}
+	for _, agentID := range al.registry.ListAgentIDs() {
+		if agent, ok := al.registry.GetAgent(agentID); ok && agent.Processes != nil {
+			agent.Processes.Close()
+		}
+	}
+}
+
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	for _, agentID := range al.registry.ListAgentIDs() {
 		if agent, ok := al.registry.GetAgent(agentID); ok {
@@ -214,6 +375,20 @@ func (al *AgentLoop) SetChannelManager(cm *channels.Manager) {
 	al.channelManager = cm
 }
 
+// DefaultAgentTools returns the default agent's tool registry.
+func (al *AgentLoop) DefaultAgentTools() *tools.ToolRegistry {
+	if agent := al.registry.GetDefaultAgent(); agent != nil {
+		return agent.Tools
+	}
+	return tools.NewToolRegistry()
+}
+
+// CooldownTracker returns the tracker shared by all agents' fallback chains,
+// so external health probes can feed provider state into it.
+func (al *AgentLoop) CooldownTracker() *providers.CooldownTracker {
+	return al.cooldown
+}
+
 // RecordLastChannel records the last active channel for this workspace.
 // This uses the atomic state save mechanism to prevent data loss on crash.
 func (al *AgentLoop) RecordLastChannel(channel string) error {
@@ -248,7 +423,8 @@ func (al *AgentLoop) ProcessDirectWithChannel(
 		SessionKey: sessionKey,
 	}
 
-	return al.processMessage(ctx, msg)
+	response, _, err := al.processMessage(ctx, msg, false)
+	return response, err
 }
 
 // ProcessHeartbeat processes a heartbeat request without session history.
@@ -267,7 +443,15 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 	})
 }
 
-func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+// processMessage handles msg and returns the reply, and whether the reply
+// should be spoken. Replies to commands are always sent as text. fromChannel
+// is true for messages a channel received from a user, the only ones that
+// may approve queued changes.
+func (al *AgentLoop) processMessage(
+	ctx context.Context,
+	msg bus.InboundMessage,
+	fromChannel bool,
+) (string, bool, error) {
 	// Add message preview to log (show full content for error messages)
 	var logContent string
 	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
@@ -285,15 +469,57 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 
 	// Route system messages to processSystemMessage
 	if msg.Channel == "system" {
-		return al.processSystemMessage(ctx, msg)
+		response, err := al.processSystemMessage(ctx, msg)
+		return response, false, err
 	}
 
 	// Check for commands
 	if response, handled := al.handleCommand(ctx, msg); handled {
-		return response, nil
+		return response, false, nil
 	}
 
 	// Route to determine agent and session key
+	agent, sessionKey, route := al.routeMessage(msg)
+
+	logger.InfoCF("agent", "Routed message",
+		map[string]any{
+			"agent_id":    agent.ID,
+			"session_key": sessionKey,
+			"matched_by":  route.MatchedBy,
+		})
+
+	if response, handled := handleCheckpointCommand(agent, sessionKey, msg.Content); handled {
+		return response, false, nil
+	}
+	if response, handled := handlePlanCommand(agent, sessionKey, msg.Content); handled {
+		return response, false, nil
+	}
+	if response, handled := handleTasksCommand(agent, msg.Content); handled {
+		return response, false, nil
+	}
+	ttsEnabled := al.cfg.Voice.TTS.Provider != ""
+	if response, handled := handleVoiceCommand(agent, sessionKey, msg.Content, ttsEnabled); handled {
+		return response, false, nil
+	}
+	if response, handled := handleApproveCommand(ctx, agent, sessionKey, msg.Content, fromChannel); handled {
+		return response, false, nil
+	}
+
+	voice := ttsEnabled && agent.Sessions.VoiceReply(sessionKey)
+	response, err := al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         msg.Channel,
+		ChatID:          msg.ChatID,
+		UserMessage:     msg.Content,
+		DefaultResponse: "I've completed processing but have no response to give.",
+		EnableSummary:   true,
+		SendResponse:    false,
+	})
+	return response, voice, err
+}
+
+// routeMessage resolves the agent that handles msg and its session key.
+func (al *AgentLoop) routeMessage(msg bus.InboundMessage) (*AgentInstance, string, routing.ResolvedRoute) {
 	route := al.registry.ResolveRoute(routing.RouteInput{
 		Channel:    msg.Channel,
 		AccountID:  msg.Metadata["account_id"],
@@ -313,23 +539,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	// namespacing them under the resolved agent. This avoids all CLI traffic
 	// collapsing into agent:<id>:main and reusing stale context.
 	sessionKey := resolveSessionKey(route.SessionKey, msg.SessionKey, msg.Channel, agent.ID)
-
-	logger.InfoCF("agent", "Routed message",
-		map[string]any{
-			"agent_id":    agent.ID,
-			"session_key": sessionKey,
-			"matched_by":  route.MatchedBy,
-		})
-
-	return al.runAgentLoop(ctx, agent, processOptions{
-		SessionKey:      sessionKey,
-		Channel:         msg.Channel,
-		ChatID:          msg.ChatID,
-		UserMessage:     msg.Content,
-		DefaultResponse: "I've completed processing but have no response to give.",
-		EnableSummary:   true,
-		SendResponse:    false,
-	})
+	return agent, sessionKey, route
 }
 
 func resolveSessionKey(routeSessionKey, msgSessionKey, channel, agentID string) string {
@@ -373,11 +583,12 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		originChatID = msg.ChatID
 	}
 
-	// Extract subagent result from message content
-	// Format: "Task 'label' completed.\n\nResult:\n<actual content>"
+	// Subagent results carry their task in the metadata and only the
+	// result in the content.
 	content := msg.Content
-	if idx := strings.Index(content, "Result:\n"); idx >= 0 {
-		content = content[idx+8:] // Extract just the result part
+	userMessage := fmt.Sprintf("[System: %s] %s", msg.SenderID, content)
+	if status := msg.Metadata[tools.SubagentMetaStatus]; status != "" {
+		userMessage = formatSubagentResult(msg.Metadata, content)
 	}
 
 	// Skip internal channels - only log, don't send to user
@@ -391,17 +602,32 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		return "", nil
 	}
 
-	// Use default agent for system messages
+	// Report to the agent that spawned the task or started the process, or
+	// else the default agent
 	agent := al.registry.GetDefaultAgent()
+	ownerID := msg.Metadata[tools.SubagentMetaParent]
+	if ownerID == "" {
+		ownerID = msg.Metadata[tools.ProcessMetaAgent]
+	}
+	owned := false
+	if ownerID != "" {
+		if owner, ok := al.registry.GetAgent(ownerID); ok {
+			agent, owned = owner, true
+		}
+	}
 
-	// Use the origin session for context
+	// Use the session that started the process, or else the agent's main
+	// session
 	sessionKey := routing.BuildAgentMainSessionKey(agent.ID)
+	if session := msg.Metadata[tools.ProcessMetaSession]; session != "" && owned {
+		sessionKey = session
+	}
 
 	return al.runAgentLoop(ctx, agent, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         originChannel,
 		ChatID:          originChatID,
-		UserMessage:     fmt.Sprintf("[System: %s] %s", msg.SenderID, msg.Content),
+		UserMessage:     userMessage,
 		DefaultResponse: "Background task completed.",
 		EnableSummary:   false,
 		SendResponse:    true,
@@ -410,6 +636,10 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 
 // runAgentLoop is the core message processing logic.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opts processOptions) (string, error) {
+	// MCP tools should be registered before the first request lists tools.
+	al.WaitForMCP(ctx)
+	ctx = tools.WithSessionKey(ctx, opts.SessionKey)
+
 	// 0. Record last channel for heartbeat notifications (skip internal channels)
 	if opts.Channel != "" && opts.ChatID != "" {
 		// Don't record internal channels (cli, system, subagent)
@@ -421,8 +651,21 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		}
 	}
 
-	// 1. Update tool contexts
+	// 1. Update tool contexts, select the session's plan and group file
+	// snapshots under this turn
 	al.updateToolContexts(agent, opts.Channel, opts.ChatID)
+	if agent.Plans != nil {
+		agent.Plans.SetSession(opts.SessionKey)
+	}
+	if agent.Checkpoints != nil {
+		recorder := agent.Checkpoints.BeginTurn(opts.SessionKey)
+		defer func() {
+			if err := recorder.End(); err != nil {
+				logger.WarnCF("agent", "Failed to prune checkpoints", map[string]any{"error": err.Error()})
+			}
+		}()
+		agent = agent.withTools(agent.Tools.WithSnapshotter(recorder))
+	}
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
@@ -472,6 +715,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 			Channel: opts.Channel,
 			ChatID:  opts.ChatID,
 			Content: finalContent,
+			Voice:   agent.Sessions.VoiceReply(opts.SessionKey),
 		})
 	}
 
@@ -501,6 +745,11 @@ func (al *AgentLoop) runLLMIteration(
 	for iteration < agent.MaxIterations {
 		iteration++
 
+		// Show plan changes made by the previous iteration's tool calls
+		if iteration > 1 {
+			messages[0] = agent.ContextBuilder.UpdatePlanContext(messages[0], opts.SessionKey)
+		}
+
 		logger.DebugCF("agent", "LLM iteration",
 			map[string]any{
 				"agent_id":  agent.ID,
@@ -599,6 +848,10 @@ func (al *AgentLoop) runLLMIteration(
 					newHistory, newSummary, "",
 					nil, opts.Channel, opts.ChatID,
 				)
+				messages[0] = agent.ContextBuilder.UpdatePlanContext(messages[0], opts.SessionKey)
+				if opts.SystemPrompt != "" {
+					messages[0] = withSystemPrompt(messages[0], opts.SystemPrompt)
+				}
 				continue
 			}
 			break
@@ -1072,7 +1325,7 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 	switch cmd {
 	case "/show":
 		if len(args) < 1 {
-			return "Usage: /show [model|channel|agents]", true
+			return "Usage: /show [model|channel|agents|providers]", true
 		}
 		switch args[0] {
 		case "model":
@@ -1086,6 +1339,8 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 		case "agents":
 			agentIDs := al.registry.ListAgentIDs()
 			return fmt.Sprintf("Registered agents: %s", strings.Join(agentIDs, ", ")), true
+		case "providers":
+			return al.providerStatus(), true
 		default:
 			return fmt.Sprintf("Unknown show target: %s", args[0]), true
 		}
@@ -1145,6 +1400,53 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 	return "", false
 }
 
+// providerStatus renders the circuit-breaker state of every fallback
+// candidate across agents, followed by any other provider with recorded failures.
+func (al *AgentLoop) providerStatus() string {
+	if al.cooldown == nil {
+		return "Provider cooldowns not initialized"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Provider status:\n")
+	seen := make(map[string]bool)
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+		for _, c := range agent.Candidates {
+			key := providers.ModelKey(c.Provider, c.Model)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			seen[c.Provider] = true
+			sb.WriteString(formatCooldownStatus(key, al.cooldown.Status(c.Provider)))
+		}
+	}
+	for _, status := range al.cooldown.Snapshot() {
+		if !seen[status.Provider] {
+			sb.WriteString(formatCooldownStatus(status.Provider, status))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func formatCooldownStatus(name string, status providers.CooldownStatus) string {
+	line := fmt.Sprintf("- %s: %s", name, status.State)
+	if status.Remaining > 0 {
+		line += fmt.Sprintf(" (%s remaining)", status.Remaining.Round(time.Second))
+	}
+	if status.ErrorCount > 0 {
+		line += fmt.Sprintf(", %d error(s)", status.ErrorCount)
+	}
+	if status.LastReason != "" {
+		line += fmt.Sprintf(", last: %s", status.LastReason)
+	}
+	return line + "\n"
+}
+
 // extractPeer extracts the routing peer from inbound message metadata.
 func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	peerKind := msg.Metadata["peer_kind"]
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/routing"
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
@@ -141,6 +143,41 @@ func TestNewAgentLoop_StateInitialized(t *testing.T) {
 	}
 }
 
+func TestAgentLoop_CooldownPersistedAndShown(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "openai/gpt-4o",
+				ModelFallbacks:    []string{"anthropic/claude-sonnet"},
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &mockProvider{})
+	al.CooldownTracker().MarkFailure("openai", providers.FailoverRateLimit)
+
+	// A new loop on the same workspace must reload the cooldown.
+	al2 := NewAgentLoop(cfg, msgBus, &mockProvider{})
+	if al2.CooldownTracker().IsAvailable("openai") {
+		t.Fatal("expected openai cooldown to survive restart")
+	}
+
+	resp, handled := al2.handleCommand(context.Background(), bus.InboundMessage{Content: "/show providers"})
+	if !handled {
+		t.Fatal("expected /show providers to be handled")
+	}
+	for _, want := range []string{"openai/gpt-4o: open", "last: rate_limit", "anthropic/claude-sonnet: closed"} {
+		if !strings.Contains(resp, want) {
+			t.Errorf("response missing %q:\n%s", want, resp)
+		}
+	}
+}
+
 // TestToolRegistry_ToolRegistration verifies tools can be registered and retrieved
 func TestToolRegistry_ToolRegistration(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "agent-test-*")
@@ -428,7 +465,7 @@ func (h testHelper) executeAndGetResponse(tb testing.TB, ctx context.Context, ms
 	timeoutCtx, cancel := context.WithTimeout(ctx, responseTimeout)
 	defer cancel()
 
-	response, err := h.al.processMessage(timeoutCtx, msg)
+	response, _, err := h.al.processMessage(timeoutCtx, msg, true)
 	if err != nil {
 		tb.Fatalf("processMessage failed: %v", err)
 	}
@@ -631,3 +668,54 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+func TestProcessSystemMessage_RoutesProcessExitToOwner(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.List = []config.AgentConfig{
+		{ID: "main", Default: true},
+		{ID: "ops", Workspace: t.TempDir()},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+
+	session := "agent:ops:telegram:42"
+	_, err := al.processSystemMessage(context.Background(), bus.InboundMessage{
+		Channel:  "system",
+		SenderID: "process:proc-1",
+		ChatID:   "telegram:42",
+		Content:  "Process proc-1 completed: exited with status 0.",
+		Metadata: map[string]string{
+			tools.ProcessMetaID:      "proc-1",
+			tools.ProcessMetaAgent:   "ops",
+			tools.ProcessMetaSession: session,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops, _ := al.registry.GetAgent("ops")
+	history := ops.Sessions.GetHistory(session)
+	if len(history) == 0 || !strings.Contains(history[0].Content, "Process proc-1 completed") {
+		t.Errorf("ops session history = %+v, want the exit notification", history)
+	}
+	main := al.registry.GetDefaultAgent()
+	if history := main.Sessions.GetHistory(routing.BuildAgentMainSessionKey(main.ID)); len(history) != 0 {
+		t.Errorf("default agent got %d messages for another agent's process", len(history))
+	}
+}
+
+func TestGitRepos_FallsBackToGitReposEnv(t *testing.T) {
+	local := t.TempDir()
+	t.Setenv("GIT_REPOS", local+", sipeed/picoclaw ,"+filepath.Join(local, "missing"))
+
+	cfg := config.DefaultConfig()
+	if repos := gitRepos(cfg); len(repos) != 1 || repos[0] != local {
+		t.Errorf("gitRepos = %v, want only the local directory", repos)
+	}
+
+	cfg.Tools.Git.Repos = []string{"/srv/repo"}
+	if repos := gitRepos(cfg); len(repos) != 1 || repos[0] != "/srv/repo" {
+		t.Errorf("configured repos should win: %v", repos)
+	}
+}
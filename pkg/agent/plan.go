@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handlePlanCommand handles /plan, which shows the checklist of the routed
+// agent and session, and /plan clear, which deletes it.
+func handlePlanCommand(agent *AgentInstance, sessionKey, content string) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] != "/plan" {
+		return "", false
+	}
+	if agent.Plans == nil {
+		return "Plans are not available for this agent.", true
+	}
+
+	if len(fields) > 1 {
+		if fields[1] != "clear" {
+			return "Usage: /plan [clear]", true
+		}
+		if err := agent.Plans.Delete(sessionKey); err != nil {
+			return fmt.Sprintf("Failed to clear the plan: %v", err), true
+		}
+		return "Plan cleared.", true
+	}
+
+	p, err := agent.Plans.Get(sessionKey)
+	if err != nil {
+		return fmt.Sprintf("Failed to load the plan: %v", err), true
+	}
+	if p == nil {
+		return "No plan in this session.", true
+	}
+	return p.Format(), true
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestPlan_ToolCommandAndContext(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+
+	if response, _ := handlePlanCommand(agent, "chat-1", "/plan"); response != "No plan in this session." {
+		t.Errorf("unexpected /plan response %q", response)
+	}
+
+	agent.Plans.SetSession("chat-1")
+	messages := agent.ContextBuilder.BuildMessages(nil, "", "weekly report", nil, "cli", "direct")
+	if strings.Contains(messages[0].Content, planHeader) {
+		t.Error("system prompt should have no plan before one is created")
+	}
+
+	result := agent.Tools.Execute(context.Background(), "plan", map[string]any{
+		"action": "create",
+		"goal":   "Email a weekly report",
+		"items":  []any{"Collect git activity", "Write the report", "Send the email"},
+	})
+	if result.IsError {
+		t.Fatalf("create failed: %s", result.ForLLM)
+	}
+	result = agent.Tools.Execute(context.Background(), "plan", map[string]any{
+		"action": "update", "item": float64(1), "status": "done", "note": "12 commits",
+	})
+	if result.IsError {
+		t.Fatalf("update failed: %s", result.ForLLM)
+	}
+
+	messages[0] = agent.ContextBuilder.UpdatePlanContext(messages[0], "chat-1")
+	if !strings.Contains(messages[0].Content, "[x] 1. Collect git activity — 12 commits") {
+		t.Errorf("system prompt does not show the updated plan:\n%s", messages[0].Content)
+	}
+	last := messages[0].SystemParts[len(messages[0].SystemParts)-1]
+	if !strings.HasPrefix(last.Text, planHeader) {
+		t.Errorf("plan should be the last system block, got %q", last.Text)
+	}
+
+	response, _ := handlePlanCommand(agent, "chat-1", "/plan")
+	if !strings.Contains(response, "(1/3 done)") || !strings.Contains(response, "[ ] 3. Send the email") {
+		t.Errorf("unexpected /plan response %q", response)
+	}
+	if response, _ := handlePlanCommand(agent, "chat-2", "/plan"); response != "No plan in this session." {
+		t.Errorf("plans should be per session, got %q", response)
+	}
+
+	agent.Tools.Execute(context.Background(), "plan", map[string]any{"action": "complete"})
+	messages[0] = agent.ContextBuilder.UpdatePlanContext(messages[0], "chat-1")
+	if strings.Contains(messages[0].Content, planHeader) {
+		t.Error("a completed plan should be dropped from the system prompt")
+	}
+
+	if response, _ := handlePlanCommand(agent, "chat-1", "/plan clear"); response != "Plan cleared." {
+		t.Errorf("unexpected /plan clear response %q", response)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"os"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// openVault opens the secrets vault. A vault that cannot be read is
+// reported and treated as absent.
+func openVault() *secrets.Vault {
+	vault, err := secrets.Open(secrets.DefaultPath(), secrets.DefaultKeyPath())
+	if err != nil {
+		logger.ErrorCF("agent", "Cannot open secrets vault, secrets are unavailable",
+			map[string]any{"error": err.Error()})
+		return nil
+	}
+	return vault
+}
+
+// httpSecrets resolves the http_request secrets declared in config and
+// adds the vault secrets allowed for http_request.
+func httpSecrets(cfg *config.Config, vault *secrets.Vault) map[string]tools.HTTPSecret {
+	result := make(map[string]tools.HTTPSecret, len(cfg.Tools.HTTP.Secrets))
+	for name, s := range cfg.Tools.HTTP.Secrets {
+		value := s.Value
+		if s.Env != "" {
+			value = os.Getenv(s.Env)
+		}
+		if value == "" {
+			logger.WarnCF("agent", "http_request secret has no value, skipping",
+				map[string]any{"secret": name, "env": s.Env})
+			continue
+		}
+		if len(s.Hosts) == 0 {
+			logger.WarnCF("agent", "http_request secret has no hosts and will not be sent",
+				map[string]any{"secret": name})
+		}
+		result[name] = tools.HTTPSecret{Value: value, Hosts: s.Hosts}
+	}
+	if vault != nil {
+		for _, s := range vault.List() {
+			if _, ok := result[s.Name]; !ok && s.AllowsTool("http_request") {
+				result[s.Name] = tools.HTTPSecret{Value: s.Value, Hosts: s.Hosts}
+			}
+		}
+	}
+	return result
+}
+
+// newHTTPRequestTool builds the http_request tool with its secrets.
+func newHTTPRequestTool(cfg *config.Config, vault *secrets.Vault) (*tools.HTTPRequestTool, error) {
+	timeout := time.Duration(cfg.Tools.HTTP.TimeoutSeconds) * time.Second
+	return tools.NewHTTPRequestTool(cfg.Tools.Web.Proxy, timeout, httpSecrets(cfg, vault))
+}
+
+// newRedactor masks the values of vault secrets, of the secrets declared
+// for http_request and of email passwords.
+func newRedactor(cfg *config.Config, vault *secrets.Vault) *secrets.Redactor {
+	redactor := secrets.NewRedactor()
+	if vault != nil {
+		for _, s := range vault.List() {
+			redactor.Add(s.Name, s.Value)
+		}
+	}
+	for name, s := range cfg.Tools.HTTP.Secrets {
+		if s.Env != "" {
+			redactor.Add(name, os.Getenv(s.Env))
+		} else {
+			redactor.Add(name, s.Value)
+		}
+	}
+	for _, ac := range cfg.Tools.Email.Accounts {
+		redactor.Add("email:"+ac.Name, emailPassword(ac, vault))
+	}
+	return redactor
+}
+
+// protectSecrets gives each agent's exec tool the vault secrets it is
+// allowed, and masks secret values in tool results, process exit
+// notifications, outbound messages and logs.
+func protectSecrets(cfg *config.Config, msgBus *bus.MessageBus, registry *AgentRegistry, vault *secrets.Vault) {
+	redact := newRedactor(cfg, vault).Redact
+	for _, agentID := range registry.ListAgentIDs() {
+		agent, ok := registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+		agent.Tools.SetRedactor(redact)
+		if agent.Processes != nil {
+			agent.Processes.SetRedactor(redact)
+		}
+		if tool, ok := agent.Tools.Get("exec"); ok && vault != nil {
+			if execTool, ok := tool.(*tools.ExecTool); ok {
+				execTool.SetSecrets(vault)
+				execTool.SetSkills(agent.ContextBuilder.ListSkills)
+			}
+		}
+	}
+	msgBus.SetRedactor(redact)
+	logger.SetRedactor(redact)
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+func TestProtectSecrets_RedactsToolResultsAndOutbound(t *testing.T) {
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Set(secrets.Secret{Name: "PC_TEST_TOKEN", Value: "tok-4242", Tools: []string{"exec"}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PC_TEST_HTTP_KEY", "http-key-99")
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Tools.HTTP.Secrets = map[string]config.HTTPSecretConfig{"WEATHER": {Env: "PC_TEST_HTTP_KEY"}}
+	registry := NewAgentRegistry(cfg, &mockProvider{})
+	msgBus := bus.NewMessageBus()
+	protectSecrets(cfg, msgBus, registry, vault)
+	defer logger.SetRedactor(nil)
+
+	agent := registry.GetDefaultAgent()
+	result := agent.Tools.Execute(context.Background(), "exec", map[string]any{
+		"command": "echo $PC_TEST_TOKEN; echo http-key-99",
+	})
+	if result.IsError {
+		t.Fatalf("exec failed: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "tok-4242") || strings.Contains(result.ForUser, "http-key-99") {
+		t.Fatalf("secret leaked: %q", result.ForLLM)
+	}
+	for _, want := range []string{"[REDACTED:PC_TEST_TOKEN]", "[REDACTED:WEATHER]"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("result %q does not contain %q", result.ForLLM, want)
+		}
+	}
+
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "your token is tok-4242"})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || msg.Content != "your token is [REDACTED:PC_TEST_TOKEN]" {
+		t.Fatalf("outbound message = %q", msg.Content)
+	}
+}
+
+func TestProtectSecrets_RedactsProcessExitNotifications(t *testing.T) {
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.Set(secrets.Secret{Name: "PC_TEST_TOKEN", Value: "tok-4242", Tools: []string{"exec"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	registry := NewAgentRegistry(cfg, &mockProvider{})
+	msgBus := bus.NewMessageBus()
+	agent := registry.GetDefaultAgent()
+	tool, _ := agent.Tools.Get("exec")
+	agent.Processes = tools.NewProcessManager(agent.ID, tool.(*tools.ExecTool), msgBus)
+	defer agent.Processes.Close()
+	agent.Tools.Register(tools.NewProcessTool(agent.Processes))
+	protectSecrets(cfg, msgBus, registry, vault)
+	defer logger.SetRedactor(nil)
+
+	result := agent.Tools.ExecuteWithContext(context.Background(), "process", map[string]any{
+		"action": "start", "command": "echo $PC_TEST_TOKEN", "notify": true,
+	}, "telegram", "1", nil)
+	if result.IsError {
+		t.Fatalf("start failed: %s", result.ForLLM)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no exit notification")
+	}
+	if strings.Contains(msg.Content, "tok-4242") || !strings.Contains(msg.Content, "[REDACTED:PC_TEST_TOKEN]") {
+		t.Errorf("exit notification = %q, want the secret redacted", msg.Content)
+	}
+}
+
+func TestHTTPSecrets_MergesVault(t *testing.T) {
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []secrets.Secret{
+		{Name: "TODOIST", Value: "from-vault", Tools: []string{"http_request"}, Hosts: []string{"api.todoist.com"}},
+		{Name: "EXEC_ONLY", Value: "exec", Tools: []string{"exec"}},
+		{Name: "CONFIGURED", Value: "vault-loses", Tools: []string{"http_request"}},
+	} {
+		if err := vault.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Tools.HTTP.Secrets = map[string]config.HTTPSecretConfig{
+		"CONFIGURED": {Value: "config-wins"},
+		"UNSET":      {Env: "PC_TEST_UNSET_VARIABLE"},
+	}
+	got := httpSecrets(cfg, vault)
+	if len(got) != 2 {
+		t.Fatalf("httpSecrets() = %v", got)
+	}
+	if got["CONFIGURED"].Value != "config-wins" {
+		t.Errorf("CONFIGURED = %q, want the config value", got["CONFIGURED"].Value)
+	}
+	if s := got["TODOIST"]; s.Value != "from-vault" || len(s.Hosts) != 1 {
+		t.Errorf("TODOIST = %+v", s)
+	}
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// subagentPrompt is added to the system prompt of an agent that runs a
+// delegated task.
+const subagentPrompt = "## Subagent\n" +
+	"You are running as a subagent: another agent delegated the task in the user message to you. " +
+	"Complete it independently with your tools and reply with a clear, concise result. " +
+	"Your reply goes back to that agent, not to the user."
+
+// subagentRunner returns the runner of parentID's spawn and subagent
+// tools. A task runs as one turn of its target agent, or of the parent
+// when it names none, with that agent's model, tools, skills and
+// workspace and without session history.
+func (al *AgentLoop) subagentRunner(parentID string) tools.SubagentRunner {
+	return func(ctx context.Context, task *tools.SubagentTask) (*tools.ToolLoopResult, error) {
+		targetID := task.AgentID
+		if targetID == "" {
+			targetID = parentID
+		}
+		agent, ok := al.registry.GetAgent(targetID)
+		if !ok {
+			return nil, fmt.Errorf("agent %q not found", targetID)
+		}
+
+		// The task may run while the agent answers the user, so it gets
+		// copies of the tools that keep per-turn state, its own plan and
+		// its own checkpoint turn. Its session only lives as long as the
+		// task.
+		sessionKey := fmt.Sprintf("agent:%s:%s", agent.ID, task.ID)
+		defer func() {
+			if err := agent.Sessions.Delete(sessionKey); err != nil {
+				logger.WarnCF("agent", "Failed to delete subagent session",
+					map[string]any{"session_key": sessionKey, "error": err.Error()})
+			}
+			if agent.Plans != nil {
+				if err := agent.Plans.Delete(sessionKey); err != nil {
+					logger.WarnCF("agent", "Failed to delete subagent plan",
+						map[string]any{"session_key": sessionKey, "error": err.Error()})
+				}
+			}
+		}()
+		turn := agent.withTools(agent.Tools.ForSession(sessionKey))
+		if agent.Checkpoints != nil {
+			recorder := agent.Checkpoints.BeginTurn(sessionKey)
+			defer func() {
+				if err := recorder.End(); err != nil {
+					logger.WarnCF("agent", "Failed to prune checkpoints", map[string]any{"error": err.Error()})
+				}
+			}()
+			turn = turn.withTools(turn.Tools.WithSnapshotter(recorder))
+		}
+		al.updateToolContexts(turn, task.OriginChannel, task.OriginChatID)
+
+		messages := agent.ContextBuilder.BuildMessages(nil, "", task.Task, nil, task.OriginChannel, task.OriginChatID)
+		messages[0] = agent.ContextBuilder.UpdatePlanContext(messages[0], sessionKey)
+		messages[0] = withSystemPrompt(messages[0], subagentPrompt)
+		// Keep the task in the session, so that it survives a rebuild of
+		// the messages after context compression.
+		agent.Sessions.AddMessage(sessionKey, "user", task.Task)
+
+		content, iterations, err := al.runLLMIteration(ctx, turn, messages, processOptions{
+			SessionKey:   sessionKey,
+			Channel:      task.OriginChannel,
+			ChatID:       task.OriginChatID,
+			SystemPrompt: subagentPrompt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &tools.ToolLoopResult{Content: content, Iterations: iterations}, nil
+	}
+}
+
+// withSystemPrompt appends text to the system message as a block of its
+// own.
+func withSystemPrompt(system providers.Message, text string) providers.Message {
+	system.Content += "\n\n---\n\n" + text
+	system.SystemParts = append(system.SystemParts, providers.ContentBlock{Type: "text", Text: text})
+	return system
+}
+
+// formatSubagentResult renders the result of a spawned subagent, reported
+// in a system message, as the message that the parent agent answers.
+func formatSubagentResult(meta map[string]string, content string) string {
+	name := meta[tools.SubagentMetaLabel]
+	if name == "" {
+		name = meta[tools.SubagentMetaTaskID]
+	}
+	if agentID := meta[tools.SubagentMetaAgent]; agentID != "" {
+		name += " (agent " + agentID + ")"
+	}
+	return fmt.Sprintf("[System: subagent task %s %s]\n%s", name, meta[tools.SubagentMetaStatus], content)
+}
+
+// reportOrphanedSubagents hands the subagent tasks that the last restart
+// interrupted back to the agents that started them.
+func (al *AgentLoop) reportOrphanedSubagents() {
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok || agent.SubagentTasks == nil {
+			continue
+		}
+		if n := agent.SubagentTasks.ReportOrphans(); n > 0 {
+			logger.InfoCF("agent", "Reported subagent tasks interrupted by restart",
+				map[string]any{"agent_id": agentID, "count": n})
+		}
+	}
+}
+
+// handleTasksCommand handles /tasks, which lists the subagent tasks of the
+// routed agent, and /tasks cancel <id>.
+func handleTasksCommand(agent *AgentInstance, content string) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] != "/tasks" {
+		return "", false
+	}
+	if agent.SubagentTasks == nil {
+		return "Subagents are not available for this agent.", true
+	}
+
+	switch {
+	case len(fields) == 1:
+		tasks := agent.SubagentTasks.ListTasks()
+		if len(tasks) == 0 {
+			return "No subagent tasks.", true
+		}
+		var sb strings.Builder
+		sb.WriteString("Subagent tasks:\n")
+		for _, task := range tasks {
+			sb.WriteString("  " + tools.FormatSubagentTask(task) + "\n")
+		}
+		return strings.TrimRight(sb.String(), "\n"), true
+	case len(fields) == 3 && fields[1] == "cancel":
+		if err := agent.SubagentTasks.Cancel(fields[2]); err != nil {
+			return fmt.Sprintf("Cancel failed: %v", err), true
+		}
+		return fmt.Sprintf("Canceling %s.", fields[2]), true
+	default:
+		return "Usage: /tasks [cancel <task-id>]", true
+	}
+}
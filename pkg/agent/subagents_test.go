@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// delegatingProvider makes the main agent's model delegate one task to the
+// research agent and records the model and system prompt of every call.
+type delegatingProvider struct {
+	mu      sync.Mutex
+	models  []string
+	systems []string
+}
+
+func (p *delegatingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	toolDefs []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	p.models = append(p.models, model)
+	p.systems = append(p.systems, messages[0].Content)
+	p.mu.Unlock()
+
+	if model == "research-model" {
+		return &providers.LLMResponse{Content: "Found 3 sources"}, nil
+	}
+	if last := messages[len(messages)-1]; last.Role == "tool" {
+		return &providers.LLMResponse{Content: "Done: " + last.Content}, nil
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{
+		ID:        "call-1",
+		Name:      "subagent",
+		Arguments: map[string]any{"task": "Find sources", "label": "sources", "agent_id": "research"},
+	}}}, nil
+}
+
+func (p *delegatingProvider) GetDefaultModel() string {
+	return "main-model"
+}
+
+func TestSubagent_RunsOnTargetAgent(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.Model = "main-model"
+	cfg.Agents.List = []config.AgentConfig{
+		{ID: "main", Default: true, Subagents: &config.SubagentsConfig{AllowAgents: []string{"research"}}},
+		{
+			ID:        "research",
+			Workspace: t.TempDir(),
+			Model:     &config.AgentModelConfig{Primary: "research-model"},
+		},
+	}
+	provider := &delegatingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	response, err := al.ProcessDirect(context.Background(), "collect sources", "cli:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "Found 3 sources") {
+		t.Errorf("response %q should contain the subagent's result", response)
+	}
+
+	if len(provider.models) != 3 || provider.models[1] != "research-model" {
+		t.Fatalf("models = %v, want the research agent's model for the delegated turn", provider.models)
+	}
+	if !strings.Contains(provider.systems[1], "## Subagent") {
+		t.Error("the delegated turn should be told it runs as a subagent")
+	}
+	if strings.Contains(provider.systems[0], "## Subagent") {
+		t.Error("the parent turn should not get the subagent prompt")
+	}
+
+	main, _ := al.registry.GetAgent("main")
+	tasks := main.SubagentTasks.ListTasks()
+	if len(tasks) != 1 || tasks[0].Status != "completed" || tasks[0].AgentID != "research" {
+		t.Errorf("unexpected task records %+v", tasks)
+	}
+}
+
+// isolatedTaskProvider drives a subagent turn that creates a plan and a
+// file, then fails once with a context error.
+type isolatedTaskProvider struct {
+	calls int
+	retry []providers.Message // Messages sent after the context error
+}
+
+func (p *isolatedTaskProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	toolDefs []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.calls++
+	switch p.calls {
+	case 1:
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{
+			{ID: "call-1", Name: "plan", Arguments: map[string]any{
+				"action": "create", "goal": "Write notes", "items": []any{"Draft"},
+			}},
+			{ID: "call-2", Name: "write_file", Arguments: map[string]any{"path": "notes.txt", "content": "notes"}},
+			{ID: "call-3", Name: "message", Arguments: map[string]any{"content": "working on it"}},
+		}}, nil
+	case 2:
+		return nil, errors.New("context length exceeded")
+	default:
+		p.retry = messages
+		return &providers.LLMResponse{Content: "notes written"}, nil
+	}
+}
+
+func (p *isolatedTaskProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestSubagent_TurnIsIsolatedFromParent(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	provider := &isolatedTaskProvider{}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, provider)
+	agent := al.registry.GetDefaultAgent()
+
+	// The parent is in the middle of a turn for a Telegram chat.
+	al.updateToolContexts(agent, "telegram", "chat-9")
+	agent.Plans.SetSession("agent:main:telegram:chat-9")
+
+	result, err := al.subagentRunner(agent.ID)(context.Background(), &tools.SubagentTask{
+		ID: "subagent-1", Task: "write the notes", OriginChannel: "cli", OriginChatID: "direct",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "notes written" {
+		t.Errorf("result = %q", result.Content)
+	}
+
+	// The retry after the context error still carries the task and the
+	// subagent prompt.
+	if len(provider.retry) < 2 || provider.retry[1].Role != "user" || !strings.HasPrefix(provider.retry[1].Content, "write the notes") {
+		t.Errorf("retried messages lost the task: %+v", provider.retry)
+	}
+	if !strings.Contains(provider.retry[0].Content, "## Subagent") {
+		t.Error("retried system prompt lost the subagent prompt")
+	}
+
+	// The task's message went to its own origin and did not touch the
+	// parent's message tool.
+	if out, _ := msgBus.SubscribeOutbound(context.Background()); out.Channel != "cli" || out.ChatID != "direct" {
+		t.Errorf("task message sent to %s:%s, want cli:direct", out.Channel, out.ChatID)
+	}
+	tool, _ := agent.Tools.Get("message")
+	messageTool := tool.(*tools.MessageTool)
+	if messageTool.HasSentInRound() {
+		t.Error("the task's message counted as sent in the parent's round")
+	}
+	messageTool.Execute(context.Background(), map[string]any{"content": "parent reply"})
+	if out, _ := msgBus.SubscribeOutbound(context.Background()); out.Channel != "telegram" || out.ChatID != "chat-9" {
+		t.Errorf("parent message sent to %s:%s, want telegram:chat-9", out.Channel, out.ChatID)
+	}
+	if got := agent.Plans.Session(); got != "agent:main:telegram:chat-9" {
+		t.Errorf("parent plan session changed to %q", got)
+	}
+	if p, _ := agent.Plans.Get("agent:main:telegram:chat-9"); p != nil {
+		t.Error("the task's plan was saved in the parent's session")
+	}
+	if p, _ := agent.Plans.Get("agent:main:subagent-1"); p != nil {
+		t.Error("the task's plan should be deleted with its session")
+	}
+	if history := agent.Sessions.GetHistory("agent:main:subagent-1"); len(history) != 0 {
+		t.Errorf("task session should be deleted, has %d messages", len(history))
+	}
+
+	changes, err := agent.Checkpoints.Changes("agent:main:subagent-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "notes.txt" {
+		t.Errorf("task changes = %+v, want notes.txt in the task's own turn", changes)
+	}
+}
+
+func TestFormatSubagentResult(t *testing.T) {
+	got := formatSubagentResult(map[string]string{
+		tools.SubagentMetaTaskID: "subagent-1",
+		tools.SubagentMetaAgent:  "research",
+		tools.SubagentMetaStatus: "completed",
+	}, "Found 3 sources")
+	want := "[System: subagent task subagent-1 (agent research) completed]\nFound 3 sources"
+	if got != want {
+		t.Errorf("formatSubagentResult = %q, want %q", got, want)
+	}
+}
+
+func TestHandleTasksCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	agent := al.registry.GetDefaultAgent()
+
+	if response, _ := handleTasksCommand(agent, "/tasks"); response != "No subagent tasks." {
+		t.Errorf("unexpected /tasks response %q", response)
+	}
+	if _, err := agent.SubagentTasks.Run(context.Background(), "check the weather", "weather", "", "cli", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	response, _ := handleTasksCommand(agent, "/tasks")
+	if !strings.Contains(response, "subagent-1 [completed] weather") {
+		t.Errorf("/tasks should list the finished task, got %q", response)
+	}
+	if response, _ := handleTasksCommand(agent, "/tasks cancel subagent-1"); !strings.Contains(response, "not running") {
+		t.Errorf("unexpected cancel response %q", response)
+	}
+	if _, handled := handleTasksCommand(agent, "/taskslist"); handled {
+		t.Error("/taskslist is not the /tasks command")
+	}
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handleVoiceCommand handles /voice, which shows whether replies in the
+// routed session are spoken, and /voice on|off, which changes it. ttsEnabled
+// reports whether voice.tts is configured.
+func handleVoiceCommand(agent *AgentInstance, sessionKey, content string, ttsEnabled bool) (string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || fields[0] != "/voice" {
+		return "", false
+	}
+
+	if len(fields) == 1 {
+		if agent.Sessions.VoiceReply(sessionKey) {
+			return "Voice replies are on. Use /voice off to get text replies.", true
+		}
+		return "Voice replies are off. Use /voice on to hear replies.", true
+	}
+
+	switch fields[1] {
+	case "on":
+		if !ttsEnabled {
+			return "Voice replies need a text-to-speech provider in voice.tts of the config.", true
+		}
+		agent.Sessions.SetVoiceReply(sessionKey, true)
+		if err := agent.Sessions.Save(sessionKey); err != nil {
+			return fmt.Sprintf("Failed to save the voice setting: %v", err), true
+		}
+		return "Voice replies on. Replies with code or longer than the configured limit are still sent as text.", true
+	case "off":
+		agent.Sessions.SetVoiceReply(sessionKey, false)
+		if err := agent.Sessions.Save(sessionKey); err != nil {
+			return fmt.Sprintf("Failed to save the voice setting: %v", err), true
+		}
+		return "Voice replies off.", true
+	default:
+		return "Usage: /voice [on|off]", true
+	}
+}
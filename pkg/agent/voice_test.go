@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHandleVoiceCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	agent := NewAgentInstance(nil, &cfg.Agents.Defaults, cfg, &mockProvider{})
+
+	if _, handled := handleVoiceCommand(agent, "chat-1", "/voiceover", true); handled {
+		t.Error("expected /voiceover not to be handled")
+	}
+	if response, _ := handleVoiceCommand(agent, "chat-1", "/voice on", false); agent.Sessions.VoiceReply("chat-1") {
+		t.Errorf("expected voice replies to stay off without a TTS provider, got %q", response)
+	}
+
+	handleVoiceCommand(agent, "chat-1", "/voice on", true)
+	if !agent.Sessions.VoiceReply("chat-1") {
+		t.Fatal("expected /voice on to turn voice replies on")
+	}
+	if agent.Sessions.VoiceReply("chat-2") {
+		t.Error("expected voice replies to be per session")
+	}
+	if response, _ := handleVoiceCommand(agent, "chat-1", "/voice", true); response != "Voice replies are on. Use /voice off to get text replies." {
+		t.Errorf("unexpected /voice response %q", response)
+	}
+
+	handleVoiceCommand(agent, "chat-1", "/voice off", true)
+	if agent.Sessions.VoiceReply("chat-1") {
+		t.Error("expected /voice off to turn voice replies off")
+	}
+	if response, _ := handleVoiceCommand(agent, "chat-1", "/voice loud", true); response != "Usage: /voice [on|off]" {
+		t.Errorf("unexpected response %q", response)
+	}
+}
+
+func TestProcessMessageVoiceFlag(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Voice: config.VoiceConfig{TTS: config.TTSConfig{Provider: "command"}},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	msg := func(content string) bus.InboundMessage {
+		return bus.InboundMessage{
+			Channel: "test", SenderID: "user1", ChatID: "chat1", Content: content, SessionKey: "voice-session",
+		}
+	}
+
+	if _, voice, err := al.processMessage(context.Background(), msg("/voice on"), true); err != nil || voice {
+		t.Fatalf("processMessage(/voice on) = voice %v, err %v; want a text reply", voice, err)
+	}
+	if _, voice, err := al.processMessage(context.Background(), msg("hello"), true); err != nil || !voice {
+		t.Fatalf("processMessage(hello) = voice %v, err %v; want a spoken reply", voice, err)
+	}
+	if _, voice, _ := al.processMessage(context.Background(), msg("/voice"), true); voice {
+		t.Error("expected the /voice status reply to be sent as text")
+	}
+}
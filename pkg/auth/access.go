@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+var accessTokenMu sync.Mutex
+
+// AccessToken returns a valid access token for provider from the credential
+// store, refreshing it through cfg's token endpoint and saving the result
+// when it is about to expire. refreshToken seeds the store when it holds no
+// credential for provider yet.
+func AccessToken(provider string, cfg OAuthProviderConfig, refreshToken string) (string, error) {
+	accessTokenMu.Lock()
+	defer accessTokenMu.Unlock()
+
+	cred, err := GetCredential(provider)
+	if err != nil {
+		return "", fmt.Errorf("loading auth credentials: %w", err)
+	}
+	if cred == nil && refreshToken != "" {
+		cred = &AuthCredential{RefreshToken: refreshToken, Provider: provider, AuthMethod: "oauth"}
+	}
+	if cred == nil {
+		return "", fmt.Errorf("no credentials for %s. Run: picoclaw auth login --provider %s", provider, provider)
+	}
+
+	if (cred.AccessToken == "" || cred.NeedsRefresh()) && cred.RefreshToken != "" && cfg.TokenURL != "" {
+		refreshed, err := RefreshAccessToken(cred, cfg)
+		if err != nil {
+			return "", fmt.Errorf("refreshing token: %w", err)
+		}
+		refreshed.Provider = provider
+		if err := SetCredential(provider, refreshed); err != nil {
+			return "", fmt.Errorf("saving refreshed token: %w", err)
+		}
+		cred = refreshed
+	}
+
+	if cred.AccessToken == "" || cred.IsExpired() {
+		return "", fmt.Errorf("%s credentials expired at %s. Run: picoclaw auth login --provider %s",
+			provider, cred.ExpiresAt.Local().Format(time.DateTime), provider)
+	}
+	return cred.AccessToken, nil
+}
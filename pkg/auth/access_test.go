@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessTokenSeedsAndRefreshes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var refreshes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("refresh_token") != "seed-refresh" || r.Form.Get("client_id") != "mail-client" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"fresh-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	cfg := OAuthProviderConfig{TokenURL: server.URL, ClientID: "mail-client"}
+	token, err := AccessToken("email-work", cfg, "seed-refresh")
+	if err != nil {
+		t.Fatalf("AccessToken: %v", err)
+	}
+	if token != "fresh-token" {
+		t.Errorf("token = %q, want fresh-token", token)
+	}
+
+	// The refreshed token is stored and reused until it nears expiry.
+	if token, err = AccessToken("email-work", cfg, ""); err != nil || token != "fresh-token" {
+		t.Fatalf("second AccessToken = %q, %v", token, err)
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+	cred, _ := GetCredential("email-work")
+	if cred == nil || cred.RefreshToken != "seed-refresh" || cred.Provider != "email-work" {
+		t.Fatalf("stored credential = %+v", cred)
+	}
+
+	cred.ExpiresAt = time.Now().Add(time.Minute)
+	SetCredential("email-work", cred)
+	if _, err := AccessToken("email-work", cfg, ""); err != nil {
+		t.Fatalf("AccessToken after expiry: %v", err)
+	}
+	if refreshes != 2 {
+		t.Errorf("refreshes = %d, want 2", refreshes)
+	}
+}
+
+func TestAccessTokenWithoutCredential(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := AccessToken("email-work", OAuthProviderConfig{}, "")
+	if err == nil || !strings.Contains(err.Error(), "picoclaw auth login --provider email-work") {
+		t.Errorf("err = %v, want login hint", err)
+	}
+}
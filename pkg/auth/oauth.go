@@ -26,6 +26,7 @@ type OAuthProviderConfig struct {
 	ClientSecret string // Required for Google OAuth (confidential client)
 	TokenURL     string // Override token endpoint (Google uses a different URL than issuer)
 	Scopes       string
+	RefreshScope string // Scope sent on refresh; defaults to "openid profile email"
 	Originator   string
 	Port         int
 }
@@ -58,6 +59,67 @@ func GoogleAntigravityOAuthConfig() OAuthProviderConfig {
 	}
 }
 
+// AzureCognitiveServicesScope is the Entra ID scope for Azure OpenAI data-plane access.
+const AzureCognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+// AzureEntraOAuthConfig returns the Entra ID (Azure AD) OAuth configuration for
+// Azure OpenAI. An empty tenantID uses the multi-tenant "organizations" endpoint.
+// ClientID defaults to the Azure CLI public client, matching tokens obtained with
+// `az account get-access-token --resource https://cognitiveservices.azure.com`.
+func AzureEntraOAuthConfig(tenantID, clientID, clientSecret string) OAuthProviderConfig {
+	if tenantID == "" {
+		tenantID = "organizations"
+	}
+	if clientID == "" {
+		clientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+	}
+	issuer := "https://login.microsoftonline.com/" + tenantID + "/oauth2/v2.0"
+	return OAuthProviderConfig{
+		Issuer:       issuer,
+		TokenURL:     issuer + "/token",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       AzureCognitiveServicesScope + " offline_access",
+		RefreshScope: AzureCognitiveServicesScope + " offline_access",
+	}
+}
+
+// ClientCredentialsToken obtains an access token with the OAuth2 client
+// credentials grant (service principals). cfg.ClientSecret and cfg.TokenURL are required.
+func ClientCredentialsToken(cfg OAuthProviderConfig, provider string) (*AuthCredential, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("client_id and client_secret are required for client credentials")
+	}
+	if cfg.TokenURL == "" {
+		return nil, fmt.Errorf("token URL is required for client credentials")
+	}
+
+	data := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"scope":         {cfg.Scopes},
+	}
+
+	resp, err := http.PostForm(cfg.TokenURL, data)
+	if err != nil {
+		return nil, fmt.Errorf("requesting client credentials token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("client credentials token request failed: %s", string(body))
+	}
+
+	cred, err := parseTokenResponse(body, provider)
+	if err != nil {
+		return nil, err
+	}
+	cred.AuthMethod = "client_credentials"
+	return cred, nil
+}
+
 func decodeBase64(s string) string {
 	data, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
@@ -326,11 +388,15 @@ func RefreshAccessToken(cred *AuthCredential, cfg OAuthProviderConfig) (*AuthCre
 		return nil, fmt.Errorf("no refresh token available")
 	}
 
+	scope := cfg.RefreshScope
+	if scope == "" {
+		scope = "openid profile email"
+	}
 	data := url.Values{
 		"client_id":     {cfg.ClientID},
 		"grant_type":    {"refresh_token"},
 		"refresh_token": {cred.RefreshToken},
-		"scope":         {"openid profile email"},
+		"scope":         {scope},
 	}
 	if cfg.ClientSecret != "" {
 		data.Set("client_secret", cfg.ClientSecret)
@@ -321,6 +321,82 @@ func TestRefreshAccessTokenPreservesRefreshAndAccountID(t *testing.T) {
 	}
 }
 
+func TestRefreshAccessTokenUsesRefreshScope(t *testing.T) {
+	var gotScope string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		gotScope = r.FormValue("scope")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "azure-token", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	cfg := AzureEntraOAuthConfig("tenant-1", "", "")
+	cfg.TokenURL = server.URL + "/token"
+	cred := &AuthCredential{AccessToken: "old", RefreshToken: "r", Provider: "azure", AuthMethod: "oauth"}
+
+	if _, err := RefreshAccessToken(cred, cfg); err != nil {
+		t.Fatalf("RefreshAccessToken() error: %v", err)
+	}
+	if gotScope != AzureCognitiveServicesScope+" offline_access" {
+		t.Errorf("scope = %q, want cognitive services scope", gotScope)
+	}
+}
+
+func TestAzureEntraOAuthConfig(t *testing.T) {
+	cfg := AzureEntraOAuthConfig("", "", "")
+	if cfg.TokenURL != "https://login.microsoftonline.com/organizations/oauth2/v2.0/token" {
+		t.Errorf("TokenURL = %q", cfg.TokenURL)
+	}
+	if cfg.ClientID == "" {
+		t.Error("ClientID is empty")
+	}
+
+	cfg = AzureEntraOAuthConfig("contoso", "app-id", "secret")
+	if !strings.Contains(cfg.TokenURL, "/contoso/") {
+		t.Errorf("TokenURL = %q, want tenant in path", cfg.TokenURL)
+	}
+	if cfg.ClientID != "app-id" || cfg.ClientSecret != "secret" {
+		t.Errorf("client credentials not applied: %+v", cfg)
+	}
+}
+
+func TestClientCredentialsToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "invalid grant_type", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("client_secret") != "secret" {
+			http.Error(w, "bad secret", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "sp-token", "expires_in": 3600})
+	}))
+	defer server.Close()
+
+	cfg := AzureEntraOAuthConfig("tenant", "app-id", "secret")
+	cfg.TokenURL = server.URL + "/token"
+
+	cred, err := ClientCredentialsToken(cfg, "azure")
+	if err != nil {
+		t.Fatalf("ClientCredentialsToken() error: %v", err)
+	}
+	if cred.AccessToken != "sp-token" {
+		t.Errorf("AccessToken = %q, want %q", cred.AccessToken, "sp-token")
+	}
+	if cred.AuthMethod != "client_credentials" {
+		t.Errorf("AuthMethod = %q, want client_credentials", cred.AuthMethod)
+	}
+	if cred.ExpiresAt.IsZero() {
+		t.Error("ExpiresAt should be set")
+	}
+
+	if _, err := ClientCredentialsToken(AzureEntraOAuthConfig("", "", ""), "azure"); err == nil {
+		t.Error("expected error without client secret")
+	}
+}
+
 func TestOpenAIOAuthConfig(t *testing.T) {
 	cfg := OpenAIOAuthConfig()
 	if cfg.Issuer != "https://auth.openai.com" {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
 func LoginPasteToken(provider string, r io.Reader) (*AuthCredential, error) {
@@ -24,11 +25,18 @@ func LoginPasteToken(provider string, r io.Reader) (*AuthCredential, error) {
 		return nil, fmt.Errorf("token cannot be empty")
 	}
 
-	return &AuthCredential{
+	cred := &AuthCredential{
 		AccessToken: token,
 		Provider:    provider,
 		AuthMethod:  "token",
-	}, nil
+	}
+	// Entra ID access tokens are JWTs; record their expiry so callers can report it.
+	if claims, err := parseJWTClaims(token); err == nil {
+		if exp, ok := claims["exp"].(float64); ok && exp > 0 {
+			cred.ExpiresAt = time.Unix(int64(exp), 0)
+		}
+	}
+	return cred, nil
 }
 
 func providerDisplayName(provider string) string {
@@ -37,6 +45,8 @@ func providerDisplayName(provider string) string {
 		return "console.anthropic.com"
 	case "openai":
 		return "platform.openai.com"
+	case "azure":
+		return "`az account get-access-token --resource https://cognitiveservices.azure.com`"
 	default:
 		return provider
 	}
@@ -10,6 +10,7 @@ type MessageBus struct {
 	outbound chan OutboundMessage
 	handlers map[string]MessageHandler
 	closed   bool
+	redact   func(string) string
 	mu       sync.RWMutex
 }
 
@@ -45,9 +46,20 @@ func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 	if mb.closed {
 		return
 	}
+	if mb.redact != nil {
+		msg.Content = mb.redact(msg.Content)
+	}
 	mb.outbound <- msg
 }
 
+// SetRedactor filters the content of outbound messages, e.g. to mask
+// secrets. nil disables filtering.
+func (mb *MessageBus) SetRedactor(fn func(string) string) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.redact = fn
+}
+
 func (mb *MessageBus) SubscribeOutbound(ctx context.Context) (OutboundMessage, bool) {
 	select {
 	case msg := <-mb.outbound:
@@ -14,6 +14,7 @@ type OutboundMessage struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
 	Content string `json:"content"`
+	Voice   bool   `json:"voice,omitempty"` // Speak the reply if the channel can send audio
 }
 
 type MessageHandler func(InboundMessage) error
@@ -3,8 +3,11 @@ package channels
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type Channel interface {
@@ -16,6 +19,18 @@ type Channel interface {
 	IsAllowed(senderID string) bool
 }
 
+// AudioSender is implemented by channels that can send an audio file, which
+// the manager uses for voice replies.
+type AudioSender interface {
+	SendAudio(ctx context.Context, chatID, audioPath string) error
+}
+
+// AudioReceiver is implemented by channels that receive voice messages. The
+// manager gives them the configured transcriber.
+type AudioReceiver interface {
+	SetTranscriber(transcriber voice.Transcriber)
+}
+
 type BaseChannel struct {
 	config    any
 	bus       *bus.MessageBus
@@ -101,3 +116,38 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
+
+// transcriptionTimeoutFor returns how long transcriber may take. Local
+// command backends such as whisper.cpp are slow on small devices, so they
+// get longer than the HTTP APIs.
+func transcriptionTimeoutFor(transcriber voice.Transcriber) time.Duration {
+	if _, ok := transcriber.(*voice.CommandTranscriber); ok {
+		return commandTranscriptionTimeout
+	}
+	return transcriptionTimeout
+}
+
+// transcribeVoice transcribes the audio at path for channel. It returns
+// false if no transcriber is available or transcription fails; the caller
+// then falls back to a placeholder.
+func transcribeVoice(ctx context.Context, transcriber voice.Transcriber, channel, path string) (string, bool) {
+	if transcriber == nil || !transcriber.IsAvailable() {
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcriptionTimeoutFor(transcriber))
+	defer cancel()
+
+	result, err := transcriber.Transcribe(ctx, path)
+	if err != nil {
+		logger.ErrorCF(channel, "Voice transcription failed", map[string]any{
+			"error": err.Error(),
+			"path":  path,
+		})
+		return "", false
+	}
+	logger.DebugCF(channel, "Voice transcribed successfully", map[string]any{
+		"text": result.Text,
+	})
+	return result.Text, true
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -18,15 +19,16 @@ import (
 )
 
 const (
-	transcriptionTimeout = 30 * time.Second
-	sendTimeout          = 10 * time.Second
+	transcriptionTimeout        = 30 * time.Second
+	commandTranscriptionTimeout = 2 * time.Minute // Local whisper backends are slow on small devices
+	sendTimeout                 = 10 * time.Second
 )
 
 type DiscordChannel struct {
 	*BaseChannel
 	session     *discordgo.Session
 	config      config.DiscordConfig
-	transcriber *voice.GroqTranscriber
+	transcriber voice.Transcriber
 	ctx         context.Context
 	typingMu    sync.Mutex
 	typingStop  map[string]chan struct{} // chatID → stop signal
@@ -51,7 +53,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 	}, nil
 }
 
-func (c *DiscordChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *DiscordChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -137,6 +139,30 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
+// SendAudio uploads a voice reply as an audio attachment.
+func (c *DiscordChannel) SendAudio(ctx context.Context, chatID, audioPath string) error {
+	c.stopTyping(chatID)
+
+	if !c.IsRunning() {
+		return fmt.Errorf("discord bot not running")
+	}
+	if chatID == "" {
+		return fmt.Errorf("channel ID is empty")
+	}
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return fmt.Errorf("failed to open audio: %w", err)
+	}
+	defer f.Close()
+
+	name := "reply" + filepath.Ext(audioPath)
+	if _, err := c.session.ChannelFileSend(chatID, name, f, discordgo.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to send discord audio: %w", err)
+	}
+	return nil
+}
+
 func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
 	// Use the passed ctx for timeout control
 	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
@@ -235,7 +261,7 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 
 				var transcribedText string
 				if c.transcriber != nil && c.transcriber.IsAvailable() {
-					ctx, cancel := context.WithTimeout(c.getContext(), transcriptionTimeout)
+					ctx, cancel := context.WithTimeout(c.getContext(), transcriptionTimeoutFor(c.transcriber))
 					result, err := c.transcriber.Transcribe(ctx, localPath)
 					cancel() // Release context resources immediately to avoid leaks in for loop
 
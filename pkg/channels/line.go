@@ -19,6 +19,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 const (
@@ -49,6 +50,7 @@ type LINEChannel struct {
 	botDisplayName string   // Bot's display name for text-based mention detection
 	replyTokens    sync.Map // chatID -> replyTokenEntry
 	quoteTokens    sync.Map // chatID -> quoteToken (string)
+	transcriber    voice.Transcriber
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -67,6 +69,11 @@ func NewLINEChannel(cfg config.LINEConfig, messageBus *bus.MessageBus) (*LINECha
 	}, nil
 }
 
+// SetTranscriber sets the transcriber for audio messages.
+func (c *LINEChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
 // Start launches the HTTP webhook server.
 func (c *LINEChannel) Start(ctx context.Context) error {
 	logger.InfoC("line", "Starting LINE channel (Webhook Mode)")
@@ -276,6 +283,14 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 	chatID := c.resolveChatID(event.Source)
 	isGroup := event.Source.Type == "group" || event.Source.Type == "room"
 
+	// Check allowlist first to avoid downloading and transcribing voice for rejected users
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("line", "Message rejected by allowlist", map[string]any{
+			"sender_id": senderID,
+		})
+		return
+	}
+
 	var msg lineMessage
 	if err := json.Unmarshal(event.Message, &msg); err != nil {
 		logger.ErrorCF("line", "Failed to parse message", map[string]any{
@@ -340,6 +355,9 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 			localFiles = append(localFiles, localPath)
 			mediaPaths = append(mediaPaths, localPath)
 			content = "[audio]"
+			if text, ok := transcribeVoice(c.ctx, c.transcriber, "line", localPath); ok {
+				content = fmt.Sprintf("[voice transcription: %s]", text)
+			}
 		}
 	case "video":
 		localPath := c.downloadContent(msg.ID, "video.mp4")
@@ -9,12 +9,16 @@ package channels
 import (
 	"context"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/constants"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type Manager struct {
@@ -22,6 +26,8 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	synthesizer  voice.Synthesizer
+	ttsMaxChars  int
 	mu           sync.RWMutex
 }
 
@@ -268,9 +274,22 @@ func (m *Manager) StopAll(ctx context.Context) error {
 	return nil
 }
 
+// outboundQueueSize is how many messages wait for each channel before the
+// dispatcher blocks.
+const outboundQueueSize = 100
+
+type outboundSend struct {
+	channel Channel
+	msg     bus.OutboundMessage
+}
+
+// dispatchOutbound hands each outbound message to a sender goroutine of
+// its channel, so that a slow send, such as synthesizing a voice reply,
+// only delays later messages of the same channel.
 func (m *Manager) dispatchOutbound(ctx context.Context) {
 	logger.InfoC("channels", "Outbound dispatcher started")
 
+	queues := make(map[string]chan outboundSend)
 	for {
 		select {
 		case <-ctx.Done():
@@ -298,9 +317,34 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
-			if err := channel.Send(ctx, msg); err != nil {
+			queue, ok := queues[msg.Channel]
+			if !ok {
+				queue = make(chan outboundSend, outboundQueueSize)
+				queues[msg.Channel] = queue
+				go m.sendOutbound(ctx, queue)
+			}
+			select {
+			case queue <- outboundSend{channel: channel, msg: msg}:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// sendOutbound sends the messages of one channel in order. Replies flagged
+// with Voice are spoken when possible and otherwise sent as text.
+func (m *Manager) sendOutbound(ctx context.Context, queue <-chan outboundSend) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-queue:
+			if item.msg.Voice && m.sendSpeech(ctx, item.channel, item.msg) {
+				continue
+			}
+			if err := item.channel.Send(ctx, item.msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]any{
-					"channel": msg.Channel,
+					"channel": item.msg.Channel,
 					"error":   err.Error(),
 				})
 			}
@@ -308,6 +352,85 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 	}
 }
 
+// SetTranscriber gives transcriber to every channel that receives voice
+// messages.
+func (m *Manager) SetTranscriber(transcriber voice.Transcriber) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for name, channel := range m.channels {
+		if receiver, ok := channel.(AudioReceiver); ok {
+			receiver.SetTranscriber(transcriber)
+			logger.InfoCF("voice", "Transcription attached to channel", map[string]any{
+				"channel": name,
+			})
+		}
+	}
+}
+
+// SetSynthesizer enables voice replies. Replies flagged with Voice that are
+// at most maxChars long are spoken on channels that implement AudioSender.
+func (m *Manager) SetSynthesizer(synthesizer voice.Synthesizer, maxChars int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if maxChars <= 0 {
+		maxChars = voice.DefaultTTSMaxChars
+	}
+	m.synthesizer = synthesizer
+	m.ttsMaxChars = maxChars
+}
+
+// sendSpeech sends msg as audio and reports whether it did. On false the
+// caller sends the text instead.
+func (m *Manager) sendSpeech(ctx context.Context, channel Channel, msg bus.OutboundMessage) bool {
+	m.mu.RLock()
+	synthesizer, maxChars := m.synthesizer, m.ttsMaxChars
+	m.mu.RUnlock()
+
+	sender, ok := channel.(AudioSender)
+	if !ok || synthesizer == nil || !synthesizer.IsAvailable() {
+		return false
+	}
+	// Code is sent as text: it cannot be read out usefully.
+	if strings.Contains(msg.Content, "```") {
+		return false
+	}
+	text := speechText(msg.Content)
+	if text == "" || len([]rune(text)) > maxChars {
+		return false
+	}
+
+	path, err := synthesizer.Synthesize(ctx, text)
+	if err != nil {
+		logger.ErrorCF("voice", "Speech synthesis failed, sending text", map[string]any{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+		return false
+	}
+	defer os.Remove(path)
+
+	if err := sender.SendAudio(ctx, msg.ChatID, path); err != nil {
+		logger.ErrorCF("voice", "Failed to send voice reply, sending text", map[string]any{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+var (
+	speechLink   = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	speechMarkup = strings.NewReplacer("**", "", "__", "", "`", "", "#", "", "*", "", ">", "")
+)
+
+// speechText strips Markdown from a reply so that the synthesizer does not
+// read out symbols. Links keep only their text.
+func speechText(content string) string {
+	text := speechLink.ReplaceAllString(content, "$1")
+	return strings.TrimSpace(speechMarkup.Replace(text))
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
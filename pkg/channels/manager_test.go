@@ -0,0 +1,295 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/voice"
+)
+
+type fakeSynthesizer struct {
+	dir   string
+	texts []string
+	err   error
+}
+
+func (s *fakeSynthesizer) Synthesize(ctx context.Context, text string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.texts = append(s.texts, text)
+	path := filepath.Join(s.dir, fmt.Sprintf("reply%d.ogg", len(s.texts)))
+	return path, os.WriteFile(path, []byte("audio"), 0o644)
+}
+
+func (s *fakeSynthesizer) IsAvailable() bool { return true }
+
+type audioChannel struct {
+	*BaseChannel
+	audio   []string
+	sendErr error
+	sent    chan bus.OutboundMessage
+}
+
+func (c *audioChannel) Start(ctx context.Context) error { return nil }
+func (c *audioChannel) Stop(ctx context.Context) error  { return nil }
+func (c *audioChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if c.sent != nil {
+		c.sent <- msg
+	}
+	return nil
+}
+
+func (c *audioChannel) SendAudio(ctx context.Context, chatID, audioPath string) error {
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+	c.audio = append(c.audio, audioPath)
+	return nil
+}
+
+func TestManagerSendSpeech(t *testing.T) {
+	m, err := NewManager(&config.Config{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	channel := &audioChannel{BaseChannel: NewBaseChannel("test", nil, nil, nil)}
+	msg := bus.OutboundMessage{Channel: "test", ChatID: "1", Voice: true}
+
+	msg.Content = "Hello"
+	if m.sendSpeech(context.Background(), channel, msg) {
+		t.Fatal("expected no speech without a synthesizer")
+	}
+
+	synth := &fakeSynthesizer{dir: t.TempDir()}
+	m.SetSynthesizer(synth, 40)
+
+	msg.Content = "It is **sunny**, see [the forecast](https://example.com)."
+	if !m.sendSpeech(context.Background(), channel, msg) {
+		t.Fatal("expected the reply to be spoken")
+	}
+	if len(synth.texts) != 1 || synth.texts[0] != "It is sunny, see the forecast." {
+		t.Errorf("unexpected synthesized text %q", synth.texts)
+	}
+	if _, err := os.Stat(channel.audio[0]); !os.IsNotExist(err) {
+		t.Error("expected the audio file to be removed after sending")
+	}
+
+	for _, content := range []string{
+		"This reply is far too long to be spoken aloud.",
+		"Run:\n```\nls -la\n```",
+	} {
+		msg.Content = content
+		if m.sendSpeech(context.Background(), channel, msg) {
+			t.Errorf("expected %q to be sent as text", content)
+		}
+	}
+
+	msg.Content = "Hello"
+	channel.sendErr = fmt.Errorf("upload failed")
+	if m.sendSpeech(context.Background(), channel, msg) {
+		t.Error("expected a failed upload to fall back to text")
+	}
+	synth.err = fmt.Errorf("no voice")
+	if m.sendSpeech(context.Background(), channel, msg) {
+		t.Error("expected a failed synthesis to fall back to text")
+	}
+}
+
+type fakeTranscriber struct{}
+
+func (fakeTranscriber) Transcribe(ctx context.Context, path string) (*voice.TranscriptionResponse, error) {
+	return &voice.TranscriptionResponse{Text: "hello from " + filepath.Base(path)}, nil
+}
+
+func (fakeTranscriber) IsAvailable() bool { return true }
+
+// blockingSynthesizer fails once release is closed.
+type blockingSynthesizer struct {
+	release chan struct{}
+}
+
+func (s *blockingSynthesizer) Synthesize(ctx context.Context, text string) (string, error) {
+	<-s.release
+	return "", fmt.Errorf("no voice")
+}
+
+func (s *blockingSynthesizer) IsAvailable() bool { return true }
+
+func TestManagerSpeechDoesNotBlockOtherChannels(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	m, err := NewManager(&config.Config{}, msgBus)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	slow := &audioChannel{BaseChannel: NewBaseChannel("slow", nil, nil, nil), sent: make(chan bus.OutboundMessage, 1)}
+	fast := &audioChannel{BaseChannel: NewBaseChannel("fast", nil, nil, nil), sent: make(chan bus.OutboundMessage, 1)}
+	m.RegisterChannel("slow", slow)
+	m.RegisterChannel("fast", fast)
+	synth := &blockingSynthesizer{release: make(chan struct{})}
+	m.SetSynthesizer(synth, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.dispatchOutbound(ctx)
+
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "slow", ChatID: "1", Content: "Hello", Voice: true})
+	msgBus.PublishOutbound(bus.OutboundMessage{Channel: "fast", ChatID: "2", Content: "Hi"})
+
+	select {
+	case msg := <-fast.sent:
+		if msg.Content != "Hi" {
+			t.Errorf("fast channel got %q", msg.Content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a voice reply being synthesized blocked another channel")
+	}
+
+	close(synth.release)
+	select {
+	case msg := <-slow.sent:
+		if msg.Content != "Hello" {
+			t.Errorf("slow channel got %q", msg.Content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a failed synthesis to fall back to text")
+	}
+}
+
+func TestManagerSetTranscriber(t *testing.T) {
+	m, err := NewManager(&config.Config{}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	whatsapp, _ := NewWhatsAppChannel(config.WhatsAppConfig{}, bus.NewMessageBus())
+	m.RegisterChannel("whatsapp", whatsapp)
+	line, _ := NewLINEChannel(config.LINEConfig{ChannelSecret: "s", ChannelAccessToken: "t"}, bus.NewMessageBus())
+	m.RegisterChannel("line", line)
+	m.RegisterChannel("test", &audioChannel{BaseChannel: NewBaseChannel("test", nil, nil, nil)})
+
+	m.SetTranscriber(fakeTranscriber{})
+	if whatsapp.transcriber == nil {
+		t.Fatal("expected the transcriber to be attached to WhatsApp")
+	}
+	if line.transcriber == nil {
+		t.Fatal("expected the transcriber to be attached to LINE")
+	}
+}
+
+func TestWhatsAppTranscribesVoiceNotes(t *testing.T) {
+	mediaDir := t.TempDir()
+	note := filepath.Join(mediaDir, "note.ogg")
+	outside := filepath.Join(t.TempDir(), "secret.ogg")
+	for _, path := range []string{note, outside} {
+		if err := os.WriteFile(path, []byte("audio"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msgBus := bus.NewMessageBus()
+	cfg := config.WhatsAppConfig{MediaDir: mediaDir, AllowFrom: config.FlexibleStringSlice{"123"}}
+	whatsapp, _ := NewWhatsAppChannel(cfg, msgBus)
+	transcriber := &countingTranscriber{}
+	whatsapp.SetTranscriber(transcriber)
+
+	whatsapp.handleIncomingMessage(context.Background(), map[string]any{
+		"from":  "999",
+		"media": []any{note},
+	})
+	whatsapp.handleIncomingMessage(context.Background(), map[string]any{
+		"from":  "123",
+		"media": []any{note, outside, filepath.Join(mediaDir, "photo.jpg")},
+	})
+
+	msg, ok := msgBus.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("expected an inbound message")
+	}
+	if msg.SenderID != "123" || msg.Content != "[voice transcription: hello from note.ogg]" {
+		t.Errorf("unexpected message from %s: %q", msg.SenderID, msg.Content)
+	}
+	if n := transcriber.calls.Load(); n != 1 {
+		t.Errorf("transcribed %d files, want only the allowed sender's note inside the media directory", n)
+	}
+}
+
+func TestLINETranscribesVoiceNotesOfAllowedSenders(t *testing.T) {
+	// Serve every LINE API call locally and count the content downloads.
+	var downloads atomic.Int32
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/content") {
+			downloads.Add(1)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("audio")),
+			Request:    req,
+		}, nil
+	})
+	defer func() { http.DefaultTransport = transport }()
+
+	msgBus := bus.NewMessageBus()
+	cfg := config.LINEConfig{ChannelSecret: "s", ChannelAccessToken: "t", AllowFrom: config.FlexibleStringSlice{"U123"}}
+	line, _ := NewLINEChannel(cfg, msgBus)
+	line.ctx = context.Background()
+	transcriber := &countingTranscriber{}
+	line.SetTranscriber(transcriber)
+
+	for _, sender := range []string{"U999", "U123"} {
+		line.processEvent(lineEvent{
+			Type:    "message",
+			Source:  lineSource{Type: "user", UserID: sender},
+			Message: json.RawMessage(`{"id":"m-` + sender + `","type":"audio"}`),
+		})
+	}
+
+	msg, ok := msgBus.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("expected an inbound message")
+	}
+	if msg.SenderID != "U123" || !strings.HasPrefix(msg.Content, "[voice transcription: ") {
+		t.Errorf("unexpected message from %s: %q", msg.SenderID, msg.Content)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("downloaded %d voice notes, want only the allowed sender's", n)
+	}
+	if n := transcriber.calls.Load(); n != 1 {
+		t.Errorf("transcribed %d voice notes, want only the allowed sender's", n)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+type countingTranscriber struct {
+	fakeTranscriber
+	calls atomic.Int32
+}
+
+func (t *countingTranscriber) Transcribe(ctx context.Context, path string) (*voice.TranscriptionResponse, error) {
+	t.calls.Add(1)
+	return t.fakeTranscriber.Transcribe(ctx, path)
+}
+
+func TestTranscriptionTimeoutFor(t *testing.T) {
+	if got := transcriptionTimeoutFor(voice.NewCommandTranscriber([]string{"whisper-cli"})); got != commandTranscriptionTimeout {
+		t.Errorf("command backend timeout = %v", got)
+	}
+	if got := transcriptionTimeoutFor(fakeTranscriber{}); got != transcriptionTimeout {
+		t.Errorf("HTTP backend timeout = %v", got)
+	}
+}
@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -35,7 +36,7 @@ type OneBotChannel struct {
 	selfID          int64
 	pending         map[string]chan json.RawMessage
 	pendingMu       sync.Mutex
-	transcriber     *voice.GroqTranscriber
+	transcriber     voice.Transcriber
 	lastMessageID   sync.Map
 	pendingEmojiMsg sync.Map
 }
@@ -111,7 +112,7 @@ func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*One
 	}, nil
 }
 
-func (c *OneBotChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *OneBotChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -377,6 +378,40 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("OneBot channel not running")
 	}
 
+	action, params, err := c.buildSendRequest(msg)
+	if err != nil {
+		return err
+	}
+	return c.sendMessage(msg.ChatID, action, params)
+}
+
+// SendAudio sends a voice reply as a record segment. The audio is embedded
+// as base64, so it also works when the OneBot implementation runs on
+// another host.
+func (c *OneBotChannel) SendAudio(ctx context.Context, chatID, audioPath string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("OneBot channel not running")
+	}
+
+	data, err := os.ReadFile(audioPath)
+	if err != nil {
+		return fmt.Errorf("failed to read audio: %w", err)
+	}
+	segments := []oneBotMessageSegment{{
+		Type: "record",
+		Data: map[string]any{"file": "base64://" + base64.StdEncoding.EncodeToString(data)},
+	}}
+
+	action, params, err := buildSendParams(chatID, segments)
+	if err != nil {
+		return err
+	}
+	return c.sendMessage(chatID, action, params)
+}
+
+// sendMessage writes a send action to the WebSocket and clears the pending
+// reaction of chatID.
+func (c *OneBotChannel) sendMessage(chatID, action string, params any) error {
 	c.mu.Lock()
 	conn := c.conn
 	c.mu.Unlock()
@@ -385,11 +420,6 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("OneBot WebSocket not connected")
 	}
 
-	action, params, err := c.buildSendRequest(msg)
-	if err != nil {
-		return err
-	}
-
 	echo := fmt.Sprintf("send_%d", atomic.AddInt64(&c.echoCounter, 1))
 
 	req := oneBotAPIRequest{
@@ -414,7 +444,7 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return err
 	}
 
-	if msgID, ok := c.pendingEmojiMsg.LoadAndDelete(msg.ChatID); ok {
+	if msgID, ok := c.pendingEmojiMsg.LoadAndDelete(chatID); ok {
 		if mid, ok := msgID.(string); ok && mid != "" {
 			c.setMsgEmojiLike(mid, 289, false)
 		}
@@ -444,9 +474,10 @@ func (c *OneBotChannel) buildMessageSegments(chatID, content string) []oneBotMes
 }
 
 func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, any, error) {
-	chatID := msg.ChatID
-	segments := c.buildMessageSegments(chatID, msg.Content)
+	return buildSendParams(msg.ChatID, c.buildMessageSegments(msg.ChatID, msg.Content))
+}
 
+func buildSendParams(chatID string, segments []oneBotMessageSegment) (string, any, error) {
 	var action, idKey string
 	var rawID string
 	if rest, ok := strings.CutPrefix(chatID, "group:"); ok {
@@ -661,7 +692,7 @@ func (c *OneBotChannel) parseMessageSegments(raw json.RawMessage, selfID int64)
 					if localPath != "" {
 						localFiles = append(localFiles, localPath)
 						if c.transcriber != nil && c.transcriber.IsAvailable() {
-							tctx, tcancel := context.WithTimeout(c.ctx, 30*time.Second)
+							tctx, tcancel := context.WithTimeout(c.ctx, transcriptionTimeoutFor(c.transcriber))
 							result, err := c.transcriber.Transcribe(tctx, localPath)
 							tcancel()
 							if err != nil {
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -26,7 +26,7 @@ type SlackChannel struct {
 	socketClient *socketmode.Client
 	botUserID    string
 	teamID       string
-	transcriber  *voice.GroqTranscriber
+	transcriber  voice.Transcriber
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
@@ -59,7 +59,7 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 	}, nil
 }
 
-func (c *SlackChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *SlackChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -132,13 +132,7 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("failed to send slack message: %w", err)
 	}
 
-	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
-		msgRef := ref.(slackMessageRef)
-		c.api.AddReaction("white_check_mark", slack.ItemRef{
-			Channel:   msgRef.ChannelID,
-			Timestamp: msgRef.Timestamp,
-		})
-	}
+	c.ackPending(msg.ChatID)
 
 	logger.DebugCF("slack", "Message sent", map[string]any{
 		"channel_id": channelID,
@@ -148,6 +142,49 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
+// SendAudio uploads a voice reply to the chat's channel or thread. The app
+// needs the files:write scope.
+func (c *SlackChannel) SendAudio(ctx context.Context, chatID, audioPath string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("slack channel not running")
+	}
+
+	channelID, threadTS := parseSlackChatID(chatID)
+	if channelID == "" {
+		return fmt.Errorf("invalid slack chat ID: %s", chatID)
+	}
+
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return fmt.Errorf("failed to open audio: %w", err)
+	}
+
+	_, err = c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		File:            audioPath,
+		FileSize:        int(info.Size()),
+		Filename:        "reply" + filepath.Ext(audioPath),
+		Channel:         channelID,
+		ThreadTimestamp: threadTS,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload slack audio: %w", err)
+	}
+
+	c.ackPending(chatID)
+	return nil
+}
+
+// ackPending marks the message that started the reply as handled.
+func (c *SlackChannel) ackPending(chatID string) {
+	if ref, ok := c.pendingAcks.LoadAndDelete(chatID); ok {
+		msgRef := ref.(slackMessageRef)
+		c.api.AddReaction("white_check_mark", slack.ItemRef{
+			Channel:   msgRef.ChannelID,
+			Timestamp: msgRef.Timestamp,
+		})
+	}
+}
+
 func (c *SlackChannel) eventLoop() {
 	for {
 		select {
@@ -256,7 +293,7 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 			mediaPaths = append(mediaPaths, localPath)
 
 			if utils.IsAudioFile(file.Name, file.Mimetype) && c.transcriber != nil && c.transcriber.IsAvailable() {
-				ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+				ctx, cancel := context.WithTimeout(c.ctx, transcriptionTimeoutFor(c.transcriber))
 				defer cancel()
 				result, err := c.transcriber.Transcribe(ctx, localPath)
 
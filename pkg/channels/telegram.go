@@ -29,7 +29,7 @@ type TelegramChannel struct {
 	commands     TelegramCommander
 	config       *config.Config
 	chatIDs      map[string]int64
-	transcriber  *voice.GroqTranscriber
+	transcriber  voice.Transcriber
 	placeholders sync.Map // chatID -> messageID
 	stopThinking sync.Map // chatID -> thinkingCancel
 }
@@ -86,7 +86,7 @@ func NewTelegramChannel(cfg *config.Config, bus *bus.MessageBus) (*TelegramChann
 	}, nil
 }
 
-func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
+func (c *TelegramChannel) SetTranscriber(transcriber voice.Transcriber) {
 	c.transcriber = transcriber
 }
 
@@ -156,13 +156,7 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
-	// Stop thinking animation
-	if stop, ok := c.stopThinking.Load(msg.ChatID); ok {
-		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
-			cf.Cancel()
-		}
-		c.stopThinking.Delete(msg.ChatID)
-	}
+	c.stopThinkingAnimation(msg.ChatID)
 
 	htmlContent := markdownToTelegramHTML(msg.Content)
 
@@ -193,6 +187,55 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	return nil
 }
 
+// SendAudio sends a voice reply: Ogg/Opus audio as a voice note, anything
+// else as an audio file. The "Thinking..." placeholder is deleted once the
+// audio is sent; if sending fails it is left for the text fallback to edit.
+func (c *TelegramChannel) SendAudio(ctx context.Context, chatIDStr, audioPath string) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("telegram bot not running")
+	}
+
+	chatID, err := parseChatID(chatIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid chat ID: %w", err)
+	}
+
+	c.stopThinkingAnimation(chatIDStr)
+
+	f, err := os.Open(audioPath)
+	if err != nil {
+		return fmt.Errorf("failed to open audio: %w", err)
+	}
+	defer f.Close()
+
+	if voice.IsVoiceNote(audioPath) {
+		_, err = c.bot.SendVoice(ctx, tu.Voice(tu.ID(chatID), tu.File(f)))
+	} else {
+		_, err = c.bot.SendAudio(ctx, tu.Audio(tu.ID(chatID), tu.File(f)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send telegram audio: %w", err)
+	}
+
+	if pID, ok := c.placeholders.LoadAndDelete(chatIDStr); ok {
+		if err := c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(chatID), pID.(int))); err != nil {
+			logger.DebugCF("telegram", "Failed to delete placeholder", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+	return nil
+}
+
+func (c *TelegramChannel) stopThinkingAnimation(chatID string) {
+	if stop, ok := c.stopThinking.Load(chatID); ok {
+		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
+			cf.Cancel()
+		}
+		c.stopThinking.Delete(chatID)
+	}
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message) error {
 	if message == nil {
 		return fmt.Errorf("message is nil")
@@ -267,7 +310,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 
 			var transcribedText string
 			if c.transcriber != nil && c.transcriber.IsAvailable() {
-				transcriberCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				transcriberCtx, cancel := context.WithTimeout(ctx, transcriptionTimeoutFor(c.transcriber))
 				defer cancel()
 
 				result, err := c.transcriber.Transcribe(transcriberCtx, voicePath)
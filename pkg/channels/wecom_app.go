@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +22,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 const (
@@ -39,6 +41,7 @@ type WeComAppChannel struct {
 	cancel        context.CancelFunc
 	processedMsgs map[string]bool // Message deduplication: msg_id -> processed
 	msgMu         sync.RWMutex
+	transcriber   voice.Transcriber
 }
 
 // WeComXMLMessage represents the XML message structure from WeCom
@@ -426,6 +429,14 @@ func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessag
 	senderID := msg.FromUserName
 	chatID := senderID // WeCom App uses user ID as chat ID for direct messages
 
+	// Check allowlist first to avoid downloading and transcribing voice for rejected users
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("wecom_app", "Message rejected by allowlist", map[string]any{
+			"sender_id": senderID,
+		})
+		return
+	}
+
 	// Build metadata
 	// WeCom App only supports direct messages (private chat)
 	metadata := map[string]string{
@@ -440,6 +451,9 @@ func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessag
 	}
 
 	content := msg.Content
+	if msg.MsgType == "voice" {
+		content = c.voiceContent(msg)
+	}
 
 	logger.DebugCF("wecom_app", "Received message", map[string]any{
 		"sender_id": senderID,
@@ -451,6 +465,40 @@ func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessag
 	c.HandleMessage(senderID, chatID, content, nil, metadata)
 }
 
+// SetTranscriber sets the transcriber for voice messages.
+func (c *WeComAppChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
+// voiceContent downloads a voice message through the media API and returns
+// its transcription, or a placeholder when it cannot be transcribed.
+func (c *WeComAppChannel) voiceContent(msg WeComXMLMessage) string {
+	accessToken := c.getAccessToken()
+	if c.transcriber == nil || c.ctx == nil || accessToken == "" || msg.MediaId == "" {
+		return "[voice]"
+	}
+
+	format := strings.ToLower(msg.Format)
+	if format == "" {
+		format = "amr"
+	}
+	mediaURL := fmt.Sprintf("%s/cgi-bin/media/get?access_token=%s&media_id=%s",
+		wecomAPIBase, url.QueryEscape(accessToken), url.QueryEscape(msg.MediaId))
+	localPath := utils.DownloadFile(mediaURL, "voice."+format, utils.DownloadOptions{
+		LoggerPrefix: "wecom_app",
+	})
+	if localPath == "" {
+		return "[voice]"
+	}
+	defer os.Remove(localPath)
+
+	// The webhook request is already answered, so use the channel context.
+	if text, ok := transcribeVoice(c.ctx, c.transcriber, "wecom_app", localPath); ok {
+		return fmt.Sprintf("[voice transcription: %s]", text)
+	}
+	return "[voice]"
+}
+
 // tokenRefreshLoop periodically refreshes the access token
 func (c *WeComAppChannel) tokenRefreshLoop() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -13,15 +14,17 @@ import (
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/utils"
+	"github.com/sipeed/picoclaw/pkg/voice"
 )
 
 type WhatsAppChannel struct {
 	*BaseChannel
-	conn      *websocket.Conn
-	config    config.WhatsAppConfig
-	url       string
-	mu        sync.Mutex
-	connected bool
+	conn        *websocket.Conn
+	config      config.WhatsAppConfig
+	url         string
+	mu          sync.Mutex
+	connected   bool
+	transcriber voice.Transcriber
 }
 
 func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsAppChannel, error) {
@@ -35,6 +38,12 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, bus *bus.MessageBus) (*WhatsA
 	}, nil
 }
 
+// SetTranscriber sets the transcriber for voice notes received from the
+// bridge.
+func (c *WhatsAppChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
 func (c *WhatsAppChannel) Start(ctx context.Context) error {
 	log.Printf("Starting WhatsApp channel connecting to %s...", c.url)
 
@@ -141,13 +150,13 @@ func (c *WhatsAppChannel) listen(ctx context.Context) {
 			}
 
 			if msgType == "message" {
-				c.handleIncomingMessage(msg)
+				c.handleIncomingMessage(ctx, msg)
 			}
 		}
 	}
 }
 
-func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]any) {
+func (c *WhatsAppChannel) handleIncomingMessage(ctx context.Context, msg map[string]any) {
 	senderID, ok := msg["from"].(string)
 	if !ok {
 		return
@@ -158,6 +167,12 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]any) {
 		chatID = senderID
 	}
 
+	// Check allowlist first to avoid transcribing voice notes for rejected users
+	if !c.IsAllowed(senderID) {
+		log.Printf("WhatsApp message from %s rejected by allowlist", senderID)
+		return
+	}
+
 	content, ok := msg["content"].(string)
 	if !ok {
 		content = ""
@@ -189,7 +204,59 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]any) {
 		metadata["peer_id"] = chatID
 	}
 
-	log.Printf("WhatsApp message from %s: %s...", senderID, utils.Truncate(content, 50))
+	voiceNotes := c.voiceNotes(mediaPaths)
+	if len(voiceNotes) == 0 {
+		log.Printf("WhatsApp message from %s: %s...", senderID, utils.Truncate(content, 50))
+		c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+		return
+	}
+
+	// Transcription can take minutes with a local backend, so it runs off
+	// the read loop and the bridge connection keeps being served.
+	go func() {
+		for _, path := range voiceNotes {
+			if text, ok := transcribeVoice(ctx, c.transcriber, "whatsapp", path); ok {
+				if content != "" {
+					content += "\n"
+				}
+				content += fmt.Sprintf("[voice transcription: %s]", text)
+			}
+		}
+		log.Printf("WhatsApp message from %s: %s...", senderID, utils.Truncate(content, 50))
+		c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+	}()
+}
+
+// voiceNotes returns the audio files among the media paths the bridge sent
+// that lie inside the configured media directory. The paths come from the
+// bridge, so anything outside that directory is not read.
+func (c *WhatsAppChannel) voiceNotes(mediaPaths []string) []string {
+	if c.transcriber == nil || c.config.MediaDir == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(c.config.MediaDir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	if err != nil {
+		log.Printf("WhatsApp media directory %s: %v", c.config.MediaDir, err)
+		return nil
+	}
 
-	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+	var notes []string
+	for _, path := range mediaPaths {
+		if !utils.IsAudioFile(path, "") {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, real); err != nil || !filepath.IsLocal(rel) {
+			log.Printf("WhatsApp voice note %s is outside the media directory, not transcribed", path)
+			continue
+		}
+		notes = append(notes, real)
+	}
+	return notes
 }
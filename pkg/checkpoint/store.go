@@ -0,0 +1,629 @@
+// Package checkpoint keeps copies of workspace files as they were before
+// the agent changed them, grouped by turn, so that a turn's file changes
+// can be listed, diffed and reverted.
+package checkpoint
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes is the default limit on the size of stored file contents.
+const DefaultMaxBytes = 64 << 20
+
+// FileSnapshot is the state of one file before a turn first changed it.
+type FileSnapshot struct {
+	Path string `json:"path"`           // Relative to the workspace
+	Hash string `json:"hash,omitempty"` // SHA-256 of the content; empty if the file did not exist
+	Size int64  `json:"size"`
+}
+
+// Turn groups the files changed while the agent handled one message.
+type Turn struct {
+	ID         string         `json:"id"`
+	SessionKey string         `json:"session_key,omitempty"`
+	Time       time.Time      `json:"time"`
+	Files      []FileSnapshot `json:"files"`
+	Undone     bool           `json:"undone,omitempty"`     // Its files have been restored
+	RestoreOf  string         `json:"restore_of,omitempty"` // Recorded while restoring this turn
+}
+
+// Change summarizes what a session did to one file.
+type Change struct {
+	Path   string // Relative to the workspace
+	Status string // "created", "modified" or "deleted"
+	Turns  int
+}
+
+type index struct {
+	Turns []*Turn `json:"turns"`
+}
+
+// Store is a content-addressed store of file snapshots under
+// <workspace>/checkpoints. Each distinct file content is stored once, in
+// objects/ under its SHA-256; index.json lists the turns. Only files inside
+// the workspace are recorded, and restores go through an os.Root of the
+// workspace, so an edited index cannot make them write anywhere else.
+type Store struct {
+	workspace string
+	dir       string
+	maxBytes  int64
+
+	mu     sync.Mutex
+	active map[string]*Turn // Turns being recorded, by ID
+	loose  *Turn            // Snapshots taken outside a turn
+}
+
+// Recorder records the files changed during one turn. Turns of the same
+// store may overlap, e.g. a subagent task running while the agent answers
+// the user; each groups its snapshots in its own Recorder.
+type Recorder struct {
+	store *Store
+	turn  *Turn
+}
+
+// NewStore creates a store for workspace. Nothing is written until the
+// first snapshot.
+func NewStore(workspace string) *Store {
+	if abs, err := filepath.Abs(workspace); err == nil {
+		workspace = abs
+	}
+	return &Store{
+		workspace: workspace,
+		dir:       filepath.Join(workspace, "checkpoints"),
+		maxBytes:  DefaultMaxBytes,
+		active:    make(map[string]*Turn),
+	}
+}
+
+// SetMaxBytes sets the size above which the oldest turns are pruned.
+func (s *Store) SetMaxBytes(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > 0 {
+		s.maxBytes = n
+	}
+}
+
+// BeginTurn starts a turn of sessionKey. The files changed during the turn
+// are recorded through the returned Recorder until its End is called.
+func (s *Store) BeginTurn(sessionKey string) *Recorder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turn := &Turn{ID: newTurnID(), SessionKey: sessionKey, Time: time.Now()}
+	s.active[turn.ID] = turn
+	s.loose = nil
+	return &Recorder{store: s, turn: turn}
+}
+
+// ID returns the ID of the recorded turn.
+func (r *Recorder) ID() string {
+	return r.turn.ID
+}
+
+// Snapshot records content as the state of path before the turn changed
+// it; existed is false when the file is being created. Only the first
+// snapshot of a path in a turn is kept.
+func (r *Recorder) Snapshot(path string, content []byte, existed bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.store.snapshot(r.turn, path, content, existed)
+}
+
+// End finishes the turn and prunes old turns if the store has grown past
+// its limit.
+func (r *Recorder) End() error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, r.turn.ID)
+	if len(r.turn.Files) == 0 {
+		return nil
+	}
+	return s.prune()
+}
+
+// Snapshot records a snapshot taken outside any turn. Such snapshots are
+// grouped into a turn of their own until the next turn begins.
+func (s *Store) Snapshot(path string, content []byte, existed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loose == nil {
+		s.loose = &Turn{ID: newTurnID(), Time: time.Now()}
+	}
+	return s.snapshot(s.loose, path, content, existed)
+}
+
+func (s *Store) snapshot(turn *Turn, path string, content []byte, existed bool) error {
+	path, err := s.local(path)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(turn.Files, func(f FileSnapshot) bool { return f.Path == path }) {
+		return nil
+	}
+
+	snap := FileSnapshot{Path: path}
+	if existed {
+		if snap.Hash, err = s.writeObject(content); err != nil {
+			return err
+		}
+		snap.Size = int64(len(content))
+	}
+	turn.Files = append(turn.Files, snap)
+	return s.saveTurn(turn)
+}
+
+// Turns returns all recorded turns, oldest first.
+func (s *Store) Turns() ([]*Turn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return idx.Turns, nil
+}
+
+// Turn returns the turn with the given ID or unique ID prefix.
+func (s *Store) Turn(id string) (*Turn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return findTurn(idx, id)
+}
+
+// Load returns the stored content with the given hash.
+func (s *Store) Load(hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, fmt.Errorf("invalid object hash %q", hash)
+	}
+	return os.ReadFile(s.objectPath(hash))
+}
+
+// Rel returns path relative to the workspace when it is inside it.
+func (s *Store) Rel(path string) string {
+	if rel, err := s.local(path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// ReadFile returns the current content of path, a file recorded in a turn,
+// read through the workspace root.
+func (s *Store) ReadFile(path string) ([]byte, error) {
+	rel, err := s.local(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(s.workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	defer root.Close()
+	return root.ReadFile(rel)
+}
+
+// Restore puts the files of turn id back the way they were before it. If
+// paths is not empty, only those files are restored. The files' current
+// contents are recorded in a new turn first, so a restore can itself be
+// restored.
+func (s *Store) Restore(id string, paths ...string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	turn, err := findTurn(idx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.restore(idx, turn, paths)
+}
+
+// Undo restores the most recent turn of sessionKey that changed files and
+// has not been undone yet. It returns nil if there is nothing to undo.
+func (s *Store) Undo(sessionKey string) (*Turn, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := len(idx.Turns) - 1; i >= 0; i-- {
+		turn := idx.Turns[i]
+		if turn.SessionKey != sessionKey || turn.Undone || turn.RestoreOf != "" || len(turn.Files) == 0 {
+			continue
+		}
+		restored, err := s.restore(idx, turn, nil)
+		return turn, restored, err
+	}
+	return nil, nil, nil
+}
+
+// Changes lists the files changed in sessionKey's turns that have not been
+// undone, in the order they were first changed.
+func (s *Store) Changes(sessionKey string) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	positions := make(map[string]int)
+	for _, turn := range idx.Turns {
+		if turn.SessionKey != sessionKey || turn.Undone || turn.RestoreOf != "" {
+			continue
+		}
+		for _, f := range turn.Files {
+			if i, ok := positions[f.Path]; ok {
+				changes[i].Turns++
+				continue
+			}
+			positions[f.Path] = len(changes)
+			status := "modified"
+			if f.Hash == "" {
+				status = "created"
+			}
+			changes = append(changes, Change{Path: f.Path, Status: status, Turns: 1})
+		}
+	}
+	for i := range changes {
+		if _, err := os.Stat(filepath.Join(s.workspace, changes[i].Path)); errors.Is(err, fs.ErrNotExist) {
+			if changes[i].Status == "created" {
+				changes[i].Status = "created and deleted"
+			} else {
+				changes[i].Status = "deleted"
+			}
+		}
+	}
+	return changes, nil
+}
+
+// Prune drops the oldest turns until the stored contents fit the size
+// limit, and removes contents no remaining turn refers to.
+func (s *Store) Prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prune()
+}
+
+func (s *Store) restore(idx *index, turn *Turn, paths []string) ([]string, error) {
+	wanted := func(path string) bool {
+		if len(paths) == 0 {
+			return true
+		}
+		return slices.ContainsFunc(paths, func(p string) bool {
+			rel, err := s.local(p)
+			return err == nil && rel == path
+		})
+	}
+
+	root, err := os.OpenRoot(s.workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	defer root.Close()
+
+	backup := &Turn{ID: newTurnID(), SessionKey: turn.SessionKey, Time: time.Now(), RestoreOf: turn.ID}
+	var restored []string
+	for _, f := range turn.Files {
+		path, err := s.local(f.Path)
+		if err != nil {
+			return restored, fmt.Errorf("checkpoint %s: %w", turn.ID, err)
+		}
+		if !wanted(path) {
+			continue
+		}
+
+		perm := os.FileMode(0o644)
+		current, err := root.ReadFile(path)
+		switch {
+		case err == nil:
+			hash, err := s.writeObject(current)
+			if err != nil {
+				return restored, err
+			}
+			backup.Files = append(backup.Files, FileSnapshot{Path: path, Hash: hash, Size: int64(len(current))})
+			if info, err := root.Stat(path); err == nil {
+				perm = info.Mode().Perm()
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			backup.Files = append(backup.Files, FileSnapshot{Path: path})
+		default:
+			return restored, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if f.Hash == "" {
+			if err := root.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return restored, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		} else {
+			content, err := s.Load(f.Hash)
+			if err != nil {
+				return restored, fmt.Errorf("snapshot of %s is missing: %w", path, err)
+			}
+			if err := writeRootFileAtomic(root, path, content, perm); err != nil {
+				return restored, err
+			}
+		}
+		restored = append(restored, path)
+	}
+	if len(paths) > 0 && len(restored) == 0 {
+		return nil, fmt.Errorf("turn %s did not change %s", turn.ID, strings.Join(paths, ", "))
+	}
+
+	if len(paths) == 0 {
+		turn.Undone = true
+	}
+	if len(backup.Files) > 0 {
+		idx.Turns = append(idx.Turns, backup)
+	}
+	return restored, s.save(idx)
+}
+
+// local returns path relative to the workspace. Absolute paths, as written
+// by earlier versions, are accepted when they are inside it; anything that
+// leads outside the workspace is rejected.
+func (s *Store) local(path string) (string, error) {
+	rel := path
+	if filepath.IsAbs(path) {
+		var err error
+		if rel, err = filepath.Rel(s.workspace, path); err != nil {
+			return "", fmt.Errorf("%s is outside the workspace", path)
+		}
+	}
+	rel = filepath.Clean(rel)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the workspace", path)
+	}
+	return rel, nil
+}
+
+func (s *Store) prune() error {
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	size := func(turns []*Turn) int64 {
+		seen := make(map[string]bool)
+		var total int64
+		for _, t := range turns {
+			for _, f := range t.Files {
+				if f.Hash != "" && !seen[f.Hash] {
+					seen[f.Hash] = true
+					total += f.Size
+				}
+			}
+		}
+		return total
+	}
+	dropped := 0
+	for len(idx.Turns)-dropped > 1 && size(idx.Turns[dropped:]) > s.maxBytes {
+		dropped++
+	}
+	if dropped > 0 {
+		idx.Turns = idx.Turns[dropped:]
+		if err := s.save(idx); err != nil {
+			return err
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, t := range idx.Turns {
+		for _, f := range t.Files {
+			referenced[f.Hash] = true
+		}
+	}
+	for _, t := range s.active {
+		for _, f := range t.Files {
+			referenced[f.Hash] = true
+		}
+	}
+	if s.loose != nil {
+		for _, f := range s.loose.Files {
+			referenced[f.Hash] = true
+		}
+	}
+	objects := filepath.Join(s.dir, "objects")
+	return filepath.WalkDir(objects, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		hash := filepath.Base(filepath.Dir(path)) + d.Name()
+		if !referenced[hash] {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// validHash reports whether hash is a SHA-256 in lowercase hex, the only
+// names objects are stored under.
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for i := 0; i < len(hash); i++ {
+		if !('0' <= hash[i] && hash[i] <= '9' || 'a' <= hash[i] && hash[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.dir, "objects", hash[:2], hash[2:])
+}
+
+func (s *Store) writeObject(content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := s.ensureDir(); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	if err := writeFileAtomic(path, content, 0o644); err != nil {
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return hash, nil
+}
+
+// ensureDir creates the store directory with a .gitignore that keeps it
+// out of git and out of the search tools.
+func (s *Store) ensureDir() error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	ignore := filepath.Join(s.dir, ".gitignore")
+	if _, err := os.Stat(ignore); errors.Is(err, fs.ErrNotExist) {
+		os.WriteFile(ignore, []byte("*\n"), 0o644)
+	}
+	return nil
+}
+
+func (s *Store) saveTurn(turn *Turn) error {
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	saved := *turn
+	saved.Files = slices.Clone(turn.Files)
+	if i := slices.IndexFunc(idx.Turns, func(t *Turn) bool { return t.ID == turn.ID }); i >= 0 {
+		idx.Turns[i] = &saved
+	} else {
+		idx.Turns = append(idx.Turns, &saved)
+	}
+	return s.save(idx)
+}
+
+// load reads the index from disk, so that the gateway and the CLI see
+// each other's changes. Absolute paths written by earlier versions are made
+// relative to the workspace.
+func (s *Store) load() (*index, error) {
+	idx := &index{}
+	data, err := os.ReadFile(filepath.Join(s.dir, "index.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint index: %w", err)
+	}
+	for _, t := range idx.Turns {
+		for i, f := range t.Files {
+			// The agent can write the index; a hash names a file to read.
+			if f.Hash != "" && !validHash(f.Hash) {
+				return nil, fmt.Errorf("checkpoint index: invalid object hash %q for %s", f.Hash, f.Path)
+			}
+			if rel, err := s.local(f.Path); err == nil {
+				t.Files[i].Path = rel
+			}
+		}
+	}
+	return idx, nil
+}
+
+func (s *Store) save(idx *index) error {
+	if err := s.ensureDir(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint index: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, "index.json"), data, 0o644)
+}
+
+func findTurn(idx *index, id string) (*Turn, error) {
+	var found *Turn
+	for _, t := range idx.Turns {
+		if t.ID == id {
+			return t, nil
+		}
+		if strings.HasPrefix(t.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("checkpoint %q is ambiguous", id)
+			}
+			found = t
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("checkpoint %q not found", id)
+	}
+	return found, nil
+}
+
+func newTurnID() string {
+	var b [3]byte
+	rand.Read(b[:])
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// writeRootFileAtomic writes the file name inside root the way
+// writeFileAtomic does.
+func writeRootFileAtomic(root *os.Root, name string, data []byte, perm os.FileMode) error {
+	if dir := filepath.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create parent directories: %w", err)
+		}
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", name, time.Now().UnixNano())
+	if err := root.WriteFile(tmp, data, perm); err != nil {
+		root.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := root.Rename(tmp, name); err != nil {
+		root.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotter is a Store or a Recorder.
+type snapshotter interface {
+	Snapshot(path string, content []byte, existed bool) error
+}
+
+// change snapshots path and then writes content to it, the way the
+// filesystem tools do. An empty content removes the file.
+func change(t *testing.T, s snapshotter, path, content string) {
+	t.Helper()
+	old, err := os.ReadFile(path)
+	require.NoError(t, s.Snapshot(path, old, err == nil))
+	if content == "" {
+		require.NoError(t, os.Remove(path))
+		return
+	}
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestStore_UndoRevertsLastTurn(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	a := filepath.Join(workspace, "a.txt")
+	b := filepath.Join(workspace, "b.txt")
+	require.NoError(t, os.WriteFile(a, []byte("v1"), 0o644))
+
+	r := s.BeginTurn("chat-1")
+	change(t, r, a, "v2")
+	change(t, r, a, "v3") // only the first snapshot in a turn counts
+	require.NoError(t, r.End())
+
+	r = s.BeginTurn("chat-1")
+	change(t, r, b, "new")
+	change(t, r, a, "v4")
+	require.NoError(t, r.End())
+
+	r = s.BeginTurn("chat-2")
+	change(t, r, a, "other session")
+	require.NoError(t, r.End())
+
+	turn, restored, err := s.Undo("chat-1")
+	require.NoError(t, err)
+	require.NotNil(t, turn)
+	assert.ElementsMatch(t, []string{"a.txt", "b.txt"}, restored)
+	assert.Equal(t, "v3", readFile(t, a))
+	assert.Equal(t, "<missing>", readFile(t, b))
+
+	turn, _, err = s.Undo("chat-1")
+	require.NoError(t, err)
+	require.NotNil(t, turn)
+	assert.Equal(t, "v1", readFile(t, a))
+
+	turn, _, err = s.Undo("chat-1")
+	require.NoError(t, err)
+	assert.Nil(t, turn, "nothing left to undo")
+}
+
+func TestStore_Changes(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	kept := filepath.Join(workspace, "kept.txt")
+	gone := filepath.Join(workspace, "gone.txt")
+	created := filepath.Join(workspace, "docs", "new.md")
+	require.NoError(t, os.WriteFile(kept, []byte("x"), 0o644))
+	require.NoError(t, os.WriteFile(gone, []byte("y"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Dir(created), 0o755))
+
+	r := s.BeginTurn("chat")
+	change(t, r, kept, "x2")
+	change(t, r, created, "hello")
+	require.NoError(t, r.End())
+	r = s.BeginTurn("chat")
+	change(t, r, kept, "x3")
+	change(t, r, gone, "")
+	require.NoError(t, r.End())
+
+	changes, err := s.Changes("chat")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "kept.txt", Status: "modified", Turns: 2},
+		{Path: filepath.Join("docs", "new.md"), Status: "created", Turns: 1},
+		{Path: "gone.txt", Status: "deleted", Turns: 1},
+	}, changes)
+	assert.Equal(t, filepath.Join("docs", "new.md"), s.Rel(created))
+
+	changes, err = s.Changes("another")
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestStore_RestoreCanBeRestored(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	a := filepath.Join(workspace, "a.txt")
+	b := filepath.Join(workspace, "b.txt")
+	require.NoError(t, os.WriteFile(a, []byte("a1"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("b1"), 0o644))
+
+	r := s.BeginTurn("chat")
+	change(t, r, a, "a2")
+	change(t, r, b, "b2")
+	require.NoError(t, r.End())
+	id := r.ID()
+
+	// Restore a single file by its workspace-relative path and ID prefix.
+	restored, err := s.Restore(id[:len(id)-2], "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt"}, restored)
+	assert.Equal(t, "a1", readFile(t, a))
+	assert.Equal(t, "b2", readFile(t, b))
+
+	turns, err := s.Turns()
+	require.NoError(t, err)
+	require.Len(t, turns, 2)
+	backup := turns[1]
+	assert.Equal(t, id, backup.RestoreOf)
+	assert.False(t, turns[0].Undone, "a partial restore does not undo the turn")
+
+	_, err = s.Restore(backup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "a2", readFile(t, a))
+
+	_, err = s.Restore(id, "missing.txt")
+	assert.ErrorContains(t, err, "did not change")
+	_, err = s.Restore("nope")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestStore_PruneDropsOldestTurns(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	s.SetMaxBytes(25)
+	path := filepath.Join(workspace, "f.txt")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o644))
+
+	var ids []string
+	write := func(next string) {
+		r := s.BeginTurn("chat")
+		ids = append(ids, r.ID())
+		change(t, r, path, next)
+		require.NoError(t, r.End())
+	}
+	turnIDs := func() []string {
+		turns, err := s.Turns()
+		require.NoError(t, err)
+		var out []string
+		for _, turn := range turns {
+			out = append(out, turn.ID)
+		}
+		return out
+	}
+
+	// Content shared by several turns is only counted once: four turns
+	// that snapshot two distinct 10-byte contents fit in 25 bytes.
+	for _, next := range []string{"abcdefghij", "0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		write(next)
+	}
+	assert.Equal(t, ids, turnIDs())
+
+	write("klmnopqrst")
+	assert.Equal(t, ids[3:], turnIDs())
+
+	objects, err := filepath.Glob(filepath.Join(workspace, "checkpoints", "objects", "*", "*"))
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
+
+func TestStore_SnapshotOutsideTurn(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	path := filepath.Join(workspace, "f.txt")
+
+	change(t, s, path, "created")
+	turns, err := s.Turns()
+	require.NoError(t, err)
+	require.Len(t, turns, 1)
+	assert.Equal(t, []FileSnapshot{{Path: "f.txt"}}, turns[0].Files)
+
+	data, err := os.ReadFile(filepath.Join(workspace, "checkpoints", ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(data))
+}
+
+func TestStore_OverlappingTurns(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	a := filepath.Join(workspace, "a.txt")
+	b := filepath.Join(workspace, "b.txt")
+
+	// A subagent task runs while the agent answers the user: each turn
+	// keeps its own files, whichever ends first.
+	user := s.BeginTurn("chat")
+	task := s.BeginTurn("agent:main:subagent-1")
+	change(t, user, a, "from user turn")
+	change(t, task, b, "from task")
+	require.NoError(t, task.End())
+	change(t, user, b, "user again")
+	require.NoError(t, user.End())
+
+	changes, err := s.Changes("chat")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "a.txt", Status: "created", Turns: 1},
+		{Path: "b.txt", Status: "modified", Turns: 1},
+	}, changes)
+	changes, err = s.Changes("agent:main:subagent-1")
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Path: "b.txt", Status: "created", Turns: 1}}, changes)
+}
+
+func TestStore_RejectsPathsOutsideWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	require.NoError(t, os.MkdirAll(workspace, 0o755))
+	outside := filepath.Join(dir, "outside.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("keep"), 0o644))
+	s := NewStore(workspace)
+
+	r := s.BeginTurn("chat")
+	assert.ErrorContains(t, r.Snapshot(outside, []byte("keep"), true), "outside the workspace")
+	change(t, r, filepath.Join(workspace, "in.txt"), "inside")
+	require.NoError(t, r.End())
+
+	// An index edited to point outside the workspace cannot be restored,
+	// whether the path is relative or absolute.
+	index := filepath.Join(workspace, "checkpoints", "index.json")
+	data, err := os.ReadFile(index)
+	require.NoError(t, err)
+	for _, evil := range []string{"../outside.txt", outside} {
+		encoded, err := json.Marshal(evil)
+		require.NoError(t, err)
+		edited := strings.Replace(string(data), `"in.txt"`, string(encoded), 1)
+		require.NoError(t, os.WriteFile(index, []byte(edited), 0o644))
+
+		_, err = s.Restore(r.ID())
+		assert.ErrorContains(t, err, "outside the workspace")
+		assert.Equal(t, "keep", readFile(t, outside))
+	}
+}
+
+func TestStore_RejectsInvalidObjectHashes(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	require.NoError(t, os.MkdirAll(workspace, 0o755))
+	secret := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0o644))
+	s := NewStore(workspace)
+
+	path := filepath.Join(workspace, "in.txt")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o644))
+	r := s.BeginTurn("chat")
+	change(t, r, path, "v2")
+	require.NoError(t, r.End())
+
+	turn, err := s.Turn(r.ID())
+	require.NoError(t, err)
+	hash := turn.Files[0].Hash
+	content, err := s.Load(hash)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	for _, evil := range []string{"../../../secret.txt", strings.ToUpper(hash), hash[:10]} {
+		_, err := s.Load(evil)
+		assert.ErrorContains(t, err, "invalid object hash", evil)
+	}
+
+	// An index edited to point an object outside the store is refused.
+	index := filepath.Join(workspace, "checkpoints", "index.json")
+	data, err := os.ReadFile(index)
+	require.NoError(t, err)
+	edited := strings.Replace(string(data), hash, "../../../secret.txt", 1)
+	require.NoError(t, os.WriteFile(index, []byte(edited), 0o644))
+	_, err = s.Restore(r.ID())
+	assert.ErrorContains(t, err, "invalid object hash")
+	assert.Equal(t, "v2", readFile(t, path))
+}
+
+func TestStore_LegacyAbsolutePaths(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+	path := filepath.Join(workspace, "notes.md")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o600))
+
+	r := s.BeginTurn("chat")
+	change(t, r, path, "v2")
+	require.NoError(t, r.End())
+
+	// Earlier versions stored absolute paths.
+	index := filepath.Join(workspace, "checkpoints", "index.json")
+	data, err := os.ReadFile(index)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(index, []byte(strings.Replace(string(data), `"notes.md"`, string(encoded), 1)), 0o644))
+
+	turn, restored, err := s.Undo("chat")
+	require.NoError(t, err)
+	require.NotNil(t, turn)
+	assert.Equal(t, []string{"notes.md"}, restored)
+	assert.Equal(t, "v1", readFile(t, path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "restore keeps the file mode")
+}
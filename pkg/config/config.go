@@ -3,8 +3,10 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 
 	"github.com/caarlos0/env/v11"
@@ -57,6 +59,8 @@ type Config struct {
 	Tools     ToolsConfig     `json:"tools"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
+	MCP       MCPConfig       `json:"mcp,omitzero"`
+	Voice     VoiceConfig     `json:"voice,omitzero"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Config
@@ -136,11 +140,15 @@ type AgentConfig struct {
 	Model     *AgentModelConfig `json:"model,omitempty"`
 	Skills    []string          `json:"skills,omitempty"`
 	Subagents *SubagentsConfig  `json:"subagents,omitempty"`
+	Egress    *EgressRules      `json:"egress,omitempty"` // Added to tools.egress for this agent's HTTP tools
 }
 
 type SubagentsConfig struct {
-	AllowAgents []string          `json:"allow_agents,omitempty"`
-	Model       *AgentModelConfig `json:"model,omitempty"`
+	AllowAgents    []string          `json:"allow_agents,omitempty"`
+	Model          *AgentModelConfig `json:"model,omitempty"`
+	MaxDepth       int               `json:"max_depth,omitempty"`       // How deep subagents may nest (default 2)
+	MaxConcurrent  int               `json:"max_concurrent,omitempty"`  // Subagents running at once (default 4)
+	TimeoutMinutes int               `json:"timeout_minutes,omitempty"` // Wall-clock limit per task (default 30)
 }
 
 type PeerMatch struct {
@@ -205,9 +213,10 @@ type ChannelsConfig struct {
 }
 
 type WhatsAppConfig struct {
-	Enabled   bool                `json:"enabled"    env:"PICOCLAW_CHANNELS_WHATSAPP_ENABLED"`
-	BridgeURL string              `json:"bridge_url" env:"PICOCLAW_CHANNELS_WHATSAPP_BRIDGE_URL"`
-	AllowFrom FlexibleStringSlice `json:"allow_from" env:"PICOCLAW_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	Enabled   bool                `json:"enabled"             env:"PICOCLAW_CHANNELS_WHATSAPP_ENABLED"`
+	BridgeURL string              `json:"bridge_url"          env:"PICOCLAW_CHANNELS_WHATSAPP_BRIDGE_URL"`
+	AllowFrom FlexibleStringSlice `json:"allow_from"          env:"PICOCLAW_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	MediaDir  string              `json:"media_dir,omitempty" env:"PICOCLAW_CHANNELS_WHATSAPP_MEDIA_DIR"` // Where the bridge saves media; voice notes are transcribed only from here
 }
 
 type TelegramConfig struct {
@@ -311,6 +320,39 @@ type HeartbeatConfig struct {
 	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
 }
 
+// VoiceConfig configures speech on chat channels.
+type VoiceConfig struct {
+	Transcription TranscriptionConfig `json:"transcription,omitzero"`
+	TTS           TTSConfig           `json:"tts,omitzero"`
+}
+
+// TranscriptionConfig selects the speech-to-text backend for voice messages.
+// Provider "groq" and "openai" call an OpenAI-compatible
+// /audio/transcriptions endpoint; set APIBase for a self-hosted server.
+// "command" runs a local program such as whisper.cpp. Without a provider,
+// Groq is used when a Groq API key is configured.
+type TranscriptionConfig struct {
+	Provider string   `json:"provider,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_PROVIDER"`
+	APIKey   string   `json:"api_key,omitempty"  env:"PICOCLAW_VOICE_TRANSCRIPTION_API_KEY"`
+	APIBase  string   `json:"api_base,omitempty" env:"PICOCLAW_VOICE_TRANSCRIPTION_API_BASE"`
+	Model    string   `json:"model,omitempty"`   // groq: whisper-large-v3, openai: whisper-1
+	Command  []string `json:"command,omitempty"` // command: argv with {input} or {input_wav}; transcript on stdout
+}
+
+// TTSConfig selects the text-to-speech backend for voice replies, which a
+// chat turns on with /voice. Provider "openai" calls an OpenAI-compatible
+// /audio/speech endpoint; "command" runs a local program such as piper.
+type TTSConfig struct {
+	Provider string   `json:"provider,omitempty"  env:"PICOCLAW_VOICE_TTS_PROVIDER"`
+	APIKey   string   `json:"api_key,omitempty"   env:"PICOCLAW_VOICE_TTS_API_KEY"`
+	APIBase  string   `json:"api_base,omitempty"  env:"PICOCLAW_VOICE_TTS_API_BASE"`
+	Model    string   `json:"model,omitempty"`     // openai: default tts-1
+	Voice    string   `json:"voice,omitempty"`     // openai: default alloy
+	Format   string   `json:"format,omitempty"`    // Audio format; default opus (openai) or wav (command)
+	Command  []string `json:"command,omitempty"`   // command: argv; text on stdin, {output} is the audio file
+	MaxChars int      `json:"max_chars,omitempty"` // Longer replies are sent as text (default 1500)
+}
+
 type DevicesConfig struct {
 	Enabled    bool `json:"enabled"     env:"PICOCLAW_DEVICES_ENABLED"`
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
@@ -386,7 +428,7 @@ type OpenAIProviderConfig struct {
 // ModelConfig represents a model-centric provider configuration.
 // It allows adding new providers (especially OpenAI-compatible ones) via configuration only.
 // The model field uses protocol prefix format: [protocol/]model-identifier
-// Supported protocols: openai, anthropic, antigravity, claude-cli, codex-cli, github-copilot
+// Supported protocols: openai, anthropic, azure, bedrock, antigravity, claude-cli, codex-cli, github-copilot
 // Default protocol is "openai" if no prefix is specified.
 type ModelConfig struct {
 	// Required fields
@@ -403,9 +445,20 @@ type ModelConfig struct {
 	ConnectMode string `json:"connect_mode,omitempty"` // Connection mode: stdio, grpc
 	Workspace   string `json:"workspace,omitempty"`    // Workspace path for CLI-based providers
 
+	// Deployment-style endpoints (Azure OpenAI)
+	Deployment string `json:"deployment,omitempty"`  // Deployment name; defaults to the model identifier
+	APIVersion string `json:"api_version,omitempty"` // api-version query parameter, or "v1" for the v1 API
+	AuthHeader string `json:"auth_header,omitempty"` // Credential header style: api-key (default), bearer
+
+	// Cloud region (Amazon Bedrock); defaults to AWS_REGION / shared config
+	Region string `json:"region,omitempty"`
+
 	// Optional optimizations
 	RPM            int    `json:"rpm,omitempty"`              // Requests per minute limit
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
+
+	// Tool calling: "native" (default) or "prompted" for endpoints without function calling
+	ToolMode string `json:"tool_mode,omitempty"`
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -420,8 +473,9 @@ func (c *ModelConfig) Validate() error {
 }
 
 type GatewayConfig struct {
-	Host string `json:"host" env:"PICOCLAW_GATEWAY_HOST"`
-	Port int    `json:"port" env:"PICOCLAW_GATEWAY_PORT"`
+	Host          string `json:"host"                     env:"PICOCLAW_GATEWAY_HOST"`
+	Port          int    `json:"port"                     env:"PICOCLAW_GATEWAY_PORT"`
+	ProbeInterval int    `json:"probe_interval,omitempty" env:"PICOCLAW_GATEWAY_PROBE_INTERVAL"` // minutes, 0 disables provider probes
 }
 
 type BraveConfig struct {
@@ -462,16 +516,231 @@ type CronToolsConfig struct {
 	ExecTimeoutMinutes int `json:"exec_timeout_minutes" env:"PICOCLAW_TOOLS_CRON_EXEC_TIMEOUT_MINUTES"` // 0 means no timeout
 }
 
+// ProcessToolsConfig limits the background process tool.
+type ProcessToolsConfig struct {
+	MaxProcesses   int `json:"max_processes"    env:"PICOCLAW_TOOLS_PROCESS_MAX_PROCESSES"`    // Per agent, running and finished
+	OutputBufferKB int `json:"output_buffer_kb" env:"PICOCLAW_TOOLS_PROCESS_OUTPUT_BUFFER_KB"` // Output kept per process
+}
+
+// CheckpointsConfig controls the snapshots taken before the filesystem
+// tools change a file, which /undo and "picoclaw checkpoints" restore.
+type CheckpointsConfig struct {
+	Enabled   bool `json:"enabled"     env:"PICOCLAW_TOOLS_CHECKPOINTS_ENABLED"`
+	MaxSizeMB int  `json:"max_size_mb" env:"PICOCLAW_TOOLS_CHECKPOINTS_MAX_SIZE_MB"` // Oldest turns are pruned beyond this
+}
+
+// EgressRules list hosts that HTTP tools may or may not reach. Entries are
+// host names, which may start with "*." to match subdomains, IP addresses
+// or CIDR ranges.
+type EgressRules struct {
+	Allow []string `json:"allow,omitempty"` // Reachable even if private, loopback or link-local
+	Deny  []string `json:"deny,omitempty"`  // Never reachable; takes precedence over Allow
+}
+
+// EgressConfig is the network policy of HTTP tools such as web_fetch.
+// Private, loopback, link-local and cloud metadata addresses are blocked
+// unless allowed. Rules for a tool or an agent add to the global ones.
+type EgressConfig struct {
+	AllowPrivate bool                   `json:"allow_private,omitempty" env:"PICOCLAW_TOOLS_EGRESS_ALLOW_PRIVATE"`
+	Allow        []string               `json:"allow,omitempty"`
+	Deny         []string               `json:"deny,omitempty"`
+	Tools        map[string]EgressRules `json:"tools,omitempty"` // By tool name, e.g. "web_fetch"
+}
+
+// RulesFor returns the allow and deny lists that apply to tool when used by
+// an agent with the given rules (nil if it has none).
+func (c EgressConfig) RulesFor(tool string, agent *EgressRules) EgressRules {
+	rules := EgressRules{
+		Allow: append([]string(nil), c.Allow...),
+		Deny:  append([]string(nil), c.Deny...),
+	}
+	if t, ok := c.Tools[tool]; ok {
+		rules.Allow = append(rules.Allow, t.Allow...)
+		rules.Deny = append(rules.Deny, t.Deny...)
+	}
+	if agent != nil {
+		rules.Allow = append(rules.Allow, agent.Allow...)
+		rules.Deny = append(rules.Deny, agent.Deny...)
+	}
+	return rules
+}
+
+// Validate checks that the CIDR ranges in the lists parse.
+func (r EgressRules) Validate() error {
+	if err := validateCIDRs(r.Allow); err != nil {
+		return fmt.Errorf("allow: %w", err)
+	}
+	if err := validateCIDRs(r.Deny); err != nil {
+		return fmt.Errorf("deny: %w", err)
+	}
+	return nil
+}
+
+func validateCIDRs(entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			continue
+		}
+		if _, err := netip.ParsePrefix(entry); err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+	}
+	return nil
+}
+
+// HTTPToolConfig configures the http_request tool.
+type HTTPToolConfig struct {
+	TimeoutSeconds int                         `json:"timeout_seconds,omitempty" env:"PICOCLAW_TOOLS_HTTP_TIMEOUT_SECONDS"` // Default 60
+	Secrets        map[string]HTTPSecretConfig `json:"secrets,omitempty"`                                                   // Referenced as {{secret:NAME}}
+}
+
+// HTTPSecretConfig is a credential http_request substitutes at send time.
+// Set Value, or Env to read it from an environment variable.
+type HTTPSecretConfig struct {
+	Value string   `json:"value,omitempty"`
+	Env   string   `json:"env,omitempty"`
+	Hosts []string `json:"hosts,omitempty"` // Hosts the secret may be sent to; required
+}
+
+// GitToolConfig configures the git tool, which is registered when it has
+// repositories. Without Repos, the local paths in the comma-separated
+// GIT_REPOS environment variable are used.
+type GitToolConfig struct {
+	Repos      []string `json:"repos,omitempty"       env:"PICOCLAW_TOOLS_GIT_REPOS"`
+	AllowWrite bool     `json:"allow_write,omitempty" env:"PICOCLAW_TOOLS_GIT_ALLOW_WRITE"` // Offer commit and checkout, each run only after the user sends /approve
+}
+
+// EmailToolsConfig configures the email_search, email_read and email_send
+// tools, which are registered when at least one account is set.
+type EmailToolsConfig struct {
+	Accounts       []EmailAccountConfig `json:"accounts,omitempty"`        // The first account is the default
+	AttachmentsDir string               `json:"attachments_dir,omitempty"` // Relative to the workspace; default "attachments"
+}
+
+// EmailAccountConfig is an IMAP/SMTP mailbox. With auth "password" (the
+// default) the account logs in with an app password from Password or
+// PasswordEnv; with "xoauth2" it uses the access token stored by
+// "picoclaw auth login --provider email-<name>", refreshed through OAuth
+// when it expires.
+type EmailAccountConfig struct {
+	Name        string            `json:"name"`
+	Address     string            `json:"address"`            // From address, also the login by default
+	Username    string            `json:"username,omitempty"` // Login when it differs from the address
+	Auth        string            `json:"auth,omitempty"`     // "password" or "xoauth2"
+	Password    string            `json:"password,omitempty"`
+	PasswordEnv string            `json:"password_env,omitempty"`
+	OAuth       EmailOAuthConfig  `json:"oauth,omitzero"`
+	IMAP        EmailServerConfig `json:"imap,omitzero"`
+	SMTP        EmailServerConfig `json:"smtp,omitzero"`
+	ReadOnly    bool              `json:"read_only,omitempty"` // Do not offer email_send for this account
+}
+
+// EmailServerConfig is an IMAP or SMTP endpoint. Security is "tls"
+// (default), "starttls" or "none".
+type EmailServerConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"` // Default 993 for IMAP, 465 for SMTP
+	Security string `json:"security,omitempty"`
+}
+
+// EmailOAuthConfig is the OAuth client used to refresh XOAUTH2 tokens.
+// RefreshToken seeds the credential store for headless setups.
+type EmailOAuthConfig struct {
+	TokenURL        string `json:"token_url,omitempty"`
+	ClientID        string `json:"client_id,omitempty"`
+	ClientSecret    string `json:"client_secret,omitempty"`
+	Scopes          string `json:"scopes,omitempty"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	RefreshTokenEnv string `json:"refresh_token_env,omitempty"`
+}
+
 type ExecConfig struct {
-	EnableDenyPatterns bool     `json:"enable_deny_patterns" env:"PICOCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS"`
-	CustomDenyPatterns []string `json:"custom_deny_patterns" env:"PICOCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"`
+	EnableDenyPatterns bool              `json:"enable_deny_patterns" env:"PICOCLAW_TOOLS_EXEC_ENABLE_DENY_PATTERNS"`
+	CustomDenyPatterns []string          `json:"custom_deny_patterns" env:"PICOCLAW_TOOLS_EXEC_CUSTOM_DENY_PATTERNS"`
+	Policy             ExecPolicyConfig  `json:"policy,omitzero"`
+	Sandbox            ExecSandboxConfig `json:"sandbox,omitzero"`
+}
+
+// ExecPolicyConfig adds rules to the exec command policy, which is checked
+// against the parsed shell syntax of each command. Command names may be
+// glob patterns such as "mkfs*".
+type ExecPolicyConfig struct {
+	AllowedCommands []string           `json:"allowed_commands,omitempty"` // When set, only these executables may run
+	DeniedCommands  []string           `json:"denied_commands,omitempty"`
+	ArgumentRules   []ExecArgumentRule `json:"argument_rules,omitempty"`
+	DenySubshells   bool               `json:"deny_subshells,omitempty"` // (...), $(...), <(...) and sh -c
+	DenyPipelines   bool               `json:"deny_pipelines,omitempty"`
+}
+
+// ExecArgumentRule blocks a command when all of its conditions match:
+// the leading subcommand words, any one of Flags, and any argument
+// matching ArgPattern. A rule with only Command blocks the command.
+type ExecArgumentRule struct {
+	Command    string   `json:"command"`
+	Subcommand []string `json:"subcommand,omitempty"`  // e.g. ["push"] for "git push"
+	Flags      []string `json:"flags,omitempty"`       // e.g. ["-r", "--recursive"]; combined short flags match
+	ArgPattern string   `json:"arg_pattern,omitempty"` // Regular expression matched against each argument
+	Reason     string   `json:"reason,omitempty"`      // Shown to the model when the rule blocks a command
+}
+
+// ExecSandboxConfig confines exec and cron commands with OS mechanisms
+// (Landlock, namespaces, rlimits) on Linux. When enabled on a system that
+// cannot sandbox, commands are refused rather than run unconfined.
+type ExecSandboxConfig struct {
+	Enabled       bool     `json:"enabled"                  env:"PICOCLAW_TOOLS_EXEC_SANDBOX_ENABLED"`
+	ReadOnlyPaths []string `json:"read_only_paths,omitempty"` // In addition to system binaries and libraries
+	WritablePaths []string `json:"writable_paths,omitempty"`  // In addition to the workspace
+	DenyNetwork   bool     `json:"deny_network,omitempty"   env:"PICOCLAW_TOOLS_EXEC_SANDBOX_DENY_NETWORK"`
+	CPUSeconds    int      `json:"cpu_seconds,omitempty"`
+	MemoryMB      int      `json:"memory_mb,omitempty"`
+	MaxProcesses  int      `json:"max_processes,omitempty"`
 }
 
 type ToolsConfig struct {
-	Web    WebToolsConfig    `json:"web"`
-	Cron   CronToolsConfig   `json:"cron"`
-	Exec   ExecConfig        `json:"exec"`
-	Skills SkillsToolsConfig `json:"skills"`
+	Web         WebToolsConfig     `json:"web"`
+	Cron        CronToolsConfig    `json:"cron"`
+	Exec        ExecConfig         `json:"exec"`
+	Process     ProcessToolsConfig `json:"process"`
+	Checkpoints CheckpointsConfig  `json:"checkpoints"`
+	Egress      EgressConfig       `json:"egress,omitzero"`
+	HTTP        HTTPToolConfig     `json:"http,omitzero"`
+	Email       EmailToolsConfig   `json:"email,omitzero"`
+	Git         GitToolConfig      `json:"git,omitzero"`
+	Skills      SkillsToolsConfig  `json:"skills"`
+}
+
+// MCPConfig lists external Model Context Protocol servers whose tools are
+// made available to agents.
+type MCPConfig struct {
+	Servers []MCPServerConfig `json:"servers,omitempty"`
+	Serve   MCPServeConfig    `json:"serve,omitzero"`
+}
+
+// MCPServeConfig controls "picoclaw mcp serve", which publishes PicoClaw's
+// tools to MCP clients.
+type MCPServeConfig struct {
+	Tools   []string `json:"tools,omitempty"`                                  // Exported tools; default ask_agent, read_file, web_fetch
+	Host    string   `json:"host,omitempty"    env:"PICOCLAW_MCP_SERVE_HOST"`  // HTTP listen host (default 127.0.0.1)
+	Port    int      `json:"port,omitempty"    env:"PICOCLAW_MCP_SERVE_PORT"`  // HTTP listen port (default 18791)
+	Token   string   `json:"token,omitempty"   env:"PICOCLAW_MCP_SERVE_TOKEN"` // Bearer token required over HTTP
+	Channel string   `json:"channel,omitempty"`                                // Where message and cron deliver by default
+	ChatID  string   `json:"chat_id,omitempty"`
+}
+
+// MCPServerConfig describes one MCP server. Set Command for a stdio server
+// or URL for a remote one.
+type MCPServerConfig struct {
+	Name      string            `json:"name"`                // Used to namespace tools: mcp_<name>_<tool>
+	Command   string            `json:"command,omitempty"`   // stdio: executable to launch
+	Args      []string          `json:"args,omitempty"`      // stdio: command arguments
+	Env       map[string]string `json:"env,omitempty"`       // stdio: extra environment variables
+	URL       string            `json:"url,omitempty"`       // remote: server endpoint
+	Transport string            `json:"transport,omitempty"` // remote: "http" (streamable HTTP, default) or "sse"
+	Headers   map[string]string `json:"headers,omitempty"`   // remote: extra HTTP headers (e.g. Authorization)
+	Agents    []string          `json:"agents,omitempty"`    // Agent IDs that get this server's tools; empty = all
+	Disabled  bool              `json:"disabled,omitempty"`
+	Timeout   int               `json:"timeout,omitempty"` // Per-request timeout in seconds (default 60)
 }
 
 type SkillsToolsConfig struct {
@@ -544,6 +813,10 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.ValidateEgress(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -643,6 +916,41 @@ func (c *Config) GetModelConfig(modelName string) (*ModelConfig, error) {
 	return &matches[idx], nil
 }
 
+// ReferencedModelNames returns the model names used by agent defaults and
+// per-agent overrides (primary, fallbacks and image models), deduplicated in
+// first-seen order.
+func (c *Config) ReferencedModelNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	d := c.Agents.Defaults
+	add(d.GetModelName())
+	for _, fb := range d.ModelFallbacks {
+		add(fb)
+	}
+	add(d.ImageModel)
+	for _, fb := range d.ImageModelFallbacks {
+		add(fb)
+	}
+	for _, a := range c.Agents.List {
+		if a.Model == nil {
+			continue
+		}
+		add(a.Model.Primary)
+		for _, fb := range a.Model.Fallbacks {
+			add(fb)
+		}
+	}
+	return names
+}
+
 // findMatches finds all ModelConfig entries with the given model_name.
 func (c *Config) findMatches(modelName string) []ModelConfig {
 	var matches []ModelConfig
@@ -677,6 +985,29 @@ func (c *Config) HasProvidersConfig() bool {
 		v.Mistral.APIKey != "" || v.Mistral.APIBase != ""
 }
 
+// ValidateEgress validates the egress rules in tools.egress and on each
+// agent, so that a typo cannot leave an HTTP tool without its policy.
+func (c *Config) ValidateEgress() error {
+	egress := c.Tools.Egress
+	if err := (EgressRules{Allow: egress.Allow, Deny: egress.Deny}).Validate(); err != nil {
+		return fmt.Errorf("tools.egress.%w", err)
+	}
+	for tool, rules := range egress.Tools {
+		if err := rules.Validate(); err != nil {
+			return fmt.Errorf("tools.egress.tools.%s.%w", tool, err)
+		}
+	}
+	for i, agent := range c.Agents.List {
+		if agent.Egress == nil {
+			continue
+		}
+		if err := agent.Egress.Validate(); err != nil {
+			return fmt.Errorf("agents.list[%d].egress.%w", i, err)
+		}
+	}
+	return nil
+}
+
 // ValidateModelList validates all ModelConfig entries in the model_list.
 // It checks that each model config is valid.
 // Note: Multiple entries with the same model_name are allowed for load balancing.
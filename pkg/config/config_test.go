@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -252,6 +254,31 @@ func TestDefaultConfig_Gateway(t *testing.T) {
 	if cfg.Gateway.Port == 0 {
 		t.Error("Gateway port should have default value")
 	}
+	if cfg.Gateway.ProbeInterval != 0 {
+		t.Error("Provider probes should be off by default")
+	}
+}
+
+func TestConfig_ReferencedModelNames(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.ModelName = "gpt-5.2"
+	cfg.Agents.Defaults.ModelFallbacks = []string{"claude", "gpt-5.2"}
+	cfg.Agents.Defaults.ImageModel = "vision"
+	cfg.Agents.List = []AgentConfig{
+		{ID: "a", Model: &AgentModelConfig{Primary: "local", Fallbacks: []string{"claude"}}},
+		{ID: "b"},
+	}
+
+	got := cfg.ReferencedModelNames()
+	want := []string{"gpt-5.2", "claude", "vision", "local"}
+	if len(got) != len(want) {
+		t.Fatalf("ReferencedModelNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ReferencedModelNames() = %v, want %v", got, want)
+		}
+	}
 }
 
 // TestDefaultConfig_Providers verifies provider structure
@@ -413,3 +440,57 @@ func TestLoadConfig_WebToolsProxy(t *testing.T) {
 		t.Fatalf("Tools.Web.Proxy = %q, want %q", cfg.Tools.Web.Proxy, "http://127.0.0.1:7890")
 	}
 }
+
+func TestLoadConfig_EgressRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.json")
+	configJSON := `{
+  "agents": {"list": [{"id": "home", "egress": {"allow": ["homeassistant.local"]}}]},
+  "tools": {"egress": {
+    "allow": ["10.0.0.0/8"],
+    "deny": ["*.corp.example"],
+    "tools": {"web_fetch": {"deny": ["10.0.0.1"]}}
+  }}
+}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if cfg.Tools.Egress.AllowPrivate {
+		t.Fatal("Tools.Egress.AllowPrivate should default to false")
+	}
+
+	rules := cfg.Tools.Egress.RulesFor("web_fetch", cfg.Agents.List[0].Egress)
+	wantAllow := []string{"10.0.0.0/8", "homeassistant.local"}
+	wantDeny := []string{"*.corp.example", "10.0.0.1"}
+	if !reflect.DeepEqual(rules.Allow, wantAllow) || !reflect.DeepEqual(rules.Deny, wantDeny) {
+		t.Fatalf("RulesFor(web_fetch) = %+v, want allow %v deny %v", rules, wantAllow, wantDeny)
+	}
+
+	rules = cfg.Tools.Egress.RulesFor("http_request", nil)
+	if !reflect.DeepEqual(rules.Deny, []string{"*.corp.example"}) {
+		t.Fatalf("RulesFor(http_request).Deny = %v, want only the global rule", rules.Deny)
+	}
+}
+
+func TestLoadConfig_InvalidEgressRules(t *testing.T) {
+	tests := map[string]string{
+		"tools.egress.allow":                `{"tools": {"egress": {"allow": ["10.0.0.0/33"]}}}`,
+		"tools.egress.tools.web_fetch.deny": `{"tools": {"egress": {"tools": {"web_fetch": {"deny": ["10.0.0/8"]}}}}}`,
+		"agents.list[0].egress.allow":       `{"agents": {"list": [{"id": "home", "egress": {"allow": ["a/b"]}}]}}`,
+	}
+	for want, configJSON := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(configPath, []byte(configJSON), 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error: %v", err)
+		}
+		_, err := LoadConfig(configPath)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig(%s) error = %v, want one naming %s", configJSON, err, want)
+		}
+	}
+}
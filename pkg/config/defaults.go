@@ -299,6 +299,14 @@ func DefaultConfig() *Config {
 			Exec: ExecConfig{
 				EnableDenyPatterns: true,
 			},
+			Process: ProcessToolsConfig{
+				MaxProcesses:   8,
+				OutputBufferKB: 1024,
+			},
+			Checkpoints: CheckpointsConfig{
+				Enabled:   true,
+				MaxSizeMB: 64,
+			},
 			Skills: SkillsToolsConfig{
 				Registries: SkillsRegistriesConfig{
 					ClawHub: ClawHubRegistryConfig{
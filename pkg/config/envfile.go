@@ -92,3 +92,50 @@ func parseEnvLine(line string) (key, value string, ok bool) {
 
 	return key, value, true
 }
+
+// ReadEnvFile returns the variables defined in a dotenv-like file, in the
+// formats LoadEnvFile accepts, without touching the process env.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(expandHome(strings.TrimSpace(path)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key, value, ok := parseEnvLine(scanner.Text()); ok {
+			vars[key] = value
+		}
+	}
+	return vars, scanner.Err()
+}
+
+// RemoveEnvFileKeys rewrites a dotenv-like file without the lines that
+// define keys. Comments and other lines are kept.
+func RemoveEnvFileKeys(path string, keys []string) error {
+	path = expandHome(strings.TrimSpace(path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		remove[k] = true
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if key, _, ok := parseEnvLine(line); ok && remove[key] {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return os.WriteFile(path, []byte(strings.Join(kept, "")), info.Mode().Perm())
+}
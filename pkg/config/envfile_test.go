@@ -73,3 +73,34 @@ func TestLoadEnvFile_MissingFile(t *testing.T) {
 		t.Fatal("expected loaded=false for missing env file")
 	}
 }
+
+func TestReadEnvFileAndRemoveKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.picoclaw")
+	content := "# tokens\nexport TODOIST_API_TOKEN=\"tok en\"\nGIT_REPOS=~/src\nEMAIL_PASSWORD='pw'\n"
+	if err := os.WriteFile(path, []byte(content), 0o640); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	vars, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile() error: %v", err)
+	}
+	if len(vars) != 3 || vars["TODOIST_API_TOKEN"] != "tok en" || vars["EMAIL_PASSWORD"] != "pw" {
+		t.Fatalf("ReadEnvFile() = %v", vars)
+	}
+
+	if err := RemoveEnvFileKeys(path, []string{"TODOIST_API_TOKEN", "EMAIL_PASSWORD"}); err != nil {
+		t.Fatalf("RemoveEnvFileKeys() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if got, want := string(data), "# tokens\nGIT_REPOS=~/src\n"; got != want {
+		t.Fatalf("file after RemoveEnvFileKeys() = %q, want %q", got, want)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o640 {
+		t.Fatalf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
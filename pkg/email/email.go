@@ -0,0 +1,173 @@
+// Package email talks to mail servers: a small IMAP client to search and
+// fetch messages, an SMTP sender, and MIME parsing for the fetched
+// messages.
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Security selects how a connection to a mail server is protected.
+type Security string
+
+const (
+	SecurityTLS      Security = "tls"      // Implicit TLS (IMAP 993, SMTP 465)
+	SecurityStartTLS Security = "starttls" // Plain connection upgraded with STARTTLS (SMTP 587)
+	SecurityNone     Security = "none"     // Unencrypted, for local test servers only
+)
+
+// Server is an IMAP or SMTP endpoint.
+type Server struct {
+	Host               string
+	Port               int
+	Security           Security
+	InsecureSkipVerify bool
+}
+
+// Account is a mailbox the tools can use.
+type Account struct {
+	Name     string
+	Address  string // From address
+	Username string // Login name; defaults to Address
+	Password string // App password, for password auth
+
+	// Token returns an OAuth 2.0 access token. When set, the account
+	// authenticates with XOAUTH2 instead of the password.
+	Token func(ctx context.Context) (string, error)
+
+	IMAP Server
+	SMTP Server
+}
+
+// Login returns the account's login name.
+func (a *Account) Login() string {
+	if a.Username != "" {
+		return a.Username
+	}
+	if addr, err := mail.ParseAddress(a.Address); err == nil {
+		return addr.Address
+	}
+	return a.Address
+}
+
+// OpenIMAP connects to the account's IMAP server and logs in.
+func (a *Account) OpenIMAP(ctx context.Context) (*IMAPClient, error) {
+	if a.IMAP.Host == "" {
+		return nil, fmt.Errorf("account %s has no IMAP server", a.Name)
+	}
+	c, err := DialIMAP(ctx, a.IMAP)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", a.IMAP.Host, err)
+	}
+	if a.Token != nil {
+		token, err := a.Token(ctx)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("oauth token for %s: %w", a.Name, err)
+		}
+		err = c.AuthenticateXOAuth2(a.Login(), token)
+	} else {
+		err = c.Login(a.Login(), a.Password)
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// xoauth2Response is the SASL XOAUTH2 initial response, base64-encoded.
+func xoauth2Response(user, token string) string {
+	return base64.StdEncoding.EncodeToString([]byte("user=" + user + "\x01auth=Bearer " + token + "\x01\x01"))
+}
+
+// SearchCriteria selects messages in a mailbox. Empty fields match
+// everything.
+type SearchCriteria struct {
+	From    string
+	To      string
+	Subject string
+	Text    string // Anywhere in the headers or body
+	Since   time.Time
+	Before  time.Time
+	Unseen  bool
+}
+
+func (c SearchCriteria) args() []any {
+	var args []any
+	add := func(key, value string) {
+		if value != "" {
+			args = append(args, key, imapString(value))
+		}
+	}
+	add("FROM", c.From)
+	add("TO", c.To)
+	add("SUBJECT", c.Subject)
+	add("TEXT", c.Text)
+	if !c.Since.IsZero() {
+		args = append(args, "SINCE", c.Since.Format("2-Jan-2006"))
+	}
+	if !c.Before.IsZero() {
+		args = append(args, "BEFORE", c.Before.Format("2-Jan-2006"))
+	}
+	if c.Unseen {
+		args = append(args, "UNSEEN")
+	}
+	if len(args) == 0 {
+		args = append(args, "ALL")
+	}
+	return args
+}
+
+func (c SearchCriteria) needsUTF8() bool {
+	for _, s := range []string{c.From, c.To, c.Subject, c.Text} {
+		if _, ok := imapString(s).(literal); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes a message in search results.
+type Summary struct {
+	UID            uint32
+	From           string
+	To             string
+	Cc             string
+	Subject        string
+	Date           time.Time
+	MessageID      string
+	Flags          []string
+	Size           int
+	HasAttachments bool
+}
+
+// Seen reports whether the message has been read.
+func (s *Summary) Seen() bool {
+	for _, f := range s.Flags {
+		if strings.EqualFold(f, `\Seen`) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Summary) parseHeaders(raw []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(append(bytes.TrimRight(raw, "\r\n"), "\r\n\r\n"...)))
+	if err != nil {
+		return
+	}
+	h := msg.Header
+	s.From = decodeHeader(h.Get("From"))
+	s.To = decodeHeader(h.Get("To"))
+	s.Cc = decodeHeader(h.Get("Cc"))
+	s.Subject = decodeHeader(h.Get("Subject"))
+	s.MessageID = h.Get("Message-Id")
+	s.Date, _ = h.Date()
+}
@@ -0,0 +1,254 @@
+package email_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/email"
+	"github.com/sipeed/picoclaw/pkg/email/emailtest"
+)
+
+const plainMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: =?utf-8?q?Caf=C3=A9_meeting?=\r\n" +
+	"Date: Mon, 12 Oct 2026 09:30:00 +0000\r\n" +
+	"Message-ID: <m1@example.com>\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"See you at the caf=E9 at 10.\r\n"
+
+const multipartMessage = "From: carol@example.com\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Invoice\r\n" +
+	"Date: Wed, 14 Oct 2026 15:00:00 +0000\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Invoice attached.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Invoice attached.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	m, err := email.ParseMessage([]byte(plainMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Café meeting" {
+		t.Errorf("Subject = %q", m.Subject)
+	}
+	if m.Text != "See you at the café at 10.\r\n" {
+		t.Errorf("Text = %q", m.Text)
+	}
+
+	m, err = email.ParseMessage([]byte(multipartMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(m.Text) != "Invoice attached." || !strings.Contains(m.HTML, "<p>") {
+		t.Errorf("Text = %q, HTML = %q", m.Text, m.HTML)
+	}
+	if len(m.Attachments) != 1 {
+		t.Fatalf("Attachments = %+v", m.Attachments)
+	}
+	att := m.Attachments[0]
+	if att.Filename != "invoice.pdf" || att.ContentType != "application/pdf" || string(att.Data) != "%PDF-1.4\n" {
+		t.Errorf("attachment = %s %s %q", att.Filename, att.ContentType, att.Data)
+	}
+}
+
+func TestIMAPSearchAndFetch(t *testing.T) {
+	srv, err := emailtest.NewIMAPServer("bob@example.com", "app-password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	first := srv.Append("INBOX", []byte(plainMessage), `\Seen`)
+	second := srv.Append("INBOX", []byte(multipartMessage))
+
+	account := &email.Account{Name: "test", Address: "bob@example.com", Password: "app-password", IMAP: srv.Server()}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := account.OpenIMAP(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	if n, err := c.Select("INBOX"); err != nil || n != 2 {
+		t.Fatalf("Select = %d, %v", n, err)
+	}
+	uids, err := c.Search(email.SearchCriteria{Unseen: true})
+	if err != nil || len(uids) != 1 || uids[0] != second {
+		t.Fatalf("Search unseen = %v, %v", uids, err)
+	}
+	uids, err = c.Search(email.SearchCriteria{Subject: "café"})
+	if err != nil || len(uids) != 1 || uids[0] != first {
+		t.Fatalf("Search non-ASCII subject = %v, %v", uids, err)
+	}
+	uids, err = c.Search(email.SearchCriteria{Since: time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)})
+	if err != nil || len(uids) != 1 || uids[0] != second {
+		t.Fatalf("Search since = %v, %v", uids, err)
+	}
+
+	summaries, err := c.FetchSummaries([]uint32{first, second})
+	if err != nil || len(summaries) != 2 {
+		t.Fatalf("FetchSummaries = %v, %v", summaries, err)
+	}
+	if s := summaries[0]; s.Subject != "Café meeting" || !s.Seen() || s.HasAttachments || s.From != "Alice <alice@example.com>" {
+		t.Errorf("first summary = %+v", s)
+	}
+	if s := summaries[1]; s.Subject != "Invoice" || s.Seen() || !s.HasAttachments || s.Date.Day() != 14 {
+		t.Errorf("second summary = %+v", s)
+	}
+
+	raw, err := c.FetchMessage(second)
+	if err != nil || string(raw) != multipartMessage {
+		t.Fatalf("FetchMessage = %q, %v", raw, err)
+	}
+	if flags := srv.Flags("INBOX", second); len(flags) != 0 {
+		t.Errorf("fetching marked the message: %v", flags)
+	}
+}
+
+func TestIMAPLoginErrors(t *testing.T) {
+	srv, err := emailtest.NewIMAPServer("bob@example.com", "app-password", "oauth-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	ctx := context.Background()
+
+	account := &email.Account{Address: "bob@example.com", Password: "wrong", IMAP: srv.Server()}
+	if _, err := account.OpenIMAP(ctx); !email.IsAuthError(err) {
+		t.Errorf("wrong password: err = %v, want auth error", err)
+	}
+
+	account.Token = func(context.Context) (string, error) { return "oauth-token", nil }
+	c, err := account.OpenIMAP(ctx)
+	if err != nil {
+		t.Fatalf("XOAUTH2 login: %v", err)
+	}
+	c.Logout()
+}
+
+func TestSend(t *testing.T) {
+	srv, err := emailtest.NewSMTPServer("bob@example.com", "", "oauth-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	account := &email.Account{
+		Name:    "test",
+		Address: "Bob <bob@example.com>",
+		Token:   func(context.Context) (string, error) { return "oauth-token", nil },
+		SMTP:    srv.Server(),
+	}
+	id, err := account.Send(context.Background(), email.OutgoingMessage{
+		To:          []string{"Alice <alice@example.com>"},
+		Bcc:         []string{"audit@example.com"},
+		Subject:     "Re: Café meeting",
+		Body:        "Works for me.\nBob",
+		InReplyTo:   "<m1@example.com>",
+		Attachments: []email.Attachment{{Filename: "notes.txt", Data: []byte("agenda")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := srv.Deliveries()
+	if len(deliveries) != 1 {
+		t.Fatalf("deliveries = %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.From != "bob@example.com" || strings.Join(d.To, ",") != "alice@example.com,audit@example.com" {
+		t.Errorf("envelope = %s -> %v", d.From, d.To)
+	}
+	if strings.Contains(string(d.Data), "audit@") {
+		t.Error("Bcc recipient leaked into the headers")
+	}
+
+	m, err := email.ParseMessage(d.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Re: Café meeting" || m.MessageID != id || m.InReplyTo != "<m1@example.com>" {
+		t.Errorf("headers = %q %q %q", m.Subject, m.MessageID, m.InReplyTo)
+	}
+	if m.Text != "Works for me.\r\nBob\r\n" {
+		t.Errorf("Text = %q", m.Text)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Filename != "notes.txt" || string(m.Attachments[0].Data) != "agenda" {
+		t.Errorf("Attachments = %+v", m.Attachments)
+	}
+}
+
+func TestSendRejectsLineBreaksInHeaders(t *testing.T) {
+	srv, err := emailtest.NewSMTPServer("bob@example.com", "app-password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	account := &email.Account{Address: "bob@example.com", Password: "app-password", SMTP: srv.Server()}
+
+	injected := "Hi\r\nBcc: eve@example.com"
+	for name, msg := range map[string]email.OutgoingMessage{
+		"subject":     {To: []string{"alice@example.com"}, Subject: injected},
+		"in-reply-to": {To: []string{"alice@example.com"}, InReplyTo: "<m1@example.com>\r\nBcc: eve@example.com"},
+		"address":     {To: []string{"alice@example.com\nBcc: eve@example.com"}},
+		"attachment":  {To: []string{"alice@example.com"}, Attachments: []email.Attachment{{Filename: injected}}},
+	} {
+		if _, err := account.Send(context.Background(), msg); err == nil {
+			t.Errorf("%s: Send accepted a header value with a line break", name)
+		}
+	}
+	if n := len(srv.Deliveries()); n != 0 {
+		t.Errorf("deliveries = %d, want 0", n)
+	}
+}
+
+func TestIMAPRejectsOversizedLiteral(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("* OK {99999999999}\r\n"))
+		io.Copy(io.Discard, conn)
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = email.DialIMAP(ctx, email.Server{Host: "127.0.0.1", Port: port, Security: email.SecurityNone})
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("DialIMAP = %v, want literal size error", err)
+	}
+}
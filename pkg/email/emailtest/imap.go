@@ -0,0 +1,457 @@
+// Package emailtest provides in-process IMAP and SMTP servers for testing
+// code that uses package email, in the spirit of net/http/httptest.
+package emailtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/email"
+)
+
+// StoredMessage is a message in an IMAPServer mailbox.
+type StoredMessage struct {
+	UID   uint32
+	Flags []string
+	Raw   []byte
+}
+
+// IMAPServer is a plaintext IMAP server listening on a loopback port. It
+// implements the subset of IMAP4rev1 that package email uses.
+type IMAPServer struct {
+	Addr     string
+	Username string
+	Password string // Accepted by LOGIN
+	Token    string // Accepted by AUTHENTICATE XOAUTH2
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	mailboxes map[string][]*StoredMessage
+	nextUID   uint32
+	commands  []string
+}
+
+// NewIMAPServer starts a server that accepts username with password or,
+// over XOAUTH2, token. Close it when done.
+func NewIMAPServer(username, password, token string) (*IMAPServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &IMAPServer{
+		Addr:      ln.Addr().String(),
+		Username:  username,
+		Password:  password,
+		Token:     token,
+		listener:  ln,
+		mailboxes: map[string][]*StoredMessage{"INBOX": nil},
+		nextUID:   1,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Server returns the endpoint to configure an email.Account with.
+func (s *IMAPServer) Server() email.Server {
+	host, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return email.Server{Host: host, Port: p, Security: email.SecurityNone}
+}
+
+// Append adds a message to mailbox and returns its UID.
+func (s *IMAPServer) Append(mailbox string, raw []byte, flags ...string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := s.nextUID
+	s.nextUID++
+	s.mailboxes[mailbox] = append(s.mailboxes[mailbox], &StoredMessage{UID: uid, Flags: flags, Raw: raw})
+	return uid
+}
+
+// Flags returns the flags of a message, to check that reading it did not
+// mark it as seen.
+func (s *IMAPServer) Flags(mailbox string, uid uint32) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.mailboxes[mailbox] {
+		if m.UID == uid {
+			return append([]string(nil), m.Flags...)
+		}
+	}
+	return nil
+}
+
+// Commands returns the commands received so far, without tags.
+func (s *IMAPServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Close stops the server.
+func (s *IMAPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *IMAPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+type imapSession struct {
+	s        *IMAPServer
+	r        *bufio.Reader
+	w        io.Writer
+	authed   bool
+	selected string
+}
+
+func (s *IMAPServer) handle(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	sess := &imapSession{s: s, r: bufio.NewReader(conn), w: conn}
+	fmt.Fprint(conn, "* OK emailtest IMAP ready\r\n")
+	for {
+		line, err := sess.readCommand()
+		if err != nil {
+			return
+		}
+		tag, rest, _ := strings.Cut(line, " ")
+		if !sess.dispatch(tag, rest) {
+			return
+		}
+	}
+}
+
+// readCommand reads a command line, answering literal announcements with
+// a continuation and inlining the literal as a quoted string.
+func (sess *imapSession) readCommand() (string, error) {
+	var line strings.Builder
+	for {
+		part, err := sess.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		open := strings.LastIndexByte(part, '{')
+		if open < 0 || !strings.HasSuffix(part, "}") {
+			line.WriteString(part)
+			return line.String(), nil
+		}
+		n, err := strconv.Atoi(part[open+1 : len(part)-1])
+		if err != nil {
+			line.WriteString(part)
+			return line.String(), nil
+		}
+		fmt.Fprint(sess.w, "+ Ready\r\n")
+		data := make([]byte, n)
+		if _, err := io.ReadFull(sess.r, data); err != nil {
+			return "", err
+		}
+		line.WriteString(part[:open])
+		line.WriteString(`"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(string(data)) + `"`)
+	}
+}
+
+func (sess *imapSession) dispatch(tag, rest string) bool {
+	name, args, _ := strings.Cut(rest, " ")
+	name = strings.ToUpper(name)
+	sess.s.mu.Lock()
+	if name == "LOGIN" {
+		sess.s.commands = append(sess.s.commands, "LOGIN")
+	} else {
+		sess.s.commands = append(sess.s.commands, rest)
+	}
+	sess.s.mu.Unlock()
+
+	ok := func(text string) { fmt.Fprintf(sess.w, "%s OK %s\r\n", tag, text) }
+	no := func(text string) { fmt.Fprintf(sess.w, "%s NO %s\r\n", tag, text) }
+	bad := func(text string) { fmt.Fprintf(sess.w, "%s BAD %s\r\n", tag, text) }
+
+	switch name {
+	case "CAPABILITY":
+		fmt.Fprint(sess.w, "* CAPABILITY IMAP4rev1 AUTH=PLAIN AUTH=XOAUTH2 SASL-IR\r\n")
+		ok("CAPABILITY completed")
+	case "NOOP":
+		ok("NOOP completed")
+	case "LOGOUT":
+		fmt.Fprint(sess.w, "* BYE logging out\r\n")
+		ok("LOGOUT completed")
+		return false
+	case "LOGIN":
+		fields := tokenize(args)
+		if len(fields) == 2 && fields[0] == sess.s.Username && sess.s.Password != "" && fields[1] == sess.s.Password {
+			sess.authed = true
+			ok("LOGIN completed")
+		} else {
+			no("[AUTHENTICATIONFAILED] invalid credentials")
+		}
+	case "AUTHENTICATE":
+		fields := strings.Fields(args)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "XOAUTH2") {
+			no("unsupported mechanism")
+			break
+		}
+		decoded, _ := base64.StdEncoding.DecodeString(fields[1])
+		want := "user=" + sess.s.Username + "\x01auth=Bearer " + sess.s.Token + "\x01\x01"
+		if sess.s.Token != "" && string(decoded) == want {
+			sess.authed = true
+			ok("AUTHENTICATE completed")
+		} else {
+			no("[AUTHENTICATIONFAILED] invalid token")
+		}
+	case "SELECT", "EXAMINE":
+		if !sess.authed {
+			bad("not authenticated")
+			break
+		}
+		fields := tokenize(args)
+		if len(fields) != 1 {
+			bad("missing mailbox")
+			break
+		}
+		sess.s.mu.Lock()
+		msgs, exists := sess.s.mailboxes[fields[0]]
+		sess.s.mu.Unlock()
+		if !exists {
+			no("[NONEXISTENT] no such mailbox")
+			break
+		}
+		sess.selected = fields[0]
+		fmt.Fprintf(sess.w, "* %d EXISTS\r\n", len(msgs))
+		ok("[READ-ONLY] " + name + " completed")
+	case "UID":
+		if sess.selected == "" {
+			bad("no mailbox selected")
+			break
+		}
+		sub, subArgs, _ := strings.Cut(args, " ")
+		switch strings.ToUpper(sub) {
+		case "SEARCH":
+			sess.search(subArgs)
+			ok("SEARCH completed")
+		case "FETCH":
+			if err := sess.fetch(subArgs); err != nil {
+				bad(err.Error())
+			} else {
+				ok("FETCH completed")
+			}
+		default:
+			bad("unsupported UID command")
+		}
+	default:
+		bad("unsupported command")
+	}
+	return true
+}
+
+func (sess *imapSession) messages() []*StoredMessage {
+	sess.s.mu.Lock()
+	defer sess.s.mu.Unlock()
+	return sess.s.mailboxes[sess.selected]
+}
+
+func (sess *imapSession) search(args string) {
+	fields := tokenize(args)
+	if len(fields) >= 2 && strings.EqualFold(fields[0], "CHARSET") {
+		fields = fields[2:]
+	}
+	var uids []string
+	for _, m := range sess.messages() {
+		if matches(m, fields) {
+			uids = append(uids, strconv.FormatUint(uint64(m.UID), 10))
+		}
+	}
+	fmt.Fprintf(sess.w, "* SEARCH %s\r\n", strings.Join(uids, " "))
+}
+
+func matches(m *StoredMessage, criteria []string) bool {
+	parsed, err := email.ParseMessage(m.Raw)
+	if err != nil {
+		return false
+	}
+	contains := func(haystack, needle string) bool {
+		return strings.Contains(strings.ToLower(haystack), strings.ToLower(needle))
+	}
+
+	for i := 0; i < len(criteria); i++ {
+		key := strings.ToUpper(criteria[i])
+		arg := ""
+		if i+1 < len(criteria) {
+			arg = criteria[i+1]
+		}
+		switch key {
+		case "ALL":
+			continue
+		case "UNSEEN":
+			for _, f := range m.Flags {
+				if f == `\Seen` {
+					return false
+				}
+			}
+			continue
+		case "FROM", "TO", "SUBJECT":
+			field := map[string]string{"FROM": parsed.From, "TO": parsed.To, "SUBJECT": parsed.Subject}[key]
+			if !contains(field, arg) {
+				return false
+			}
+		case "TEXT":
+			if !contains(parsed.From+parsed.To+parsed.Subject+parsed.Text+parsed.HTML, arg) {
+				return false
+			}
+		case "SINCE", "BEFORE":
+			day, err := time.Parse("2-Jan-2006", arg)
+			if err != nil {
+				return false
+			}
+			msgDay := time.Date(parsed.Date.Year(), parsed.Date.Month(), parsed.Date.Day(), 0, 0, 0, 0, time.UTC)
+			if key == "SINCE" && msgDay.Before(day) || key == "BEFORE" && !msgDay.Before(day) {
+				return false
+			}
+		default:
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (sess *imapSession) fetch(args string) error {
+	set, items, ok := strings.Cut(args, " ")
+	if !ok {
+		return fmt.Errorf("missing fetch items")
+	}
+	wanted := make(map[uint32]bool)
+	for _, part := range strings.Split(set, ",") {
+		uid, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return fmt.Errorf("unsupported sequence set %q", set)
+		}
+		wanted[uint32(uid)] = true
+	}
+	items = strings.ToUpper(items)
+
+	for seq, m := range sess.messages() {
+		if !wanted[m.UID] {
+			continue
+		}
+		parts := []string{fmt.Sprintf("UID %d", m.UID)}
+		if strings.Contains(items, "FLAGS") {
+			parts = append(parts, "FLAGS ("+strings.Join(m.Flags, " ")+")")
+		}
+		if strings.Contains(items, "RFC822.SIZE") {
+			parts = append(parts, fmt.Sprintf("RFC822.SIZE %d", len(m.Raw)))
+		}
+		if strings.Contains(items, "BODYSTRUCTURE") {
+			parts = append(parts, "BODYSTRUCTURE "+bodyStructure(m.Raw))
+		}
+		if start := strings.Index(items, "BODY.PEEK[HEADER.FIELDS"); start >= 0 {
+			end := strings.Index(items[start:], "]")
+			names := strings.Fields(strings.Trim(items[start+len("BODY.PEEK[HEADER.FIELDS"):start+end], " ()"))
+			data := headerFields(m.Raw, names)
+			section := items[start+len("BODY.PEEK") : start+end+1]
+			parts = append(parts, fmt.Sprintf("BODY%s {%d}\r\n%s", section, len(data), data))
+		}
+		if strings.Contains(items, "BODY.PEEK[]") {
+			parts = append(parts, fmt.Sprintf("BODY[] {%d}\r\n%s", len(m.Raw), m.Raw))
+		}
+		fmt.Fprintf(sess.w, "* %d FETCH (%s)\r\n", seq+1, strings.Join(parts, " "))
+	}
+	return nil
+}
+
+// headerFields returns the named header lines of raw, ending with a blank
+// line as IMAP does.
+func headerFields(raw []byte, names []string) string {
+	head, _, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+	var out strings.Builder
+	keep := false
+	for _, line := range strings.Split(string(head), "\r\n") {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') {
+			if keep {
+				out.WriteString(line + "\r\n")
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		keep = false
+		for _, n := range names {
+			if strings.EqualFold(strings.TrimSpace(name), n) {
+				keep = true
+			}
+		}
+		if keep {
+			out.WriteString(line + "\r\n")
+		}
+	}
+	out.WriteString("\r\n")
+	return out.String()
+}
+
+// bodyStructure renders a simplified BODYSTRUCTURE that lists the text
+// body and one part per attachment.
+func bodyStructure(raw []byte) string {
+	text := `("text" "plain" ("charset" "utf-8") NIL NIL "7bit" 0 0 NIL NIL NIL)`
+	parsed, err := email.ParseMessage(raw)
+	if err != nil || len(parsed.Attachments) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString("(" + text)
+	for _, att := range parsed.Attachments {
+		typ, sub, _ := strings.Cut(att.ContentType, "/")
+		fmt.Fprintf(&b, ` (%q %q NIL NIL NIL "base64" %d NIL ("attachment" ("filename" %q)) NIL)`,
+			typ, sub, len(att.Data), att.Filename)
+	}
+	b.WriteString(` "mixed" NIL NIL NIL)`)
+	return b.String()
+}
+
+// tokenize splits IMAP arguments into atoms and unquoted strings.
+func tokenize(s string) []string {
+	var fields []string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return fields
+		}
+		if s[0] == '"' {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			fields = append(fields, b.String())
+			s = s[min(i+1, len(s)):]
+			continue
+		}
+		atom, rest, _ := strings.Cut(s, " ")
+		fields = append(fields, atom)
+		s = rest
+	}
+}
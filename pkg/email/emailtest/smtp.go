@@ -0,0 +1,172 @@
+package emailtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/email"
+)
+
+// Delivery is a message accepted by an SMTPServer.
+type Delivery struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// SMTPServer is a plaintext SMTP server listening on a loopback port. It
+// records what it is sent instead of delivering it.
+type SMTPServer struct {
+	Addr     string
+	Username string
+	Password string // Accepted by AUTH PLAIN
+	Token    string // Accepted by AUTH XOAUTH2
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// NewSMTPServer starts a server that accepts username with password or,
+// over XOAUTH2, token. Close it when done.
+func NewSMTPServer(username, password, token string) (*SMTPServer, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{
+		Addr:     ln.Addr().String(),
+		Username: username,
+		Password: password,
+		Token:    token,
+		listener: ln,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Server returns the endpoint to configure an email.Account with.
+func (s *SMTPServer) Server() email.Server {
+	host, port, _ := net.SplitHostPort(s.Addr)
+	p, _ := strconv.Atoi(port)
+	return email.Server{Host: host, Port: p, Security: email.SecurityNone}
+}
+
+// Deliveries returns the messages accepted so far.
+func (s *SMTPServer) Deliveries() []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Delivery(nil), s.deliveries...)
+}
+
+// Close stops the server.
+func (s *SMTPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	reply("220 emailtest ESMTP ready")
+
+	var authed bool
+	var current Delivery
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-emailtest")
+			reply("250 AUTH PLAIN XOAUTH2")
+		case "AUTH":
+			mech, ir, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(ir)
+			switch strings.ToUpper(mech) {
+			case "PLAIN":
+				authed = s.Password != "" && string(decoded) == "\x00"+s.Username+"\x00"+s.Password
+			case "XOAUTH2":
+				authed = s.Token != "" && string(decoded) == "user="+s.Username+"\x01auth=Bearer "+s.Token+"\x01\x01"
+			}
+			if authed {
+				reply("235 2.7.0 Authentication successful")
+			} else {
+				reply("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			if !authed {
+				reply("530 5.7.0 Authentication required")
+				continue
+			}
+			current = Delivery{From: addressArg(arg)}
+			reply("250 OK")
+		case "RCPT":
+			current.To = append(current.To, addressArg(arg))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			current.Data = data.Bytes()
+			s.mu.Lock()
+			s.deliveries = append(s.deliveries, current)
+			s.mu.Unlock()
+			current = Delivery{}
+			reply("250 OK queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// addressArg extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func addressArg(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
@@ -0,0 +1,542 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IMAPClient is a minimal IMAP4rev1 client: enough to log in, search a
+// mailbox and fetch messages. It is not safe for concurrent use.
+type IMAPClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// literal is a command argument sent as an IMAP literal, used for strings
+// that cannot be quoted (non-ASCII or containing line breaks).
+type literal []byte
+
+// response is one response line with its literals, which appear in line
+// as literalMark followed by their index.
+type response struct {
+	line     string
+	literals [][]byte
+}
+
+const literalMark = "\x00"
+
+// maxLiteralSize bounds a literal the server announces, so a broken or
+// hostile server cannot make the client allocate arbitrary amounts of
+// memory. It is well above the message size limits of common providers.
+const maxLiteralSize = 64 << 20
+
+// DialIMAP connects to server and reads its greeting.
+func DialIMAP(ctx context.Context, server Server) (*IMAPClient, error) {
+	conn, err := dial(ctx, server, 993)
+	if err != nil {
+		return nil, err
+	}
+	c := &IMAPClient{conn: conn, r: bufio.NewReader(conn)}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %s", greeting.line)
+	}
+
+	if server.Security == SecurityStartTLS {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: server.Host, InsecureSkipVerify: server.InsecureSkipVerify})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("imap starttls: %w", err)
+		}
+		c.conn = tlsConn
+		c.r = bufio.NewReader(tlsConn)
+	}
+	return c, nil
+}
+
+// dial opens a plain or TLS connection to server.
+func dial(ctx context.Context, server Server, defaultPort int) (net.Conn, error) {
+	port := server.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if server.Security == SecurityTLS {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    &tls.Config{ServerName: server.Host, InsecureSkipVerify: server.InsecureSkipVerify},
+		}
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// Close closes the connection without logging out.
+func (c *IMAPClient) Close() error {
+	return c.conn.Close()
+}
+
+// Logout ends the session and closes the connection.
+func (c *IMAPClient) Logout() error {
+	_, err := c.command("LOGOUT")
+	c.conn.Close()
+	return err
+}
+
+// Login authenticates with a user name and (app) password.
+func (c *IMAPClient) Login(user, password string) error {
+	_, err := c.command("LOGIN", imapString(user), imapString(password))
+	return err
+}
+
+// AuthenticateXOAuth2 authenticates with an OAuth 2.0 access token.
+func (c *IMAPClient) AuthenticateXOAuth2(user, token string) error {
+	_, err := c.command("AUTHENTICATE", "XOAUTH2", xoauth2Response(user, token))
+	return err
+}
+
+// Select opens mailbox read-only and returns the number of messages in it.
+func (c *IMAPClient) Select(mailbox string) (int, error) {
+	responses, err := c.command("EXAMINE", imapString(encodeMailbox(mailbox)))
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range responses {
+		if n, ok := strings.CutSuffix(r.line, " EXISTS"); ok {
+			if count, err := strconv.Atoi(strings.TrimPrefix(n, "* ")); err == nil {
+				return count, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// Search returns the UIDs of messages matching criteria, in ascending
+// order.
+func (c *IMAPClient) Search(criteria SearchCriteria) ([]uint32, error) {
+	args := append([]any{"SEARCH"}, criteria.args()...)
+	if criteria.needsUTF8() {
+		args = append([]any{"SEARCH", "CHARSET", "UTF-8"}, criteria.args()...)
+	}
+	responses, err := c.command("UID", args...)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range responses {
+		rest, ok := strings.CutPrefix(r.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(rest) {
+			if uid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// FetchSummaries fetches the headers, flags and size of the messages
+// with the given UIDs.
+func (c *IMAPClient) FetchSummaries(uids []uint32) ([]*Summary, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	responses, err := c.command("UID", "FETCH", uidSet(uids),
+		"(UID FLAGS RFC822.SIZE BODYSTRUCTURE BODY.PEEK[HEADER.FIELDS (FROM TO CC SUBJECT DATE MESSAGE-ID)])")
+	if err != nil {
+		return nil, err
+	}
+	var summaries []*Summary
+	for _, r := range responses {
+		attrs, ok := fetchAttributes(r)
+		if !ok {
+			continue
+		}
+		s := &Summary{}
+		for i := 0; i+1 < len(attrs); i += 2 {
+			name, _ := attrs[i].(string)
+			switch strings.ToUpper(name) {
+			case "UID":
+				uid, _ := strconv.ParseUint(asString(attrs[i+1]), 10, 32)
+				s.UID = uint32(uid)
+			case "FLAGS":
+				for _, f := range asList(attrs[i+1]) {
+					s.Flags = append(s.Flags, asString(f))
+				}
+			case "RFC822.SIZE":
+				s.Size, _ = strconv.Atoi(asString(attrs[i+1]))
+			case "BODYSTRUCTURE":
+				s.HasAttachments = hasAttachment(attrs[i+1])
+			default:
+				if strings.HasPrefix(strings.ToUpper(name), "BODY[") {
+					s.parseHeaders(asBytes(attrs[i+1]))
+				}
+			}
+		}
+		if s.UID != 0 {
+			summaries = append(summaries, s)
+		}
+	}
+	return summaries, nil
+}
+
+// FetchMessage returns the raw RFC 5322 message with the given UID without
+// marking it as read.
+func (c *IMAPClient) FetchMessage(uid uint32) ([]byte, error) {
+	responses, err := c.command("UID", "FETCH", strconv.FormatUint(uint64(uid), 10), "(UID BODY.PEEK[])")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range responses {
+		attrs, ok := fetchAttributes(r)
+		if !ok {
+			continue
+		}
+		for i := 0; i+1 < len(attrs); i += 2 {
+			if name, _ := attrs[i].(string); strings.EqualFold(name, "BODY[]") {
+				return asBytes(attrs[i+1]), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+// command sends a tagged command and returns the untagged responses. A NO
+// or BAD completion is returned as an error.
+func (c *IMAPClient) command(name string, args ...any) ([]response, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+
+	var line strings.Builder
+	line.WriteString(tag + " " + name)
+	for _, arg := range args {
+		line.WriteByte(' ')
+		switch a := arg.(type) {
+		case literal:
+			fmt.Fprintf(&line, "{%d}\r\n", len(a))
+			if _, err := io.WriteString(c.conn, line.String()); err != nil {
+				return nil, err
+			}
+			line.Reset()
+			cont, err := c.readResponse()
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(cont.line, "+") {
+				return nil, fmt.Errorf("imap %s: %s", name, cont.line)
+			}
+			line.Write(a)
+		default:
+			fmt.Fprint(&line, a)
+		}
+	}
+	line.WriteString("\r\n")
+	if _, err := io.WriteString(c.conn, line.String()); err != nil {
+		return nil, err
+	}
+
+	var untagged []response
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if status, ok := strings.CutPrefix(r.line, tag+" "); ok {
+			if strings.HasPrefix(status, "OK") {
+				return untagged, nil
+			}
+			return untagged, &ServerError{Command: name, Message: status}
+		}
+		if strings.HasPrefix(r.line, "+") {
+			// A SASL challenge after a failed AUTHENTICATE carries the
+			// error details; answer with an empty line to get the NO.
+			io.WriteString(c.conn, "\r\n")
+			continue
+		}
+		untagged = append(untagged, r)
+	}
+}
+
+// readResponse reads one response line and the literals embedded in it.
+func (c *IMAPClient) readResponse() (response, error) {
+	var r response
+	var line strings.Builder
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return r, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		n, ok := literalSize(part)
+		if !ok {
+			line.WriteString(part)
+			r.line = line.String()
+			return r, nil
+		}
+		if n > maxLiteralSize {
+			return r, fmt.Errorf("imap literal of %d bytes exceeds the %d byte limit", n, maxLiteralSize)
+		}
+		line.WriteString(part[:strings.LastIndexByte(part, '{')])
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return r, err
+		}
+		line.WriteString(literalMark + strconv.Itoa(len(r.literals)) + literalMark)
+		r.literals = append(r.literals, data)
+	}
+}
+
+// literalSize reports the size of the literal announced at the end of an
+// IMAP line, e.g. "{42}".
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	return n, err == nil && n >= 0
+}
+
+// ServerError is a NO or BAD reply to an IMAP command.
+type ServerError struct {
+	Command string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("imap %s failed: %s", e.Command, e.Message)
+}
+
+// IsAuthError reports whether err is a rejected login.
+func IsAuthError(err error) bool {
+	var se *ServerError
+	return errors.As(err, &se) && (se.Command == "LOGIN" || se.Command == "AUTHENTICATE")
+}
+
+// fetchAttributes returns the attribute list of a "* n FETCH (...)"
+// response.
+func fetchAttributes(r response) ([]any, bool) {
+	rest, ok := strings.CutPrefix(r.line, "* ")
+	if !ok {
+		return nil, false
+	}
+	_, rest, ok = strings.Cut(rest, " ")
+	if !ok || !strings.HasPrefix(strings.ToUpper(rest), "FETCH ") {
+		return nil, false
+	}
+	p := &parser{s: rest[len("FETCH "):], literals: r.literals}
+	list, ok := p.value().([]any)
+	return list, ok
+}
+
+// parser reads IMAP data items: atoms, quoted strings, literals and
+// parenthesized lists. Section specs such as BODY[HEADER.FIELDS (FROM)]
+// are read as one atom.
+type parser struct {
+	s        string
+	pos      int
+	literals [][]byte
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) value() any {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil
+	}
+	switch p.s[p.pos] {
+	case '(':
+		p.pos++
+		list := []any{}
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.s) {
+				return list
+			}
+			if p.s[p.pos] == ')' {
+				p.pos++
+				return list
+			}
+			list = append(list, p.value())
+		}
+	case '"':
+		p.pos++
+		var b strings.Builder
+		for p.pos < len(p.s) && p.s[p.pos] != '"' {
+			if p.s[p.pos] == '\\' && p.pos+1 < len(p.s) {
+				p.pos++
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		}
+		p.pos++
+		return b.String()
+	case literalMark[0]:
+		end := strings.Index(p.s[p.pos+1:], literalMark)
+		if end < 0 {
+			p.pos = len(p.s)
+			return nil
+		}
+		i, _ := strconv.Atoi(p.s[p.pos+1 : p.pos+1+end])
+		p.pos += end + 2
+		if i < len(p.literals) {
+			return p.literals[i]
+		}
+		return nil
+	}
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.s) {
+		ch := p.s[p.pos]
+		if ch == '[' {
+			depth++
+		} else if ch == ']' {
+			depth--
+		} else if depth == 0 && (ch == ' ' || ch == ')' || ch == '(') {
+			break
+		}
+		p.pos++
+	}
+	atom := p.s[start:p.pos]
+	if strings.EqualFold(atom, "NIL") {
+		return nil
+	}
+	return atom
+}
+
+func asString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+func asBytes(v any) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+func asList(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
+
+// hasAttachment reports whether a BODYSTRUCTURE has a part with an
+// attachment disposition.
+func hasAttachment(v any) bool {
+	list := asList(v)
+	for _, item := range list {
+		switch item := item.(type) {
+		case []any:
+			if len(item) >= 2 {
+				if s, ok := item[0].(string); ok && strings.EqualFold(s, "attachment") {
+					return true
+				}
+			}
+			if hasAttachment(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// imapString formats s as an IMAP string argument.
+func imapString(s string) any {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 || s[i] == '\r' || s[i] == '\n' {
+			return literal(s)
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// uidSet formats UIDs as an IMAP sequence set.
+func uidSet(uids []uint32) string {
+	parts := make([]string, len(uids))
+	for i, uid := range uids {
+		parts[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// encodeMailbox encodes a mailbox name in IMAP's modified UTF-7.
+func encodeMailbox(name string) string {
+	var b strings.Builder
+	var pending []rune
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		var utf16 []byte
+		for _, r := range pending {
+			if r > 0xFFFF {
+				r -= 0x10000
+				hi, lo := 0xD800+(r>>10), 0xDC00+(r&0x3FF)
+				utf16 = append(utf16, byte(hi>>8), byte(hi), byte(lo>>8), byte(lo))
+				continue
+			}
+			utf16 = append(utf16, byte(r>>8), byte(r))
+		}
+		b.WriteByte('&')
+		b.WriteString(strings.ReplaceAll(base64.RawStdEncoding.EncodeToString(utf16), "/", ","))
+		b.WriteByte('-')
+		pending = nil
+	}
+	for _, r := range name {
+		switch {
+		case r == '&':
+			flush()
+			b.WriteString("&-")
+		case r >= 0x20 && r <= 0x7E:
+			flush()
+			b.WriteRune(r)
+		default:
+			pending = append(pending, r)
+		}
+	}
+	flush()
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OutgoingMessage is an email to send.
+type OutgoingMessage struct {
+	To          []string
+	Cc          []string
+	Bcc         []string
+	Subject     string
+	Body        string // Plain text
+	InReplyTo   string // Message-ID of the message being answered
+	Attachments []Attachment
+}
+
+// Send delivers msg through the account's SMTP server and returns the
+// Message-ID it was sent with.
+func (a *Account) Send(ctx context.Context, msg OutgoingMessage) (string, error) {
+	if a.SMTP.Host == "" {
+		return "", fmt.Errorf("account %s has no SMTP server", a.Name)
+	}
+	recipients := make([]string, 0, len(msg.To)+len(msg.Cc)+len(msg.Bcc))
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for _, addr := range list {
+			parsed, err := mail.ParseAddress(addr)
+			if err != nil {
+				return "", fmt.Errorf("invalid recipient %q: %w", addr, err)
+			}
+			recipients = append(recipients, parsed.Address)
+		}
+	}
+	if len(recipients) == 0 {
+		return "", errors.New("no recipients")
+	}
+
+	messageID, data, err := a.compose(msg, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := dial(ctx, a.SMTP, 465)
+	if err != nil {
+		return "", fmt.Errorf("connect to %s: %w", a.SMTP.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, a.SMTP.Host)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("smtp greeting: %w", err)
+	}
+	defer c.Close()
+
+	if a.SMTP.Security == SecurityStartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: a.SMTP.Host, InsecureSkipVerify: a.SMTP.InsecureSkipVerify}); err != nil {
+			return "", fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	var auth smtp.Auth
+	if a.Token != nil {
+		token, err := a.Token(ctx)
+		if err != nil {
+			return "", fmt.Errorf("oauth token for %s: %w", a.Name, err)
+		}
+		auth = &xoauth2Auth{user: a.Login(), token: token}
+	} else if a.Password != "" {
+		auth = &plainAuth{user: a.Login(), password: a.Password}
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return "", fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(a.Address)
+	if err != nil {
+		return "", fmt.Errorf("invalid account address %q: %w", a.Address, err)
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return "", fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return "", fmt.Errorf("smtp RCPT TO %s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("smtp DATA: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp DATA: %w", err)
+	}
+	c.Quit()
+	return messageID, nil
+}
+
+// compose renders msg as a MIME message. Bcc recipients are left out of
+// the headers.
+func (a *Account) compose(msg OutgoingMessage, now time.Time) (string, []byte, error) {
+	from, err := mail.ParseAddress(a.Address)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid account address %q: %w", a.Address, err)
+	}
+	if err := checkHeaderValues(msg); err != nil {
+		return "", nil, err
+	}
+	messageID := fmt.Sprintf("<%s@%s>", randomHex(12), domainOf(from.Address))
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+		}
+	}
+	header("From", from.String())
+	header("To", formatAddresses(msg.To))
+	header("Cc", formatAddresses(msg.Cc))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("In-Reply-To", msg.InReplyTo)
+	header("References", msg.InReplyTo)
+	header("MIME-Version", "1.0")
+
+	if len(msg.Attachments) == 0 {
+		writeTextPart(&buf, msg.Body)
+		return messageID, buf.Bytes(), nil
+	}
+
+	boundary := "picoclaw-" + randomHex(12)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": boundary}))
+	buf.WriteString("\r\n--" + boundary + "\r\n")
+	writeTextPart(&buf, msg.Body)
+	for _, att := range msg.Attachments {
+		buf.WriteString("\r\n--" + boundary + "\r\n")
+		contentType := att.ContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(att.Filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		name := mime.QEncoding.Encode("utf-8", att.Filename)
+		fmt.Fprintf(&buf, "Content-Type: %s; name=%q\r\n", contentType, name)
+		fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n", name)
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		encoded := base64.StdEncoding.EncodeToString(att.Data)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded + "\r\n")
+	}
+	buf.WriteString("\r\n--" + boundary + "--\r\n")
+	return messageID, buf.Bytes(), nil
+}
+
+// checkHeaderValues rejects line breaks in the values that end up in header
+// fields, where they would start headers of their own.
+func checkHeaderValues(msg OutgoingMessage) error {
+	fields := map[string][]string{
+		"To":          msg.To,
+		"Cc":          msg.Cc,
+		"Bcc":         msg.Bcc,
+		"Subject":     {msg.Subject},
+		"In-Reply-To": {msg.InReplyTo},
+	}
+	for _, att := range msg.Attachments {
+		fields["Attachment filename"] = append(fields["Attachment filename"], att.Filename)
+	}
+	for name, values := range fields {
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("%s must not contain line breaks", name)
+			}
+		}
+	}
+	return nil
+}
+
+func writeTextPart(buf *bytes.Buffer, body string) {
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")))
+	qp.Close()
+	buf.WriteString("\r\n")
+}
+
+func formatAddresses(list []string) string {
+	formatted := make([]string, 0, len(list))
+	for _, addr := range list {
+		if parsed, err := mail.ParseAddress(addr); err == nil {
+			formatted = append(formatted, parsed.String())
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func domainOf(addr string) string {
+	if _, domain, ok := strings.Cut(addr, "@"); ok && domain != "" {
+		return domain
+	}
+	return "localhost"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// plainAuth is SASL PLAIN. Unlike smtp.PlainAuth it does not refuse
+// unencrypted connections to hosts other than localhost; whether TLS is
+// required is the account configuration's choice.
+type plainAuth struct {
+	user, password string
+}
+
+func (a *plainAuth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	return "PLAIN", []byte("\x00" + a.user + "\x00" + a.password), nil
+}
+
+func (a *plainAuth) Next(_ []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, errors.New("unexpected server challenge")
+	}
+	return nil, nil
+}
+
+// xoauth2Auth is SASL XOAUTH2, used by Gmail and Outlook.
+type xoauth2Auth struct {
+	user, token string
+}
+
+func (a *xoauth2Auth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	return "XOAUTH2", []byte("user=" + a.user + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(challenge []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sent error details; an empty reply makes it finish
+		// with the failure status.
+		return []byte{}, nil
+	}
+	return nil, nil
+}
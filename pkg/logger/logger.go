@@ -34,6 +34,7 @@ var (
 	logger       *Logger
 	once         sync.Once
 	mu           sync.RWMutex
+	redact       func(string) string
 )
 
 type Logger struct {
@@ -67,6 +68,14 @@ func GetLevel() LogLevel {
 	return currentLevel
 }
 
+// SetRedactor filters every log line, e.g. to mask secrets. nil disables
+// filtering.
+func SetRedactor(fn func(string) string) {
+	mu.Lock()
+	defer mu.Unlock()
+	redact = fn
+}
+
 func EnableFileLogging(filePath string) error {
 	mu.Lock()
 	defer mu.Unlock()
@@ -101,6 +110,17 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		return
 	}
 
+	mu.RLock()
+	filter := redact
+	mu.RUnlock()
+
+	// Redact before encoding: JSON escapes quotes, backslashes and control
+	// characters, so a secret containing them no longer matches afterwards.
+	if filter != nil {
+		message = filter(message)
+		fields = redactFields(filter, fields)
+	}
+
 	entry := LogEntry{
 		Level:     logLevelNames[level],
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -119,6 +139,9 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 	if logger.file != nil {
 		jsonData, err := json.Marshal(entry)
 		if err == nil {
+			if filter != nil {
+				jsonData = []byte(filter(string(jsonData)))
+			}
 			logger.file.Write(append(jsonData, '\n'))
 		}
 	}
@@ -137,6 +160,9 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 		message,
 		fieldStr,
 	)
+	if filter != nil {
+		logLine = filter(logLine)
+	}
 
 	log.Println(logLine)
 
@@ -145,6 +171,29 @@ func logMessage(level LogLevel, component string, message string, fields map[str
 	}
 }
 
+// redactFields returns a copy of fields with filter applied to every value.
+// Values that are not strings are formatted first and replaced only when
+// the filter changes them.
+func redactFields(filter func(string) string, fields map[string]any) map[string]any {
+	if len(fields) == 0 {
+		return fields
+	}
+	out := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			out[k] = filter(s)
+			continue
+		}
+		formatted := fmt.Sprintf("%v", v)
+		if redacted := filter(formatted); redacted != formatted {
+			out[k] = redacted
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
 func formatComponent(component string) string {
 	if component == "" {
 		return ""
@@ -1,6 +1,11 @@
 package logger
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -137,3 +142,39 @@ func TestLoggerHelperFunctions(t *testing.T) {
 	DebugC("test", "Debug with component")
 	WarnF("Warning with fields", map[string]any{"key": "value"})
 }
+
+func TestRedactorAppliesBeforeEncoding(t *testing.T) {
+	secret := "p\"a\\ss\nword"
+	SetRedactor(func(s string) string { return strings.ReplaceAll(s, secret, "[REDACTED]") })
+	defer SetRedactor(nil)
+
+	path := filepath.Join(t.TempDir(), "log.json")
+	if err := EnableFileLogging(path); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableFileLogging()
+
+	InfoCF("test", "token "+secret, map[string]any{
+		"value": secret,
+		"error": errors.New("bad " + secret),
+		"count": 3,
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry LogEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", data, err)
+	}
+	if entry.Message != "token [REDACTED]" {
+		t.Errorf("message = %q", entry.Message)
+	}
+	if entry.Fields["value"] != "[REDACTED]" || entry.Fields["error"] != "bad [REDACTED]" {
+		t.Errorf("fields not redacted: %v", entry.Fields)
+	}
+	if entry.Fields["count"] != float64(3) {
+		t.Errorf("count = %v, want 3", entry.Fields["count"])
+	}
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// AgentRunner runs a prompt through the agent against a session.
+// *agent.AgentLoop satisfies it.
+type AgentRunner interface {
+	ProcessDirect(ctx context.Context, content, sessionKey string) (string, error)
+}
+
+// AskAgentTool hands a prompt to the full agent, with its tools, memory and
+// workspace restrictions, and returns the final reply.
+type AskAgentTool struct {
+	runner AgentRunner
+}
+
+// NewAskAgentTool creates the ask_agent tool.
+func NewAskAgentTool(runner AgentRunner) *AskAgentTool {
+	return &AskAgentTool{runner: runner}
+}
+
+var sessionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+func (t *AskAgentTool) Name() string { return "ask_agent" }
+
+func (t *AskAgentTool) Description() string {
+	return "Ask the PicoClaw agent to handle a request. The agent can use its own tools and " +
+		"remembers earlier turns in the same session."
+}
+
+func (t *AskAgentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"prompt": map[string]any{
+				"type":        "string",
+				"description": "The request for the agent",
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Session name; turns in the same session share history (default: default)",
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+func (t *AskAgentTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	prompt, _ := args["prompt"].(string)
+	if prompt == "" {
+		return tools.ErrorResult("prompt is required")
+	}
+	session, _ := args["session"].(string)
+	if session == "" {
+		session = "default"
+	}
+	if !sessionNamePattern.MatchString(session) {
+		return tools.ErrorResult("session must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+
+	response, err := t.runner.ProcessDirect(ctx, prompt, "mcp:"+session)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("agent error: %v", err)).WithError(err)
+	}
+	return tools.NewToolResult(response)
+}
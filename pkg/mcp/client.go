@@ -0,0 +1,313 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Client is a connection to one MCP server.
+type Client struct {
+	name      string
+	transport transport
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[string]chan *message
+
+	notifyMu sync.RWMutex
+	onNotify func(method string, params json.RawMessage)
+
+	serverInfo   Implementation
+	capabilities ServerCapabilities
+	instructions string
+}
+
+func newClient(name string, t transport) *Client {
+	return &Client{name: name, transport: t, pending: make(map[string]chan *message)}
+}
+
+// OnNotification registers a handler for server notifications such as
+// notifications/tools/list_changed.
+func (c *Client) OnNotification(fn func(method string, params json.RawMessage)) {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	c.onNotify = fn
+}
+
+// ClientVersion is reported to servers in clientInfo.
+var ClientVersion = "dev"
+
+// connect starts the transport and performs the initialize handshake.
+func (c *Client) connect(ctx context.Context) error {
+	if err := c.transport.start(ctx, c.dispatch); err != nil {
+		return err
+	}
+	go func() {
+		<-c.transport.done()
+		c.failPending()
+	}()
+
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      Implementation{Name: "picoclaw", Version: ClientVersion},
+	}, &result)
+	if err != nil {
+		c.transport.close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.instructions = result.Instructions
+
+	if ht, ok := c.transport.(*httpTransport); ok {
+		ht.initialized(result.ProtocolVersion)
+	}
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		c.transport.close()
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+	return nil
+}
+
+// Capabilities returns what the server advertised during initialization.
+func (c *Client) Capabilities() ServerCapabilities { return c.capabilities }
+
+// ServerInfo returns the server's name and version.
+func (c *Client) ServerInfo() Implementation { return c.serverInfo }
+
+// Done is closed when the connection is lost.
+func (c *Client) Done() <-chan struct{} { return c.transport.done() }
+
+// Close shuts the connection down.
+func (c *Client) Close() error { return c.transport.close() }
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	msg := message{JSONRPC: jsonrpcVersion, ID: json.RawMessage(id), Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *message, 1)
+	c.mu.Lock()
+	if c.pending == nil {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		if c.pending != nil {
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.send(ctx, data); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp == nil {
+			return ErrClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("decoding %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		c.notify(context.Background(), "notifications/cancelled", map[string]any{"requestId": json.RawMessage(id)})
+		return ctx.Err()
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	msg := message{JSONRPC: jsonrpcVersion, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = raw
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.transport.send(ctx, data)
+}
+
+// dispatch routes an incoming message to the waiting caller, the
+// notification handler, or answers a server request.
+func (c *Client) dispatch(data []byte) {
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if json.Unmarshal(data, &batch) == nil {
+			for _, item := range batch {
+				c.dispatch(item)
+			}
+		}
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.WarnCF("mcp", "Invalid message from server", map[string]any{"server": c.name, "error": err.Error()})
+		return
+	}
+
+	switch {
+	case msg.isResponse():
+		c.mu.Lock()
+		if ch := c.pending[string(msg.ID)]; ch != nil {
+			select {
+			case ch <- &msg:
+			default:
+			}
+		}
+		c.mu.Unlock()
+	case msg.isRequest():
+		go c.answer(&msg)
+	case msg.Method != "":
+		c.notifyMu.RLock()
+		fn := c.onNotify
+		c.notifyMu.RUnlock()
+		if fn != nil {
+			fn(msg.Method, msg.Params)
+		}
+	}
+}
+
+// answer replies to server-initiated requests. Only ping is supported;
+// sampling, roots and elicitation are not advertised.
+func (c *Client) answer(req *message) {
+	resp := message{JSONRPC: jsonrpcVersion, ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &RPCError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	data, _ := json.Marshal(resp)
+	c.transport.send(context.Background(), data)
+}
+
+func (c *Client) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.pending = nil
+}
+
+// ListTools returns every tool, following pagination cursors.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for {
+		var page listToolsResult
+		if err := c.call(ctx, "tools/list", cursorParams(cursor), &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Tools...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool by its server-side name.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListResources returns every resource, following pagination cursors.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var all []Resource
+	cursor := ""
+	for {
+		var page listResourcesResult
+		if err := c.call(ctx, "resources/list", cursorParams(cursor), &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Resources...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// ReadResource reads a resource by URI.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result readResourceResult
+	if err := c.call(ctx, "resources/read", map[string]any{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// ListPrompts returns every prompt, following pagination cursors.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var all []Prompt
+	cursor := ""
+	for {
+		var page listPromptsResult
+		if err := c.call(ctx, "prompts/list", cursorParams(cursor), &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Prompts...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// GetPrompt renders a prompt with the given arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	var result GetPromptResult
+	if err := c.call(ctx, "prompts/get", map[string]any{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func cursorParams(cursor string) any {
+	if cursor == "" {
+		return map[string]any{}
+	}
+	return map[string]any{"cursor": cursor}
+}
@@ -0,0 +1,377 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+const (
+	defaultRequestTimeout = 60 * time.Second
+	startupWait           = 15 * time.Second
+	maxReconnectDelay     = time.Minute
+	maxToolNameLength     = 64
+)
+
+// Manager connects to the configured MCP servers, keeps them connected and
+// mirrors their tools into agent tool registries.
+type Manager struct {
+	servers []*server
+
+	mu         sync.RWMutex
+	registries map[string]*tools.ToolRegistry // agent ID -> registry
+
+	started time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager creates a manager for the enabled servers in cfg.
+func NewManager(cfg config.MCPConfig) *Manager {
+	m := &Manager{registries: make(map[string]*tools.ToolRegistry)}
+	for _, sc := range cfg.Servers {
+		if sc.Disabled {
+			continue
+		}
+		m.servers = append(m.servers, &server{cfg: sc, mgr: m, ready: make(chan struct{})})
+	}
+	return m
+}
+
+// AddRegistry attaches an agent's registry. Servers restricted with
+// "agents" only register their tools for the listed agent IDs.
+func (m *Manager) AddRegistry(agentID string, registry *tools.ToolRegistry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registries[agentID] = registry
+}
+
+// Start connects to every server in the background and returns at once.
+// Failed servers keep retrying; use WaitReady to wait for the first
+// attempts.
+func (m *Manager) Start(ctx context.Context) {
+	if len(m.servers) == 0 {
+		return
+	}
+	m.started = time.Now()
+	ctx, m.cancel = context.WithCancel(ctx)
+	for _, s := range m.servers {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			s.run(ctx)
+		}()
+	}
+}
+
+// WaitReady waits until each server has either connected (and registered
+// its tools) or failed its first attempt. It gives up once ctx is done or
+// the startup timeout since Start has passed, so after startup it returns
+// immediately.
+func (m *Manager) WaitReady(ctx context.Context) {
+	if m.started.IsZero() {
+		return
+	}
+	deadline := time.NewTimer(time.Until(m.started.Add(startupWait)))
+	defer deadline.Stop()
+	for _, s := range m.servers {
+		select {
+		case <-s.ready:
+		case <-deadline.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close disconnects all servers.
+func (m *Manager) Close() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	for _, s := range m.servers {
+		s.disconnect()
+	}
+	m.wg.Wait()
+}
+
+// Status reports whether each server is currently connected, keyed by name.
+func (m *Manager) Status() map[string]bool {
+	out := make(map[string]bool, len(m.servers))
+	for _, s := range m.servers {
+		out[s.cfg.Name] = s.currentClient() != nil
+	}
+	return out
+}
+
+// registriesFor returns the registries a server's tools belong in.
+func (m *Manager) registriesFor(sc config.MCPServerConfig) []*tools.ToolRegistry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*tools.ToolRegistry
+	for agentID, reg := range m.registries {
+		if len(sc.Agents) == 0 || slices.Contains(sc.Agents, agentID) {
+			out = append(out, reg)
+		}
+	}
+	return out
+}
+
+// server is one configured MCP server and its current connection.
+type server struct {
+	cfg config.MCPServerConfig
+	mgr *Manager
+
+	mu        sync.RWMutex
+	client    *Client
+	toolNames []string
+
+	readyOnce sync.Once
+	ready     chan struct{}
+	refreshMu sync.Mutex
+}
+
+func (s *server) currentClient() *Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+func (s *server) timeout() time.Duration {
+	if s.cfg.Timeout > 0 {
+		return time.Duration(s.cfg.Timeout) * time.Second
+	}
+	return defaultRequestTimeout
+}
+
+// run keeps the server connected until ctx is done, reconnecting with
+// exponential backoff.
+func (s *server) run(ctx context.Context) {
+	delay := time.Second
+	for ctx.Err() == nil {
+		client, err := s.connect(ctx)
+		if err != nil {
+			logger.WarnCF("mcp", "MCP server connection failed", map[string]any{
+				"server": s.cfg.Name, "error": err.Error(), "retry_in": delay.String(),
+			})
+			s.readyOnce.Do(func() { close(s.ready) })
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxReconnectDelay)
+			continue
+		}
+
+		delay = time.Second
+		s.mu.Lock()
+		s.client = client
+		s.mu.Unlock()
+
+		if err := s.refresh(ctx); err != nil {
+			logger.WarnCF("mcp", "Failed to list MCP tools", map[string]any{"server": s.cfg.Name, "error": err.Error()})
+		}
+		logger.InfoCF("mcp", "MCP server connected", map[string]any{
+			"server": s.cfg.Name, "server_name": client.ServerInfo().Name, "tools": len(s.registeredNames()),
+		})
+		s.readyOnce.Do(func() { close(s.ready) })
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.Done():
+		}
+
+		s.mu.Lock()
+		if s.client == client {
+			s.client = nil
+		}
+		s.mu.Unlock()
+		logger.WarnCF("mcp", "MCP server disconnected, reconnecting", map[string]any{"server": s.cfg.Name})
+	}
+}
+
+func (s *server) connect(ctx context.Context) (*Client, error) {
+	connectCtx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+
+	if s.cfg.Command == "" && s.cfg.URL == "" {
+		return nil, fmt.Errorf("mcp server %q needs a command or url", s.cfg.Name)
+	}
+
+	var t transport
+	switch {
+	case s.cfg.Command != "":
+		t = newStdioTransport(s.cfg.Name, s.cfg.Command, s.cfg.Args, s.cfg.Env)
+	case s.cfg.Transport == "sse":
+		t = newSSETransport(s.cfg.URL, s.cfg.Headers)
+	default:
+		t = newHTTPTransport(s.cfg.URL, s.cfg.Headers)
+	}
+
+	client := newClient(s.cfg.Name, t)
+	client.OnNotification(s.handleNotification(ctx))
+	err := client.connect(connectCtx)
+
+	// Older servers only speak HTTP+SSE; fall back when the streamable
+	// endpoint rejects the POST and no transport was pinned.
+	var statusErr *HTTPStatusError
+	if err != nil && s.cfg.Transport == "" && s.cfg.URL != "" && s.cfg.Command == "" &&
+		errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusMethodNotAllowed) {
+		client = newClient(s.cfg.Name, newSSETransport(s.cfg.URL, s.cfg.Headers))
+		client.OnNotification(s.handleNotification(ctx))
+		err = client.connect(connectCtx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (s *server) handleNotification(ctx context.Context) func(string, json.RawMessage) {
+	return func(method string, _ json.RawMessage) {
+		switch method {
+		case "notifications/tools/list_changed":
+			go func() {
+				if err := s.refresh(ctx); err != nil {
+					logger.WarnCF("mcp", "Failed to refresh MCP tools", map[string]any{
+						"server": s.cfg.Name, "error": err.Error(),
+					})
+				}
+			}()
+		case "notifications/message":
+			// Server log messages are not surfaced.
+		}
+	}
+}
+
+// refresh re-lists the server's tools and swaps them into the registries.
+func (s *server) refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	client := s.currentClient()
+	if client == nil {
+		return ErrClosed
+	}
+	listCtx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+
+	var newTools []tools.Tool
+	caps := client.Capabilities()
+	if caps.Resources != nil {
+		newTools = append(newTools, &resourcesTool{server: s})
+	}
+	if caps.Prompts != nil {
+		newTools = append(newTools, &promptsTool{server: s})
+	}
+	if caps.Tools != nil {
+		infos, err := client.ListTools(listCtx)
+		if err != nil {
+			return err
+		}
+		taken := make(map[string]bool, len(infos)+len(newTools))
+		for _, t := range newTools {
+			taken[t.Name()] = true
+		}
+		for _, info := range infos {
+			// Sanitizing or truncating can map different tools to one name.
+			name := toolName(s.cfg.Name, info.Name)
+			if taken[name] {
+				name = withHashSuffix(name, s.cfg.Name+"/"+info.Name)
+			}
+			taken[name] = true
+			newTools = append(newTools, &Tool{server: s, info: info, name: name})
+		}
+	}
+
+	names := make([]string, 0, len(newTools))
+	for _, t := range newTools {
+		names = append(names, t.Name())
+	}
+
+	s.mu.Lock()
+	old := s.toolNames
+	s.toolNames = names
+	s.mu.Unlock()
+
+	for _, reg := range s.mgr.registriesFor(s.cfg) {
+		for _, name := range old {
+			if !slices.Contains(names, name) {
+				reg.Unregister(name)
+			}
+		}
+		for _, t := range newTools {
+			reg.Register(t)
+		}
+	}
+	return nil
+}
+
+func (s *server) registeredNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.toolNames
+}
+
+func (s *server) disconnect() {
+	s.mu.Lock()
+	client := s.client
+	s.client = nil
+	s.mu.Unlock()
+	if client != nil {
+		client.Close()
+	}
+}
+
+// callContext bounds a tool call by the server timeout.
+func (s *server) callContext(ctx context.Context) (*Client, context.Context, context.CancelFunc, error) {
+	client := s.currentClient()
+	if client == nil {
+		return nil, nil, nil, fmt.Errorf("mcp server %q is not connected (reconnecting)", s.cfg.Name)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, s.timeout())
+	return client, callCtx, cancel, nil
+}
+
+var toolNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// toolName namespaces a server tool as mcp_<server>_<tool>, restricted to
+// the characters and length providers accept for function names. Names
+// that are too long are cut and end in a hash of the full name, so tools
+// that share a long prefix stay apart.
+func toolName(serverName, tool string) string {
+	name := "mcp_" + toolNameSanitizer.ReplaceAllString(serverName, "_") + "_" +
+		toolNameSanitizer.ReplaceAllString(tool, "_")
+	if len(name) > maxToolNameLength {
+		return withHashSuffix(name, serverName+"/"+tool)
+	}
+	return strings.TrimRight(name, "_")
+}
+
+// withHashSuffix appends a short hash of key to name, cutting name so the
+// result fits maxToolNameLength.
+func withHashSuffix(name, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	if len(name) > maxToolNameLength-len(suffix) {
+		name = name[:maxToolNameLength-len(suffix)]
+	}
+	return strings.TrimRight(name, "_") + suffix
+}
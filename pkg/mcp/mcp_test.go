@@ -0,0 +1,399 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// fakeServer answers MCP requests the way a small tool server would.
+type fakeServer struct {
+	mu    sync.Mutex
+	tools []ToolInfo
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{tools: []ToolInfo{{
+		Name:        "echo",
+		Description: "Echo the text back",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+		},
+	}}}
+}
+
+func (f *fakeServer) addTool(info ToolInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tools = append(f.tools, info)
+}
+
+// handle returns the response for a request, or nil for notifications.
+func (f *fakeServer) handle(t *testing.T, data []byte) []byte {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Errorf("invalid message %s: %v", data, err)
+		return nil
+	}
+	if len(msg.ID) == 0 {
+		return nil
+	}
+
+	var result any
+	switch msg.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]any{
+				"tools":     map[string]any{"listChanged": true},
+				"resources": map[string]any{},
+				"prompts":   map[string]any{},
+			},
+			"serverInfo": map[string]any{"name": "fake", "version": "1.0"},
+		}
+	case "tools/list":
+		f.mu.Lock()
+		result = map[string]any{"tools": f.tools}
+		f.mu.Unlock()
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		switch params.Name {
+		case "echo":
+			result = CallToolResult{Content: []Content{{Type: "text", Text: fmt.Sprint(params.Arguments["text"])}}}
+		default:
+			result = CallToolResult{Content: []Content{{Type: "text", Text: "boom"}}, IsError: true}
+		}
+	case "resources/list":
+		result = map[string]any{"resources": []Resource{{URI: "file:///notes.txt", Name: "notes", MimeType: "text/plain"}}}
+	case "resources/read":
+		result = map[string]any{"contents": []ResourceContents{{URI: "file:///notes.txt", Text: "remember the milk"}}}
+	case "prompts/list":
+		result = map[string]any{"prompts": []Prompt{{Name: "greet", Arguments: []PromptArgument{{Name: "who", Required: true}}}}}
+	case "prompts/get":
+		var params struct {
+			Arguments map[string]string `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		result = GetPromptResult{Messages: []PromptMessage{{
+			Role:    "user",
+			Content: Content{Type: "text", Text: "Say hello to " + params.Arguments["who"]},
+		}}}
+	default:
+		resp, _ := json.Marshal(message{
+			JSONRPC: jsonrpcVersion, ID: msg.ID,
+			Error: &RPCError{Code: codeMethodNotFound, Message: "unknown method"},
+		})
+		return resp
+	}
+	raw, _ := json.Marshal(result)
+	resp, _ := json.Marshal(message{JSONRPC: jsonrpcVersion, ID: msg.ID, Result: raw})
+	return resp
+}
+
+// streamableServer serves the fake server over streamable HTTP with a GET
+// stream for notifications.
+type streamableServer struct {
+	*fakeServer
+	notify chan string
+	posts  sync.Map // method -> session header seen
+}
+
+func newStreamableServer(t *testing.T) (*streamableServer, *httptest.Server) {
+	s := &streamableServer{fakeServer: newFakeServer(), notify: make(chan string, 4)}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var body []byte
+			body, _ = readAll(r)
+			var msg message
+			json.Unmarshal(body, &msg)
+			s.posts.Store(msg.Method, r.Header.Get("Mcp-Session-Id"))
+			w.Header().Set("Mcp-Session-Id", "session-1")
+			resp := s.handle(t, body)
+			if resp == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			if msg.Method == "tools/call" {
+				// Exercise SSE-framed responses as well as plain JSON.
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(resp)
+		case http.MethodGet:
+			if r.Header.Get("Mcp-Session-Id") != "session-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case method := <-s.notify:
+					fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":%q}\n\n", method)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func readAll(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	var sb strings.Builder
+	_, err := bufio.NewReader(r.Body).WriteTo(&sb)
+	return []byte(sb.String()), err
+}
+
+func startManager(t *testing.T, sc config.MCPServerConfig) (*Manager, *tools.ToolRegistry) {
+	t.Helper()
+	m := NewManager(config.MCPConfig{Servers: []config.MCPServerConfig{sc}})
+	reg := tools.NewToolRegistry()
+	m.AddRegistry("main", reg)
+	m.Start(t.Context())
+	m.WaitReady(t.Context())
+	t.Cleanup(m.Close)
+	return m, reg
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager_StreamableHTTP(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	m, reg := startManager(t, config.MCPServerConfig{Name: "fake", URL: ts.URL})
+
+	if !m.Status()["fake"] {
+		t.Fatal("server not connected after Start")
+	}
+	if session, _ := srv.posts.Load("tools/list"); session != "session-1" {
+		t.Errorf("tools/list session header = %v, want session-1", session)
+	}
+
+	echo, ok := reg.Get("mcp_fake_echo")
+	if !ok {
+		t.Fatal("mcp_fake_echo not registered")
+	}
+	if !strings.Contains(echo.Description(), "Echo the text back") {
+		t.Errorf("description = %q", echo.Description())
+	}
+	result := reg.Execute(t.Context(), "mcp_fake_echo", map[string]any{"text": "hello"})
+	if result.IsError || result.ForLLM != "hello" {
+		t.Errorf("echo result = %+v", result)
+	}
+
+	// list_changed arrives on the GET stream and re-registers tools.
+	srv.addTool(ToolInfo{Name: "fail", InputSchema: map[string]any{"type": "object"}})
+	srv.notify <- "notifications/tools/list_changed"
+	waitFor(t, func() bool { _, ok := reg.Get("mcp_fake_fail"); return ok })
+
+	result = reg.Execute(t.Context(), "mcp_fake_fail", nil)
+	if !result.IsError || result.ForLLM != "boom" {
+		t.Errorf("fail result = %+v", result)
+	}
+}
+
+func TestManager_ResourcesAndPrompts(t *testing.T) {
+	_, ts := newStreamableServer(t)
+	_, reg := startManager(t, config.MCPServerConfig{Name: "fake", URL: ts.URL})
+
+	result := reg.Execute(t.Context(), "mcp_fake_resources", map[string]any{"action": "list"})
+	if !strings.Contains(result.ForLLM, "file:///notes.txt") {
+		t.Errorf("resources list = %q", result.ForLLM)
+	}
+	result = reg.Execute(t.Context(), "mcp_fake_resources", map[string]any{"action": "read", "uri": "file:///notes.txt"})
+	if result.ForLLM != "remember the milk" {
+		t.Errorf("resources read = %q", result.ForLLM)
+	}
+
+	result = reg.Execute(t.Context(), "mcp_fake_prompts", map[string]any{"action": "list"})
+	if !strings.Contains(result.ForLLM, "greet") || !strings.Contains(result.ForLLM, "who (string, required)") {
+		t.Errorf("prompts list = %q", result.ForLLM)
+	}
+	result = reg.Execute(t.Context(), "mcp_fake_prompts", map[string]any{
+		"action": "get", "name": "greet", "arguments": map[string]any{"who": "Ada"},
+	})
+	if !strings.Contains(result.ForLLM, "Say hello to Ada") {
+		t.Errorf("prompts get = %q", result.ForLLM)
+	}
+}
+
+func TestManager_CollidingToolNames(t *testing.T) {
+	srv, ts := newStreamableServer(t)
+	srv.addTool(ToolInfo{Name: "do.thing"})
+	srv.addTool(ToolInfo{Name: "do_thing"})
+	srv.addTool(ToolInfo{Name: "resources"})
+	_, reg := startManager(t, config.MCPServerConfig{Name: "fake", URL: ts.URL})
+
+	names := map[string]string{}
+	for _, name := range reg.List() {
+		tool, _ := reg.Get(name)
+		if mt, ok := tool.(*Tool); ok {
+			names[mt.info.Name] = name
+		}
+	}
+	if len(names) != 4 {
+		t.Fatalf("registered tools = %v, want every server tool under its own name", reg.List())
+	}
+	if names["do.thing"] == names["do_thing"] || names["resources"] == "mcp_fake_resources" {
+		t.Errorf("colliding names: %v", names)
+	}
+	if _, ok := reg.Get("mcp_fake_resources"); !ok {
+		t.Error("resources tool was replaced")
+	}
+}
+
+func TestManager_AgentRestriction(t *testing.T) {
+	_, ts := newStreamableServer(t)
+	m := NewManager(config.MCPConfig{Servers: []config.MCPServerConfig{
+		{Name: "fake", URL: ts.URL, Agents: []string{"coder"}},
+		{Name: "off", URL: ts.URL, Disabled: true},
+	}})
+	mainReg, coderReg := tools.NewToolRegistry(), tools.NewToolRegistry()
+	m.AddRegistry("main", mainReg)
+	m.AddRegistry("coder", coderReg)
+	m.Start(t.Context())
+	m.WaitReady(t.Context())
+	defer m.Close()
+
+	if _, ok := coderReg.Get("mcp_fake_echo"); !ok {
+		t.Error("coder agent should have mcp_fake_echo")
+	}
+	if _, ok := mainReg.Get("mcp_fake_echo"); ok {
+		t.Error("main agent should not have mcp_fake_echo")
+	}
+	if _, ok := m.Status()["off"]; ok {
+		t.Error("disabled server should not be managed")
+	}
+}
+
+func TestManager_LegacySSEFallback(t *testing.T) {
+	fake := newFakeServer()
+	events := make(chan []byte, 8)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: endpoint\ndata: /messages?session=abc\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case data := <-events:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := readAll(r)
+		if resp := fake.handle(t, body); resp != nil {
+			events <- resp
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	// POST /sse is rejected with 405, so the manager falls back to SSE.
+	_, reg := startManager(t, config.MCPServerConfig{Name: "legacy", URL: ts.URL + "/sse"})
+	result := reg.Execute(t.Context(), "mcp_legacy_echo", map[string]any{"text": "over sse"})
+	if result.IsError || result.ForLLM != "over sse" {
+		t.Errorf("echo result = %+v", result)
+	}
+}
+
+func TestManager_Stdio(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	_, reg := startManager(t, config.MCPServerConfig{
+		Name:    "local",
+		Command: exe,
+		Args:    []string{"-test.run=TestHelperStdioServer"},
+		Env:     map[string]string{"PICOCLAW_MCP_HELPER": "1"},
+	})
+	result := reg.Execute(t.Context(), "mcp_local_echo", map[string]any{"text": "via stdio"})
+	if result.IsError || result.ForLLM != "via stdio" {
+		t.Errorf("echo result = %+v", result)
+	}
+}
+
+// TestHelperStdioServer is not a real test: TestManager_Stdio runs the test
+// binary with it as a stdio MCP server.
+func TestHelperStdioServer(t *testing.T) {
+	if os.Getenv("PICOCLAW_MCP_HELPER") != "1" {
+		return
+	}
+	fake := newFakeServer()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if resp := fake.handle(t, scanner.Bytes()); resp != nil {
+			os.Stdout.Write(append(resp, '\n'))
+		}
+	}
+	os.Exit(0)
+}
+
+func TestTool_DisconnectedServer(t *testing.T) {
+	s := &server{cfg: config.MCPServerConfig{Name: "gone"}}
+	tool := &Tool{server: s, info: ToolInfo{Name: "x"}, name: toolName("gone", "x")}
+	result := tool.Execute(t.Context(), nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "not connected") {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct{ server, tool, want string }{
+		{"github", "create_issue", "mcp_github_create_issue"},
+		{"my server", "do.thing", "mcp_my_server_do_thing"},
+	}
+	for _, tt := range tests {
+		if got := toolName(tt.server, tt.tool); got != tt.want {
+			t.Errorf("toolName(%q, %q) = %q, want %q", tt.server, tt.tool, got, tt.want)
+		}
+	}
+
+	long1 := toolName("s", strings.Repeat("a", 80)+"_one")
+	long2 := toolName("s", strings.Repeat("a", 80)+"_two")
+	if len(long1) != maxToolNameLength || !strings.HasPrefix(long1, "mcp_s_aaaa") {
+		t.Errorf("long name = %q (%d chars)", long1, len(long1))
+	}
+	if long1 == long2 {
+		t.Errorf("names sharing a long prefix collide: %q", long1)
+	}
+}
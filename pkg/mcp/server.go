@@ -0,0 +1,300 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// JSON-RPC error codes used by the server.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeInvalidParams  = -32602
+)
+
+const maxRequestBody = 4 << 20
+
+// Server publishes the tools in a registry over MCP.
+type Server struct {
+	info     Implementation
+	registry *tools.ToolRegistry
+
+	// Channel and chat ID handed to contextual tools (message, cron) so
+	// replies and scheduled jobs have somewhere to go.
+	channel string
+	chatID  string
+
+	token string
+}
+
+// NewServer creates a server exposing every tool in registry.
+func NewServer(info Implementation, registry *tools.ToolRegistry) *Server {
+	return &Server{info: info, registry: registry}
+}
+
+// SetContext sets the channel and chat ID passed to contextual tools.
+func (s *Server) SetContext(channel, chatID string) {
+	s.channel = channel
+	s.chatID = chatID
+}
+
+// SetToken requires HTTP clients to send "Authorization: Bearer <token>".
+func (s *Server) SetToken(token string) {
+	s.token = token
+}
+
+// ServeStdio reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is exhausted or ctx is cancelled. Requests are
+// handled concurrently so a long tool call does not block pings.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	write := func(data []byte) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	lines := make(chan []byte)
+	errCh := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReaderSize(r, 64*1024)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+				select {
+				case lines <- []byte(trimmed):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errCh:
+			return err
+		case line := <-lines:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp := s.handle(ctx, line); resp != nil {
+					write(resp)
+				}
+			}()
+		}
+	}
+}
+
+// ServeHTTP implements the streamable HTTP transport without server-initiated
+// streams: every POST is answered with a single JSON body.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowedOrigin(r) {
+		http.Error(w, "forbidden origin", http.StatusForbidden)
+		return
+	}
+	if s.token != "" {
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if r.Method != http.MethodPost {
+		// No server-initiated stream (GET) and no session to end (DELETE).
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+	resp := s.handle(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// allowedOrigin rejects browser requests from non-local origins to guard
+// against DNS rebinding. Requests without an Origin header are allowed.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// handle processes one message or batch and returns the encoded response,
+// or nil when there is nothing to send back.
+func (s *Server) handle(ctx context.Context, data []byte) []byte {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return encodeError(nil, codeParseError, "parse error")
+		}
+		var responses []json.RawMessage
+		for _, item := range batch {
+			if resp := s.handle(ctx, item); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		out, _ := json.Marshal(responses)
+		return out
+	}
+
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return encodeError(nil, codeParseError, "parse error")
+	}
+	if msg.Method == "" {
+		// Responses to requests we never send; ignore.
+		return nil
+	}
+	if !msg.isRequest() {
+		// Notifications (initialized, cancelled) need no reply.
+		return nil
+	}
+
+	result, rpcErr := s.dispatch(ctx, &msg)
+	if rpcErr != nil {
+		return encodeError(msg.ID, rpcErr.Code, rpcErr.Message)
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return encodeError(msg.ID, codeInvalidRequest, err.Error())
+	}
+	out, _ := json.Marshal(message{JSONRPC: jsonrpcVersion, ID: msg.ID, Result: raw})
+	return out
+}
+
+func (s *Server) dispatch(ctx context.Context, msg *message) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		json.Unmarshal(msg.Params, &params)
+		logger.InfoCF("mcp", "MCP client connected", map[string]any{
+			"client": params.ClientInfo.Name, "version": params.ClientInfo.Version,
+		})
+		return map[string]any{
+			"protocolVersion": negotiateVersion(params.ProtocolVersion),
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      s.info,
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": s.listTools()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			return nil, &RPCError{Code: codeInvalidParams, Message: "tools/call needs a tool name"}
+		}
+		if _, ok := s.registry.Get(params.Name); !ok {
+			return nil, &RPCError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		if params.Arguments == nil {
+			params.Arguments = map[string]any{}
+		}
+		result := s.registry.ExecuteWithContext(ctx, params.Name, params.Arguments, s.channel, s.chatID, nil)
+		return toCallToolResult(result), nil
+	default:
+		return nil, &RPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+func (s *Server) listTools() []ToolInfo {
+	names := s.registry.List()
+	infos := make([]ToolInfo, 0, len(names))
+	for _, name := range names {
+		tool, ok := s.registry.Get(name)
+		if !ok {
+			continue
+		}
+		infos = append(infos, ToolInfo{
+			Name:        name,
+			Description: tool.Description(),
+			InputSchema: tool.Parameters(),
+		})
+	}
+	return infos
+}
+
+func toCallToolResult(result *tools.ToolResult) CallToolResult {
+	text := result.ForLLM
+	if text == "" {
+		text = result.ForUser
+	}
+	return CallToolResult{
+		Content: []Content{{Type: "text", Text: text}},
+		IsError: result.IsError,
+	}
+}
+
+// negotiateVersion echoes versions the server understands and otherwise
+// offers its own, as the spec requires.
+func negotiateVersion(requested string) string {
+	switch requested {
+	case "2024-11-05", "2025-03-26", ProtocolVersion:
+		return requested
+	}
+	return ProtocolVersion
+}
+
+func encodeError(id json.RawMessage, code int, msg string) []byte {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	out, _ := json.Marshal(message{JSONRPC: jsonrpcVersion, ID: id, Error: &RPCError{Code: code, Message: msg}})
+	return out
+}
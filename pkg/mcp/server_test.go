@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+type stubTool struct {
+	name    string
+	channel string
+}
+
+func (t *stubTool) Name() string        { return t.name }
+func (t *stubTool) Description() string { return "Stub tool" }
+func (t *stubTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}
+}
+
+func (t *stubTool) SetContext(channel, chatID string) { t.channel = channel + ":" + chatID }
+
+func (t *stubTool) Execute(_ context.Context, args map[string]any) *tools.ToolResult {
+	text, _ := args["text"].(string)
+	if text == "" {
+		return tools.ErrorResult("text is required")
+	}
+	return tools.NewToolResult(text + " from " + t.channel)
+}
+
+type stubRunner struct {
+	sessionKey string
+}
+
+func (r *stubRunner) ProcessDirect(_ context.Context, content, sessionKey string) (string, error) {
+	r.sessionKey = sessionKey
+	if content == "fail" {
+		return "", errors.New("provider down")
+	}
+	return "agent says: " + content, nil
+}
+
+func newTestServer() (*Server, *stubRunner) {
+	runner := &stubRunner{}
+	registry := tools.NewToolRegistry()
+	registry.Register(&stubTool{name: "shout"})
+	registry.Register(NewAskAgentTool(runner))
+	srv := NewServer(Implementation{Name: "picoclaw", Version: "test"}, registry)
+	srv.SetContext("telegram", "42")
+	return srv, runner
+}
+
+func TestServer_HTTPRoundTrip(t *testing.T) {
+	srv, runner := newTestServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := newClient("self", newHTTPTransport(ts.URL, nil))
+	if err := client.connect(t.Context()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Close()
+
+	if client.ServerInfo().Name != "picoclaw" || client.Capabilities().Tools == nil {
+		t.Errorf("server info = %+v, caps = %+v", client.ServerInfo(), client.Capabilities())
+	}
+
+	infos, err := client.ListTools(t.Context())
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "ask_agent" || infos[1].Name != "shout" {
+		t.Errorf("tools = %+v", infos)
+	}
+
+	result, err := client.CallTool(t.Context(), "shout", map[string]any{"text": "hi"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError || contentText(result.Content) != "hi from telegram:42" {
+		t.Errorf("shout result = %+v", result)
+	}
+
+	result, err = client.CallTool(t.Context(), "shout", nil)
+	if err != nil || !result.IsError {
+		t.Errorf("shout without text = %+v, err = %v", result, err)
+	}
+
+	result, err = client.CallTool(t.Context(), "ask_agent", map[string]any{"prompt": "hello", "session": "editor"})
+	if err != nil || contentText(result.Content) != "agent says: hello" {
+		t.Errorf("ask_agent = %+v, err = %v", result, err)
+	}
+	if runner.sessionKey != "mcp:editor" {
+		t.Errorf("session key = %q, want mcp:editor", runner.sessionKey)
+	}
+
+	if _, err := client.CallTool(t.Context(), "exec", nil); err == nil {
+		t.Error("calling an unexported tool should fail")
+	}
+}
+
+func TestServer_Stdio(t *testing.T) {
+	srv, _ := newTestServer()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.ServeStdio(t.Context(), inR, outW)
+		outW.Close()
+	}()
+
+	go func() {
+		io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n")
+		io.WriteString(inW, `{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n")
+		io.WriteString(inW, `{"jsonrpc":"2.0","id":2,"method":"nope"}`+"\n")
+		inW.Close()
+	}()
+
+	out, _ := io.ReadAll(outR)
+	if err := <-done; err != nil {
+		t.Fatalf("ServeStdio: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d responses, want 2: %s", len(lines), out)
+	}
+	joined := string(out)
+	if !strings.Contains(joined, `"id":1,"result":{}`) {
+		t.Errorf("missing ping response: %s", joined)
+	}
+	if !strings.Contains(joined, `"id":2`) || !strings.Contains(joined, "-32601") {
+		t.Errorf("missing method-not-found response: %s", joined)
+	}
+}
+
+func TestServer_HTTPGuards(t *testing.T) {
+	srv, _ := newTestServer()
+	srv.SetToken("secret")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	post := func(headers map[string]string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post(nil); code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", code)
+	}
+	if code := post(map[string]string{"Authorization": "Bearer secret"}); code != http.StatusOK {
+		t.Errorf("with token: status %d, want 200", code)
+	}
+	if code := post(map[string]string{
+		"Authorization": "Bearer secret", "Origin": "https://evil.example",
+	}); code != http.StatusForbidden {
+		t.Errorf("foreign origin: status %d, want 403", code)
+	}
+	if code := post(map[string]string{
+		"Authorization": "Bearer secret", "Origin": "http://localhost:3000",
+	}); code != http.StatusOK {
+		t.Errorf("local origin: status %d, want 200", code)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestAskAgentTool_Validation(t *testing.T) {
+	tool := NewAskAgentTool(&stubRunner{})
+	if r := tool.Execute(t.Context(), map[string]any{}); !r.IsError {
+		t.Error("missing prompt should fail")
+	}
+	if r := tool.Execute(t.Context(), map[string]any{"prompt": "x", "session": "../etc"}); !r.IsError {
+		t.Error("invalid session name should fail")
+	}
+	if r := tool.Execute(t.Context(), map[string]any{"prompt": "fail"}); !r.IsError || r.Err == nil {
+		t.Errorf("agent error result = %+v", r)
+	}
+}
@@ -0,0 +1,281 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// Tool adapts a tool from an MCP server to tools.Tool. Calls go to the
+// server's current connection, so a tool survives reconnects.
+type Tool struct {
+	server *server
+	info   ToolInfo
+	name   string
+}
+
+func (t *Tool) Name() string { return t.name }
+
+func (t *Tool) Description() string {
+	desc := t.info.Description
+	if desc == "" {
+		desc = t.info.Title
+	}
+	return fmt.Sprintf("[MCP %s] %s", t.server.cfg.Name, desc)
+}
+
+func (t *Tool) Parameters() map[string]any {
+	if len(t.info.InputSchema) == 0 {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	schema := t.info.InputSchema
+	if _, ok := schema["properties"]; !ok {
+		schema = cloneSchema(schema)
+		schema["properties"] = map[string]any{}
+	}
+	return schema
+}
+
+func (t *Tool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	client, callCtx, cancel, err := t.server.callContext(ctx)
+	if err != nil {
+		return tools.ErrorResult(err.Error()).WithError(err)
+	}
+	defer cancel()
+
+	result, err := client.CallTool(callCtx, t.info.Name, args)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("MCP tool %s failed: %v", t.name, err)).WithError(err)
+	}
+	text := contentText(result.Content)
+	if text == "" && result.StructuredContent != nil {
+		if data, err := json.Marshal(result.StructuredContent); err == nil {
+			text = string(data)
+		}
+	}
+	if result.IsError {
+		if text == "" {
+			text = "tool reported an error"
+		}
+		return tools.ErrorResult(text)
+	}
+	if text == "" {
+		text = "(no output)"
+	}
+	return tools.NewToolResult(text)
+}
+
+// resourcesTool lists and reads a server's resources.
+type resourcesTool struct {
+	server *server
+}
+
+func (t *resourcesTool) Name() string { return toolName(t.server.cfg.Name, "resources") }
+
+func (t *resourcesTool) Description() string {
+	return fmt.Sprintf("[MCP %s] List the server's resources, or read one by URI.", t.server.cfg.Name)
+}
+
+func (t *resourcesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "read"},
+				"description": "list resources or read one",
+			},
+			"uri": map[string]any{
+				"type":        "string",
+				"description": "Resource URI (required for read)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *resourcesTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	client, callCtx, cancel, err := t.server.callContext(ctx)
+	if err != nil {
+		return tools.ErrorResult(err.Error()).WithError(err)
+	}
+	defer cancel()
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "list":
+		resources, err := client.ListResources(callCtx)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("listing resources: %v", err)).WithError(err)
+		}
+		if len(resources) == 0 {
+			return tools.NewToolResult("No resources available.")
+		}
+		var sb strings.Builder
+		for _, r := range resources {
+			fmt.Fprintf(&sb, "- %s (%s)", r.URI, r.Name)
+			if r.MimeType != "" {
+				fmt.Fprintf(&sb, " [%s]", r.MimeType)
+			}
+			if r.Description != "" {
+				fmt.Fprintf(&sb, ": %s", r.Description)
+			}
+			sb.WriteByte('\n')
+		}
+		return tools.NewToolResult(sb.String())
+	case "read":
+		uri, _ := args["uri"].(string)
+		if uri == "" {
+			return tools.ErrorResult("uri is required for read")
+		}
+		contents, err := client.ReadResource(callCtx, uri)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("reading resource: %v", err)).WithError(err)
+		}
+		parts := make([]string, 0, len(contents))
+		for _, c := range contents {
+			parts = append(parts, resourceText(c))
+		}
+		return tools.NewToolResult(strings.Join(parts, "\n\n"))
+	default:
+		return tools.ErrorResult("action must be list or read")
+	}
+}
+
+// promptsTool lists and renders a server's prompts.
+type promptsTool struct {
+	server *server
+}
+
+func (t *promptsTool) Name() string { return toolName(t.server.cfg.Name, "prompts") }
+
+func (t *promptsTool) Description() string {
+	return fmt.Sprintf("[MCP %s] List the server's prompt templates, or render one with arguments.", t.server.cfg.Name)
+}
+
+func (t *promptsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "get"},
+				"description": "list prompts or get (render) one",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Prompt name (required for get)",
+			},
+			"arguments": map[string]any{
+				"type":                 "object",
+				"description":          "Prompt arguments as string values",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *promptsTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	client, callCtx, cancel, err := t.server.callContext(ctx)
+	if err != nil {
+		return tools.ErrorResult(err.Error()).WithError(err)
+	}
+	defer cancel()
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "list":
+		prompts, err := client.ListPrompts(callCtx)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("listing prompts: %v", err)).WithError(err)
+		}
+		if len(prompts) == 0 {
+			return tools.NewToolResult("No prompts available.")
+		}
+		var sb strings.Builder
+		for _, p := range prompts {
+			fmt.Fprintf(&sb, "- %s", p.Name)
+			if p.Description != "" {
+				fmt.Fprintf(&sb, ": %s", p.Description)
+			}
+			for _, a := range p.Arguments {
+				req := ""
+				if a.Required {
+					req = ", required"
+				}
+				fmt.Fprintf(&sb, "\n    %s (string%s) %s", a.Name, req, a.Description)
+			}
+			sb.WriteByte('\n')
+		}
+		return tools.NewToolResult(sb.String())
+	case "get":
+		name, _ := args["name"].(string)
+		if name == "" {
+			return tools.ErrorResult("name is required for get")
+		}
+		promptArgs := map[string]string{}
+		if raw, ok := args["arguments"].(map[string]any); ok {
+			for k, v := range raw {
+				promptArgs[k] = fmt.Sprint(v)
+			}
+		}
+		result, err := client.GetPrompt(callCtx, name, promptArgs)
+		if err != nil {
+			return tools.ErrorResult(fmt.Sprintf("getting prompt: %v", err)).WithError(err)
+		}
+		var sb strings.Builder
+		if result.Description != "" {
+			sb.WriteString(result.Description + "\n\n")
+		}
+		for _, m := range result.Messages {
+			fmt.Fprintf(&sb, "[%s]\n%s\n\n", m.Role, contentText([]Content{m.Content}))
+		}
+		return tools.NewToolResult(strings.TrimSpace(sb.String()))
+	default:
+		return tools.ErrorResult("action must be list or get")
+	}
+}
+
+// contentText flattens MCP content items into text for the LLM. Binary
+// items are summarized rather than inlined.
+func contentText(items []Content) string {
+	parts := make([]string, 0, len(items))
+	for _, c := range items {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s content: %s, %d bytes base64]", c.Type, c.MimeType, len(c.Data)))
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s %s]", c.URI, c.Name))
+		case "resource":
+			if c.Resource != nil {
+				parts = append(parts, resourceText(*c.Resource))
+			}
+		default:
+			if data, err := json.Marshal(c); err == nil {
+				parts = append(parts, string(data))
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func resourceText(c ResourceContents) string {
+	if c.Text != "" || c.Blob == "" {
+		return c.Text
+	}
+	return fmt.Sprintf("[binary resource %s: %s, %d bytes base64]", c.URI, c.MimeType, len(c.Blob))
+}
+
+func cloneSchema(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in)+1)
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,480 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrClosed is returned for requests on a connection that has been lost.
+var ErrClosed = errors.New("mcp: connection closed")
+
+// transport moves raw JSON-RPC messages between client and server.
+// Incoming messages are passed to the handler given to start; done is
+// closed when the connection is lost or closed.
+type transport interface {
+	start(ctx context.Context, handle func([]byte)) error
+	send(ctx context.Context, msg []byte) error
+	close() error
+	done() <-chan struct{}
+}
+
+// closer closes a done channel exactly once.
+type closer struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+func newCloser() *closer { return &closer{ch: make(chan struct{})} }
+
+func (c *closer) close()                { c.once.Do(func() { close(c.ch) }) }
+func (c *closer) done() <-chan struct{} { return c.ch }
+
+// stdioTransport talks to a server launched as a subprocess using
+// newline-delimited JSON on stdin/stdout.
+type stdioTransport struct {
+	name    string
+	command string
+	args    []string
+	env     map[string]string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	closed *closer
+}
+
+func newStdioTransport(name, command string, args []string, env map[string]string) *stdioTransport {
+	return &stdioTransport{name: name, command: command, args: args, env: env, closed: newCloser()}
+}
+
+func (t *stdioTransport) start(ctx context.Context, handle func([]byte)) error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = os.Environ()
+	for k, v := range t.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", t.command, err)
+	}
+
+	t.mu.Lock()
+	t.cmd = cmd
+	t.stdin = stdin
+	t.mu.Unlock()
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.DebugCF("mcp", "Server stderr", map[string]any{"server": t.name, "line": scanner.Text()})
+		}
+	}()
+
+	go func() {
+		defer t.closed.close()
+		reader := bufio.NewReaderSize(stdout, 64*1024)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				handle(line)
+			}
+			if err != nil {
+				cmd.Wait()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.closed.done():
+		return ErrClosed
+	default:
+	}
+	if t.stdin == nil {
+		return ErrClosed
+	}
+	if _, err := t.stdin.Write(append(msg, '\n')); err != nil {
+		return fmt.Errorf("writing to %s: %w", t.name, err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) close() error {
+	t.mu.Lock()
+	cmd, stdin := t.cmd, t.stdin
+	t.mu.Unlock()
+	if stdin != nil {
+		stdin.Close()
+	}
+	if cmd != nil && cmd.Process != nil {
+		// Give the server a moment to exit after stdin closes.
+		select {
+		case <-t.closed.done():
+		case <-time.After(2 * time.Second):
+			cmd.Process.Kill()
+		}
+	}
+	t.closed.close()
+	return nil
+}
+
+func (t *stdioTransport) done() <-chan struct{} { return t.closed.done() }
+
+// HTTPStatusError reports a non-success HTTP status from a remote server.
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("mcp: HTTP %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// httpTransport implements the streamable HTTP transport: every message is
+// POSTed, responses come back as JSON or as an SSE stream, and an optional
+// GET stream carries server-initiated notifications.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+	protocol  string
+	handle    func([]byte)
+	cancel    context.CancelFunc
+	closed    *closer
+}
+
+func newHTTPTransport(endpoint string, headers map[string]string) *httpTransport {
+	return &httpTransport{url: endpoint, headers: headers, client: &http.Client{}, closed: newCloser()}
+}
+
+func (t *httpTransport) start(ctx context.Context, handle func([]byte)) error {
+	t.mu.Lock()
+	t.handle = handle
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocol != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocol)
+	}
+}
+
+func (t *httpTransport) send(ctx context.Context, msg []byte) error {
+	select {
+	case <-t.closed.done():
+		return ErrClosed
+	default:
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: POST %s: %w", t.url, err)
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusNotFound && t.hasSession() {
+		// The server dropped our session; reconnecting starts a new one.
+		t.closed.close()
+		return ErrClosed
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if resp.StatusCode == http.StatusAccepted {
+		return nil
+	}
+
+	t.mu.Lock()
+	handle := t.handle
+	t.mu.Unlock()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readSSE(resp.Body, func(event, data string) {
+			if event == "" || event == "message" {
+				handle([]byte(data))
+			}
+		})
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		handle(body)
+	}
+	return nil
+}
+
+func (t *httpTransport) hasSession() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID != ""
+}
+
+// initialized records the negotiated protocol version and opens the GET
+// stream for server notifications. Servers without one answer 405.
+func (t *httpTransport) initialized(protocol string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	t.protocol = protocol
+	t.cancel = cancel
+	handle := t.handle
+	t.mu.Unlock()
+
+	go func() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		t.setHeaders(req)
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return
+		}
+		readSSE(resp.Body, func(event, data string) {
+			if event == "" || event == "message" {
+				handle([]byte(data))
+			}
+		})
+	}()
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	cancel, session := t.cancel, t.sessionID
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	if session != "" {
+		ctx, cancelDelete := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancelDelete()
+		if req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil); err == nil {
+			t.setHeaders(req)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	t.closed.close()
+	return nil
+}
+
+func (t *httpTransport) done() <-chan struct{} { return t.closed.done() }
+
+// sseTransport implements the legacy HTTP+SSE transport: a long-lived GET
+// stream announces a POST endpoint and then carries all server messages.
+type sseTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu       sync.Mutex
+	endpoint string
+	cancel   context.CancelFunc
+	closed   *closer
+}
+
+func newSSETransport(endpoint string, headers map[string]string) *sseTransport {
+	return &sseTransport{url: endpoint, headers: headers, client: &http.Client{}, closed: newCloser()}
+}
+
+func (t *sseTransport) start(ctx context.Context, handle func([]byte)) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("mcp: GET %s: %w", t.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		cancel()
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	t.mu.Lock()
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	endpointCh := make(chan string, 1)
+	go func() {
+		defer t.closed.close()
+		defer resp.Body.Close()
+		readSSE(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				select {
+				case endpointCh <- data:
+				default:
+				}
+			case "", "message":
+				handle([]byte(data))
+			}
+		})
+	}()
+
+	select {
+	case ep := <-endpointCh:
+		base, _ := url.Parse(t.url)
+		ref, err := url.Parse(strings.TrimSpace(ep))
+		if err != nil {
+			t.close()
+			return fmt.Errorf("mcp: invalid endpoint event %q: %w", ep, err)
+		}
+		t.mu.Lock()
+		t.endpoint = base.ResolveReference(ref).String()
+		t.mu.Unlock()
+		return nil
+	case <-t.closed.done():
+		return fmt.Errorf("mcp: SSE stream closed before endpoint event")
+	case <-ctx.Done():
+		t.close()
+		return ctx.Err()
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, msg []byte) error {
+	select {
+	case <-t.closed.done():
+		return ErrClosed
+	default:
+	}
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: POST %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	t.mu.Lock()
+	cancel := t.cancel
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	t.closed.close()
+	return nil
+}
+
+func (t *sseTransport) done() <-chan struct{} { return t.closed.done() }
+
+// readSSE parses a text/event-stream body, calling fn for every event.
+func readSSE(r io.Reader, fn func(event, data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if len(data) > 0 {
+		fn(event, strings.Join(data, "\n"))
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,243 @@
+// Package plan keeps a checklist per session so that the agent can track
+// the steps of a multi-step request across iterations and summaries.
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Item statuses.
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusSkipped    = "skipped"
+)
+
+// Statuses lists the valid item statuses.
+var Statuses = []string{StatusPending, StatusInProgress, StatusDone, StatusSkipped}
+
+// Item is one step of a plan.
+type Item struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Plan is the checklist of one session.
+type Plan struct {
+	Goal      string    `json:"goal"`
+	Items     []Item    `json:"items"`
+	Notes     []string  `json:"notes,omitempty"`
+	Completed bool      `json:"completed,omitempty"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+}
+
+// New creates a plan with one pending item per step.
+func New(goal string, steps []string) *Plan {
+	now := time.Now()
+	p := &Plan{Goal: goal, Created: now, Updated: now}
+	for _, step := range steps {
+		p.AddItem(step)
+	}
+	return p
+}
+
+// AddItem appends a pending item and returns it.
+func (p *Plan) AddItem(text string) Item {
+	id := 1
+	if n := len(p.Items); n > 0 {
+		id = p.Items[n-1].ID + 1
+	}
+	item := Item{ID: id, Text: text, Status: StatusPending}
+	p.Items = append(p.Items, item)
+	return item
+}
+
+// Item returns the item with the given ID.
+func (p *Plan) Item(id int) (*Item, bool) {
+	for i := range p.Items {
+		if p.Items[i].ID == id {
+			return &p.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// Progress returns the number of finished (done or skipped) items and the
+// total number of items.
+func (p *Plan) Progress() (finished, total int) {
+	for _, item := range p.Items {
+		if item.Status == StatusDone || item.Status == StatusSkipped {
+			finished++
+		}
+	}
+	return finished, len(p.Items)
+}
+
+// Format renders the plan as a Markdown checklist.
+func (p *Plan) Format() string {
+	var sb strings.Builder
+	finished, total := p.Progress()
+	state := fmt.Sprintf("%d/%d done", finished, total)
+	if p.Completed {
+		state = "completed, " + state
+	}
+	fmt.Fprintf(&sb, "Goal: %s (%s)\n", p.Goal, state)
+	for _, item := range p.Items {
+		fmt.Fprintf(&sb, "%s %d. %s", statusMark(item.Status), item.ID, item.Text)
+		if item.Status == StatusSkipped {
+			sb.WriteString(" (skipped)")
+		}
+		if item.Note != "" {
+			sb.WriteString(" — " + item.Note)
+		}
+		sb.WriteString("\n")
+	}
+	if len(p.Notes) > 0 {
+		sb.WriteString("Notes:\n")
+		for _, note := range p.Notes {
+			sb.WriteString("- " + note + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func statusMark(status string) string {
+	switch status {
+	case StatusDone:
+		return "[x]"
+	case StatusInProgress:
+		return "[~]"
+	case StatusSkipped:
+		return "[-]"
+	default:
+		return "[ ]"
+	}
+}
+
+// Store keeps one plan per session key under <workspace>/plans, one JSON
+// file per session. Plans are cached in memory after the first read.
+type Store struct {
+	dir string
+
+	mu      sync.Mutex
+	plans   map[string]*Plan
+	session string
+}
+
+// NewStore creates a store for workspace. Nothing is written until the
+// first plan is saved.
+func NewStore(workspace string) *Store {
+	return &Store{
+		dir:   filepath.Join(workspace, "plans"),
+		plans: make(map[string]*Plan),
+	}
+}
+
+// SetSession sets the session that the plan tool and the context builder
+// act on while the agent handles a message.
+func (s *Store) SetSession(sessionKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = sessionKey
+}
+
+// Session returns the session set by SetSession.
+func (s *Store) Session() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session
+}
+
+// Get returns a copy of the plan of sessionKey, or nil if it has none.
+func (s *Store) Get(sessionKey string) (*Plan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.load(sessionKey)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return p.clone(), nil
+}
+
+// Save stores p as the plan of sessionKey.
+func (s *Store) Save(sessionKey string, p *Plan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = p.clone()
+	p.Updated = time.Now()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create plan directory: %w", err)
+	}
+	path := s.path(sessionKey)
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	s.plans[sessionKey] = p
+	return nil
+}
+
+// Delete removes the plan of sessionKey.
+func (s *Store) Delete(sessionKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.plans, sessionKey)
+	if err := os.Remove(s.path(sessionKey)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete plan: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) load(sessionKey string) (*Plan, error) {
+	if p, ok := s.plans[sessionKey]; ok {
+		return p, nil
+	}
+	data, err := os.ReadFile(s.path(sessionKey))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	s.plans[sessionKey] = &p
+	return &p, nil
+}
+
+// path returns the file of sessionKey. Session keys contain colons, which
+// are not allowed in Windows file names.
+func (s *Store) path(sessionKey string) string {
+	name := strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(sessionKey)
+	return filepath.Join(s.dir, name+".json")
+}
+
+func (p *Plan) clone() *Plan {
+	c := *p
+	c.Items = append([]Item(nil), p.Items...)
+	c.Notes = append([]string(nil), p.Notes...)
+	return &c
+}
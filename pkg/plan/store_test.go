@@ -0,0 +1,65 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PersistsPlansPerSession(t *testing.T) {
+	workspace := t.TempDir()
+	s := NewStore(workspace)
+
+	p := New("Ship the release", []string{"Tag", "Publish"})
+	p.Items[0].Status = StatusDone
+	p.Notes = append(p.Notes, "tag is v1.2.0")
+	require.NoError(t, s.Save("agent:main:telegram:direct:42", p))
+
+	reloaded, err := NewStore(workspace).Get("agent:main:telegram:direct:42")
+	require.NoError(t, err)
+	require.NotNil(t, reloaded)
+	assert.Equal(t, "Ship the release", reloaded.Goal)
+	assert.Equal(t, StatusDone, reloaded.Items[0].Status)
+	assert.Equal(t, []string{"tag is v1.2.0"}, reloaded.Notes)
+
+	other, err := s.Get("agent:main:cli:default")
+	require.NoError(t, err)
+	assert.Nil(t, other)
+
+	require.NoError(t, s.Delete("agent:main:telegram:direct:42"))
+	gone, err := NewStore(workspace).Get("agent:main:telegram:direct:42")
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+}
+
+func TestPlan_GetReturnsCopy(t *testing.T) {
+	s := NewStore(t.TempDir())
+	require.NoError(t, s.Save("k", New("goal", []string{"one"})))
+
+	p, err := s.Get("k")
+	require.NoError(t, err)
+	p.Items[0].Status = StatusDone
+
+	again, err := s.Get("k")
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, again.Items[0].Status, "unsaved changes must not leak into the store")
+}
+
+func TestPlan_Format(t *testing.T) {
+	p := New("Weekly report", []string{"Collect", "Write", "Send", "Archive"})
+	p.Items[0].Status = StatusDone
+	p.Items[1].Status = StatusInProgress
+	p.Items[1].Note = "draft in report.md"
+	p.Items[3].Status = StatusSkipped
+	item := p.AddItem("Follow up")
+	assert.Equal(t, 5, item.ID)
+
+	want := "Goal: Weekly report (2/5 done)\n" +
+		"[x] 1. Collect\n" +
+		"[~] 2. Write — draft in report.md\n" +
+		"[ ] 3. Send\n" +
+		"[-] 4. Archive (skipped)\n" +
+		"[ ] 5. Follow up"
+	assert.Equal(t, want, p.Format())
+}
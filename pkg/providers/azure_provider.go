@@ -0,0 +1,192 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/openai_compat"
+)
+
+// DefaultAzureAPIVersion is the GA data-plane api-version used when none is configured.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureProvider talks to Azure OpenAI deployments. Requests are routed to
+// {api_base}/openai/deployments/{deployment}/chat/completions?api-version=...
+// (or {api_base}/openai/v1/chat/completions when api_version is "v1") and
+// authenticated with either an api-key header or an Entra ID bearer token.
+type AzureProvider struct {
+	delegate *openai_compat.Provider
+}
+
+// AzureOptions configures an AzureProvider.
+type AzureOptions struct {
+	APIKey         string
+	APIBase        string // Resource endpoint, e.g. https://my-resource.openai.azure.com
+	Proxy          string
+	MaxTokensField string
+	Deployment     string
+	APIVersion     string
+	AuthHeader     string                 // "api-key" (default) or "bearer"
+	TokenSource    func() (string, error) // Entra ID token source; implies bearer auth
+}
+
+func NewAzureProvider(opts AzureOptions) (*AzureProvider, error) {
+	apiBase := strings.TrimRight(strings.TrimSpace(opts.APIBase), "/")
+	if apiBase == "" {
+		return nil, fmt.Errorf("api_base is required for azure protocol")
+	}
+
+	apiVersion := opts.APIVersion
+	if apiVersion == "" {
+		apiVersion = DefaultAzureAPIVersion
+	}
+
+	endpoint := openai_compat.EndpointOptions{TokenSource: opts.TokenSource}
+
+	switch {
+	case strings.Contains(apiBase, "/openai/deployments/"), strings.HasSuffix(apiBase, "/openai/v1"):
+		// Fully-qualified base supplied by the user; use it as-is.
+	case apiVersion == "v1":
+		apiBase += "/openai/v1"
+	default:
+		if opts.Deployment == "" {
+			return nil, fmt.Errorf("deployment is required for azure protocol")
+		}
+		apiBase += "/openai/deployments/" + opts.Deployment
+	}
+	if apiVersion != "v1" {
+		endpoint.QueryParams = map[string]string{"api-version": apiVersion}
+	}
+
+	authHeader := strings.ToLower(strings.TrimSpace(opts.AuthHeader))
+	switch {
+	case opts.TokenSource != nil, authHeader == "bearer":
+		endpoint.AuthHeader = "Authorization"
+		endpoint.AuthScheme = "Bearer"
+	case authHeader == "" || authHeader == "api-key":
+		endpoint.AuthHeader = "api-key"
+	default:
+		return nil, fmt.Errorf("unsupported auth_header %q for azure protocol (want api-key or bearer)", opts.AuthHeader)
+	}
+
+	return &AzureProvider{
+		delegate: openai_compat.NewProviderWithEndpoint(
+			opts.APIKey, apiBase, opts.Proxy, opts.MaxTokensField, endpoint,
+		),
+	}, nil
+}
+
+func (p *AzureProvider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	return p.delegate.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *AzureProvider) GetDefaultModel() string {
+	return ""
+}
+
+// createAzureProvider builds an AzureProvider from a model_list entry.
+// auth_method "oauth"/"token" uses Entra ID bearer tokens from the auth store
+// (picoclaw auth login --provider azure), falling back to a service principal
+// from AZURE_TENANT_ID / AZURE_CLIENT_ID / AZURE_CLIENT_SECRET.
+func createAzureProvider(cfg *config.ModelConfig, modelID string) (LLMProvider, error) {
+	deployment := cfg.Deployment
+	if deployment == "" {
+		deployment = modelID
+	}
+
+	opts := AzureOptions{
+		APIKey:         cfg.APIKey,
+		APIBase:        cfg.APIBase,
+		Proxy:          cfg.Proxy,
+		MaxTokensField: cfg.MaxTokensField,
+		Deployment:     deployment,
+		APIVersion:     cfg.APIVersion,
+		AuthHeader:     cfg.AuthHeader,
+	}
+
+	if cfg.AuthMethod == "oauth" || cfg.AuthMethod == "token" {
+		opts.APIKey = ""
+		opts.TokenSource = createAzureTokenSource()
+	} else if cfg.APIKey == "" {
+		return nil, fmt.Errorf("api_key is required for azure protocol (model: %s); "+
+			"set auth_method to \"oauth\" to use Entra ID", cfg.Model)
+	}
+
+	return NewAzureProvider(opts)
+}
+
+// createAzureTokenSource returns an Entra ID token source. Stored credentials
+// are refreshed when close to expiry; service principal tokens are cached
+// in memory until they need refreshing.
+func createAzureTokenSource() func() (string, error) {
+	var (
+		mu     sync.Mutex
+		cached *auth.AuthCredential
+	)
+	tenantID := os.Getenv("AZURE_TENANT_ID")
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	clientSecret := os.Getenv("AZURE_CLIENT_SECRET")
+
+	return func() (string, error) {
+		cred, err := getCredential("azure")
+		if err != nil {
+			return "", fmt.Errorf("loading auth credentials: %w", err)
+		}
+		if cred != nil {
+			if cred.NeedsRefresh() && cred.RefreshToken != "" {
+				refreshed, err := auth.RefreshAccessToken(cred, auth.AzureEntraOAuthConfig(tenantID, clientID, ""))
+				if err != nil {
+					return "", fmt.Errorf("refreshing token: %w", err)
+				}
+				refreshed.Provider = "azure"
+				if err := auth.SetCredential("azure", refreshed); err != nil {
+					return "", fmt.Errorf("saving refreshed token: %w", err)
+				}
+				return refreshed.AccessToken, nil
+			}
+			if cred.IsExpired() {
+				return "", fmt.Errorf("azure token expired. Run: picoclaw auth login --provider azure")
+			}
+			return cred.AccessToken, nil
+		}
+
+		if clientID == "" || clientSecret == "" || tenantID == "" {
+			return "", fmt.Errorf("no credentials for azure. Run: picoclaw auth login --provider azure " +
+				"or set AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if cached != nil && !cached.NeedsRefresh() {
+			return cached.AccessToken, nil
+		}
+		oauthCfg := auth.AzureEntraOAuthConfig(tenantID, clientID, clientSecret)
+		oauthCfg.Scopes = auth.AzureCognitiveServicesScope // client credentials cannot request offline_access
+		token, err := auth.ClientCredentialsToken(oauthCfg, "azure")
+		if err != nil {
+			return "", err
+		}
+		if token.ExpiresAt.IsZero() {
+			token.ExpiresAt = time.Now().Add(time.Hour)
+		}
+		cached = token
+		return cached.AccessToken, nil
+	}
+}
@@ -0,0 +1,198 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newAzureTestServer(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"content": "ok"}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAzureProvider_DeploymentURLAndAPIKeyHeader(t *testing.T) {
+	var gotPath, gotVersion, gotKey, gotAuth string
+	server := newAzureTestServer(t, func(r *http.Request) {
+		gotPath = r.URL.Path
+		gotVersion = r.URL.Query().Get("api-version")
+		gotKey = r.Header.Get("api-key")
+		gotAuth = r.Header.Get("Authorization")
+	})
+
+	cfg := &config.ModelConfig{
+		ModelName:  "azure-gpt",
+		Model:      "azure/gpt-4o",
+		APIBase:    server.URL + "/",
+		APIKey:     "azure-key",
+		Deployment: "prod-gpt4o",
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*AzureProvider); !ok {
+		t.Fatalf("provider type = %T, want *AzureProvider", provider)
+	}
+	if modelID != "gpt-4o" {
+		t.Errorf("modelID = %q, want %q", modelID, "gpt-4o")
+	}
+
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, modelID, nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if gotPath != "/openai/deployments/prod-gpt4o/chat/completions" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotVersion != DefaultAzureAPIVersion {
+		t.Errorf("api-version = %q, want %q", gotVersion, DefaultAzureAPIVersion)
+	}
+	if gotKey != "azure-key" {
+		t.Errorf("api-key header = %q, want %q", gotKey, "azure-key")
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization header = %q, want empty", gotAuth)
+	}
+}
+
+func TestAzureProvider_DeploymentDefaultsToModelID(t *testing.T) {
+	var gotPath string
+	server := newAzureTestServer(t, func(r *http.Request) { gotPath = r.URL.Path })
+
+	provider, modelID, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "azure-mini",
+		Model:     "azure/my-mini",
+		APIBase:   server.URL,
+		APIKey:    "k",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, modelID, nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if gotPath != "/openai/deployments/my-mini/chat/completions" {
+		t.Errorf("path = %q", gotPath)
+	}
+}
+
+func TestAzureProvider_V1APIWithBearerHeader(t *testing.T) {
+	var gotPath, gotQuery, gotAuth string
+	server := newAzureTestServer(t, func(r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+	})
+
+	provider, err := NewAzureProvider(AzureOptions{
+		APIKey:     "key",
+		APIBase:    server.URL,
+		APIVersion: "v1",
+		AuthHeader: "bearer",
+	})
+	if err != nil {
+		t.Fatalf("NewAzureProvider() error = %v", err)
+	}
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if gotPath != "/openai/v1/chat/completions" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotQuery != "" {
+		t.Errorf("query = %q, want none for v1 API", gotQuery)
+	}
+	if gotAuth != "Bearer key" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer key")
+	}
+}
+
+func TestAzureProvider_EntraTokenFromAuthStore(t *testing.T) {
+	originalGetCredential := getCredential
+	t.Cleanup(func() { getCredential = originalGetCredential })
+	getCredential = func(provider string) (*auth.AuthCredential, error) {
+		if provider != "azure" {
+			t.Fatalf("provider = %q, want azure", provider)
+		}
+		return &auth.AuthCredential{AccessToken: "entra-token", Provider: "azure", AuthMethod: "token"}, nil
+	}
+
+	var gotAuth, gotKey string
+	server := newAzureTestServer(t, func(r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("api-key")
+	})
+
+	provider, modelID, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName:  "azure-entra",
+		Model:      "azure/gpt-4o",
+		APIBase:    server.URL,
+		AuthMethod: "token",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, err := provider.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, modelID, nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if gotAuth != "Bearer entra-token" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer entra-token")
+	}
+	if gotKey != "" {
+		t.Errorf("api-key header = %q, want empty", gotKey)
+	}
+}
+
+func TestAzureProvider_ConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.ModelConfig
+	}{
+		{
+			name: "missing api_base",
+			cfg:  &config.ModelConfig{ModelName: "a", Model: "azure/gpt-4o", APIKey: "k"},
+		},
+		{
+			name: "missing api_key",
+			cfg:  &config.ModelConfig{ModelName: "a", Model: "azure/gpt-4o", APIBase: "https://x.openai.azure.com"},
+		},
+		{
+			name: "bad auth_header",
+			cfg: &config.ModelConfig{
+				ModelName: "a", Model: "azure/gpt-4o", APIKey: "k",
+				APIBase: "https://x.openai.azure.com", AuthHeader: "x-custom",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := CreateProviderFromConfig(tt.cfg); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
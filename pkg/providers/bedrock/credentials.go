@@ -0,0 +1,277 @@
+package bedrock
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are AWS access keys used for SigV4 signing.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // zero for long-lived keys
+}
+
+// expiresSoon reports whether temporary credentials need refreshing.
+func (c Credentials) expiresSoon(now time.Time) bool {
+	return !c.Expires.IsZero() && now.Add(5*time.Minute).After(c.Expires)
+}
+
+// CredentialSource returns credentials for signing a request.
+type CredentialSource func(ctx context.Context) (Credentials, error)
+
+const defaultIMDSEndpoint = "http://169.254.169.254"
+
+// DefaultCredentialSource resolves credentials like the AWS SDKs, in order:
+//  1. AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY / AWS_SESSION_TOKEN
+//  2. the shared credentials and config files for AWS_PROFILE (default "default")
+//  3. the EC2 instance metadata service (IMDSv2)
+//
+// Results are cached until they are close to expiry.
+func DefaultCredentialSource(profile string) CredentialSource {
+	var (
+		mu     sync.Mutex
+		cached *Credentials
+	)
+	return func(ctx context.Context) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if cached != nil && !cached.expiresSoon(time.Now()) {
+			return *cached, nil
+		}
+
+		if creds, ok := envCredentials(); ok {
+			cached = &creds
+			return creds, nil
+		}
+		creds, ok, err := sharedFileCredentials(profile)
+		if err != nil {
+			return Credentials{}, err
+		}
+		if ok {
+			cached = &creds
+			return creds, nil
+		}
+		creds, err = imdsCredentials(ctx)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("no AWS credentials found in environment, shared config "+
+				"or instance metadata: %w", err)
+		}
+		cached = &creds
+		return creds, nil
+	}
+}
+
+// StaticCredentialSource always returns creds.
+func StaticCredentialSource(creds Credentials) CredentialSource {
+	return func(context.Context) (Credentials, error) { return creds, nil }
+}
+
+func envCredentials() (Credentials, bool) {
+	id := os.Getenv("AWS_ACCESS_KEY_ID")
+	secret := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return Credentials{}, false
+	}
+	return Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, true
+}
+
+// resolveProfile returns the explicit profile, AWS_PROFILE or "default".
+func resolveProfile(profile string) string {
+	if profile != "" {
+		return profile
+	}
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+func sharedCredentialsPath() string {
+	if p := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "credentials")
+}
+
+func sharedConfigPath() string {
+	if p := os.Getenv("AWS_CONFIG_FILE"); p != "" {
+		return p
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "config")
+}
+
+// sharedFileCredentials reads static keys for the profile from the shared
+// credentials file, then from the config file.
+func sharedFileCredentials(profile string) (Credentials, bool, error) {
+	profile = resolveProfile(profile)
+
+	for _, file := range []struct {
+		path    string
+		section string
+	}{
+		{sharedCredentialsPath(), profile},
+		{sharedConfigPath(), configSection(profile)},
+	} {
+		sections, err := readINI(file.path)
+		if err != nil {
+			return Credentials{}, false, err
+		}
+		values := sections[file.section]
+		if values["aws_access_key_id"] != "" && values["aws_secret_access_key"] != "" {
+			return Credentials{
+				AccessKeyID:     values["aws_access_key_id"],
+				SecretAccessKey: values["aws_secret_access_key"],
+				SessionToken:    values["aws_session_token"],
+			}, true, nil
+		}
+	}
+	return Credentials{}, false, nil
+}
+
+// SharedConfigRegion returns the region configured for the profile in the
+// shared config file, if any.
+func SharedConfigRegion(profile string) string {
+	sections, err := readINI(sharedConfigPath())
+	if err != nil {
+		return ""
+	}
+	return sections[configSection(resolveProfile(profile))]["region"]
+}
+
+// configSection maps a profile name to its section in ~/.aws/config, where
+// non-default profiles are written as [profile name].
+func configSection(profile string) string {
+	if profile == "default" {
+		return profile
+	}
+	return "profile " + profile
+}
+
+// readINI parses the minimal INI dialect used by the AWS shared files.
+// A missing file yields no sections.
+func readINI(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sections := make(map[string]map[string]string)
+	var current map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			current = make(map[string]string)
+			sections[name] = current
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || current == nil {
+			continue
+		}
+		current[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return sections, scanner.Err()
+}
+
+// imdsCredentials fetches the instance role credentials using IMDSv2.
+func imdsCredentials(ctx context.Context) (Credentials, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return Credentials{}, fmt.Errorf("instance metadata disabled")
+	}
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = defaultIMDSEndpoint
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	client := &http.Client{}
+
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := imdsDo(client, tokenReq)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("imds token: %w", err)
+	}
+
+	get := func(path string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		return imdsDo(client, req)
+	}
+
+	const credsPath = "/latest/meta-data/iam/security-credentials/"
+	roles, err := get(credsPath)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("imds role: %w", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, fmt.Errorf("imds: no instance role attached")
+	}
+
+	body, err := get(credsPath + role)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("imds credentials: %w", err)
+	}
+	var out struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal([]byte(body), &out); err != nil {
+		return Credentials{}, fmt.Errorf("imds credentials: %w", err)
+	}
+	return Credentials{
+		AccessKeyID:     out.AccessKeyID,
+		SecretAccessKey: out.SecretAccessKey,
+		SessionToken:    out.Token,
+		Expires:         out.Expiration,
+	}, nil
+}
+
+func imdsDo(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,106 @@
+package bedrock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func clearAWSEnv(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestDefaultCredentialSource_Env(t *testing.T) {
+	clearAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SKENV")
+
+	creds, err := DefaultCredentialSource("")(t.Context())
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if creds.AccessKeyID != "AKENV" || creds.SecretAccessKey != "SKENV" {
+		t.Errorf("creds = %+v", creds)
+	}
+}
+
+func TestDefaultCredentialSource_SharedFiles(t *testing.T) {
+	clearAWSEnv(t)
+	os.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"), []byte(
+		"[default]\naws_access_key_id = AKDEF\naws_secret_access_key = SKDEF\n",
+	), 0o600)
+	os.WriteFile(os.Getenv("AWS_CONFIG_FILE"), []byte(
+		"[profile work]\nregion = eu-west-1\naws_access_key_id=AKWORK\naws_secret_access_key=SKWORK\n",
+	), 0o600)
+
+	creds, err := DefaultCredentialSource("")(t.Context())
+	if err != nil || creds.AccessKeyID != "AKDEF" {
+		t.Errorf("default profile creds = %+v, err = %v", creds, err)
+	}
+
+	t.Setenv("AWS_PROFILE", "work")
+	creds, err = DefaultCredentialSource("")(t.Context())
+	if err != nil || creds.AccessKeyID != "AKWORK" {
+		t.Errorf("work profile creds = %+v, err = %v", creds, err)
+	}
+	if region := SharedConfigRegion(""); region != "eu-west-1" {
+		t.Errorf("region = %q, want eu-west-1", region)
+	}
+}
+
+func TestDefaultCredentialSource_IMDS(t *testing.T) {
+	clearAWSEnv(t)
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Write([]byte("imds-token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("bedrock-role\n"))
+		case "/latest/meta-data/iam/security-credentials/bedrock-role":
+			w.Write([]byte(`{"AccessKeyId":"AKIMDS","SecretAccessKey":"SKIMDS","Token":"TOK","Expiration":"` +
+				expires.Format(time.RFC3339) + `"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+
+	creds, err := DefaultCredentialSource("")(t.Context())
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if creds.AccessKeyID != "AKIMDS" || creds.SessionToken != "TOK" || !creds.Expires.Equal(expires) {
+		t.Errorf("creds = %+v", creds)
+	}
+}
+
+func TestDefaultCredentialSource_NoneFound(t *testing.T) {
+	clearAWSEnv(t)
+	if _, err := DefaultCredentialSource("")(t.Context()); err == nil {
+		t.Error("expected error when no credentials are available")
+	}
+}
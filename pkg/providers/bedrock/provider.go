@@ -0,0 +1,391 @@
+// Package bedrock implements an LLM provider for Amazon Bedrock using the
+// Converse API, with requests signed by AWS Signature Version 4.
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+type (
+	ToolCall       = protocoltypes.ToolCall
+	FunctionCall   = protocoltypes.FunctionCall
+	LLMResponse    = protocoltypes.LLMResponse
+	UsageInfo      = protocoltypes.UsageInfo
+	Message        = protocoltypes.Message
+	ToolDefinition = protocoltypes.ToolDefinition
+)
+
+const signingService = "bedrock"
+
+// Options configures a Provider.
+type Options struct {
+	Region      string           // AWS region; resolved from env / shared config when empty
+	APIBase     string           // Override for https://bedrock-runtime.{region}.amazonaws.com
+	Proxy       string           // HTTP proxy URL
+	Profile     string           // Shared config profile (defaults to AWS_PROFILE or "default")
+	APIKey      string           // Bedrock API key; sent as a bearer token instead of SigV4
+	Credentials CredentialSource // Overrides the default AWS credential chain
+}
+
+type Provider struct {
+	baseURL     string
+	region      string
+	apiKey      string
+	credentials CredentialSource
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// NewProvider creates a Bedrock Converse provider.
+func NewProvider(opts Options) (*Provider, error) {
+	region := opts.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = SharedConfigRegion(opts.Profile)
+	}
+	if region == "" {
+		return nil, fmt.Errorf("region is required for bedrock protocol (set region or AWS_REGION)")
+	}
+
+	baseURL := strings.TrimRight(opts.APIBase, "/")
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+
+	creds := opts.Credentials
+	if creds == nil {
+		creds = DefaultCredentialSource(opts.Profile)
+	}
+
+	client := &http.Client{Timeout: 120 * time.Second}
+	if opts.Proxy != "" {
+		parsed, err := url.Parse(opts.Proxy)
+		if err == nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyURL(parsed)}
+		} else {
+			log.Printf("bedrock: invalid proxy URL %q: %v", opts.Proxy, err)
+		}
+	}
+
+	return &Provider{
+		baseURL:     baseURL,
+		region:      region,
+		apiKey:      opts.APIKey,
+		credentials: creds,
+		httpClient:  client,
+		now:         time.Now,
+	}, nil
+}
+
+func (p *Provider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	body, err := json.Marshal(buildConverseRequest(messages, tools, options))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.converseURL(model), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	} else {
+		creds, err := p.credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("loading AWS credentials: %w", err)
+		}
+		SignRequest(req, body, creds, p.region, signingService, p.now())
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(respBody))
+	}
+
+	return parseConverseResponse(respBody)
+}
+
+func (p *Provider) GetDefaultModel() string {
+	return ""
+}
+
+// converseURL builds {base}/model/{modelId}/converse. Model IDs and ARNs
+// contain ':' and '/', so the ID is escaped as a single path segment.
+func (p *Provider) converseURL(model string) string {
+	return p.baseURL + "/model/" + uriEncode(model, true) + "/converse"
+}
+
+// Converse API wire types.
+
+type converseRequest struct {
+	Messages        []converseMessage `json:"messages"`
+	System          []contentBlock    `json:"system,omitempty"`
+	InferenceConfig *inferenceConfig  `json:"inferenceConfig,omitempty"`
+	ToolConfig      *toolConfig       `json:"toolConfig,omitempty"`
+}
+
+type converseMessage struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Text             string            `json:"text,omitempty"`
+	ToolUse          *toolUseBlock     `json:"toolUse,omitempty"`
+	ToolResult       *toolResultBlock  `json:"toolResult,omitempty"`
+	ReasoningContent *reasoningContent `json:"reasoningContent,omitempty"`
+}
+
+type toolUseBlock struct {
+	ToolUseID string         `json:"toolUseId"`
+	Name      string         `json:"name"`
+	Input     map[string]any `json:"input"`
+}
+
+type toolResultBlock struct {
+	ToolUseID string         `json:"toolUseId"`
+	Content   []contentBlock `json:"content"`
+}
+
+type reasoningContent struct {
+	ReasoningText *struct {
+		Text string `json:"text"`
+	} `json:"reasoningText,omitempty"`
+}
+
+type inferenceConfig struct {
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+type toolConfig struct {
+	Tools []toolEntry `json:"tools"`
+}
+
+type toolEntry struct {
+	ToolSpec toolSpec `json:"toolSpec"`
+}
+
+type toolSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+		TotalTokens  int `json:"totalTokens"`
+	} `json:"usage"`
+}
+
+func buildConverseRequest(messages []Message, tools []ToolDefinition, options map[string]any) converseRequest {
+	var req converseRequest
+
+	appendBlocks := func(role string, blocks ...contentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Converse requires alternating roles; merge consecutive turns
+		// (e.g. several tool results) into one message.
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == role {
+			req.Messages[n-1].Content = append(req.Messages[n-1].Content, blocks...)
+			return
+		}
+		req.Messages = append(req.Messages, converseMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if msg.Content != "" {
+				req.System = append(req.System, contentBlock{Text: msg.Content})
+			}
+		case "assistant":
+			var blocks []contentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, contentBlock{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				name, args := tc.Name, tc.Arguments
+				if tc.Function != nil {
+					if name == "" {
+						name = tc.Function.Name
+					}
+					if len(args) == 0 && tc.Function.Arguments != "" {
+						json.Unmarshal([]byte(tc.Function.Arguments), &args)
+					}
+				}
+				if args == nil {
+					args = map[string]any{}
+				}
+				blocks = append(blocks, contentBlock{ToolUse: &toolUseBlock{ToolUseID: tc.ID, Name: name, Input: args}})
+			}
+			appendBlocks("assistant", blocks...)
+		case "tool":
+			content := msg.Content
+			if content == "" {
+				content = "(empty)"
+			}
+			appendBlocks("user", contentBlock{ToolResult: &toolResultBlock{
+				ToolUseID: msg.ToolCallID,
+				Content:   []contentBlock{{Text: content}},
+			}})
+		default:
+			if msg.Content != "" {
+				appendBlocks("user", contentBlock{Text: msg.Content})
+			}
+		}
+	}
+
+	if len(tools) > 0 {
+		req.ToolConfig = &toolConfig{}
+		for _, t := range tools {
+			schema := t.Function.Parameters
+			if len(schema) == 0 {
+				schema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			req.ToolConfig.Tools = append(req.ToolConfig.Tools, toolEntry{ToolSpec: toolSpec{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				InputSchema: map[string]any{"json": schema},
+			}})
+		}
+	}
+
+	cfg := &inferenceConfig{}
+	if maxTokens, ok := asInt(options["max_tokens"]); ok {
+		cfg.MaxTokens = maxTokens
+	}
+	if temperature, ok := asFloat(options["temperature"]); ok {
+		cfg.Temperature = &temperature
+	}
+	if cfg.MaxTokens > 0 || cfg.Temperature != nil {
+		req.InferenceConfig = cfg
+	}
+
+	return req
+}
+
+func parseConverseResponse(body []byte) (*LLMResponse, error) {
+	var resp converseResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var content, reasoning strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range resp.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			args := block.ToolUse.Input
+			if args == nil {
+				args = map[string]any{}
+			}
+			argsJSON, _ := json.Marshal(args)
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        block.ToolUse.ToolUseID,
+				Type:      "function",
+				Name:      block.ToolUse.Name,
+				Arguments: args,
+				Function:  &FunctionCall{Name: block.ToolUse.Name, Arguments: string(argsJSON)},
+			})
+		case block.ReasoningContent != nil && block.ReasoningContent.ReasoningText != nil:
+			reasoning.WriteString(block.ReasoningContent.ReasoningText.Text)
+		case block.Text != "":
+			content.WriteString(block.Text)
+		}
+	}
+
+	finishReason := resp.StopReason
+	switch resp.StopReason {
+	case "end_turn", "stop_sequence":
+		finishReason = "stop"
+	case "tool_use":
+		finishReason = "tool_calls"
+	case "max_tokens":
+		finishReason = "length"
+	}
+
+	return &LLMResponse{
+		Content:          content.String(),
+		ReasoningContent: reasoning.String(),
+		ToolCalls:        toolCalls,
+		FinishReason:     finishReason,
+		Usage: &UsageInfo{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+func asInt(v any) (int, bool) {
+	switch val := v.(type) {
+	case int:
+		return val, true
+	case int64:
+		return int(val), true
+	case float64:
+		return int(val), true
+	case float32:
+		return int(val), true
+	default:
+		return 0, false
+	}
+}
+
+func asFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,227 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers/protocoltypes"
+)
+
+var testCreds = Credentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "secret", SessionToken: "session"}
+
+// newSigV4Server is a Bedrock stand-in that rejects requests whose SigV4
+// signature does not verify against testCreds.
+func newSigV4Server(t *testing.T, handler func(t *testing.T, r *http.Request, body converseRequest) any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := verifySignature(r, body, testCreds.SecretAccessKey); err != "" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"message": err})
+			return
+		}
+		var req converseRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		json.NewEncoder(w).Encode(handler(t, r, req))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func verifySignature(r *http.Request, body []byte, secret string) string {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+		return "missing SigV4 authorization"
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	_, scope, _ := strings.Cut(fields["Credential"], "/")
+	if !strings.HasSuffix(scope, "/us-east-1/bedrock/aws4_request") {
+		return "bad credential scope " + scope
+	}
+	if r.Header.Get("X-Amz-Security-Token") != testCreds.SessionToken {
+		return "missing security token"
+	}
+	now, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return "bad x-amz-date"
+	}
+
+	// Recompute over exactly the headers the client claims to have signed.
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	verify := &http.Request{Method: r.Method, URL: r.URL, Host: r.Host, Header: http.Header{}}
+	for _, name := range signed {
+		if name != "host" {
+			verify.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	signedHeaders, canonical := canonicalHeaders(verify)
+	if signedHeaders != fields["SignedHeaders"] {
+		return "signed header mismatch"
+	}
+	if computeSignature(verify, body, signedHeaders, canonical, secret, scope, now) != fields["Signature"] {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+func newTestProvider(t *testing.T, baseURL string) *Provider {
+	t.Helper()
+	p, err := NewProvider(Options{Region: "us-east-1", APIBase: baseURL, Credentials: StaticCredentialSource(testCreds)})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return p
+}
+
+func TestProvider_ChatSignedTextRequest(t *testing.T) {
+	var gotPath string
+	server := newSigV4Server(t, func(t *testing.T, r *http.Request, req converseRequest) any {
+		gotPath = r.URL.EscapedPath()
+		if len(req.System) != 1 || req.System[0].Text != "be brief" {
+			t.Errorf("system = %+v", req.System)
+		}
+		if req.InferenceConfig == nil || req.InferenceConfig.MaxTokens != 256 {
+			t.Errorf("inferenceConfig = %+v", req.InferenceConfig)
+		}
+		return map[string]any{
+			"output":     map[string]any{"message": map[string]any{"role": "assistant", "content": []any{map[string]any{"text": "hi"}}}},
+			"stopReason": "end_turn",
+			"usage":      map[string]any{"inputTokens": 10, "outputTokens": 2, "totalTokens": 12},
+		}
+	})
+
+	p := newTestProvider(t, server.URL)
+	resp, err := p.Chat(t.Context(), []Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hello"},
+	}, nil, "anthropic.claude-3-5-sonnet-20240620-v1:0", map[string]any{"max_tokens": 256, "temperature": 0.2})
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if gotPath != "/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/converse" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if resp.Content != "hi" || resp.FinishReason != "stop" {
+		t.Errorf("resp = %+v", resp)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 10 || resp.Usage.CompletionTokens != 2 || resp.Usage.TotalTokens != 12 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestProvider_ToolUseRoundTrip(t *testing.T) {
+	server := newSigV4Server(t, func(t *testing.T, r *http.Request, req converseRequest) any {
+		if req.ToolConfig == nil || req.ToolConfig.Tools[0].ToolSpec.Name != "read_file" {
+			t.Errorf("toolConfig = %+v", req.ToolConfig)
+		}
+		if _, ok := req.ToolConfig.Tools[0].ToolSpec.InputSchema["json"]; !ok {
+			t.Error("inputSchema must wrap the JSON schema under \"json\"")
+		}
+
+		// user, assistant(toolUse x2), user(toolResult x2)
+		if len(req.Messages) != 3 {
+			t.Fatalf("messages = %+v", req.Messages)
+		}
+		if tu := req.Messages[1].Content[0].ToolUse; tu == nil || tu.ToolUseID != "t1" || tu.Input["path"] != "a.txt" {
+			t.Errorf("toolUse = %+v", req.Messages[1].Content)
+		}
+		results := req.Messages[2]
+		if results.Role != "user" || len(results.Content) != 2 || results.Content[1].ToolResult.ToolUseID != "t2" {
+			t.Errorf("tool results = %+v", results)
+		}
+
+		return map[string]any{
+			"output": map[string]any{"message": map[string]any{"role": "assistant", "content": []any{
+				map[string]any{"text": "Reading another."},
+				map[string]any{"toolUse": map[string]any{
+					"toolUseId": "t3", "name": "read_file", "input": map[string]any{"path": "b.txt"},
+				}},
+			}}},
+			"stopReason": "tool_use",
+			"usage":      map[string]any{"inputTokens": 1, "outputTokens": 1, "totalTokens": 2},
+		}
+	})
+
+	tools := []ToolDefinition{{Type: "function", Function: protocoltypes.ToolFunctionDefinition{
+		Name:       "read_file",
+		Parameters: map[string]any{"type": "object", "properties": map[string]any{"path": map[string]any{"type": "string"}}},
+	}}}
+	p := newTestProvider(t, server.URL)
+	resp, err := p.Chat(t.Context(), []Message{
+		{Role: "user", Content: "read both"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "t1", Name: "read_file", Arguments: map[string]any{"path": "a.txt"}},
+			{ID: "t2", Function: &FunctionCall{Name: "read_file", Arguments: `{"path":"c.txt"}`}},
+		}},
+		{Role: "tool", ToolCallID: "t1", Content: "A"},
+		{Role: "tool", ToolCallID: "t2", Content: "C"},
+	}, tools, "meta.llama3-70b-instruct-v1:0", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if resp.FinishReason != "tool_calls" || len(resp.ToolCalls) != 1 {
+		t.Fatalf("resp = %+v", resp)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID != "t3" || tc.Name != "read_file" || tc.Arguments["path"] != "b.txt" || tc.Function.Arguments != `{"path":"b.txt"}` {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if resp.Content != "Reading another." {
+		t.Errorf("content = %q", resp.Content)
+	}
+}
+
+func TestProvider_BadSignatureIsAuthError(t *testing.T) {
+	server := newSigV4Server(t, func(t *testing.T, r *http.Request, req converseRequest) any {
+		t.Error("handler should not be reached")
+		return nil
+	})
+
+	p, _ := NewProvider(Options{
+		Region: "us-east-1", APIBase: server.URL,
+		Credentials: StaticCredentialSource(Credentials{AccessKeyID: "AKIDTEST", SecretAccessKey: "wrong", SessionToken: "session"}),
+	})
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	if err == nil || !strings.Contains(err.Error(), "Status: 403") {
+		t.Errorf("err = %v, want 403 API error", err)
+	}
+}
+
+func TestProvider_APIKeyUsesBearer(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]any{"stopReason": "end_turn"})
+	}))
+	defer server.Close()
+
+	p, _ := NewProvider(Options{Region: "us-east-1", APIBase: server.URL, APIKey: "bedrock-key"})
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if gotAuth != "Bearer bedrock-key" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+}
+
+func TestNewProvider_RegionRequired(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/missing")
+	if _, err := NewProvider(Options{}); err == nil {
+		t.Error("expected error without region")
+	}
+}
@@ -0,0 +1,39 @@
+package bedrock
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Known-answer test from the AWS SigV4 test suite ("get-vanilla").
+func TestSignRequest_GetVanilla(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	SignRequest(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n  %s\nwant\n  %s", got, want)
+	}
+}
+
+func TestSignRequest_SessionTokenSigned(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-west-2.amazonaws.com/model/a%3A0/converse", nil)
+	SignRequest(req, []byte("{}"), Credentials{AccessKeyID: "AK", SecretAccessKey: "SK", SessionToken: "TOK"},
+		"us-west-2", "bedrock", time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "TOK" {
+		t.Error("session token header not set")
+	}
+	signed, _ := canonicalHeaders(req)
+	if signed != "host;x-amz-date;x-amz-security-token" {
+		t.Errorf("signed headers = %q", signed)
+	}
+	if got := canonicalURI(req); got != "/model/a%253A0/converse" {
+		t.Errorf("canonical URI = %q, want double-encoded path", got)
+	}
+}
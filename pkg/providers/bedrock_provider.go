@@ -0,0 +1,54 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers/bedrock"
+)
+
+// BedrockProvider calls Amazon Bedrock models (Claude, Llama, ...) through
+// the Converse API. Requests are SigV4-signed with credentials from the
+// environment, the shared AWS config files or instance metadata.
+type BedrockProvider struct {
+	delegate *bedrock.Provider
+}
+
+func NewBedrockProvider(opts bedrock.Options) (*BedrockProvider, error) {
+	delegate, err := bedrock.NewProvider(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BedrockProvider{delegate: delegate}, nil
+}
+
+func (p *BedrockProvider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	return p.delegate.Chat(ctx, messages, tools, model, options)
+}
+
+func (p *BedrockProvider) GetDefaultModel() string {
+	return ""
+}
+
+// createBedrockProvider builds a BedrockProvider from a model_list entry.
+// api_key, when set, is a Bedrock API key sent as a bearer token instead of
+// signing with AWS credentials.
+func createBedrockProvider(cfg *config.ModelConfig) (LLMProvider, error) {
+	return NewBedrockProvider(bedrock.Options{
+		Region:  cfg.Region,
+		APIBase: cfg.APIBase,
+		Proxy:   cfg.Proxy,
+		APIKey:  cfg.APIKey,
+	})
+}
@@ -1,9 +1,16 @@
 package providers
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 const (
@@ -11,21 +18,53 @@ const (
 )
 
 // CooldownTracker manages per-provider cooldown state for the fallback chain.
-// Thread-safe via sync.RWMutex. In-memory only (resets on restart).
+// Thread-safe via sync.RWMutex. State is in-memory unless the tracker was
+// created with NewCooldownTrackerWithStore, in which case every change is
+// written to disk so cooldowns survive restarts. Successes on a healthy
+// provider change nothing and are not written.
 type CooldownTracker struct {
 	mu            sync.RWMutex
 	entries       map[string]*cooldownEntry
 	failureWindow time.Duration
 	nowFunc       func() time.Time // for testing
+	storePath     string
 }
 
 type cooldownEntry struct {
-	ErrorCount     int
-	FailureCounts  map[FailoverReason]int
-	CooldownEnd    time.Time      // standard cooldown expiry
-	DisabledUntil  time.Time      // billing-specific disable expiry
-	DisabledReason FailoverReason // reason for disable (billing)
-	LastFailure    time.Time
+	ErrorCount     int                    `json:"error_count"`
+	FailureCounts  map[FailoverReason]int `json:"failure_counts,omitempty"`
+	CooldownEnd    time.Time              `json:"cooldown_end,omitzero"`     // standard cooldown expiry
+	DisabledUntil  time.Time              `json:"disabled_until,omitzero"`   // billing-specific disable expiry
+	DisabledReason FailoverReason         `json:"disabled_reason,omitempty"` // reason for disable (billing)
+	LastFailure    time.Time              `json:"last_failure,omitzero"`
+	LastReason     FailoverReason         `json:"last_reason,omitempty"` // classification of the latest failure
+	LastError      string                 `json:"last_error,omitempty"`
+}
+
+// Circuit states reported in CooldownStatus.State.
+const (
+	CircuitClosed   = "closed"    // healthy, requests flow normally
+	CircuitOpen     = "open"      // in standard cooldown, skipped by the fallback chain
+	CircuitHalfOpen = "half-open" // cooldown expired; next request is a trial
+	CircuitDisabled = "disabled"  // billing disable, long cooldown
+)
+
+// CooldownStatus is a point-in-time view of one provider's cooldown state.
+type CooldownStatus struct {
+	Provider      string
+	State         string
+	Remaining     time.Duration
+	ErrorCount    int
+	FailureCounts map[FailoverReason]int
+	LastReason    FailoverReason
+	LastError     string
+	LastFailure   time.Time
+}
+
+// cooldownFile is the on-disk format written by a persistent tracker.
+type cooldownFile struct {
+	Providers map[string]*cooldownEntry `json:"providers"`
+	UpdatedAt time.Time                 `json:"updated_at"`
 }
 
 // NewCooldownTracker creates a tracker with default 24h failure window.
@@ -37,9 +76,38 @@ func NewCooldownTracker() *CooldownTracker {
 	}
 }
 
+// NewCooldownTrackerWithStore creates a tracker persisted to path. Existing
+// state is loaded immediately; entries whose failures fell outside the
+// failure window are dropped. A missing or unreadable file starts empty.
+func NewCooldownTrackerWithStore(path string) *CooldownTracker {
+	ct := NewCooldownTracker()
+	ct.storePath = path
+	if err := ct.load(); err != nil {
+		logger.WarnCF("providers", "Failed to load cooldown state", map[string]any{
+			"path":  path,
+			"error": err.Error(),
+		})
+	}
+	return ct
+}
+
 // MarkFailure records a failure for a provider and sets appropriate cooldown.
 // Resets error counts if last failure was more than failureWindow ago.
 func (ct *CooldownTracker) MarkFailure(provider string, reason FailoverReason) {
+	ct.markFailure(provider, reason, "")
+}
+
+// MarkFailureError is MarkFailure for a classified error; the error message
+// is kept as the provider's last error for status reporting.
+func (ct *CooldownTracker) MarkFailureError(provider string, err *FailoverError) {
+	msg := ""
+	if err.Wrapped != nil {
+		msg = err.Wrapped.Error()
+	}
+	ct.markFailure(provider, err.Reason, msg)
+}
+
+func (ct *CooldownTracker) markFailure(provider string, reason FailoverReason, errMsg string) {
 	ct.mu.Lock()
 	defer ct.mu.Unlock()
 
@@ -55,6 +123,8 @@ func (ct *CooldownTracker) MarkFailure(provider string, reason FailoverReason) {
 	entry.ErrorCount++
 	entry.FailureCounts[reason]++
 	entry.LastFailure = now
+	entry.LastReason = reason
+	entry.LastError = errMsg
 
 	if reason == FailoverBilling {
 		billingCount := entry.FailureCounts[FailoverBilling]
@@ -63,6 +133,8 @@ func (ct *CooldownTracker) MarkFailure(provider string, reason FailoverReason) {
 	} else {
 		entry.CooldownEnd = now.Add(calculateStandardCooldown(entry.ErrorCount))
 	}
+
+	ct.persist()
 }
 
 // MarkSuccess resets all counters and cooldowns for a provider.
@@ -74,12 +146,17 @@ func (ct *CooldownTracker) MarkSuccess(provider string) {
 	if entry == nil {
 		return
 	}
+	if entry.ErrorCount == 0 && entry.CooldownEnd.IsZero() && entry.DisabledUntil.IsZero() {
+		return
+	}
 
 	entry.ErrorCount = 0
 	entry.FailureCounts = make(map[FailoverReason]int)
 	entry.CooldownEnd = time.Time{}
 	entry.DisabledUntil = time.Time{}
 	entry.DisabledReason = ""
+
+	ct.persist()
 }
 
 // IsAvailable returns true if the provider is not in cooldown or disabled.
@@ -162,6 +239,164 @@ func (ct *CooldownTracker) FailureCount(provider string, reason FailoverReason)
 	return entry.FailureCounts[reason]
 }
 
+// Status returns the cooldown status of a single provider.
+func (ct *CooldownTracker) Status(provider string) CooldownStatus {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+	return ct.statusLocked(provider, ct.nowFunc())
+}
+
+// Snapshot returns the status of every provider the tracker knows about,
+// sorted by provider name.
+func (ct *CooldownTracker) Snapshot() []CooldownStatus {
+	ct.mu.RLock()
+	defer ct.mu.RUnlock()
+
+	now := ct.nowFunc()
+	out := make([]CooldownStatus, 0, len(ct.entries))
+	for provider := range ct.entries {
+		out = append(out, ct.statusLocked(provider, now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+func (ct *CooldownTracker) statusLocked(provider string, now time.Time) CooldownStatus {
+	status := CooldownStatus{Provider: provider, State: CircuitClosed}
+	entry := ct.entries[provider]
+	if entry == nil {
+		return status
+	}
+
+	status.ErrorCount = entry.ErrorCount
+	status.LastReason = entry.LastReason
+	status.LastError = entry.LastError
+	status.LastFailure = entry.LastFailure
+	status.FailureCounts = make(map[FailoverReason]int, len(entry.FailureCounts))
+	for reason, n := range entry.FailureCounts {
+		status.FailureCounts[reason] = n
+	}
+
+	switch {
+	case !entry.DisabledUntil.IsZero() && now.Before(entry.DisabledUntil):
+		status.State = CircuitDisabled
+		status.Remaining = entry.DisabledUntil.Sub(now)
+		if !entry.CooldownEnd.IsZero() && entry.CooldownEnd.Sub(now) > status.Remaining {
+			status.Remaining = entry.CooldownEnd.Sub(now)
+		}
+	case !entry.CooldownEnd.IsZero() && now.Before(entry.CooldownEnd):
+		status.State = CircuitOpen
+		status.Remaining = entry.CooldownEnd.Sub(now)
+	case entry.ErrorCount > 0:
+		status.State = CircuitHalfOpen
+	}
+	return status
+}
+
+// persist drops expired entries and writes the tracker state to storePath
+// using temp file + rename. Errors are logged rather than returned so a
+// read-only workspace never breaks the fallback chain. Must be called with
+// the lock held.
+func (ct *CooldownTracker) persist() {
+	if ct.storePath == "" {
+		return
+	}
+	now := ct.nowFunc()
+	for provider, entry := range ct.entries {
+		if ct.expired(entry, now) {
+			delete(ct.entries, provider)
+		}
+	}
+	if err := ct.saveLocked(); err != nil {
+		logger.WarnCF("providers", "Failed to save cooldown state", map[string]any{
+			"path":  ct.storePath,
+			"error": err.Error(),
+		})
+	}
+}
+
+func (ct *CooldownTracker) saveLocked() error {
+	data, err := json.MarshalIndent(cooldownFile{
+		Providers: ct.entries,
+		UpdatedAt: ct.nowFunc(),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cooldown state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ct.storePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tempFile := ct.storePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, ct.storePath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+func (ct *CooldownTracker) load() error {
+	data, err := os.ReadFile(ct.storePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read cooldown state: %w", err)
+	}
+
+	var file cooldownFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal cooldown state: %w", err)
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	now := ct.nowFunc()
+	for provider, entry := range file.Providers {
+		if entry == nil {
+			continue
+		}
+		if ct.expired(entry, now) {
+			continue
+		}
+		if entry.FailureCounts == nil {
+			entry.FailureCounts = make(map[FailoverReason]int)
+		}
+		ct.entries[provider] = entry
+	}
+	return nil
+}
+
+// expired reports whether entry has no active cooldown and its last
+// failure fell outside the failure window, so it no longer affects anything.
+func (ct *CooldownTracker) expired(entry *cooldownEntry, now time.Time) bool {
+	stale := entry.LastFailure.IsZero() || now.Sub(entry.LastFailure) > ct.failureWindow
+	active := now.Before(entry.CooldownEnd) || now.Before(entry.DisabledUntil)
+	return stale && !active
+}
+
+// LoadCooldownSnapshot reads a tracker state file without keeping a tracker
+// around, for offline status views (e.g. the CLI).
+func LoadCooldownSnapshot(path string) ([]CooldownStatus, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ct := NewCooldownTracker()
+	ct.storePath = path
+	if err := ct.load(); err != nil {
+		return nil, err
+	}
+	return ct.Snapshot(), nil
+}
+
 func (ct *CooldownTracker) getOrCreate(provider string) *cooldownEntry {
 	entry := ct.entries[provider]
 	if entry == nil {
@@ -1,6 +1,9 @@
 package providers
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -267,3 +270,113 @@ func TestCooldown_MultipleProviders(t *testing.T) {
 		t.Error("groq should be available")
 	}
 }
+
+func TestCooldown_PersistAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "provider_cooldowns.json")
+	ct := NewCooldownTrackerWithStore(path)
+	ct.MarkFailureError("openai", &FailoverError{
+		Reason: FailoverRateLimit, Provider: "openai", Wrapped: errors.New("429 too many requests"),
+	})
+	ct.MarkFailure("anthropic", FailoverBilling)
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	reloaded := NewCooldownTrackerWithStore(path)
+	if reloaded.IsAvailable("openai") || reloaded.IsAvailable("anthropic") {
+		t.Fatal("reloaded tracker should keep both providers in cooldown")
+	}
+	if reloaded.FailureCount("openai", FailoverRateLimit) != 1 {
+		t.Errorf("rate_limit count = %d, want 1", reloaded.FailureCount("openai", FailoverRateLimit))
+	}
+
+	status := reloaded.Status("openai")
+	if status.State != CircuitOpen || status.LastReason != FailoverRateLimit {
+		t.Errorf("openai status = %+v", status)
+	}
+	if status.LastError != "429 too many requests" {
+		t.Errorf("LastError = %q", status.LastError)
+	}
+	if got := reloaded.Status("anthropic").State; got != CircuitDisabled {
+		t.Errorf("anthropic state = %q, want %q", got, CircuitDisabled)
+	}
+
+	reloaded.MarkSuccess("openai")
+	if !NewCooldownTrackerWithStore(path).IsAvailable("openai") {
+		t.Error("success should be persisted")
+	}
+}
+
+func TestCooldown_PersistsOnlyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cooldowns.json")
+	ct := NewCooldownTrackerWithStore(path)
+	current := time.Now()
+	ct.nowFunc = func() time.Time { return current }
+
+	ct.MarkSuccess("openai")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("success on an unknown provider should not write state")
+	}
+
+	ct.MarkFailure("openai", FailoverRateLimit)
+	ct.MarkSuccess("openai")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	ct.MarkSuccess("openai")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("repeated success should not write state")
+	}
+
+	// Entries past the failure window are dropped on the next write.
+	current = current.Add(48 * time.Hour)
+	ct.MarkFailure("anthropic", FailoverTimeout)
+	snapshot, err := LoadCooldownSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot) != 1 || snapshot[0].Provider != "anthropic" {
+		t.Errorf("snapshot = %+v, want only anthropic", snapshot)
+	}
+	if len(ct.Snapshot()) != 1 {
+		t.Errorf("expired entry kept in memory: %+v", ct.Snapshot())
+	}
+}
+
+func TestCooldown_ReloadDropsStaleEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cooldowns.json")
+	ct := NewCooldownTrackerWithStore(path)
+	old := time.Now().Add(-48 * time.Hour)
+	ct.nowFunc = func() time.Time { return old }
+	ct.MarkFailure("openai", FailoverTimeout)
+
+	if snap := NewCooldownTrackerWithStore(path).Snapshot(); len(snap) != 0 {
+		t.Errorf("snapshot = %+v, want stale entry dropped", snap)
+	}
+}
+
+func TestCooldown_StatusStates(t *testing.T) {
+	now := time.Now()
+	ct, current := newTestTracker(now)
+
+	if got := ct.Status("openai").State; got != CircuitClosed {
+		t.Errorf("unknown provider state = %q, want closed", got)
+	}
+
+	ct.MarkFailure("openai", FailoverTimeout)
+	status := ct.Status("openai")
+	if status.State != CircuitOpen || status.Remaining != time.Minute {
+		t.Errorf("after failure: %+v", status)
+	}
+
+	*current = now.Add(2 * time.Minute)
+	if got := ct.Status("openai").State; got != CircuitHalfOpen {
+		t.Errorf("after cooldown state = %q, want half-open", got)
+	}
+
+	ct.MarkSuccess("openai")
+	if got := ct.Status("openai").State; got != CircuitClosed {
+		t.Errorf("after success state = %q, want closed", got)
+	}
+}
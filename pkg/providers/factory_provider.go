@@ -53,13 +53,29 @@ func ExtractProtocol(model string) (protocol, modelID string) {
 
 // CreateProviderFromConfig creates a provider based on the ModelConfig.
 // It uses the protocol prefix in the Model field to determine which provider to create.
-// Supported protocols: openai, anthropic, antigravity, claude-cli, codex-cli, github-copilot
+// Supported protocols: openai, anthropic, azure, bedrock, antigravity, claude-cli, codex-cli, github-copilot
+// Entries with tool_mode "prompted" are wrapped in a PromptedToolsProvider.
 // Returns the provider, the model ID (without protocol prefix), and any error.
 func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
 	if cfg == nil {
 		return nil, "", fmt.Errorf("config is nil")
 	}
 
+	switch cfg.ToolMode {
+	case "", ToolModeNative:
+		return createProviderFromConfig(cfg)
+	case ToolModePrompted:
+		provider, modelID, err := createProviderFromConfig(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return NewPromptedToolsProvider(provider), modelID, nil
+	default:
+		return nil, "", fmt.Errorf("unknown tool_mode %q in model %q (want native or prompted)", cfg.ToolMode, cfg.Model)
+	}
+}
+
+func createProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, error) {
 	if cfg.Model == "" {
 		return nil, "", fmt.Errorf("model is required")
 	}
@@ -118,6 +134,20 @@ func CreateProviderFromConfig(cfg *config.ModelConfig) (LLMProvider, string, err
 		}
 		return NewHTTPProviderWithMaxTokensField(cfg.APIKey, apiBase, cfg.Proxy, cfg.MaxTokensField), modelID, nil
 
+	case "azure", "azure-openai":
+		provider, err := createAzureProvider(cfg, modelID)
+		if err != nil {
+			return nil, "", err
+		}
+		return provider, modelID, nil
+
+	case "bedrock":
+		provider, err := createBedrockProvider(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return provider, modelID, nil
+
 	case "antigravity":
 		return NewAntigravityProvider(), modelID, nil
 
@@ -168,6 +168,25 @@ func TestCreateProviderFromConfig_Antigravity(t *testing.T) {
 	}
 }
 
+func TestCreateProviderFromConfig_Bedrock(t *testing.T) {
+	cfg := &config.ModelConfig{
+		ModelName: "test-bedrock",
+		Model:     "bedrock/anthropic.claude-3-5-sonnet-20240620-v1:0",
+		Region:    "us-west-2",
+	}
+
+	provider, modelID, err := CreateProviderFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*BedrockProvider); !ok {
+		t.Fatalf("provider type = %T, want *BedrockProvider", provider)
+	}
+	if modelID != "anthropic.claude-3-5-sonnet-20240620-v1:0" {
+		t.Errorf("modelID = %q, want %q", modelID, "anthropic.claude-3-5-sonnet-20240620-v1:0")
+	}
+}
+
 func TestCreateProviderFromConfig_ClaudeCLI(t *testing.T) {
 	cfg := &config.ModelConfig{
 		ModelName: "test-claude-cli",
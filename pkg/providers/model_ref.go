@@ -58,6 +58,16 @@ func NormalizeProvider(provider string) string {
 	return p
 }
 
+// CooldownKey returns the key CooldownTracker uses for the model reference
+// raw, as resolved by ResolveCandidates for the fallback chain. Probes use
+// it too, so both see the same cooldown state.
+func CooldownKey(raw, defaultProvider string) string {
+	if ref := ParseModelRef(raw, defaultProvider); ref != nil {
+		return ref.Provider
+	}
+	return NormalizeProvider(defaultProvider)
+}
+
 // ModelKey returns a canonical "provider/model" key for deduplication.
 func ModelKey(provider, model string) string {
 	return NormalizeProvider(provider) + "/" + strings.ToLower(strings.TrimSpace(model))
@@ -31,14 +31,33 @@ type Provider struct {
 	apiKey         string
 	apiBase        string
 	maxTokensField string // Field name for max tokens (e.g., "max_completion_tokens" for o1/glm models)
+	endpoint       EndpointOptions
 	httpClient     *http.Client
 }
 
+// EndpointOptions customizes how requests are addressed and authenticated.
+// The zero value targets a standard OpenAI-compatible API:
+// POST {apiBase}/chat/completions with "Authorization: Bearer <apiKey>".
+// Deployment-style endpoints such as Azure OpenAI override the path,
+// add query parameters (api-version) and use a different auth header.
+type EndpointOptions struct {
+	ChatPath    string                 // Path appended to apiBase (default "/chat/completions")
+	QueryParams map[string]string      // Extra query parameters appended to every request
+	AuthHeader  string                 // Header carrying the credential (default "Authorization")
+	AuthScheme  string                 // Scheme prefix for the credential (default "Bearer" for Authorization)
+	TokenSource func() (string, error) // Optional per-request credential; overrides apiKey when set
+}
+
 func NewProvider(apiKey, apiBase, proxy string) *Provider {
 	return NewProviderWithMaxTokensField(apiKey, apiBase, proxy, "")
 }
 
 func NewProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField string) *Provider {
+	return NewProviderWithEndpoint(apiKey, apiBase, proxy, maxTokensField, EndpointOptions{})
+}
+
+// NewProviderWithEndpoint creates a provider with custom request addressing and auth.
+func NewProviderWithEndpoint(apiKey, apiBase, proxy, maxTokensField string, endpoint EndpointOptions) *Provider {
 	client := &http.Client{
 		Timeout: 120 * time.Second,
 	}
@@ -58,6 +77,7 @@ func NewProviderWithMaxTokensField(apiKey, apiBase, proxy, maxTokensField string
 		apiKey:         apiKey,
 		apiBase:        strings.TrimRight(apiBase, "/"),
 		maxTokensField: maxTokensField,
+		endpoint:       endpoint,
 		httpClient:     client,
 	}
 }
@@ -124,14 +144,14 @@ func (p *Provider) Chat(
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.chatURL(), bytes.NewReader(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if p.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if err := p.setAuthHeader(req); err != nil {
+		return nil, err
 	}
 
 	resp, err := p.httpClient.Do(req)
@@ -152,6 +172,118 @@ func (p *Provider) Chat(
 	return parseResponse(body)
 }
 
+// ListModels queries the models endpoint. It is a cheap way to verify
+// reachability and credentials without spending tokens.
+// Non-200 responses are returned as *StatusError.
+func (p *Provider) ListModels(ctx context.Context) ([]string, error) {
+	if p.apiBase == "" {
+		return nil, fmt.Errorf("API base not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.endpointURL("/models"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := p.setAuthHeader(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal models: %w", err)
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+// StatusError is returned for non-200 responses from auxiliary endpoints.
+// The message keeps the "Status: N" shape used by Chat so error
+// classification treats both the same way.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API request failed:\n  Status: %d\n  Body:   %s", e.StatusCode, e.Body)
+}
+
+// chatURL builds the chat completions URL from apiBase and the endpoint options.
+func (p *Provider) chatURL() string {
+	path := p.endpoint.ChatPath
+	if path == "" {
+		path = "/chat/completions"
+	}
+	return p.endpointURL(path)
+}
+
+// endpointURL joins apiBase and path and appends the configured query parameters.
+func (p *Provider) endpointURL(path string) string {
+	target := p.apiBase + path
+	if len(p.endpoint.QueryParams) == 0 {
+		return target
+	}
+	query := url.Values{}
+	for k, v := range p.endpoint.QueryParams {
+		query.Set(k, v)
+	}
+	sep := "?"
+	if strings.Contains(target, "?") {
+		sep = "&"
+	}
+	return target + sep + query.Encode()
+}
+
+// setAuthHeader attaches the credential using the configured header style.
+func (p *Provider) setAuthHeader(req *http.Request) error {
+	credential := p.apiKey
+	if p.endpoint.TokenSource != nil {
+		tok, err := p.endpoint.TokenSource()
+		if err != nil {
+			return fmt.Errorf("refreshing token: %w", err)
+		}
+		credential = tok
+	}
+	if credential == "" {
+		return nil
+	}
+
+	header := p.endpoint.AuthHeader
+	scheme := p.endpoint.AuthScheme
+	if header == "" {
+		header = "Authorization"
+		if scheme == "" {
+			scheme = "Bearer"
+		}
+	}
+	if scheme != "" {
+		credential = scheme + " " + credential
+	}
+	req.Header.Set(header, credential)
+	return nil
+}
+
 func parseResponse(body []byte) (*LLMResponse, error) {
 	var apiResponse struct {
 		Choices []struct {
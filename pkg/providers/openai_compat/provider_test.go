@@ -325,3 +325,39 @@ func TestNormalizeModel_UsesAPIBase(t *testing.T) {
 		t.Fatalf("normalizeModel(openrouter) = %q, want %q", got, "openrouter/auto")
 	}
 }
+
+func TestProviderChat_EndpointOptions(t *testing.T) {
+	var gotPath, gotVersion, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotVersion = r.URL.Query().Get("api-version")
+		gotHeader = r.Header.Get("api-key")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"content": "ok"}, "finish_reason": "stop"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	p := NewProviderWithEndpoint("", server.URL+"/deployments/d1", "", "", EndpointOptions{
+		ChatPath:    "/completions",
+		QueryParams: map[string]string{"api-version": "2024-10-21"},
+		AuthHeader:  "api-key",
+		TokenSource: func() (string, error) { return "dynamic", nil },
+	})
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if gotPath != "/deployments/d1/completions" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotVersion != "2024-10-21" {
+		t.Errorf("api-version = %q", gotVersion)
+	}
+	if gotHeader != "dynamic" {
+		t.Errorf("api-key = %q, want token source value without scheme", gotHeader)
+	}
+}
@@ -0,0 +1,332 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers/openai_compat"
+)
+
+// ErrProbeUnsupported is returned by Prober implementations that cannot
+// check the given endpoint cheaply; callers fall back to a minimal completion.
+var ErrProbeUnsupported = errors.New("probe not supported")
+
+// Prober is implemented by providers that offer a cheaper health check than
+// a chat completion, e.g. listing models.
+type Prober interface {
+	Probe(ctx context.Context, model string) error
+}
+
+// Tool-call support values reported in ProbeResult.ToolCalls.
+const (
+	ToolCallsSupported   = "supported"
+	ToolCallsUnsupported = "unsupported"
+)
+
+// Auth status values reported in ProbeResult.AuthStatus.
+const (
+	AuthStatusOK      = "ok"
+	AuthStatusFailed  = "failed"
+	AuthStatusUnknown = "unknown"
+)
+
+// ProbeOptions controls how much a probe checks.
+type ProbeOptions struct {
+	Timeout    time.Duration // Per-request timeout (default 30s)
+	Completion bool          // Always send a minimal completion instead of using Prober
+	Tools      bool          // Also check native tool-call support (implies Completion)
+}
+
+// ProbeResult is the outcome of probing one model.
+type ProbeResult struct {
+	Model      string
+	OK         bool
+	Latency    time.Duration
+	AuthStatus string
+	ToolCalls  string // "", ToolCallsSupported or ToolCallsUnsupported
+	Reason     FailoverReason
+	Err        error
+	CheckedAt  time.Time
+}
+
+const probePrompt = "Reply with the single word OK."
+
+var probeTool = ToolDefinition{
+	Type: "function",
+	Function: ToolFunctionDefinition{
+		Name:        "probe_ping",
+		Description: "Health check tool. Call it with no arguments.",
+		Parameters:  map[string]any{"type": "object", "properties": map[string]any{}},
+	},
+}
+
+// ProbeModel checks that provider can serve model, using the cheapest request
+// available, and classifies failures with ClassifyError.
+func ProbeModel(
+	ctx context.Context,
+	provider LLMProvider,
+	providerName, model string,
+	opts ProbeOptions,
+) ProbeResult {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	result := ProbeResult{Model: model, AuthStatus: AuthStatusUnknown, CheckedAt: time.Now()}
+
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ErrProbeUnsupported
+	if prober, ok := provider.(Prober); ok && !opts.Completion && !opts.Tools {
+		err = prober.Probe(probeCtx, model)
+	}
+	if errors.Is(err, ErrProbeUnsupported) {
+		_, err = provider.Chat(probeCtx, []Message{{Role: "user", Content: probePrompt}}, nil, model,
+			map[string]any{"max_tokens": 16, "temperature": 0.0})
+	}
+	result.Latency = time.Since(start)
+
+	if err != nil {
+		if errors.Is(probeCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("probe timed out after %s: %w", timeout, context.DeadlineExceeded)
+		}
+		result.Err = err
+		result.Reason = FailoverUnknown
+		if fe := ClassifyError(err, providerName, model); fe != nil {
+			result.Reason = fe.Reason
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			result.Reason = FailoverTimeout
+		}
+		if result.Reason == FailoverAuth {
+			result.AuthStatus = AuthStatusFailed
+		}
+		return result
+	}
+
+	result.OK = true
+	result.AuthStatus = AuthStatusOK
+
+	if opts.Tools {
+		toolCtx, toolCancel := context.WithTimeout(ctx, timeout)
+		defer toolCancel()
+		resp, toolErr := provider.Chat(toolCtx,
+			[]Message{{Role: "user", Content: "Call the probe_ping tool now."}},
+			[]ToolDefinition{probeTool}, model,
+			map[string]any{"max_tokens": 64, "temperature": 0.0})
+		if toolErr == nil && resp != nil && len(resp.ToolCalls) > 0 {
+			result.ToolCalls = ToolCallsSupported
+		} else {
+			result.ToolCalls = ToolCallsUnsupported
+		}
+	}
+
+	return result
+}
+
+// Probe lists models on the endpoint. Endpoints without a models route
+// (e.g. some self-hosted servers) report ErrProbeUnsupported.
+func (p *HTTPProvider) Probe(ctx context.Context, model string) error {
+	return probeModelsEndpoint(ctx, p.delegate)
+}
+
+// Probe lists models on the endpoint. Classic deployment URLs have no models
+// route, so those fall back to a minimal completion.
+func (p *AzureProvider) Probe(ctx context.Context, model string) error {
+	return probeModelsEndpoint(ctx, p.delegate)
+}
+
+func probeModelsEndpoint(ctx context.Context, delegate *openai_compat.Provider) error {
+	_, err := delegate.ListModels(ctx)
+	var statusErr *openai_compat.StatusError
+	if errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusMethodNotAllowed) {
+		return ErrProbeUnsupported
+	}
+	return err
+}
+
+// ProbeTarget is one model_list entry prepared for probing.
+type ProbeTarget struct {
+	Name        string // model_list model_name
+	Provider    LLMProvider
+	Protocol    string // model_list protocol, e.g. openai
+	ProviderKey string // key used by CooldownTracker / FallbackChain
+	Model       string // model ID passed to Chat
+}
+
+// BuildProbeTargets creates providers for model_list entries. When names is
+// non-empty only matching model_name entries are included. Entries that fail
+// to initialize are returned in errs keyed by model_name.
+func BuildProbeTargets(cfg *config.Config, names ...string) ([]ProbeTarget, map[string]error) {
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var targets []ProbeTarget
+	errs := make(map[string]error)
+	seen := make(map[string]int)
+	for i := range cfg.ModelList {
+		mc := &cfg.ModelList[i]
+		if len(wanted) > 0 && !wanted[mc.ModelName] {
+			continue
+		}
+
+		// Load-balanced entries share a model_name; number the extras.
+		name := mc.ModelName
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, seen[name])
+		}
+
+		provider, modelID, err := CreateProviderFromConfig(mc)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+		protocol, _ := ExtractProtocol(mc.Model)
+		targets = append(targets, ProbeTarget{
+			Name:        name,
+			Provider:    provider,
+			Protocol:    NormalizeProvider(protocol),
+			ProviderKey: CooldownKey(mc.ModelName, cfg.Agents.Defaults.Provider),
+			Model:       modelID,
+		})
+	}
+	return targets, errs
+}
+
+// ProbeMonitor periodically probes targets, feeding outcomes into a
+// CooldownTracker so the fallback chain skips providers known to be down
+// and recovers them as soon as a probe succeeds.
+type ProbeMonitor struct {
+	targets  []ProbeTarget
+	cooldown *CooldownTracker
+	interval time.Duration
+	opts     ProbeOptions
+
+	mu       sync.RWMutex
+	results  map[string]ProbeResult
+	onResult func(target ProbeTarget, result ProbeResult)
+}
+
+// NewProbeMonitor creates a monitor. cooldown may be nil.
+func NewProbeMonitor(targets []ProbeTarget, cooldown *CooldownTracker, interval time.Duration) *ProbeMonitor {
+	return &ProbeMonitor{
+		targets:  targets,
+		cooldown: cooldown,
+		interval: interval,
+		results:  make(map[string]ProbeResult),
+	}
+}
+
+// SetResultHandler registers a callback invoked after every probe.
+func (m *ProbeMonitor) SetResultHandler(fn func(target ProbeTarget, result ProbeResult)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onResult = fn
+}
+
+// SetOptions overrides the probe options used on each round.
+func (m *ProbeMonitor) SetOptions(opts ProbeOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts = opts
+}
+
+// Results returns the latest result per target name.
+func (m *ProbeMonitor) Results() map[string]ProbeResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string]ProbeResult, len(m.results))
+	for k, v := range m.results {
+		out[k] = v
+	}
+	return out
+}
+
+// RunOnce probes every target sequentially.
+func (m *ProbeMonitor) RunOnce(ctx context.Context) {
+	m.mu.RLock()
+	opts := m.opts
+	m.mu.RUnlock()
+
+	for _, target := range m.targets {
+		if ctx.Err() != nil {
+			return
+		}
+		result := ProbeModel(ctx, target.Provider, target.Protocol, target.Model, opts)
+		m.record(target, result)
+	}
+}
+
+func (m *ProbeMonitor) record(target ProbeTarget, result ProbeResult) {
+	if m.cooldown != nil {
+		switch {
+		case result.OK:
+			m.cooldown.MarkSuccess(target.ProviderKey)
+		case result.Reason != FailoverUnknown && result.Reason != FailoverFormat:
+			m.cooldown.markFailure(target.ProviderKey, result.Reason, result.Err.Error())
+		}
+	}
+
+	if !result.OK {
+		logger.WarnCF("providers", "Provider probe failed", map[string]any{
+			"model_name": target.Name,
+			"provider":   target.ProviderKey,
+			"reason":     string(result.Reason),
+			"error":      result.Err.Error(),
+		})
+	}
+
+	m.mu.Lock()
+	m.results[target.Name] = result
+	handler := m.onResult
+	m.mu.Unlock()
+
+	if handler != nil {
+		handler(target, result)
+	}
+}
+
+// Start probes immediately and then on every interval until ctx is done.
+func (m *ProbeMonitor) Start(ctx context.Context) {
+	if m.interval <= 0 || len(m.targets) == 0 {
+		return
+	}
+	go func() {
+		m.RunOnce(ctx)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// Close releases stateful providers held by the monitor.
+func (m *ProbeMonitor) Close() {
+	for _, target := range m.targets {
+		if sp, ok := target.Provider.(StatefulProvider); ok {
+			sp.Close()
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type probeStubProvider struct {
+	err       error
+	toolCalls bool
+	calls     atomic.Int32
+}
+
+func (p *probeStubProvider) Chat(
+	ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any,
+) (*LLMResponse, error) {
+	p.calls.Add(1)
+	if p.err != nil {
+		return nil, p.err
+	}
+	resp := &LLMResponse{Content: "OK", FinishReason: "stop"}
+	if len(tools) > 0 && p.toolCalls {
+		resp.ToolCalls = []ToolCall{{ID: "1", Name: tools[0].Function.Name}}
+	}
+	return resp, nil
+}
+
+func (p *probeStubProvider) GetDefaultModel() string { return "" }
+
+func TestProbeModel_Success(t *testing.T) {
+	p := &probeStubProvider{toolCalls: true}
+	result := ProbeModel(t.Context(), p, "openai", "gpt-4o", ProbeOptions{Tools: true})
+
+	if !result.OK {
+		t.Fatalf("OK = false, err = %v", result.Err)
+	}
+	if result.AuthStatus != AuthStatusOK {
+		t.Errorf("AuthStatus = %q, want %q", result.AuthStatus, AuthStatusOK)
+	}
+	if result.ToolCalls != ToolCallsSupported {
+		t.Errorf("ToolCalls = %q, want %q", result.ToolCalls, ToolCallsSupported)
+	}
+	if p.calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2 (completion + tool check)", p.calls.Load())
+	}
+}
+
+func TestProbeModel_NoToolCalls(t *testing.T) {
+	result := ProbeModel(t.Context(), &probeStubProvider{}, "ollama", "tiny", ProbeOptions{Tools: true})
+	if result.ToolCalls != ToolCallsUnsupported {
+		t.Errorf("ToolCalls = %q, want %q", result.ToolCalls, ToolCallsUnsupported)
+	}
+}
+
+func TestProbeModel_AuthFailure(t *testing.T) {
+	p := &probeStubProvider{err: errors.New("API request failed:\n  Status: 401\n  Body: invalid api key")}
+	result := ProbeModel(t.Context(), p, "openai", "gpt-4o", ProbeOptions{Tools: true})
+
+	if result.OK {
+		t.Fatal("OK = true, want false")
+	}
+	if result.Reason != FailoverAuth {
+		t.Errorf("Reason = %q, want %q", result.Reason, FailoverAuth)
+	}
+	if result.AuthStatus != AuthStatusFailed {
+		t.Errorf("AuthStatus = %q, want %q", result.AuthStatus, AuthStatusFailed)
+	}
+	if result.ToolCalls != "" {
+		t.Errorf("ToolCalls = %q, want empty when probe fails", result.ToolCalls)
+	}
+}
+
+func TestProbeModel_UsesModelsEndpoint(t *testing.T) {
+	var modelsHits, chatHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/models":
+			modelsHits.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{{"id": "gpt-4o"}}})
+		case "/chat/completions":
+			chatHits.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{
+				"choices": []map[string]any{{"message": map[string]any{"content": "OK"}, "finish_reason": "stop"}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	result := ProbeModel(t.Context(), p, "openai", "gpt-4o", ProbeOptions{})
+	if !result.OK {
+		t.Fatalf("OK = false, err = %v", result.Err)
+	}
+	if modelsHits.Load() != 1 || chatHits.Load() != 0 {
+		t.Errorf("models=%d chat=%d, want models endpoint only", modelsHits.Load(), chatHits.Load())
+	}
+}
+
+func TestProbeModel_FallsBackToCompletionWithoutModelsRoute(t *testing.T) {
+	var chatHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		chatHits.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]any{"content": "OK"}, "finish_reason": "stop"}},
+		})
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	result := ProbeModel(t.Context(), p, "vllm", "m", ProbeOptions{})
+	if !result.OK {
+		t.Fatalf("OK = false, err = %v", result.Err)
+	}
+	if chatHits.Load() != 1 {
+		t.Errorf("chat hits = %d, want 1", chatHits.Load())
+	}
+}
+
+func TestProbeMonitor_UpdatesCooldown(t *testing.T) {
+	now := time.Now()
+	ct := NewCooldownTracker()
+	ct.nowFunc = func() time.Time { return now }
+
+	failing := &probeStubProvider{err: errors.New("status: 429 too many requests")}
+	healthy := &probeStubProvider{}
+	ct.MarkFailure("anthropic", FailoverRateLimit)
+
+	monitor := NewProbeMonitor([]ProbeTarget{
+		{Name: "gpt", Provider: failing, ProviderKey: "openai", Model: "gpt-4o"},
+		{Name: "claude", Provider: healthy, ProviderKey: "anthropic", Model: "claude"},
+	}, ct, time.Minute)
+
+	var seen []string
+	monitor.SetResultHandler(func(target ProbeTarget, result ProbeResult) {
+		seen = append(seen, target.Name)
+	})
+	monitor.RunOnce(t.Context())
+
+	if ct.IsAvailable("openai") {
+		t.Error("openai should be in cooldown after failed probe")
+	}
+	if ct.FailureCount("openai", FailoverRateLimit) != 1 {
+		t.Errorf("rate_limit count = %d, want 1", ct.FailureCount("openai", FailoverRateLimit))
+	}
+	if !ct.IsAvailable("anthropic") {
+		t.Error("anthropic should be available after successful probe")
+	}
+	if len(seen) != 2 {
+		t.Errorf("handler calls = %v, want 2", seen)
+	}
+	if results := monitor.Results(); results["gpt"].OK || !results["claude"].OK {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestBuildProbeTargets(t *testing.T) {
+	cfg := &config.Config{ModelList: []config.ModelConfig{
+		{ModelName: "gpt", Model: "openai/gpt-4o", APIKey: "k1"},
+		{ModelName: "gpt", Model: "openai/gpt-4o", APIKey: "k2"},
+		{ModelName: "claude", Model: "anthropic/claude-sonnet-4.6"},
+		{ModelName: "groq/llama", Model: "groq/llama", APIKey: "k"},
+		{ModelName: "other", Model: "groq/llama", APIKey: "k"},
+	}}
+	cfg.Agents.Defaults.Provider = "gpt"
+
+	targets, errs := BuildProbeTargets(cfg, "gpt", "claude", "groq/llama")
+	if len(targets) != 3 {
+		t.Fatalf("targets = %d, want 3", len(targets))
+	}
+	if targets[0].Name != "gpt" || targets[1].Name != "gpt#2" {
+		t.Errorf("names = %q, %q", targets[0].Name, targets[1].Name)
+	}
+	if targets[0].Protocol != "openai" || targets[0].Model != "gpt-4o" {
+		t.Errorf("target = %+v", targets[0])
+	}
+	// Probes and the fallback chain must share cooldown keys.
+	for i, ref := range []string{"gpt", "gpt", "groq/llama"} {
+		chain := ResolveCandidates(ModelConfig{Primary: ref}, cfg.Agents.Defaults.Provider)[0].Provider
+		if targets[i].ProviderKey != chain {
+			t.Errorf("%s: ProviderKey = %q, fallback chain uses %q", targets[i].Name, targets[i].ProviderKey, chain)
+		}
+	}
+	if _, ok := errs["claude"]; !ok {
+		t.Errorf("expected config error for claude without api_key, got %v", errs)
+	}
+}
@@ -0,0 +1,359 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Tool modes for model_list entries (config tool_mode).
+const (
+	ToolModeNative   = "native"
+	ToolModePrompted = "prompted"
+)
+
+const (
+	toolCallOpenTag  = "<tool_call>"
+	toolCallCloseTag = "</tool_call>"
+)
+
+// PromptedToolsProvider adds tool calling to completion endpoints without
+// native function calling. Tool schemas are rendered into the system prompt,
+// tool calls are parsed out of the reply text, and earlier tool calls and
+// results are replayed as plain text turns.
+type PromptedToolsProvider struct {
+	delegate LLMProvider
+}
+
+// NewPromptedToolsProvider wraps delegate with prompted tool calling.
+func NewPromptedToolsProvider(delegate LLMProvider) *PromptedToolsProvider {
+	return &PromptedToolsProvider{delegate: delegate}
+}
+
+func (p *PromptedToolsProvider) Chat(
+	ctx context.Context,
+	messages []Message,
+	tools []ToolDefinition,
+	model string,
+	options map[string]any,
+) (*LLMResponse, error) {
+	resp, err := p.delegate.Chat(ctx, promptedMessages(messages, tools), nil, model, options)
+	if err != nil || resp == nil || len(tools) == 0 {
+		return resp, err
+	}
+
+	calls, rest := parsePromptedToolCalls(resp.Content, tools)
+	if len(calls) > 0 {
+		resp.ToolCalls = calls
+		resp.Content = rest
+		resp.FinishReason = "tool_calls"
+	}
+	return resp, nil
+}
+
+func (p *PromptedToolsProvider) GetDefaultModel() string {
+	return p.delegate.GetDefaultModel()
+}
+
+// Close releases the wrapped provider if it holds resources.
+func (p *PromptedToolsProvider) Close() {
+	if sp, ok := p.delegate.(StatefulProvider); ok {
+		sp.Close()
+	}
+}
+
+// Probe forwards to the wrapped provider's cheap health check when it has one.
+func (p *PromptedToolsProvider) Probe(ctx context.Context, model string) error {
+	if prober, ok := p.delegate.(Prober); ok {
+		return prober.Probe(ctx, model)
+	}
+	return ErrProbeUnsupported
+}
+
+// buildPromptedToolsPrompt renders tool definitions and the call grammar.
+func buildPromptedToolsPrompt(tools []ToolDefinition) string {
+	var sb strings.Builder
+
+	sb.WriteString("## Tools\n\n")
+	sb.WriteString("You can call the tools below. To call a tool, reply with one block per call:\n\n")
+	sb.WriteString(toolCallOpenTag + "\n")
+	sb.WriteString(`{"name": "tool_name", "arguments": {"param": "value"}}`)
+	sb.WriteString("\n" + toolCallCloseTag + "\n\n")
+	sb.WriteString("Rules:\n")
+	sb.WriteString("- \"arguments\" is a JSON object matching the tool's parameters.\n")
+	sb.WriteString("- You may emit several blocks to call several tools; then stop and wait.\n")
+	sb.WriteString("- Tool results come back in <tool_result> blocks in the next user turn.\n")
+	sb.WriteString("- When no tool is needed, answer normally without any block.\n\n")
+	sb.WriteString("### Available tools\n\n")
+
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		fmt.Fprintf(&sb, "#### %s\n", tool.Function.Name)
+		if tool.Function.Description != "" {
+			fmt.Fprintf(&sb, "%s\n", tool.Function.Description)
+		}
+		if len(tool.Function.Parameters) > 0 {
+			paramsJSON, _ := json.Marshal(tool.Function.Parameters)
+			fmt.Fprintf(&sb, "Parameters: %s\n", paramsJSON)
+		}
+		sb.WriteString("\n")
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// promptedMessages converts a native tool-calling transcript into plain
+// system/user/assistant turns: the tool prompt is appended to the system
+// message, assistant tool calls become <tool_call> blocks and tool results
+// become <tool_result> blocks in a user turn.
+func promptedMessages(messages []Message, tools []ToolDefinition) []Message {
+	out := make([]Message, 0, len(messages)+1)
+	toolNames := make(map[string]string) // tool_call_id -> tool name
+
+	if len(tools) > 0 {
+		toolsPrompt := buildPromptedToolsPrompt(tools)
+		if len(messages) > 0 && messages[0].Role == "system" {
+			sys := messages[0]
+			sys.Content = strings.TrimRight(sys.Content, "\n") + "\n\n" + toolsPrompt
+			sys.SystemParts = nil
+			out = append(out, sys)
+			messages = messages[1:]
+		} else {
+			out = append(out, Message{Role: "system", Content: toolsPrompt})
+		}
+	}
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			var sb strings.Builder
+			if msg.Content != "" {
+				sb.WriteString(msg.Content)
+				sb.WriteString("\n")
+			}
+			for _, tc := range msg.ToolCalls {
+				tc = NormalizeToolCall(tc)
+				toolNames[tc.ID] = tc.Name
+				callJSON, _ := json.Marshal(map[string]any{"name": tc.Name, "arguments": tc.Arguments})
+				fmt.Fprintf(&sb, "%s\n%s\n%s\n", toolCallOpenTag, callJSON, toolCallCloseTag)
+			}
+			out = append(out, Message{Role: "assistant", Content: strings.TrimRight(sb.String(), "\n")})
+
+		case msg.Role == "tool":
+			block := fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", toolNames[msg.ToolCallID], msg.Content)
+			// Consecutive results go into one user turn; many chat templates
+			// reject two user messages in a row.
+			if n := len(out); n > 0 && out[n-1].Role == "user" && strings.HasPrefix(out[n-1].Content, "<tool_result") {
+				out[n-1].Content += "\n" + block
+				continue
+			}
+			out = append(out, Message{Role: "user", Content: block})
+
+		default:
+			msg.ToolCalls = nil
+			msg.ToolCallID = ""
+			out = append(out, msg)
+		}
+	}
+
+	return out
+}
+
+var (
+	codeFenceRe     = regexp.MustCompile("(?s)```(?:json|tool_call)?\\s*(.*?)```")
+	trailingCommaRe = regexp.MustCompile(`,\s*([}\]])`)
+	promptedCallSeq atomic.Uint64
+)
+
+// parsePromptedToolCalls extracts tool calls from model text and returns the
+// remaining prose. Accepted forms, in order:
+//   - <tool_call>{...}</tool_call> blocks (closing tag optional at end of text)
+//   - the {"tool_calls":[...]} envelope used by the CLI providers
+//   - a reply that is only a JSON object (optionally fenced) naming a known tool
+//
+// Call objects may use "arguments" or "parameters", given as an object or a
+// JSON-encoded string; trailing commas are tolerated.
+func parsePromptedToolCalls(text string, tools []ToolDefinition) ([]ToolCall, string) {
+	known := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		known[t.Function.Name] = true
+	}
+
+	if strings.Contains(text, toolCallOpenTag) {
+		var calls []ToolCall
+		var rest strings.Builder
+		remaining := text
+		for {
+			start := strings.Index(remaining, toolCallOpenTag)
+			if start == -1 {
+				rest.WriteString(remaining)
+				break
+			}
+			rest.WriteString(remaining[:start])
+			body := remaining[start+len(toolCallOpenTag):]
+			end := strings.Index(body, toolCallCloseTag)
+			if end == -1 {
+				remaining = ""
+			} else {
+				remaining = body[end+len(toolCallCloseTag):]
+				body = body[:end]
+			}
+			for _, obj := range findJSONObjects(unfence(body)) {
+				if tc, ok := parsePromptedCall(obj); ok {
+					calls = append(calls, tc)
+				}
+			}
+		}
+		if len(calls) > 0 {
+			return calls, strings.TrimSpace(rest.String())
+		}
+	}
+
+	if calls := extractToolCallsFromText(text); len(calls) > 0 {
+		for i := range calls {
+			calls[i] = NormalizeToolCall(calls[i])
+			if calls[i].ID == "" {
+				calls[i].ID = newPromptedCallID()
+			}
+		}
+		return calls, stripToolCallsFromText(text)
+	}
+
+	// Bare JSON reply: only accepted when it names a known tool, so ordinary
+	// JSON answers are not mistaken for calls.
+	body := strings.TrimSpace(unfence(strings.TrimSpace(text)))
+	if strings.HasPrefix(body, "{") {
+		if objs := findJSONObjects(body); len(objs) == 1 && len(strings.TrimSpace(objs[0])) == len(body) {
+			if tc, ok := parsePromptedCall(objs[0]); ok && known[tc.Name] {
+				return []ToolCall{tc}, ""
+			}
+		}
+	}
+
+	return nil, text
+}
+
+// parsePromptedCall decodes one call object.
+func parsePromptedCall(raw string) (ToolCall, bool) {
+	var call struct {
+		ID         string          `json:"id"`
+		Name       string          `json:"name"`
+		Arguments  json.RawMessage `json:"arguments"`
+		Parameters json.RawMessage `json:"parameters"`
+		Function   *struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal([]byte(raw), &call); err != nil {
+		if err := json.Unmarshal([]byte(trailingCommaRe.ReplaceAllString(raw, "$1")), &call); err != nil {
+			return ToolCall{}, false
+		}
+	}
+
+	name, args := call.Name, call.Arguments
+	if call.Function != nil {
+		if name == "" {
+			name = call.Function.Name
+		}
+		if len(args) == 0 {
+			args = call.Function.Arguments
+		}
+	}
+	if len(args) == 0 {
+		args = call.Parameters
+	}
+	if name == "" {
+		return ToolCall{}, false
+	}
+
+	arguments := map[string]any{}
+	if len(args) > 0 {
+		var asString string
+		if json.Unmarshal(args, &asString) == nil {
+			args = json.RawMessage(asString)
+		}
+		if err := json.Unmarshal(args, &arguments); err != nil || arguments == nil {
+			arguments = map[string]any{}
+		}
+	}
+
+	id := call.ID
+	if id == "" {
+		id = newPromptedCallID()
+	}
+	argsJSON, _ := json.Marshal(arguments)
+	return ToolCall{
+		ID:        id,
+		Type:      "function",
+		Name:      name,
+		Arguments: arguments,
+		Function:  &FunctionCall{Name: name, Arguments: string(argsJSON)},
+	}, true
+}
+
+// findJSONObjects returns every top-level {...} object in text. Unlike
+// findMatchingBrace it skips braces inside JSON strings.
+func findJSONObjects(text string) []string {
+	var objs []string
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' {
+			continue
+		}
+		end := scanJSONObject(text, i)
+		if end == -1 {
+			break
+		}
+		objs = append(objs, text[i:end])
+		i = end - 1
+	}
+	return objs
+}
+
+func scanJSONObject(text string, pos int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := pos; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// unfence strips a surrounding markdown code fence, if any.
+func unfence(s string) string {
+	if m := codeFenceRe.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+func newPromptedCallID() string {
+	return fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), promptedCallSeq.Add(1))
+}
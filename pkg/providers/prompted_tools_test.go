@@ -0,0 +1,197 @@
+// PicoClaw - Ultra-lightweight personal AI agent
+// License: MIT
+//
+// Copyright (c) 2026 PicoClaw contributors
+
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+type recordingProvider struct {
+	reply    string
+	messages []Message
+	tools    []ToolDefinition
+}
+
+func (p *recordingProvider) Chat(
+	ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any,
+) (*LLMResponse, error) {
+	p.messages = messages
+	p.tools = tools
+	return &LLMResponse{Content: p.reply, FinishReason: "stop"}, nil
+}
+
+func (p *recordingProvider) GetDefaultModel() string { return "" }
+
+var promptedTestTools = []ToolDefinition{
+	{
+		Type: "function",
+		Function: ToolFunctionDefinition{
+			Name:        "read_file",
+			Description: "Read a file",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"path": map[string]any{"type": "string"}},
+			},
+		},
+	},
+	{Type: "function", Function: ToolFunctionDefinition{Name: "list_dir", Description: "List a directory"}},
+}
+
+func TestPromptedTools_RendersSchemasAndParsesCalls(t *testing.T) {
+	delegate := &recordingProvider{
+		reply: "Let me look.\n<tool_call>\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"a.txt\"}}\n</tool_call>",
+	}
+	p := NewPromptedToolsProvider(delegate)
+
+	resp, err := p.Chat(t.Context(), []Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "read a.txt"},
+	}, promptedTestTools, "tiny", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if delegate.tools != nil {
+		t.Error("tools must not be sent to the endpoint")
+	}
+	sys := delegate.messages[0]
+	if sys.Role != "system" || !strings.HasPrefix(sys.Content, "You are helpful.") ||
+		!strings.Contains(sys.Content, "#### read_file") || !strings.Contains(sys.Content, `"path"`) {
+		t.Errorf("system prompt = %q", sys.Content)
+	}
+
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("ToolCalls = %+v, want 1", resp.ToolCalls)
+	}
+	tc := resp.ToolCalls[0]
+	if tc.Name != "read_file" || tc.Arguments["path"] != "a.txt" || tc.ID == "" {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if tc.Function == nil || tc.Function.Arguments != `{"path":"a.txt"}` {
+		t.Errorf("Function = %+v", tc.Function)
+	}
+	if resp.Content != "Let me look." || resp.FinishReason != "tool_calls" {
+		t.Errorf("content = %q, finish = %q", resp.Content, resp.FinishReason)
+	}
+}
+
+func TestPromptedTools_ReplaysToolTurnsAsText(t *testing.T) {
+	delegate := &recordingProvider{reply: "done"}
+	p := NewPromptedToolsProvider(delegate)
+
+	_, err := p.Chat(t.Context(), []Message{
+		{Role: "user", Content: "look around"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "c1", Name: "list_dir", Arguments: map[string]any{"path": "."}},
+			{ID: "c2", Name: "read_file", Arguments: map[string]any{"path": "a.txt"}},
+		}},
+		{Role: "tool", ToolCallID: "c1", Content: "a.txt"},
+		{Role: "tool", ToolCallID: "c2", Content: "hello"},
+	}, promptedTestTools, "tiny", nil)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	msgs := delegate.messages
+	if len(msgs) != 4 {
+		t.Fatalf("messages = %d, want 4 (system, user, assistant, user): %+v", len(msgs), msgs)
+	}
+	if msgs[0].Role != "system" {
+		t.Errorf("first message role = %q, want system", msgs[0].Role)
+	}
+	assistant := msgs[2]
+	if assistant.Role != "assistant" || len(assistant.ToolCalls) != 0 ||
+		strings.Count(assistant.Content, "<tool_call>") != 2 {
+		t.Errorf("assistant turn = %+v", assistant)
+	}
+	results := msgs[3]
+	if results.Role != "user" || !strings.Contains(results.Content, `<tool_result name="list_dir">`) ||
+		!strings.Contains(results.Content, `<tool_result name="read_file">`) {
+		t.Errorf("tool results turn = %+v", results)
+	}
+}
+
+func TestParsePromptedToolCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantName string
+		wantArg  string
+		wantRest string
+	}{
+		{
+			name:     "unterminated tag with fence and trailing comma",
+			text:     "<tool_call>\n```json\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"x}.txt\",},}\n```",
+			wantName: "read_file",
+			wantArg:  "x}.txt",
+		},
+		{
+			name:     "arguments as string",
+			text:     `<tool_call>{"name":"read_file","arguments":"{\"path\":\"b\"}"}</tool_call> ok`,
+			wantName: "read_file",
+			wantArg:  "b",
+			wantRest: "ok",
+		},
+		{
+			name:     "cli envelope",
+			text:     `{"tool_calls":[{"id":"x","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"c\"}"}}]}`,
+			wantName: "read_file",
+			wantArg:  "c",
+		},
+		{
+			name:     "bare json with parameters",
+			text:     "```json\n{\"name\": \"read_file\", \"parameters\": {\"path\": \"d\"}}\n```",
+			wantName: "read_file",
+			wantArg:  "d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, rest := parsePromptedToolCalls(tt.text, promptedTestTools)
+			if len(calls) != 1 {
+				t.Fatalf("calls = %+v, want 1", calls)
+			}
+			if calls[0].Name != tt.wantName || calls[0].Arguments["path"] != tt.wantArg {
+				t.Errorf("call = %+v", calls[0])
+			}
+			if rest != tt.wantRest {
+				t.Errorf("rest = %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParsePromptedToolCalls_IgnoresPlainJSON(t *testing.T) {
+	text := `{"name": "Alice", "arguments": {"age": 3}}`
+	calls, rest := parsePromptedToolCalls(text, promptedTestTools)
+	if len(calls) != 0 || rest != text {
+		t.Errorf("calls = %+v, rest = %q; unknown tool names must not become calls", calls, rest)
+	}
+}
+
+func TestCreateProviderFromConfig_ToolMode(t *testing.T) {
+	provider, _, err := CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "local", Model: "ollama/tiny", APIBase: "http://localhost:11434/v1", ToolMode: "prompted",
+	})
+	if err != nil {
+		t.Fatalf("CreateProviderFromConfig() error = %v", err)
+	}
+	if _, ok := provider.(*PromptedToolsProvider); !ok {
+		t.Errorf("provider type = %T, want *PromptedToolsProvider", provider)
+	}
+
+	_, _, err = CreateProviderFromConfig(&config.ModelConfig{
+		ModelName: "local", Model: "ollama/tiny", APIBase: "http://localhost:11434/v1", ToolMode: "xml",
+	})
+	if err == nil {
+		t.Error("expected error for unknown tool_mode")
+	}
+}
@@ -0,0 +1,84 @@
+// Package sandbox confines commands started by PicoClaw's tools using
+// operating-system mechanisms rather than command-string inspection.
+//
+// On Linux a command is started through a re-executed copy of the current
+// binary that applies resource limits and a Landlock ruleset to itself and
+// then execs the real command, so the restrictions are inherited by the
+// command and everything it spawns. Network denial uses a fresh user and
+// network namespace.
+package sandbox
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned when the platform has no sandbox support.
+var ErrUnsupported = errors.New("exec sandbox is only supported on Linux")
+
+// Policy describes what a sandboxed command may do.
+type Policy struct {
+	// ReadOnly paths may be read and executed. Missing paths are ignored.
+	ReadOnly []string `json:"read_only,omitempty"`
+	// ReadWrite paths may additionally be written, created and removed.
+	ReadWrite []string `json:"read_write,omitempty"`
+	// DenyNetwork runs the command in an empty network namespace.
+	DenyNetwork bool `json:"deny_network,omitempty"`
+	// CPUSeconds limits CPU time (RLIMIT_CPU). Zero means unlimited.
+	CPUSeconds int `json:"cpu_seconds,omitempty"`
+	// MemoryBytes limits address space (RLIMIT_AS). Zero means unlimited.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+	// MaxProcesses limits processes for the user (RLIMIT_NPROC). Zero
+	// means unlimited. Not enforced for root.
+	MaxProcesses int `json:"max_processes,omitempty"`
+}
+
+// DefaultReadOnlyPaths are the system locations most commands need in
+// order to run: binaries, shared libraries, and the few files in /etc
+// used for dynamic linking, DNS and TLS. Other users' files and the rest
+// of /etc stay hidden.
+var DefaultReadOnlyPaths = []string{
+	"/bin",
+	"/sbin",
+	"/usr",
+	"/lib",
+	"/lib32",
+	"/lib64",
+	"/libx32",
+	"/etc/alternatives",
+	"/etc/ld.so.cache",
+	"/etc/ld.so.conf",
+	"/etc/ld.so.conf.d",
+	"/etc/localtime",
+	"/etc/resolv.conf",
+	"/etc/hosts",
+	"/etc/nsswitch.conf",
+	"/etc/ssl",
+	"/etc/ca-certificates",
+	"/etc/pki",
+	"/proc",
+}
+
+// devicePaths are granted read/write so redirections like >/dev/null work.
+var devicePaths = []string{
+	"/dev/null",
+	"/dev/zero",
+	"/dev/full",
+	"/dev/random",
+	"/dev/urandom",
+	"/dev/tty",
+}
+
+// existing filters out paths that do not exist.
+func existing(paths []string) []string {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
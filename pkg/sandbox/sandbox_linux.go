@@ -0,0 +1,275 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// helperArg0 marks a re-executed process that should apply a policy.
+	helperArg0 = "picoclaw-sandbox"
+	policyEnv  = "PICOCLAW_SANDBOX_POLICY"
+
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	prSetNoNewPrivs = 38
+	oPath           = 0x200000 // O_PATH, missing from package syscall
+	rlimitNproc     = 6
+)
+
+// Landlock filesystem access rights.
+const (
+	accessExecute    = 1 << 0
+	accessWriteFile  = 1 << 1
+	accessReadFile   = 1 << 2
+	accessReadDir    = 1 << 3
+	accessRemoveDir  = 1 << 4
+	accessRemoveFile = 1 << 5
+	accessMakeChar   = 1 << 6
+	accessMakeDir    = 1 << 7
+	accessMakeReg    = 1 << 8
+	accessMakeSock   = 1 << 9
+	accessMakeFifo   = 1 << 10
+	accessMakeBlock  = 1 << 11
+	accessMakeSym    = 1 << 12
+	accessRefer      = 1 << 13 // ABI 2
+	accessTruncate   = 1 << 14 // ABI 3
+	accessIoctlDev   = 1 << 15 // ABI 5
+
+	accessFileRights = accessExecute | accessWriteFile | accessReadFile | accessTruncate | accessIoctlDev
+	accessReadOnly   = accessExecute | accessReadFile | accessReadDir
+)
+
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+} // the kernel reads the first 12 bytes (packed struct)
+
+func init() {
+	if len(os.Args) < 2 || os.Args[0] != helperArg0 || os.Getenv(policyEnv) == "" {
+		return
+	}
+	if err := runHelper(); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(126)
+	}
+}
+
+// runHelper applies the policy from the environment to this process and
+// execs the target command in its place.
+func runHelper() error {
+	var policy Policy
+	if err := json.Unmarshal([]byte(os.Getenv(policyEnv)), &policy); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	os.Unsetenv(policyEnv)
+
+	target, err := exec.LookPath(os.Args[1])
+	if err != nil {
+		return err
+	}
+
+	// Landlock and no_new_privs apply to the calling thread, which is the
+	// one that survives execve.
+	runtime.LockOSThread()
+
+	if err := applyRlimits(policy); err != nil {
+		return err
+	}
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("prctl(NO_NEW_PRIVS): %w", errno)
+	}
+	if err := restrictFilesystem(policy); err != nil {
+		return err
+	}
+	return syscall.Exec(target, os.Args[1:], os.Environ())
+}
+
+func applyRlimits(p Policy) error {
+	set := func(resource int, value uint64, name string) error {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("setrlimit %s: %w", name, err)
+		}
+		return nil
+	}
+	if p.CPUSeconds > 0 {
+		if err := set(syscall.RLIMIT_CPU, uint64(p.CPUSeconds), "cpu"); err != nil {
+			return err
+		}
+	}
+	if p.MemoryBytes > 0 {
+		if err := set(syscall.RLIMIT_AS, uint64(p.MemoryBytes), "memory"); err != nil {
+			return err
+		}
+	}
+	if p.MaxProcesses > 0 {
+		if err := set(rlimitNproc, uint64(p.MaxProcesses), "processes"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// landlockABI returns the kernel's Landlock ABI version.
+func landlockABI() (int, error) {
+	v, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	switch errno {
+	case 0:
+		return int(v), nil
+	case syscall.ENOSYS:
+		return 0, errors.New("the kernel lacks Landlock support (Linux 5.13+ built with CONFIG_SECURITY_LANDLOCK is required)")
+	case syscall.EOPNOTSUPP:
+		return 0, errors.New("Landlock is disabled on this system (add \"landlock\" to the lsm= kernel boot parameter)")
+	default:
+		return 0, fmt.Errorf("landlock_create_ruleset: %w", errno)
+	}
+}
+
+func handledAccess(abi int) uint64 {
+	access := uint64(accessExecute | accessWriteFile | accessReadFile | accessReadDir |
+		accessRemoveDir | accessRemoveFile | accessMakeChar | accessMakeDir | accessMakeReg |
+		accessMakeSock | accessMakeFifo | accessMakeBlock | accessMakeSym)
+	if abi >= 2 {
+		access |= accessRefer
+	}
+	if abi >= 3 {
+		access |= accessTruncate
+	}
+	if abi >= 5 {
+		access |= accessIoctlDev
+	}
+	return access
+}
+
+func restrictFilesystem(p Policy) error {
+	abi, err := landlockABI()
+	if err != nil {
+		return err
+	}
+	handled := handledAccess(abi)
+
+	attr := rulesetAttr{handledAccessFS: handled}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("landlock_create_ruleset: %w", errno)
+	}
+	defer syscall.Close(int(fd))
+
+	for _, path := range existing(p.ReadOnly) {
+		if err := addPathRule(int(fd), path, accessReadOnly&handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range existing(devicePaths) {
+		if err := addPathRule(int(fd), path, (accessReadFile|accessWriteFile|accessTruncate|accessIoctlDev)&handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range existing(p.ReadWrite) {
+		if err := addPathRule(int(fd), path, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.Syscall(sysLandlockRestrictSelf, fd, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock_restrict_self: %w", errno)
+	}
+	return nil
+}
+
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	fd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer syscall.Close(fd)
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+		// Directory-only rights are rejected on files.
+		access &= accessFileRights
+	}
+
+	attr := pathBeneathAttr{allowedAccess: access, parentFd: int32(fd)}
+	if _, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock_add_rule %s: %w", path, errno)
+	}
+	return nil
+}
+
+// Supported reports whether commands can be sandboxed on this system, with
+// an error that explains what is missing.
+func Supported() error {
+	_, err := landlockABI()
+	return err
+}
+
+// Wrap rewrites cmd so that it runs under policy. It must be called after
+// cmd.SysProcAttr has been set up and before cmd.Start.
+func Wrap(cmd *exec.Cmd, policy Policy) error {
+	if err := Supported(); err != nil {
+		return err
+	}
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating sandbox helper: %w", err)
+	}
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, policyEnv+"="+string(encoded))
+	cmd.Args = append([]string{helperArg0, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = self
+
+	if policy.DenyNetwork {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	}
+	return nil
+}
+
+// StartError explains a failure to start a wrapped command, pointing at
+// user namespaces when network denial could not be set up.
+func StartError(policy Policy, err error) error {
+	if policy.DenyNetwork && (errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EINVAL)) {
+		return fmt.Errorf("%w (network denial needs unprivileged user namespaces; "+
+			"check kernel.unprivileged_userns_clone / user.max_user_namespaces)", err)
+	}
+	return err
+}
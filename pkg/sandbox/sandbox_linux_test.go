@@ -0,0 +1,92 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runSandboxed(t *testing.T, policy Policy, script string) (string, error) {
+	t.Helper()
+	if err := Supported(); err != nil {
+		t.Skipf("sandbox unsupported: %v", err)
+	}
+	cmd := exec.Command("sh", "-c", script)
+	if err := Wrap(cmd, policy); err != nil {
+		t.Fatalf("Wrap: %v", err)
+	}
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), StartError(policy, err)
+}
+
+func TestWrap_FilesystemConfinedToWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("top secret"), 0o644)
+	policy := Policy{ReadOnly: DefaultReadOnlyPaths, ReadWrite: []string{workspace}}
+
+	out, err := runSandboxed(t, policy, "echo hello > "+workspace+"/out.txt && cat "+workspace+"/out.txt")
+	if err != nil || out != "hello" {
+		t.Fatalf("workspace write: out=%q err=%v", out, err)
+	}
+
+	// Path tricks that defeat string matching don't help inside the sandbox.
+	if out, err := runSandboxed(t, policy, "cd "+outside+"; cat secret.txt"); err == nil {
+		t.Errorf("read outside workspace succeeded: %q", out)
+	}
+	if out, err := runSandboxed(t, policy, "cd /; cat etc/shadow etc/passwd"); err == nil {
+		t.Errorf("read /etc succeeded: %q", out)
+	}
+	if _, err := runSandboxed(t, policy, "touch "+outside+"/new.txt"); err == nil {
+		t.Error("write outside workspace succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Error("file created outside workspace")
+	}
+
+	// Read-only paths are readable but not writable.
+	policy.ReadOnly = append(policy.ReadOnly, outside)
+	if out, err := runSandboxed(t, policy, "cat "+outside+"/secret.txt"); err != nil || out != "top secret" {
+		t.Errorf("read-only path read: out=%q err=%v", out, err)
+	}
+	if _, err := runSandboxed(t, policy, "echo x > "+outside+"/secret.txt"); err == nil {
+		t.Error("write to read-only path succeeded")
+	}
+
+	if out, err := runSandboxed(t, policy, "echo discarded > /dev/null && echo ok"); err != nil || out != "ok" {
+		t.Errorf("/dev/null redirect: out=%q err=%v", out, err)
+	}
+}
+
+func TestWrap_DenyNetwork(t *testing.T) {
+	policy := Policy{ReadOnly: DefaultReadOnlyPaths, DenyNetwork: true}
+	out, err := runSandboxed(t, policy, "cat /proc/net/dev")
+	if err != nil {
+		t.Skipf("network namespace unavailable: %v (%s)", err, out)
+	}
+	for _, line := range strings.Split(out, "\n")[2:] {
+		name := strings.TrimSpace(strings.SplitN(line, ":", 2)[0])
+		if name != "lo" {
+			t.Errorf("unexpected interface %q in sandbox", name)
+		}
+	}
+}
+
+func TestWrap_ResourceLimits(t *testing.T) {
+	policy := Policy{
+		ReadOnly:    DefaultReadOnlyPaths,
+		CPUSeconds:  7,
+		MemoryBytes: 512 << 20,
+	}
+	out, err := runSandboxed(t, policy, "ulimit -t; ulimit -v")
+	if err != nil {
+		t.Fatalf("err = %v (%s)", err, out)
+	}
+	if out != "7\n524288" {
+		t.Errorf("limits = %q, want 7 and 524288", out)
+	}
+}
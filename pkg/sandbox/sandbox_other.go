@@ -0,0 +1,20 @@
+//go:build !linux
+
+package sandbox
+
+import "os/exec"
+
+// Supported reports whether commands can be sandboxed on this system.
+func Supported() error {
+	return ErrUnsupported
+}
+
+// Wrap rewrites cmd so that it runs under policy.
+func Wrap(_ *exec.Cmd, _ Policy) error {
+	return ErrUnsupported
+}
+
+// StartError explains a failure to start a wrapped command.
+func StartError(_ Policy, err error) error {
+	return err
+}
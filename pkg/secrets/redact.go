@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minRedactLength keeps very short values, which would mask unrelated
+// text, out of the redactor.
+const minRedactLength = 4
+
+// Redactor replaces secret values with [REDACTED:name]. It is safe for
+// concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	names    map[string]string // value -> name
+	replacer *strings.Replacer
+}
+
+// NewRedactor creates an empty redactor.
+func NewRedactor() *Redactor {
+	return &Redactor{names: make(map[string]string)}
+}
+
+// Add registers a secret value. Its URL-encoded form is masked too, since
+// values often end up in query strings.
+func (r *Redactor) Add(name, value string) {
+	if len(value) < minRedactLength {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[value] = name
+	if escaped := url.QueryEscape(value); escaped != value {
+		r.names[escaped] = name
+	}
+	r.replacer = nil
+}
+
+// Redact masks every registered value in s.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	r.mu.RLock()
+	replacer := r.replacer
+	empty := len(r.names) == 0
+	r.mu.RUnlock()
+	if empty {
+		return s
+	}
+	if replacer == nil {
+		replacer = r.build()
+	}
+	return replacer.Replace(s)
+}
+
+func (r *Redactor) build() *strings.Replacer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replacer != nil {
+		return r.replacer
+	}
+	// Longer values first, so that a secret containing another one is
+	// masked whole.
+	values := make([]string, 0, len(r.names))
+	for v := range r.names {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, "[REDACTED:"+r.names[v]+"]")
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+	return r.replacer
+}
@@ -0,0 +1,25 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	var nilRedactor *Redactor
+	assert.Equal(t, "text", nilRedactor.Redact("text"))
+
+	r := NewRedactor()
+	assert.Equal(t, "nothing yet", r.Redact("nothing yet"))
+
+	r.Add("TOKEN", "abc123")
+	r.Add("LONG", "abc123xyz")
+	r.Add("SPACED", "p@ss word")
+	r.Add("SHORT", "ab")
+
+	assert.Equal(t, "TOKEN=[REDACTED:TOKEN]", r.Redact("TOKEN=abc123"))
+	assert.Equal(t, "[REDACTED:LONG] and [REDACTED:TOKEN]", r.Redact("abc123xyz and abc123"))
+	assert.Equal(t, "GET /?q=[REDACTED:SPACED]", r.Redact("GET /?q=p%40ss+word"))
+	assert.Equal(t, "ab is too short to mask", r.Redact("ab is too short to mask"))
+}
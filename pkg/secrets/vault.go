@@ -0,0 +1,344 @@
+// Package secrets keeps API keys and tokens out of the agent's reach. A
+// Vault stores them encrypted on disk; tools receive them by name only
+// where allowed, and a Redactor masks their values in anything the model,
+// the user or the logs would see.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyEnv holds a base64-encoded 32-byte vault key. When set, it is used
+// instead of the key file.
+const KeyEnv = "PICOCLAW_SECRETS_KEY"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Secret is a named credential. The name doubles as the environment
+// variable it is exposed as.
+type Secret struct {
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	Tools     []string  `json:"tools,omitempty"`  // Tools that receive the secret, e.g. exec, http_request
+	Skills    []string  `json:"skills,omitempty"` // Skills whose own scripts receive it via exec
+	Hosts     []string  `json:"hosts,omitempty"`  // Hosts http_request may send it to; required for http_request
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AllowsTool reports whether tool may receive the secret.
+func (s Secret) AllowsTool(tool string) bool {
+	return slices.Contains(s.Tools, tool)
+}
+
+// AllowsSkill reports whether commands run for skill may receive the
+// secret.
+func (s Secret) AllowsSkill(skill string) bool {
+	return skill != "" && slices.Contains(s.Skills, skill)
+}
+
+// ValidName reports whether name can be used as a secret name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Vault is a file-backed store of secrets, encrypted with AES-256-GCM.
+// The key lives in a separate file created on first use, or in
+// PICOCLAW_SECRETS_KEY.
+type Vault struct {
+	path    string
+	keyPath string
+
+	mu      sync.RWMutex
+	secrets map[string]Secret
+}
+
+type vaultFile struct {
+	Version int    `json:"version"`
+	Nonce   string `json:"nonce"`
+	Data    string `json:"data"`
+}
+
+// DefaultPath returns the vault location, ~/.picoclaw/secrets.enc.
+func DefaultPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "secrets.enc")
+}
+
+// DefaultKeyPath returns the key location, secrets.key in the user's
+// configuration directory (~/.config/picoclaw on Linux). It is kept out of
+// ~/.picoclaw so that a copy of that directory does not carry its own key.
+func DefaultKeyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "picoclaw", "secrets.key")
+}
+
+// Open loads the vault at path, encrypted with the key in keyPath. A
+// missing vault is empty. A key left next to the vault by earlier versions
+// is moved to keyPath.
+func Open(path, keyPath string) (*Vault, error) {
+	v := &Vault{
+		path:    path,
+		keyPath: keyPath,
+		secrets: make(map[string]Secret),
+	}
+	if err := v.migrateKey(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse vault %s: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported vault version %d", file.Version)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(file.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("parse vault %s: %w", path, err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(file.Data)
+	if err != nil {
+		return nil, fmt.Errorf("parse vault %s: %w", path, err)
+	}
+	gcm, err := v.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("parse vault %s: bad nonce", path)
+	}
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt vault %s: wrong key or corrupted file", path)
+	}
+
+	var list []Secret
+	if err := json.Unmarshal(plain, &list); err != nil {
+		return nil, fmt.Errorf("parse vault %s: %w", path, err)
+	}
+	for _, s := range list {
+		v.secrets[s.Name] = s
+	}
+	return v, nil
+}
+
+// migrateKey moves the key file that earlier versions created next to the
+// vault to v.keyPath, unless a key is already there.
+func (v *Vault) migrateKey() error {
+	legacy := strings.TrimSuffix(v.path, filepath.Ext(v.path)) + ".key"
+	if legacy == v.keyPath {
+		return nil
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(v.keyPath); err == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return fmt.Errorf("move vault key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(v.keyPath), 0o700); err != nil {
+		return fmt.Errorf("move vault key: %w", err)
+	}
+	if err := os.WriteFile(v.keyPath, data, 0o600); err != nil {
+		return fmt.Errorf("move vault key: %w", err)
+	}
+	if err := os.Remove(legacy); err != nil {
+		return fmt.Errorf("move vault key: %w", err)
+	}
+	return nil
+}
+
+// Path returns the vault file.
+func (v *Vault) Path() string {
+	return v.path
+}
+
+// Get returns the secret called name.
+func (v *Vault) Get(name string) (Secret, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	s, ok := v.secrets[name]
+	return s, ok
+}
+
+// List returns all secrets sorted by name.
+func (v *Vault) List() []Secret {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	list := make([]Secret, 0, len(v.secrets))
+	for _, s := range v.secrets {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Set adds or replaces a secret and saves the vault.
+func (v *Vault) Set(s Secret) error {
+	if !ValidName(s.Name) {
+		return fmt.Errorf("invalid secret name %q: use letters, digits and underscores", s.Name)
+	}
+	if s.Value == "" {
+		return fmt.Errorf("secret %s has no value", s.Name)
+	}
+	s.UpdatedAt = time.Now().UTC()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	prev, existed := v.secrets[s.Name]
+	v.secrets[s.Name] = s
+	if err := v.save(); err != nil {
+		if existed {
+			v.secrets[s.Name] = prev
+		} else {
+			delete(v.secrets, s.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove deletes a secret and saves the vault. It reports whether the
+// secret existed.
+func (v *Vault) Remove(name string) (bool, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	prev, ok := v.secrets[name]
+	if !ok {
+		return false, nil
+	}
+	delete(v.secrets, name)
+	if err := v.save(); err != nil {
+		v.secrets[name] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes the vault atomically. The caller holds v.mu.
+func (v *Vault) save() error {
+	list := make([]Secret, 0, len(v.secrets))
+	for _, s := range v.secrets {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	plain, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := v.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(vaultFile{
+		Version: 1,
+		Nonce:   base64.StdEncoding.EncodeToString(nonce),
+		Data:    base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plain, nil)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return fmt.Errorf("save vault: %w", err)
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("save vault: %w", err)
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("save vault: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the vault's AEAD, creating the key file when create is
+// set and no key exists yet.
+func (v *Vault) cipher(create bool) (cipher.AEAD, error) {
+	key, err := v.key(create)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (v *Vault) key(create bool) ([]byte, error) {
+	if encoded := strings.TrimSpace(os.Getenv(KeyEnv)); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s must be 32 bytes, base64-encoded", KeyEnv)
+		}
+		return key, nil
+	}
+
+	data, err := os.ReadFile(v.keyPath)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid vault key in %s", v.keyPath)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read vault key: %w", err)
+	}
+	if !create {
+		return nil, fmt.Errorf("vault key %s not found (or set %s)", v.keyPath, KeyEnv)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.keyPath), 0o700); err != nil {
+		return nil, fmt.Errorf("create vault key: %w", err)
+	}
+	f, err := os.OpenFile(v.keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create vault key: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		return nil, fmt.Errorf("create vault key: %w", err)
+	}
+	return key, nil
+}
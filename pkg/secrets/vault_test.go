@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVault_RoundTripEncrypted(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data", "secrets.enc")
+	keyPath := filepath.Join(dir, "config", "secrets.key")
+
+	vault, err := Open(path, keyPath)
+	require.NoError(t, err)
+	assert.Empty(t, vault.List())
+
+	require.NoError(t, vault.Set(Secret{Name: "TODOIST_API_TOKEN", Value: "tok-very-secret", Tools: []string{"exec"}}))
+	require.NoError(t, vault.Set(Secret{Name: "GITHUB_TOKEN", Value: "ghp_abc", Skills: []string{"github"}}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "tok-very-secret")
+	assert.NotContains(t, string(data), "TODOIST_API_TOKEN")
+
+	for _, p := range []string{path, keyPath} {
+		info, err := os.Stat(p)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), p)
+	}
+
+	reopened, err := Open(path, keyPath)
+	require.NoError(t, err)
+	list := reopened.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "GITHUB_TOKEN", list[0].Name)
+	s, ok := reopened.Get("TODOIST_API_TOKEN")
+	require.True(t, ok)
+	assert.Equal(t, "tok-very-secret", s.Value)
+	assert.True(t, s.AllowsTool("exec"))
+	assert.False(t, s.AllowsSkill("github"))
+	assert.True(t, list[0].AllowsSkill("github"))
+
+	removed, err := reopened.Remove("GITHUB_TOKEN")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = reopened.Remove("GITHUB_TOKEN")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	again, err := Open(path, keyPath)
+	require.NoError(t, err)
+	assert.Len(t, again.List(), 1)
+}
+
+func TestVault_Errors(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	keyPath := filepath.Join(dir, "secrets.key")
+	vault, err := Open(path, keyPath)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, vault.Set(Secret{Name: "bad-name", Value: "x"}), "invalid secret name")
+	assert.ErrorContains(t, vault.Set(Secret{Name: "EMPTY"}), "has no value")
+	require.NoError(t, vault.Set(Secret{Name: "API_KEY", Value: "value"}))
+
+	// A different key cannot decrypt the vault.
+	t.Setenv(KeyEnv, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	_, err = Open(path, keyPath)
+	assert.ErrorContains(t, err, "wrong key or corrupted file")
+
+	t.Setenv(KeyEnv, "short")
+	_, err = Open(path, keyPath)
+	assert.ErrorContains(t, err, "must be 32 bytes")
+
+	t.Setenv(KeyEnv, "")
+	require.NoError(t, os.Remove(keyPath))
+	_, err = Open(path, keyPath)
+	assert.ErrorContains(t, err, "vault key")
+}
+
+func TestVault_MovesLegacyKey(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	legacy := filepath.Join(dir, "secrets.key")
+	vault, err := Open(path, legacy)
+	require.NoError(t, err)
+	require.NoError(t, vault.Set(Secret{Name: "API_KEY", Value: "value"}))
+
+	keyPath := filepath.Join(dir, "config", "picoclaw", "secrets.key")
+	moved, err := Open(path, keyPath)
+	require.NoError(t, err)
+	s, ok := moved.Get("API_KEY")
+	require.True(t, ok)
+	assert.Equal(t, "value", s.Value)
+
+	_, err = os.Stat(legacy)
+	assert.True(t, os.IsNotExist(err), "legacy key should be removed")
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
@@ -12,11 +12,12 @@ import (
 )
 
 type Session struct {
-	Key      string              `json:"key"`
-	Messages []providers.Message `json:"messages"`
-	Summary  string              `json:"summary,omitempty"`
-	Created  time.Time           `json:"created"`
-	Updated  time.Time           `json:"updated"`
+	Key        string              `json:"key"`
+	Messages   []providers.Message `json:"messages"`
+	Summary    string              `json:"summary,omitempty"`
+	Created    time.Time           `json:"created"`
+	Updated    time.Time           `json:"updated"`
+	VoiceReply bool                `json:"voice_reply,omitempty"` // Speak replies (/voice)
 }
 
 type SessionManager struct {
@@ -122,6 +123,26 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	}
 }
 
+// SetVoiceReply turns voice replies on or off for key, creating the session
+// if needed.
+func (sm *SessionManager) SetVoiceReply(key string, on bool) {
+	session := sm.GetOrCreate(key)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	session.VoiceReply = on
+	session.Updated = time.Now()
+}
+
+// VoiceReply reports whether replies in key are spoken.
+func (sm *SessionManager) VoiceReply(key string) bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	return ok && session.VoiceReply
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -178,10 +199,11 @@ func (sm *SessionManager) Save(key string) error {
 	}
 
 	snapshot := Session{
-		Key:     stored.Key,
-		Summary: stored.Summary,
-		Created: stored.Created,
-		Updated: stored.Updated,
+		Key:        stored.Key,
+		Summary:    stored.Summary,
+		Created:    stored.Created,
+		Updated:    stored.Updated,
+		VoiceReply: stored.VoiceReply,
 	}
 	if len(stored.Messages) > 0 {
 		snapshot.Messages = make([]providers.Message, len(stored.Messages))
@@ -233,6 +255,26 @@ func (sm *SessionManager) Save(key string) error {
 	return nil
 }
 
+// Delete removes a session from memory and its file from storage.
+func (sm *SessionManager) Delete(key string) error {
+	sm.mu.Lock()
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+
+	if sm.storage == "" {
+		return nil
+	}
+	filename := sanitizeFilename(key)
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return os.ErrInvalid
+	}
+	err := os.Remove(filepath.Join(sm.storage, filename+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func (sm *SessionManager) loadSessions() error {
 	files, err := os.ReadDir(sm.storage)
 	if err != nil {
@@ -72,3 +72,52 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestVoiceReply_PersistsAcrossReload(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	key := "telegram:42"
+	if sm.VoiceReply(key) {
+		t.Fatal("expected voice replies off for a new session")
+	}
+	sm.SetVoiceReply(key, true)
+	if !sm.VoiceReply(key) {
+		t.Fatal("expected voice replies on after SetVoiceReply")
+	}
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save(%q) failed: %v", key, err)
+	}
+
+	sm2 := NewSessionManager(tmpDir)
+	if !sm2.VoiceReply(key) {
+		t.Fatal("expected voice replies to survive a reload")
+	}
+	sm2.SetVoiceReply(key, false)
+	if sm2.VoiceReply(key) {
+		t.Fatal("expected voice replies off after SetVoiceReply(false)")
+	}
+}
+
+func TestDelete_RemovesSessionAndFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	key := "agent:main:subagent-1"
+	sm.AddMessage(key, "user", "task")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := sm.Delete(key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if history := sm.GetHistory(key); len(history) != 0 {
+		t.Errorf("history after Delete = %v", history)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "agent_main_subagent-1.json")); !os.IsNotExist(err) {
+		t.Errorf("session file should be removed, stat error: %v", err)
+	}
+	if err := sm.Delete(key); err != nil {
+		t.Errorf("deleting a missing session: %v", err)
+	}
+}
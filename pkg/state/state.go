@@ -170,3 +170,9 @@ func (sm *Manager) load() error {
 
 	return nil
 }
+
+// CooldownPath returns the file where provider cooldown state for the
+// workspace is persisted, alongside state.json.
+func CooldownPath(workspace string) string {
+	return filepath.Join(workspace, "state", "provider_cooldowns.json")
+}
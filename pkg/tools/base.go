@@ -17,6 +17,29 @@ type ContextualTool interface {
 	SetContext(channel, chatID string)
 }
 
+// SessionTool is an optional interface for tools that keep per-turn state,
+// such as the message context or the plan session. ForSession returns a
+// copy of the tool for a turn of its own in sessionKey, e.g. a subagent
+// task, which then leaves the state of the agent's other turns alone.
+type SessionTool interface {
+	Tool
+	ForSession(sessionKey string) Tool
+}
+
+type sessionKeyKey struct{}
+
+// WithSessionKey returns a context for a turn in the session sessionKey.
+// Subagents started in the turn inherit it.
+func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyKey{}, sessionKey)
+}
+
+// SessionKey returns the session of the turn running under ctx, or "".
+func SessionKey(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyKey{}).(string)
+	return key
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"strings"
 )
 
-// EditFileTool edits a file by replacing old_text with new_text.
+// EditFileTool edits a file by replacing old_text with new_text, or by
+// replacing a range of lines addressed the same way as read_file.
 // The old_text must exist exactly in the file.
 type EditFileTool struct {
 	fs fileSystem
@@ -30,7 +32,9 @@ func (t *EditFileTool) Name() string {
 }
 
 func (t *EditFileTool) Description() string {
-	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file."
+	return "Edit a file by replacing old_text with new_text. The old_text must exist exactly in the file. " +
+		"Alternatively, give offset and limit (as in read_file) to replace that range of lines with new_text; " +
+		"limit 0 inserts new_text before line offset."
 }
 
 func (t *EditFileTool) Parameters() map[string]any {
@@ -42,15 +46,24 @@ func (t *EditFileTool) Parameters() map[string]any {
 				"description": "The file path to edit",
 			},
 			"old_text": map[string]any{
-				"type":        "string",
-				"description": "The exact text to find and replace",
+				"type": "string",
+				"description": "The exact text to find and replace. With offset, optionally the current " +
+					"content of the line range, to guard against editing the wrong lines",
 			},
 			"new_text": map[string]any{
 				"type":        "string",
 				"description": "The text to replace with",
 			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "First line to replace (1-based), instead of searching for old_text",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines to replace starting at offset (default 1)",
+			},
 		},
-		"required": []string{"path", "old_text", "new_text"},
+		"required": []string{"path", "new_text"},
 	}
 }
 
@@ -60,16 +73,25 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("path is required")
 	}
 
-	oldText, ok := args["old_text"].(string)
-	if !ok {
-		return ErrorResult("old_text is required")
-	}
+	oldText, hasOldText := args["old_text"].(string)
 
 	newText, ok := args["new_text"].(string)
 	if !ok {
 		return ErrorResult("new_text is required")
 	}
 
+	if _, ok := args["offset"]; ok {
+		offset := intArg(args, "offset", 0, math.MaxInt)
+		limit := intArg(args, "limit", 1, math.MaxInt)
+		if err := editFileLines(t.fs, path, offset, limit, oldText, newText); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("File edited: %s", path))
+	}
+	if !hasOldText {
+		return ErrorResult("old_text is required")
+	}
+
 	if err := editFile(t.fs, path, oldText, newText); err != nil {
 		return ErrorResult(err.Error())
 	}
@@ -148,6 +170,21 @@ func editFile(sysFs fileSystem, path, oldText, newText string) error {
 	return sysFs.WriteFile(path, newContent)
 }
 
+// editFileLines replaces limit lines starting at line offset with newText.
+func editFileLines(sysFs fileSystem, path string, offset, limit int, oldText, newText string) error {
+	content, err := sysFs.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	newContent, err := replaceLineRange(content, offset, limit, oldText, newText)
+	if err != nil {
+		return err
+	}
+
+	return sysFs.WriteFile(path, newContent)
+}
+
 // appendFile reads the existing content (if any) via sysFs, appends new content, and writes back.
 func appendFile(sysFs fileSystem, path, appendContent string) error {
 	content, err := sysFs.ReadFile(path)
@@ -175,3 +212,46 @@ func replaceEditContent(content []byte, oldText, newText string) ([]byte, error)
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
 	return []byte(newContent), nil
 }
+
+// replaceLineRange replaces lines [offset, offset+limit) of content with
+// newText, keeping the file's line endings. When oldText is given it must
+// match the lines being replaced.
+func replaceLineRange(content []byte, offset, limit int, oldText, newText string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if offset < 1 || offset > len(lines)+1 {
+		return nil, fmt.Errorf("offset %d is out of range: the file has %d lines", offset, len(lines))
+	}
+	end := offset - 1 + limit
+	if end > len(lines) {
+		return nil, fmt.Errorf("lines %d-%d are out of range: the file has %d lines", offset, end, len(lines))
+	}
+
+	replaced := strings.Join(lines[offset-1:end], "")
+	if oldText != "" && strings.TrimRight(replaced, "\r\n") != strings.TrimRight(oldText, "\r\n") {
+		return nil, fmt.Errorf("old_text does not match lines %d-%d. Read the file again with line_numbers "+
+			"to get the current line numbers", offset, end)
+	}
+
+	newline := "\n"
+	if len(lines) > 0 && strings.HasSuffix(lines[0], "\r\n") {
+		newline = "\r\n"
+	}
+	appendsToTerminatedFile := limit == 0 && end == len(lines) && end > 0 && strings.HasSuffix(lines[end-1], "\n")
+	if newText != "" && !strings.HasSuffix(newText, "\n") &&
+		(end < len(lines) || strings.HasSuffix(replaced, "\n") || appendsToTerminatedFile) {
+		newText += newline
+	}
+
+	var out strings.Builder
+	out.WriteString(strings.Join(lines[:offset-1], ""))
+	// An insertion after a last line without a newline needs one.
+	if offset-1 == len(lines) && offset > 1 && !strings.HasSuffix(lines[offset-2], "\n") && newText != "" {
+		out.WriteString(newline)
+	}
+	out.WriteString(newText)
+	out.WriteString(strings.Join(lines[end:], ""))
+	return []byte(out.String()), nil
+}
@@ -435,3 +435,46 @@ func TestEditFileTool_Restricted_FileNotFound(t *testing.T) {
 	assert.True(t, result.IsError)
 	assert.Contains(t, result.ForLLM, "not found")
 }
+
+func TestEditTool_EditFile_LineRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	tool := NewEditFileTool(tmpDir, true)
+
+	cases := []struct {
+		name    string
+		content string
+		args    map[string]any
+		want    string
+	}{
+		{"replace one line", "a\nb\nc\n", map[string]any{"offset": 2.0, "new_text": "B"}, "a\nB\nc\n"},
+		{"replace range", "a\nb\nc\nd\n", map[string]any{"offset": 2.0, "limit": 2.0, "new_text": "x\n"}, "a\nx\nd\n"},
+		{"delete lines", "a\nb\nc\n", map[string]any{"offset": 1.0, "limit": 2.0, "new_text": ""}, "c\n"},
+		{"insert", "a\nb\n", map[string]any{"offset": 2.0, "limit": 0.0, "new_text": "new"}, "a\nnew\nb\n"},
+		{"insert at end", "a\n", map[string]any{"offset": 2.0, "limit": 0.0, "new_text": "z"}, "a\nz\n"},
+		{"no final newline", "a\nb", map[string]any{"offset": 2.0, "new_text": "c"}, "a\nc"},
+		{"crlf", "a\r\nb\r\n", map[string]any{"offset": 1.0, "new_text": "x"}, "x\r\nb\r\n"},
+		{"guarded", "a\nb\n", map[string]any{"offset": 2.0, "old_text": "b", "new_text": "c"}, "a\nc\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.WriteFile(testFile, []byte(tc.content), 0o644)
+			tc.args["path"] = "test.txt"
+			result := tool.Execute(t.Context(), tc.args)
+			assert.False(t, result.IsError, result.ForLLM)
+			got, _ := os.ReadFile(testFile)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+
+	os.WriteFile(testFile, []byte("a\nb\n"), 0o644)
+	result := tool.Execute(t.Context(), map[string]any{"path": "test.txt", "offset": 5.0, "new_text": "x"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "out of range")
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"path": "test.txt", "offset": 1.0, "old_text": "b", "new_text": "x",
+	})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "does not match lines 1-1")
+}
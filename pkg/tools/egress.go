@@ -0,0 +1,358 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EgressPolicy selects the network destinations an HTTP tool may reach.
+// Allow and Deny entries are host names, which may start with "*." to
+// match subdomains, IP addresses or CIDR ranges.
+type EgressPolicy struct {
+	AllowPrivate bool     // Allow private, loopback and link-local addresses (not cloud metadata)
+	Allow        []string // Reachable even if they resolve to blocked addresses
+	Deny         []string // Never reachable; takes precedence over Allow
+}
+
+// EgressError reports a request blocked by an EgressGuard.
+type EgressError struct {
+	Host   string
+	Addr   netip.Addr // Invalid when the host name itself is denied
+	Reason string
+}
+
+func (e *EgressError) Error() string {
+	if !e.Addr.IsValid() || e.Host == e.Addr.String() {
+		return fmt.Sprintf("access to %s is blocked: %s", e.Host, e.Reason)
+	}
+	return fmt.Sprintf("access to %s (%s) is blocked: %s", e.Host, e.Addr, e.Reason)
+}
+
+// blockedRanges are not reachable unless AllowPrivate is set or an Allow
+// entry matches.
+var blockedRanges = []struct {
+	prefix netip.Prefix
+	reason string
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), "unspecified address"},
+	{netip.MustParsePrefix("10.0.0.0/8"), "private address"},
+	{netip.MustParsePrefix("100.64.0.0/10"), "shared address space"},
+	{netip.MustParsePrefix("127.0.0.0/8"), "loopback address"},
+	{netip.MustParsePrefix("169.254.0.0/16"), "link-local address"},
+	{netip.MustParsePrefix("172.16.0.0/12"), "private address"},
+	{netip.MustParsePrefix("192.0.0.0/24"), "reserved address"},
+	{netip.MustParsePrefix("192.168.0.0/16"), "private address"},
+	{netip.MustParsePrefix("198.18.0.0/15"), "benchmarking address"},
+	{netip.MustParsePrefix("224.0.0.0/4"), "multicast address"},
+	{netip.MustParsePrefix("240.0.0.0/4"), "reserved address"},
+	{netip.MustParsePrefix("::/128"), "unspecified address"},
+	{netip.MustParsePrefix("::1/128"), "loopback address"},
+	{netip.MustParsePrefix("fc00::/7"), "private address"},
+	{netip.MustParsePrefix("fe80::/10"), "link-local address"},
+	{netip.MustParsePrefix("ff00::/8"), "multicast address"},
+}
+
+// metadataAddrs and metadataHosts are the cloud instance metadata
+// endpoints, which hand out credentials. AllowPrivate does not open them.
+var (
+	metadataAddrs = []netip.Addr{
+		netip.MustParseAddr("169.254.169.254"), // AWS, GCP, Azure, OpenStack, ...
+		netip.MustParseAddr("169.254.170.2"),   // AWS ECS task credentials
+		netip.MustParseAddr("100.100.100.200"), // Alibaba Cloud
+		netip.MustParseAddr("fd00:ec2::254"),   // AWS IPv6
+	}
+	metadataHosts = []string{"metadata.google.internal", "metadata.goog"}
+)
+
+// nat64Prefix embeds IPv4 addresses, which are checked like plain IPv4.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// egressRule matches a host name pattern or an address range.
+type egressRule struct {
+	host   string // Lower case; "*.example.com" matches subdomains
+	prefix netip.Prefix
+}
+
+func parseEgressRules(entries []string) ([]egressRule, error) {
+	var rules []egressRule
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+			}
+			rules = append(rules, egressRule{prefix: prefix.Masked()})
+		default:
+			if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+				rules = append(rules, egressRule{prefix: netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())})
+				continue
+			}
+			rules = append(rules, egressRule{host: strings.TrimSuffix(entry, ".")})
+		}
+	}
+	return rules, nil
+}
+
+func matchHostRules(rules []egressRule, host string) bool {
+	for _, r := range rules {
+		if r.host == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(r.host, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == r.host {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAddrRules(rules []egressRule, addr netip.Addr) bool {
+	for _, r := range rules {
+		if r.prefix.IsValid() && r.prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// EgressGuard keeps HTTP clients from reaching internal networks. It
+// checks the addresses a host resolves to when connecting and dials the
+// checked address itself, so that a second DNS answer cannot point the
+// connection elsewhere (DNS rebinding). Redirects are checked the same way.
+type EgressGuard struct {
+	policy EgressPolicy
+	allow  []egressRule
+	deny   []egressRule
+
+	lookup func(ctx context.Context, network, host string) ([]netip.Addr, error)
+	dialer *net.Dialer
+
+	mu      sync.Mutex
+	proxies map[string]bool // Proxy addresses, which are dialed without checks
+}
+
+// NewEgressGuard creates a guard for policy.
+func NewEgressGuard(policy EgressPolicy) (*EgressGuard, error) {
+	allow, err := parseEgressRules(policy.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("egress allow list: %w", err)
+	}
+	deny, err := parseEgressRules(policy.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("egress deny list: %w", err)
+	}
+	return &EgressGuard{
+		policy:  policy,
+		allow:   allow,
+		deny:    deny,
+		lookup:  net.DefaultResolver.LookupNetIP,
+		dialer:  &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		proxies: make(map[string]bool),
+	}, nil
+}
+
+// defaultEgressGuard blocks private, loopback, link-local and metadata
+// addresses.
+func defaultEgressGuard() *EgressGuard {
+	guard, _ := NewEgressGuard(EgressPolicy{})
+	return guard
+}
+
+// checkHost checks a host name before it is resolved.
+func (g *EgressGuard) checkHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if matchHostRules(g.deny, host) {
+		return &EgressError{Host: host, Reason: "host is on the deny list"}
+	}
+	if !matchHostRules(g.allow, host) {
+		for _, m := range metadataHosts {
+			if host == m {
+				return &EgressError{Host: host, Reason: "cloud metadata endpoint"}
+			}
+		}
+	}
+	return nil
+}
+
+// checkAddr checks an address host resolved to.
+func (g *EgressGuard) checkAddr(host string, addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("") // zoned addresses never match a prefix
+	check := addr
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		check = netip.AddrFrom4([4]byte(b[12:]))
+	}
+	if matchAddrRules(g.deny, addr) || matchAddrRules(g.deny, check) {
+		return &EgressError{Host: host, Addr: addr, Reason: "address is on the deny list"}
+	}
+	if matchHostRules(g.allow, strings.ToLower(host)) || matchAddrRules(g.allow, addr) {
+		return nil
+	}
+	for _, m := range metadataAddrs {
+		if check == m {
+			return &EgressError{Host: host, Addr: addr, Reason: "cloud metadata endpoint"}
+		}
+	}
+	if g.policy.AllowPrivate {
+		return nil
+	}
+	for _, r := range blockedRanges {
+		if r.prefix.Contains(check) {
+			return &EgressError{Host: host, Addr: addr, Reason: r.reason}
+		}
+	}
+	return nil
+}
+
+// resolve returns the permitted addresses of host, or the reason the
+// first address was blocked if none is permitted.
+func (g *EgressGuard) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if err := g.checkHost(host); err != nil {
+		return nil, err
+	}
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		if addrs, err = g.lookup(ctx, "ip", host); err != nil {
+			return nil, err
+		}
+	}
+
+	var permitted []netip.Addr
+	var firstErr error
+	for _, addr := range addrs {
+		if err := g.checkAddr(host, addr); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		permitted = append(permitted, addr.Unmap())
+	}
+	if len(permitted) == 0 {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, firstErr
+	}
+	return permitted, nil
+}
+
+// DialContext connects to addr after checking where its host resolves,
+// dialing the checked address rather than resolving the name again.
+func (g *EgressGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if g.isProxy(addr) {
+		return g.dialer.DialContext(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialErr error
+	for _, ip := range addrs {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// CheckURL checks the scheme and host of a URL a tool is about to request.
+func (g *EgressGuard) CheckURL(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("only http/https URLs are allowed")
+	}
+	_, err := g.resolve(ctx, u.Hostname())
+	return err
+}
+
+// protect routes client's connections through the guard. Requests sent
+// through a proxy are checked before they are handed to it, since the
+// proxy connects to the target itself. The proxy resolves the host again,
+// so the address checked here is not pinned; a rebinding DNS server can
+// still reach private ranges unless the proxy blocks them too. A client
+// without an *http.Transport gets a clone of the default one.
+func (g *EgressGuard) protect(client *http.Client) {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		client.Transport = transport
+	}
+	transport.DialContext = g.DialContext
+
+	if proxy := transport.Proxy; proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			proxyURL, err := proxy(req)
+			if err != nil || proxyURL == nil {
+				return proxyURL, err
+			}
+			if err := g.CheckURL(req.Context(), req.URL); err != nil {
+				return nil, err
+			}
+			g.trustProxy(proxyURL)
+			return proxyURL, nil
+		}
+	}
+
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := g.checkHost(req.URL.Hostname()); err != nil {
+			return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), err)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to %s: only http/https URLs are allowed", req.URL.Redacted())
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+func (g *EgressGuard) trustProxy(proxyURL *url.URL) {
+	port := proxyURL.Port()
+	if port == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.proxies[net.JoinHostPort(proxyURL.Hostname(), port)] = true
+}
+
+func (g *EgressGuard) isProxy(addr string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.proxies[addr]
+}
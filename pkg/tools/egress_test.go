@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDNS makes guard resolve names from answers instead of DNS.
+func fakeDNS(guard *EgressGuard, answers map[string][]string) {
+	guard.lookup = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		var addrs []netip.Addr
+		for _, a := range answers[host] {
+			addrs = append(addrs, netip.MustParseAddr(a))
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no such host %s", host)
+		}
+		return addrs, nil
+	}
+}
+
+func TestEgressGuard_CheckAddr(t *testing.T) {
+	strict, err := NewEgressGuard(EgressPolicy{})
+	require.NoError(t, err)
+	private, err := NewEgressGuard(EgressPolicy{AllowPrivate: true})
+	require.NoError(t, err)
+	custom, err := NewEgressGuard(EgressPolicy{
+		Allow: []string{"10.1.0.0/16", "nas.home.arpa"},
+		Deny:  []string{"8.8.8.0/24", "10.1.2.3"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		guard   *EgressGuard
+		host    string
+		addr    string
+		blocked string
+	}{
+		{strict, "example.com", "93.184.216.34", ""},
+		{strict, "example.com", "2606:2800:220:1::1", ""},
+		{strict, "127.0.0.1", "127.0.0.1", "loopback address"},
+		{strict, "router", "192.168.1.1", "private address"},
+		{strict, "::ffff:10.0.0.1", "::ffff:10.0.0.1", "private address"},
+		{strict, "fe80::1%eth0", "fe80::1%eth0", "link-local address"},
+		{strict, "::1", "::1", "loopback address"},
+		{strict, "nat64", "64:ff9b::a9fe:a9fe", "cloud metadata endpoint"},
+		{strict, "169.254.169.254", "169.254.169.254", "cloud metadata endpoint"},
+		{private, "router", "192.168.1.1", ""},
+		{private, "169.254.169.254", "169.254.169.254", "cloud metadata endpoint"},
+		{private, "ecs", "169.254.170.2", "cloud metadata endpoint"},
+		{custom, "svc", "10.1.9.9", ""},
+		{custom, "svc", "10.1.2.3", "address is on the deny list"},
+		{custom, "svc", "10.2.0.1", "private address"},
+		{custom, "nas.home.arpa", "192.168.1.20", ""},
+		{custom, "dns.google", "8.8.8.8", "address is on the deny list"},
+	}
+	for _, tt := range tests {
+		err := tt.guard.checkAddr(tt.host, netip.MustParseAddr(tt.addr))
+		if tt.blocked == "" {
+			assert.NoError(t, err, "%s (%s)", tt.host, tt.addr)
+		} else {
+			assert.ErrorContains(t, err, tt.blocked, "%s (%s)", tt.host, tt.addr)
+		}
+	}
+}
+
+func TestEgressGuard_HostRules(t *testing.T) {
+	guard, err := NewEgressGuard(EgressPolicy{Deny: []string{"*.internal.example.com", "Evil.Example"}})
+	require.NoError(t, err)
+
+	assert.NoError(t, guard.checkHost("example.com"))
+	assert.NoError(t, guard.checkHost("internal.example.com"))
+	assert.ErrorContains(t, guard.checkHost("db.internal.example.com"), "deny list")
+	assert.ErrorContains(t, guard.checkHost("evil.example."), "deny list")
+	assert.ErrorContains(t, guard.checkHost("metadata.google.internal"), "cloud metadata endpoint")
+
+	_, err = NewEgressGuard(EgressPolicy{Allow: []string{"10.0.0.0/33"}})
+	assert.ErrorContains(t, err, "egress allow list")
+}
+
+func TestEgressGuard_DialsResolvedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	guard, err := NewEgressGuard(EgressPolicy{Allow: []string{"pinned.test"}})
+	require.NoError(t, err)
+	fakeDNS(guard, map[string][]string{
+		"pinned.test": {"127.0.0.1"},
+		"rebind.test": {"127.0.0.1"},
+		"mixed.test":  {"10.0.0.1", "127.0.0.1"},
+	})
+	client := &http.Client{Timeout: 5 * time.Second}
+	guard.protect(client)
+
+	// pinned.test only exists in the fake DNS, so the request can only
+	// succeed if the guard dials the address it checked.
+	resp, err := client.Get("http://pinned.test:" + port + "/")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "ok", string(body))
+
+	for _, host := range []string{"rebind.test", "mixed.test", "127.0.0.1"} {
+		_, err = client.Get("http://" + host + ":" + port + "/")
+		var blocked *EgressError
+		require.True(t, errors.As(err, &blocked), "%s: %v", host, err)
+	}
+}
+
+func TestEgressGuard_ChecksRedirects(t *testing.T) {
+	var target string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/secret" {
+			fmt.Fprint(w, "secret")
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	guard, err := NewEgressGuard(EgressPolicy{Allow: []string{"public.test"}})
+	require.NoError(t, err)
+	fakeDNS(guard, map[string][]string{"public.test": {"127.0.0.1"}})
+	tool := NewWebFetchTool(1000)
+	tool.SetEgressGuard(guard)
+
+	target = "http://public.test:" + port + "/secret"
+	result := tool.Execute(t.Context(), map[string]any{"url": "http://public.test:" + port + "/"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForUser, "secret")
+
+	target = "http://127.0.0.1:" + port + "/secret"
+	result = tool.Execute(t.Context(), map[string]any{"url": "http://public.test:" + port + "/"})
+	require.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "access to 127.0.0.1 is blocked: loopback address")
+
+	target = "http://metadata.google.internal/computeMetadata/v1/"
+	result = tool.Execute(t.Context(), map[string]any{"url": "http://public.test:" + port + "/"})
+	require.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "cloud metadata endpoint")
+}
+
+func TestEgressGuard_ChecksTargetBeforeProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		fmt.Fprint(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	guard, err := NewEgressGuard(EgressPolicy{})
+	require.NoError(t, err)
+	fakeDNS(guard, map[string][]string{
+		"public.test":   {"93.184.216.34"},
+		"internal.test": {"10.0.0.5"},
+	})
+	client, err := createHTTPClient(proxy.URL, 5*time.Second)
+	require.NoError(t, err)
+	guard.protect(client)
+
+	// The proxy itself listens on loopback, which is fine: it was
+	// configured by the operator, not chosen by the model.
+	resp, err := client.Get("http://public.test/page")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{"public.test"}, proxied)
+
+	_, err = client.Get("http://internal.test/")
+	var blocked *EgressError
+	require.True(t, errors.As(err, &blocked), "%v", err)
+	assert.Equal(t, []string{"public.test"}, proxied, "blocked requests must not reach the proxy")
+
+	u, _ := url.Parse("ftp://public.test/")
+	assert.ErrorContains(t, guard.CheckURL(t.Context(), u), "only http/https")
+}
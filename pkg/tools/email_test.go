@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/email"
+	"github.com/sipeed/picoclaw/pkg/email/emailtest"
+)
+
+const testInvoiceEmail = "From: Carol <carol@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Invoice October\r\n" +
+	"Date: Wed, 14 Oct 2026 15:00:00 +0000\r\n" +
+	"Message-ID: <inv-10@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=b1\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<html><body><p>Please find the invoice attached.</p></body></html>\r\n" +
+	"--b1\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"../../invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--b1--\r\n"
+
+const testNewsletterEmail = "From: news@example.org\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Weekly digest\r\n" +
+	"Date: Mon, 12 Oct 2026 08:00:00 +0000\r\n" +
+	"\r\n" +
+	"Nothing new this week.\r\n"
+
+func newTestEmailAccount(t *testing.T) (*email.Account, *emailtest.IMAPServer, *emailtest.SMTPServer) {
+	t.Helper()
+	imapServer, err := emailtest.NewIMAPServer("bob@example.com", "app-password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(imapServer.Close)
+	smtpServer, err := emailtest.NewSMTPServer("bob@example.com", "app-password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(smtpServer.Close)
+	account := &email.Account{
+		Name:     "work",
+		Address:  "bob@example.com",
+		Password: "app-password",
+		IMAP:     imapServer.Server(),
+		SMTP:     smtpServer.Server(),
+	}
+	return account, imapServer, smtpServer
+}
+
+func TestEmailSearchTool(t *testing.T) {
+	account, srv, _ := newTestEmailAccount(t)
+	srv.Append("INBOX", []byte(testNewsletterEmail), `\Seen`)
+	srv.Append("INBOX", []byte(testInvoiceEmail))
+	tool := NewEmailSearchTool([]*email.Account{account})
+
+	result := tool.Execute(context.Background(), map[string]any{})
+	if result.IsError {
+		t.Fatalf("search failed: %s", result.ForLLM)
+	}
+	lines := strings.Split(result.ForLLM, "\n")
+	if !strings.HasPrefix(result.ForLLM, "2 messages in INBOX match.") || len(lines) != 4 {
+		t.Fatalf("unexpected listing:\n%s", result.ForLLM)
+	}
+	if !strings.Contains(lines[2], "[uid 2]") || !strings.Contains(lines[2], "Invoice October (unread, attachments)") {
+		t.Errorf("newest message should come first, unread with attachments: %q", lines[2])
+	}
+	if strings.Contains(lines[3], "unread") {
+		t.Errorf("seen message listed as unread: %q", lines[3])
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"unread": true, "since": "2026-10-13"})
+	if result.IsError || !strings.Contains(result.ForLLM, "1 messages") || !strings.Contains(result.ForLLM, "Invoice") {
+		t.Errorf("filtered search:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"since": "13/10/2026"})
+	if !result.IsError {
+		t.Error("expected an error for a malformed date")
+	}
+	result = tool.Execute(context.Background(), map[string]any{"account": "personal"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "available: work") {
+		t.Errorf("unknown account: %s", result.ForLLM)
+	}
+}
+
+func TestEmailReadTool_SavesAttachments(t *testing.T) {
+	account, srv, _ := newTestEmailAccount(t)
+	uid := srv.Append("INBOX", []byte(testInvoiceEmail))
+	workspace := t.TempDir()
+	tool := NewEmailReadTool([]*email.Account{account}, workspace, true, "")
+
+	result := tool.Execute(context.Background(), map[string]any{"uid": float64(uid)})
+	if result.IsError {
+		t.Fatalf("read failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{
+		"From: Carol <carol@example.com>",
+		"Subject: Invoice October",
+		"Message-ID: <inv-10@example.com>",
+		"Please find the invoice attached.",
+		"invoice.pdf (application/pdf, 9 bytes) saved to " + filepath.Join("attachments", "work", "1", "invoice.pdf"),
+	} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("result missing %q:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Contains(result.ForLLM, "<p>") {
+		t.Error("HTML body was not converted to text")
+	}
+
+	data, err := os.ReadFile(filepath.Join(workspace, "attachments", "work", "1", "invoice.pdf"))
+	if err != nil || string(data) != "%PDF-1.4\n" {
+		t.Errorf("saved attachment = %q, %v", data, err)
+	}
+	if flags := srv.Flags("INBOX", uid); len(flags) != 0 {
+		t.Errorf("reading marked the message: %v", flags)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"uid": float64(99)})
+	if !result.IsError {
+		t.Error("expected an error for a missing message")
+	}
+}
+
+func TestEmailSendTool(t *testing.T) {
+	account, _, smtpServer := newTestEmailAccount(t)
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "report.csv"), []byte("a,b\n1,2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewEmailSendTool([]*email.Account{account}, workspace, true)
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"to":          []any{"Carol <carol@example.com>"},
+		"subject":     "Re: Invoice October",
+		"body":        "Paid, thanks.",
+		"attachments": []any{"report.csv"},
+		"in_reply_to": "<inv-10@example.com>",
+	})
+	if result.IsError {
+		t.Fatalf("send failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Email sent from bob@example.com to Carol <carol@example.com>") {
+		t.Errorf("result = %s", result.ForLLM)
+	}
+
+	deliveries := smtpServer.Deliveries()
+	if len(deliveries) != 1 || deliveries[0].To[0] != "carol@example.com" {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+	msg, err := email.ParseMessage(deliveries[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.InReplyTo != "<inv-10@example.com>" || len(msg.Attachments) != 1 || msg.Attachments[0].Filename != "report.csv" {
+		t.Errorf("sent message = %+v", msg)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{
+		"to":          "carol@example.com",
+		"subject":     "secrets",
+		"body":        "see attached",
+		"attachments": []any{"/etc/passwd"},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("attachment outside the workspace: %s", result.ForLLM)
+	}
+	if len(smtpServer.Deliveries()) != 1 {
+		t.Error("message with a rejected attachment was sent")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -100,7 +101,8 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read the contents of a file. Large files are returned in pages of lines; use offset and limit " +
+		"to read further, or tail for the end of a log. Binary files are summarized with a hexdump."
 }
 
 func (t *ReadFileTool) Parameters() map[string]any {
@@ -111,6 +113,22 @@ func (t *ReadFileTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Line number to start reading from (1-based, default 1)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of lines to read (default %d)", defaultReadLines),
+			},
+			"tail": map[string]any{
+				"type":        "integer",
+				"description": "Read the last N lines instead of starting at offset",
+			},
+			"line_numbers": map[string]any{
+				"type":        "boolean",
+				"description": "Prefix each line with its line number, for use with edit_file's offset and limit",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -122,11 +140,18 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("path is required")
 	}
 
-	content, err := t.fs.ReadFile(path)
+	opts := readOptions{
+		offset: intArg(args, "offset", 1, math.MaxInt),
+		limit:  intArg(args, "limit", 0, math.MaxInt),
+		tail:   intArg(args, "tail", 0, math.MaxInt),
+	}
+	opts.lineNumbers, _ = args["line_numbers"].(bool)
+
+	content, err := readFileContent(t.fs, path, opts)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
-	return NewToolResult(string(content))
+	return NewToolResult(content)
 }
 
 type WriteFileTool struct {
@@ -250,8 +275,16 @@ func formatDirEntries(entries []os.DirEntry) *ToolResult {
 // unrestricted (host filesystem) and sandbox (os.Root) implementations to share the same polymorphic interface.
 type fileSystem interface {
 	ReadFile(path string) ([]byte, error)
+	// Open opens a file for streaming reads; the caller closes it.
+	Open(path string) (fs.File, error)
 	WriteFile(path string, data []byte) error
+	// Chmod sets the permission bits of path; WriteFile creates files 0644.
+	Chmod(path string, mode fs.FileMode) error
+	Remove(path string) error
 	ReadDir(path string) ([]os.DirEntry, error)
+	// WithFS calls fn with a read-only view of the filesystem and the
+	// location of path within it, for tools that walk directory trees.
+	WithFS(path string, fn func(fsys fs.FS, dir string) error) error
 }
 
 // hostFs is an unrestricted fileReadWriter that operates directly on the host filesystem.
@@ -271,10 +304,43 @@ func (h *hostFs) ReadFile(path string) ([]byte, error) {
 	return content, nil
 }
 
+func (h *hostFs) Open(path string) (fs.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, readError(err)
+	}
+	return f, nil
+}
+
+func (h *hostFs) Chmod(path string, mode fs.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to change file mode: %w", err)
+	}
+	return nil
+}
+
+func (h *hostFs) Remove(path string) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove file: %w", err)
+	}
+	return nil
+}
+
 func (h *hostFs) ReadDir(path string) ([]os.DirEntry, error) {
 	return os.ReadDir(path)
 }
 
+func (h *hostFs) WithFS(path string, fn func(fsys fs.FS, dir string) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fn(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	}
+	return fn(os.DirFS(path), ".")
+}
+
 func (h *hostFs) WriteFile(path string, data []byte) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -326,15 +392,7 @@ func (r *sandboxFs) ReadFile(path string) ([]byte, error) {
 	err := r.execute(path, func(root *os.Root, relPath string) error {
 		fileContent, err := root.ReadFile(relPath)
 		if err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("failed to read file: file not found: %w", err)
-			}
-			// os.Root returns "escapes from parent" for paths outside the root
-			if os.IsPermission(err) || strings.Contains(err.Error(), "escapes from parent") ||
-				strings.Contains(err.Error(), "permission denied") {
-				return fmt.Errorf("failed to read file: access denied: %w", err)
-			}
-			return fmt.Errorf("failed to read file: %w", err)
+			return readError(err)
 		}
 		content = fileContent
 		return nil
@@ -342,6 +400,33 @@ func (r *sandboxFs) ReadFile(path string) ([]byte, error) {
 	return content, err
 }
 
+func (r *sandboxFs) Open(path string) (fs.File, error) {
+	var file fs.File
+	err := r.execute(path, func(root *os.Root, relPath string) error {
+		// Files opened through the root stay usable after it is closed.
+		f, err := root.Open(relPath)
+		if err != nil {
+			return readError(err)
+		}
+		file = f
+		return nil
+	})
+	return file, err
+}
+
+// readError describes a failure to open or read a file.
+func readError(err error) error {
+	if os.IsNotExist(err) {
+		return fmt.Errorf("failed to read file: file not found: %w", err)
+	}
+	// os.Root returns "escapes from parent" for paths outside the root
+	if os.IsPermission(err) || strings.Contains(err.Error(), "escapes from parent") ||
+		strings.Contains(err.Error(), "permission denied") {
+		return fmt.Errorf("failed to read file: access denied: %w", err)
+	}
+	return fmt.Errorf("failed to read file: %w", err)
+}
+
 func (r *sandboxFs) WriteFile(path string, data []byte) error {
 	return r.execute(path, func(root *os.Root, relPath string) error {
 		dir := filepath.Dir(relPath)
@@ -369,6 +454,24 @@ func (r *sandboxFs) WriteFile(path string, data []byte) error {
 	})
 }
 
+func (r *sandboxFs) Chmod(path string, mode fs.FileMode) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		if err := root.Chmod(relPath, mode); err != nil {
+			return fmt.Errorf("failed to change file mode: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *sandboxFs) Remove(path string) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		if err := root.Remove(relPath); err != nil {
+			return fmt.Errorf("failed to remove file: %w", err)
+		}
+		return nil
+	})
+}
+
 func (r *sandboxFs) ReadDir(path string) ([]os.DirEntry, error) {
 	var entries []os.DirEntry
 	err := r.execute(path, func(root *os.Root, relPath string) error {
@@ -382,6 +485,12 @@ func (r *sandboxFs) ReadDir(path string) ([]os.DirEntry, error) {
 	return entries, err
 }
 
+func (r *sandboxFs) WithFS(path string, fn func(fsys fs.FS, dir string) error) error {
+	return r.execute(path, func(root *os.Root, relPath string) error {
+		return fn(root.FS(), filepath.ToSlash(relPath))
+	})
+}
+
 // Helper to get a safe relative path for os.Root usage
 func getSafeRelPath(workspace, path string) (string, error) {
 	if workspace == "" {
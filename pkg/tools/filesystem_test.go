@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -486,3 +487,74 @@ func TestRootRW_Write(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, newData, content)
 }
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func TestFilesystemTool_ReadFile_Paging(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "big.log"), []byte(numberedLines(5000)), 0o644)
+	tool := NewReadFileTool(workspace, true)
+
+	result := tool.Execute(t.Context(), map[string]any{"path": "big.log"})
+	assert.False(t, result.IsError, result.ForLLM)
+	assert.True(t, strings.HasPrefix(result.ForLLM, "line 1\n"))
+	assert.Contains(t, result.ForLLM, "line 2000\n")
+	assert.NotContains(t, result.ForLLM, "line 2001\n")
+	assert.Contains(t, result.ForLLM, "[Showing lines 1-2000 of 5000. Truncated, 3000 more lines; continue with offset=2001.]")
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"path": "big.log", "offset": 10.0, "limit": 2.0, "line_numbers": true,
+	})
+	assert.True(t, strings.HasPrefix(result.ForLLM, "    10\tline 10\n    11\tline 11\n\n[Showing lines 10-11 of 5000."),
+		result.ForLLM)
+
+	result = tool.Execute(t.Context(), map[string]any{"path": "big.log", "tail": 2.0})
+	assert.True(t, strings.HasPrefix(result.ForLLM, "line 4999\nline 5000\n\n[Showing lines 4999-5000 of 5000."),
+		result.ForLLM)
+
+	result = tool.Execute(t.Context(), map[string]any{"path": "big.log", "offset": 6000.0})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "past the end of the file (5000 lines)")
+}
+
+func TestFilesystemTool_ReadFile_ByteCap(t *testing.T) {
+	workspace := t.TempDir()
+	long := strings.Repeat("x", 5000)
+	var content strings.Builder
+	for range 100 {
+		content.WriteString(long + "\n")
+	}
+	os.WriteFile(filepath.Join(workspace, "wide.txt"), []byte(content.String()), 0o644)
+
+	result := NewReadFileTool(workspace, true).Execute(t.Context(), map[string]any{"path": "wide.txt"})
+	assert.Contains(t, result.ForLLM, "... [line truncated, 3000 more bytes]")
+	assert.Contains(t, result.ForLLM, "output capped at 100 KB")
+	assert.LessOrEqual(t, len(result.ForLLM), maxReadBytes+1000)
+}
+
+func TestFilesystemTool_ReadFile_Encodings(t *testing.T) {
+	workspace := t.TempDir()
+	utf16le := []byte{0xFF, 0xFE, 'h', 0, 'i', 0, '\n', 0, 0xE9, 0}
+	os.WriteFile(filepath.Join(workspace, "utf16.txt"), utf16le, 0o644)
+	os.WriteFile(filepath.Join(workspace, "latin1.txt"), []byte("caf\xe9\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "image.png"), append([]byte("\x89PNG\r\n\x1a\n\x00\x00"), 1, 2, 3), 0o644)
+	tool := NewReadFileTool(workspace, true)
+
+	result := tool.Execute(t.Context(), map[string]any{"path": "utf16.txt"})
+	assert.Equal(t, "[Decoded from UTF-16LE]\nhi\né\n", result.ForLLM)
+
+	result = tool.Execute(t.Context(), map[string]any{"path": "latin1.txt"})
+	assert.Equal(t, "[Decoded from Latin-1]\ncafé\n", result.ForLLM)
+
+	result = tool.Execute(t.Context(), map[string]any{"path": "image.png"})
+	assert.False(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "Binary file: image.png")
+	assert.Contains(t, result.ForLLM, "Type: image/png")
+	assert.Contains(t, result.ForLLM, "00000000  89 50 4e 47")
+}
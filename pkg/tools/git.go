@@ -0,0 +1,641 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gitTimeout         = 60 * time.Second
+	defaultGitMaxBytes = 30000
+	maxGitMaxBytes     = 200000
+
+	// gitApprovalTTL is how long a commit or checkout waits for /approve.
+	gitApprovalTTL = 10 * time.Minute
+)
+
+// gitRevPattern accepts revisions and ranges such as HEAD~3, v1.2.0,
+// origin/main, main..feature and HEAD@{2}. The leading character rules out
+// options.
+var gitRevPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./@{}~^:+-]*$`)
+
+// gitBranchPattern accepts names for new branches.
+var gitBranchPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*$`)
+
+var (
+	gitReadActions  = []string{"status", "log", "diff", "show", "blame", "branches"}
+	gitWriteActions = []string{"commit", "checkout"}
+)
+
+// gitRepo is a repository the git tool may inspect.
+type gitRepo struct {
+	name string
+	path string
+}
+
+// gitPending is a commit or checkout waiting for the user's approval.
+type gitPending struct {
+	session string // Session whose turn queued the change; only it may approve
+	repo    gitRepo
+	action  string
+	steps   [][]string
+	expires time.Time
+}
+
+// GitTool inspects configured local git repositories with a fixed set of
+// actions. Each action runs git with an argument list built here, never
+// through a shell. Commit and checkout are only offered when enabled, and
+// the tool only queues them: they run when the user sends /approve with
+// the code the tool handed out, in the same session, which the agent loop
+// passes to Approve.
+type GitTool struct {
+	repos      []gitRepo
+	allowWrite bool
+	timeout    time.Duration
+
+	mu      sync.Mutex
+	pending map[string]gitPending
+}
+
+// NewGitTool creates the tool for the given repository paths. A leading
+// "~" is expanded. Repositories are named after their directory, or by
+// path when two directories share a name. Repositories inside workspace
+// are refused: the agent can write their config, and git runs programs
+// named there outside the exec sandbox.
+func NewGitTool(workspace string, paths []string, allowWrite bool) (*GitTool, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no git repositories configured")
+	}
+	home, _ := os.UserHomeDir()
+	t := &GitTool{allowWrite: allowWrite, timeout: gitTimeout, pending: make(map[string]gitPending)}
+	names := make(map[string]int)
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			p = filepath.Join(home, p[1:])
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("git repo %s: %w", p, err)
+		}
+		if workspace != "" && inWorkspace(abs, workspace) {
+			return nil, fmt.Errorf("git repo %s is inside the workspace %s, where the agent could change "+
+				"its config; move it out or leave it to the exec tool", abs, workspace)
+		}
+		t.repos = append(t.repos, gitRepo{name: filepath.Base(abs), path: abs})
+		names[filepath.Base(abs)]++
+	}
+	for i := range t.repos {
+		if names[t.repos[i].name] > 1 {
+			t.repos[i].name = t.repos[i].path
+		}
+	}
+	return t, nil
+}
+
+func (t *GitTool) Name() string {
+	return "git"
+}
+
+func (t *GitTool) Description() string {
+	names := make([]string, len(t.repos))
+	for i, r := range t.repos {
+		names[i] = r.name
+	}
+	desc := "Inspect local git repositories: status, log (filter by author, date and path), " +
+		"diff and show (stat or patch), blame and branches. Repositories: " + strings.Join(names, ", ") + "."
+	if t.allowWrite {
+		desc += " commit and checkout change the repository; they wait until the user approves them with /approve."
+	}
+	return desc
+}
+
+func (t *GitTool) Parameters() map[string]any {
+	actions := gitReadActions
+	if t.allowWrite {
+		actions = slices.Concat(gitReadActions, gitWriteActions)
+	}
+	props := map[string]any{
+		"action": map[string]any{
+			"type":        "string",
+			"enum":        actions,
+			"description": "What to do",
+		},
+		"repo": map[string]any{
+			"type":        "string",
+			"description": "Repository name or path (default: the first one, " + t.repos[0].name + ")",
+		},
+		"rev": map[string]any{
+			"type": "string",
+			"description": "Revision or range: log starting point, diff against (e.g. HEAD~3, main..feature), " +
+				"show target (default HEAD), blame revision",
+		},
+		"path": map[string]any{
+			"type":        "string",
+			"description": "Limit to this file or directory, relative to the repository root (required for blame)",
+		},
+		"author": map[string]any{
+			"type":        "string",
+			"description": "log: only commits whose author matches this text",
+		},
+		"since": map[string]any{
+			"type":        "string",
+			"description": "log: only commits after this date, e.g. 2026-01-31 or \"1 week ago\"",
+		},
+		"until": map[string]any{
+			"type":        "string",
+			"description": "log: only commits before this date",
+		},
+		"limit": map[string]any{
+			"type":        "integer",
+			"description": "log: maximum number of commits (default 20, max 200)",
+		},
+		"format": map[string]any{
+			"type":        "string",
+			"enum":        []string{"stat", "patch"},
+			"description": "diff and show: file summary (default) or full patch",
+		},
+		"staged": map[string]any{
+			"type":        "boolean",
+			"description": "diff: compare the index with HEAD instead of the working tree with the index",
+		},
+		"start_line": map[string]any{
+			"type":        "integer",
+			"description": "blame: first line",
+		},
+		"end_line": map[string]any{
+			"type":        "integer",
+			"description": "blame: last line",
+		},
+		"max_bytes": map[string]any{
+			"type": "integer",
+			"description": fmt.Sprintf("Truncate output after this many bytes (default %d, max %d)",
+				defaultGitMaxBytes, maxGitMaxBytes),
+		},
+	}
+	if t.allowWrite {
+		props["message"] = map[string]any{
+			"type":        "string",
+			"description": "commit: commit message",
+		}
+		props["paths"] = map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "commit: files to stage first; without it, only what is already staged is committed",
+		}
+		props["branch"] = map[string]any{
+			"type":        "string",
+			"description": "checkout: branch to switch to",
+		}
+		props["create"] = map[string]any{
+			"type":        "boolean",
+			"description": "checkout: create the branch from the current commit",
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   []string{"action"},
+	}
+}
+
+func (t *GitTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	repo, err := t.repo(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var gitArgs [][]string
+	switch action {
+	case "status":
+		gitArgs = [][]string{{"status", "--short", "--branch"}}
+	case "log":
+		gitArgs, err = gitLogArgs(args)
+	case "diff":
+		gitArgs, err = gitDiffArgs(args)
+	case "show":
+		gitArgs, err = gitShowArgs(args)
+	case "blame":
+		gitArgs, err = gitBlameArgs(args)
+	case "branches":
+		gitArgs = [][]string{{"branch", "--all", "--verbose", "--no-color"}}
+	case "commit", "checkout":
+		if !t.allowWrite {
+			return ErrorResult(fmt.Sprintf("git %s is disabled; the tool is read-only (tools.git.allow_write)", action))
+		}
+		if action == "commit" {
+			gitArgs, err = gitCommitArgs(args)
+		} else {
+			gitArgs, err = gitCheckoutArgs(args)
+		}
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		session := SessionKey(ctx)
+		if session == "" {
+			return ErrorResult(fmt.Sprintf("git %s needs a chat session in which the user can approve it", action))
+		}
+		code := t.queue(gitPending{session: session, repo: repo, action: action, steps: gitArgs})
+		return SilentResult(fmt.Sprintf("git %s in %s is waiting for the user's approval:\n%s\n"+
+			"Describe the change to the user and tell them to send /approve %s to run it.",
+			action, repo.name, gitCommandLines(gitArgs), code))
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q", action))
+	}
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	output, err := t.run(ctx, repo, gitArgs)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	maxBytes := intArg(args, "max_bytes", defaultGitMaxBytes, maxGitMaxBytes)
+	if maxBytes <= 0 {
+		maxBytes = defaultGitMaxBytes
+	}
+	if len(output) > maxBytes {
+		output = output[:maxBytes] + fmt.Sprintf("\n... (truncated, %d more bytes; narrow with path or rev, "+
+			"or use format: stat)", len(output)-maxBytes)
+	}
+	return SilentResult(fmt.Sprintf("[%s] git %s\n%s", repo.name, action, output))
+}
+
+// Pending describes the commits and checkouts queued in session that
+// wait for approval, or returns "" when there are none.
+func (t *GitTool) Pending(session string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+	codes := make([]string, 0, len(t.pending))
+	for code, p := range t.pending {
+		if p.session == session {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	var sb strings.Builder
+	for _, code := range codes {
+		p := t.pending[code]
+		fmt.Fprintf(&sb, "%s: %s in %s\n%s\n", code, p.action, p.repo.name, gitCommandLines(p.steps))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Approve runs the commit or checkout queued in session under code and
+// returns git's output. Each code can be used once.
+func (t *GitTool) Approve(ctx context.Context, session, code string) (string, error) {
+	t.mu.Lock()
+	t.expire()
+	p, ok := t.pending[code]
+	ok = ok && p.session == session
+	if ok {
+		delete(t.pending, code)
+	}
+	t.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no pending git change %q (changes expire after %s)", code, gitApprovalTTL)
+	}
+	output, err := t.run(ctx, p.repo, p.steps)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[%s] git %s\n%s", p.repo.name, p.action, output), nil
+}
+
+// queue stores p until it is approved or expires and returns its code.
+func (t *GitTool) queue(p gitPending) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	code := hex.EncodeToString(b)
+	p.expires = time.Now().Add(gitApprovalTTL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+	t.pending[code] = p
+	return code
+}
+
+// expire drops pending changes that were not approved in time. t.mu must
+// be held.
+func (t *GitTool) expire() {
+	now := time.Now()
+	for code, p := range t.pending {
+		if now.After(p.expires) {
+			delete(t.pending, code)
+		}
+	}
+}
+
+// run runs the git invocations in steps and returns the output of the
+// last one.
+func (t *GitTool) run(ctx context.Context, repo gitRepo, steps [][]string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	var output string
+	for _, a := range steps {
+		out, err := runGit(ctx, repo.path, a)
+		if err != nil {
+			return "", fmt.Errorf("git %s in %s: %w", a[0], repo.name, err)
+		}
+		output = out
+	}
+	if strings.TrimSpace(output) == "" {
+		output = "(no output)"
+	}
+	return output, nil
+}
+
+// gitCommandLines renders steps as git command lines, one per line, for
+// the user to review.
+func gitCommandLines(steps [][]string) string {
+	lines := make([]string, len(steps))
+	for i, a := range steps {
+		lines[i] = "  git " + strings.Join(a, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (t *GitTool) repo(args map[string]any) (gitRepo, error) {
+	name, _ := args["repo"].(string)
+	if name == "" {
+		return t.repos[0], nil
+	}
+	for _, r := range t.repos {
+		if r.name == name || r.path == name || filepath.Clean(name) == r.path {
+			return r, nil
+		}
+	}
+	names := make([]string, len(t.repos))
+	for i, r := range t.repos {
+		names[i] = r.name
+	}
+	return gitRepo{}, fmt.Errorf("unknown repository %q (configured: %s)", name, strings.Join(names, ", "))
+}
+
+// inWorkspace reports whether path is in workspace, before or after
+// resolving symlinks.
+func inWorkspace(path, workspace string) bool {
+	workspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return false
+	}
+	if isWithinWorkspace(path, workspace) {
+		return true
+	}
+	realPath, err1 := filepath.EvalSymlinks(path)
+	realWorkspace, err2 := filepath.EvalSymlinks(workspace)
+	return err1 == nil && err2 == nil && isWithinWorkspace(realPath, realWorkspace)
+}
+
+// runGit runs git in dir without a pager, prompts, optional locks, hooks,
+// signing, or helper programs that a repository's config could name.
+// Filter drivers are switched off one by one, since git has no option
+// that ignores them all.
+func runGit(ctx context.Context, dir string, args []string) (string, error) {
+	filters, err := gitFilterDrivers(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	full := []string{
+		"-C", dir,
+		"--no-pager",
+		"-c", "core.fsmonitor=false",
+		"-c", "core.hooksPath=/dev/null",
+		"-c", "core.pager=cat",
+		"-c", "color.ui=false",
+		"-c", "diff.external=",
+		"-c", "commit.gpgSign=false",
+		"-c", "log.showSignature=false",
+		"-c", "gc.auto=0",
+		"-c", "maintenance.auto=false",
+	}
+	for _, name := range filters {
+		full = append(full,
+			"-c", "filter."+name+".clean=",
+			"-c", "filter."+name+".smudge=",
+			"-c", "filter."+name+".process=",
+			"-c", "filter."+name+".required=false")
+	}
+	return execGit(ctx, append(full, args...))
+}
+
+// gitFilterDrivers returns the names of the filter drivers configured for
+// the repository in dir. Reading config does not run any of them.
+func gitFilterDrivers(ctx context.Context, dir string) ([]string, error) {
+	out, err := execGit(ctx, []string{"-C", dir, "config", "--null", "--name-only", "--get-regexp", `^filter\.`})
+	if err != nil {
+		// config exits 1 when no key matches.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, key := range strings.Split(out, "\x00") {
+		rest, ok := strings.CutPrefix(key, "filter.")
+		i := strings.LastIndexByte(rest, '.')
+		if !ok || i <= 0 {
+			continue
+		}
+		if name := rest[:i]; !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func execGit(ctx context.Context, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_OPTIONAL_LOCKS=0", "GIT_PAGER=cat", "LC_ALL=C")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("git is not installed")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func gitRevArg(args map[string]any) (string, error) {
+	rev, _ := args["rev"].(string)
+	if rev != "" && !gitRevPattern.MatchString(rev) {
+		return "", fmt.Errorf("invalid rev %q", rev)
+	}
+	return rev, nil
+}
+
+// gitPathArg returns the path argument, which must stay inside the
+// repository.
+func gitPathArg(args map[string]any, key string) (string, error) {
+	p, _ := args[key].(string)
+	if p == "" {
+		return "", nil
+	}
+	clean := filepath.ToSlash(filepath.Clean(p))
+	if filepath.IsAbs(p) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("path %q must be relative to the repository root", p)
+	}
+	return clean, nil
+}
+
+// gitTextArg returns a free-text filter, which is passed as part of a
+// single --option=value argument.
+func gitTextArg(args map[string]any, key string) (string, error) {
+	s, _ := args[key].(string)
+	if strings.ContainsAny(s, "\x00\n") {
+		return "", fmt.Errorf("invalid %s", key)
+	}
+	return s, nil
+}
+
+func gitLogArgs(args map[string]any) ([][]string, error) {
+	a := []string{
+		"log", "--no-decorate", "--date=format:%Y-%m-%d %H:%M",
+		"--format=%h %ad %an: %s", fmt.Sprintf("-n%d", intArg(args, "limit", 20, 200)),
+	}
+	for _, key := range []string{"author", "since", "until"} {
+		v, err := gitTextArg(args, key)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			a = append(a, "--"+key+"="+v)
+		}
+	}
+	rev, err := gitRevArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if rev != "" {
+		a = append(a, rev)
+	}
+	return gitWithPath(a, args)
+}
+
+func gitDiffArgs(args map[string]any) ([][]string, error) {
+	a := []string{"diff", "--no-ext-diff", "--no-textconv"}
+	if staged, _ := args["staged"].(bool); staged {
+		a = append(a, "--cached")
+	}
+	if format, _ := args["format"].(string); format != "patch" {
+		a = append(a, "--stat")
+	}
+	rev, err := gitRevArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if rev != "" {
+		a = append(a, rev)
+	}
+	return gitWithPath(a, args)
+}
+
+func gitShowArgs(args map[string]any) ([][]string, error) {
+	a := []string{"show", "--no-ext-diff", "--no-textconv", "--format=fuller"}
+	if format, _ := args["format"].(string); format != "patch" {
+		a = append(a, "--stat")
+	}
+	rev, err := gitRevArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	return gitWithPath(append(a, rev), args)
+}
+
+func gitBlameArgs(args map[string]any) ([][]string, error) {
+	path, err := gitPathArg(args, "path")
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, errors.New("blame needs a path")
+	}
+	a := []string{"blame", "--no-textconv", "--date=short"}
+	start, end := intArg(args, "start_line", 0, 1<<30), intArg(args, "end_line", 0, 1<<30)
+	switch {
+	case start > 0 && end >= start:
+		a = append(a, fmt.Sprintf("-L%d,%d", start, end))
+	case start > 0:
+		a = append(a, fmt.Sprintf("-L%d,", start))
+	case end > 0:
+		a = append(a, fmt.Sprintf("-L1,%d", end))
+	}
+	rev, err := gitRevArg(args)
+	if err != nil {
+		return nil, err
+	}
+	if rev != "" {
+		a = append(a, rev)
+	}
+	return [][]string{append(a, "--", path)}, nil
+}
+
+func gitCommitArgs(args map[string]any) ([][]string, error) {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return nil, errors.New("commit needs a message")
+	}
+	var steps [][]string
+	if paths := stringList(args["paths"]); len(paths) > 0 {
+		add := []string{"add", "--"}
+		for _, p := range paths {
+			clean, err := gitPathArg(map[string]any{"path": p}, "path")
+			if err != nil {
+				return nil, err
+			}
+			add = append(add, clean)
+		}
+		steps = append(steps, add)
+	}
+	return append(steps, []string{"commit", "--no-verify", "--message=" + message}), nil
+}
+
+func gitCheckoutArgs(args map[string]any) ([][]string, error) {
+	branch, _ := args["branch"].(string)
+	if !gitBranchPattern.MatchString(branch) || strings.Contains(branch, "..") || strings.HasSuffix(branch, ".lock") {
+		return nil, fmt.Errorf("invalid branch %q", branch)
+	}
+	// switch refuses to overwrite local changes, unlike checkout -f.
+	if create, _ := args["create"].(bool); create {
+		return [][]string{{"switch", "--create", branch}}, nil
+	}
+	return [][]string{{"switch", branch}}, nil
+}
+
+func gitWithPath(a []string, args map[string]any) ([][]string, error) {
+	path, err := gitPathArg(args, "path")
+	if err != nil {
+		return nil, err
+	}
+	a = append(a, "--")
+	if path != "" {
+		a = append(a, path)
+	}
+	return [][]string{a}, nil
+}
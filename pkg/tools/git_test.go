@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestGitRepo creates a repository with two commits by different
+// authors.
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "project")
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	alice := []string{
+		"GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=Alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+		"GIT_AUTHOR_DATE=2026-10-01T10:00:00Z", "GIT_COMMITTER_DATE=2026-10-01T10:00:00Z",
+	}
+	bob := []string{
+		"GIT_AUTHOR_NAME=Bob", "GIT_AUTHOR_EMAIL=bob@example.com",
+		"GIT_COMMITTER_NAME=Bob", "GIT_COMMITTER_EMAIL=bob@example.com",
+		"GIT_AUTHOR_DATE=2026-10-10T10:00:00Z", "GIT_COMMITTER_DATE=2026-10-10T10:00:00Z",
+	}
+
+	git(nil, "init", "-q", "-b", "main")
+	git(nil, "config", "user.name", "Test")
+	git(nil, "config", "user.email", "test@example.com")
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	git(alice, "add", ".")
+	git(alice, "commit", "-q", "-m", "Initial commit")
+	os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("# Project\n"), 0o644)
+	git(bob, "add", ".")
+	git(bob, "commit", "-q", "-m", "Add docs")
+	return dir
+}
+
+func TestGitTool_ReadActions(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool, err := NewGitTool("", []string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	run := func(args map[string]any) string {
+		t.Helper()
+		result := tool.Execute(ctx, args)
+		if result.IsError {
+			t.Fatalf("%v failed: %s", args, result.ForLLM)
+		}
+		return result.ForLLM
+	}
+
+	out := run(map[string]any{"action": "log"})
+	if !strings.Contains(out, "Bob: Add docs") || !strings.Contains(out, "Alice: Initial commit") {
+		t.Errorf("log:\n%s", out)
+	}
+	out = run(map[string]any{"action": "log", "author": "alice"})
+	if strings.Contains(out, "Bob") || !strings.Contains(out, "Initial commit") {
+		t.Errorf("log by author:\n%s", out)
+	}
+	out = run(map[string]any{"action": "log", "since": "2026-10-05", "path": "docs"})
+	if !strings.Contains(out, "Add docs") || strings.Contains(out, "Initial commit") {
+		t.Errorf("log since with path:\n%s", out)
+	}
+
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() { println(1) }\n"), 0o644)
+	out = run(map[string]any{"action": "status"})
+	if !strings.Contains(out, "## main") || !strings.Contains(out, " M main.go") {
+		t.Errorf("status:\n%s", out)
+	}
+	out = run(map[string]any{"action": "diff"})
+	if !strings.Contains(out, "main.go | 2 +-") || strings.Contains(out, "println") {
+		t.Errorf("diff stat:\n%s", out)
+	}
+	out = run(map[string]any{"action": "diff", "format": "patch"})
+	if !strings.Contains(out, "+func main() { println(1) }") {
+		t.Errorf("diff patch:\n%s", out)
+	}
+	out = run(map[string]any{"action": "diff", "format": "patch", "max_bytes": float64(40)})
+	if !strings.Contains(out, "... (truncated") {
+		t.Errorf("diff not truncated:\n%s", out)
+	}
+
+	out = run(map[string]any{"action": "show", "rev": "HEAD~1"})
+	if !strings.Contains(out, "Initial commit") || !strings.Contains(out, "main.go") {
+		t.Errorf("show:\n%s", out)
+	}
+	out = run(map[string]any{"action": "blame", "path": "main.go", "start_line": float64(1), "end_line": float64(1)})
+	if !strings.Contains(out, "Alice") || strings.Count(strings.TrimSpace(out), "\n") != 1 {
+		t.Errorf("blame:\n%s", out)
+	}
+	out = run(map[string]any{"action": "branches"})
+	if !strings.Contains(out, "* main") {
+		t.Errorf("branches:\n%s", out)
+	}
+}
+
+func TestGitTool_RejectsUnsafeArguments(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool, err := NewGitTool("", []string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range []map[string]any{
+		{"action": "diff", "rev": "--output=/tmp/pwned"},
+		{"action": "log", "rev": "HEAD;rm -rf /"},
+		{"action": "log", "path": "../../etc"},
+		{"action": "blame", "path": "/etc/passwd"},
+		{"action": "blame"},
+		{"action": "log", "repo": "other"},
+		{"action": "push"},
+		{"action": "commit", "message": "x", "confirm": true},
+	} {
+		if result := tool.Execute(context.Background(), args); !result.IsError {
+			t.Errorf("%v was accepted:\n%s", args, result.ForLLM)
+		}
+	}
+	if _, err := os.Stat("/tmp/pwned"); err == nil {
+		t.Error("diff wrote a file")
+	}
+	if strings.Contains(tool.Description(), "commit") {
+		t.Error("read-only tool should not offer commit")
+	}
+}
+
+func TestGitTool_IgnoresRepoFilterDrivers(t *testing.T) {
+	dir := newTestGitRepo(t)
+	marker := filepath.Join(t.TempDir(), "filter-ran")
+	for _, kv := range [][]string{
+		{"filter.x.clean", "touch " + marker + "; cat"},
+		{"filter.x.smudge", "touch " + marker + "; cat"},
+		{"filter.x.required", "true"},
+		{"filter.y.z.process", "touch " + marker},
+	} {
+		if out, err := exec.Command("git", "-C", dir, "config", kv[0], kv[1]).CombinedOutput(); err != nil {
+			t.Fatalf("git config %s: %v\n%s", kv[0], err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.go filter=x\n*.md filter=y.z\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "docs", "README.md"), []byte("# Changed\n"), 0o644)
+
+	tool, err := NewGitTool("", []string{dir}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range []map[string]any{
+		{"action": "status"},
+		{"action": "diff"},
+		{"action": "diff", "format": "patch"},
+		{"action": "blame", "path": "main.go"},
+	} {
+		if result := tool.Execute(context.Background(), args); result.IsError {
+			t.Errorf("%v failed: %s", args, result.ForLLM)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("a filter driver from the repository config ran")
+	}
+}
+
+func TestNewGitTool_RefusesReposInWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	repo := filepath.Join(workspace, "project")
+	if _, err := NewGitTool(workspace, []string{repo}, false); err == nil {
+		t.Error("repository inside the workspace was accepted")
+	}
+	if _, err := NewGitTool(workspace, []string{t.TempDir()}, false); err != nil {
+		t.Errorf("repository outside the workspace was refused: %v", err)
+	}
+}
+
+func TestGitTool_WriteNeedsApproval(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool, err := NewGitTool("", []string{dir}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithSessionKey(context.Background(), "chat-1")
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("todo\n"), 0o644)
+	// A hook in the repository must not run.
+	os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte("#!/bin/sh\ntouch hook-ran\n"), 0o755)
+
+	commit := map[string]any{"action": "commit", "message": "Add notes", "paths": []any{"notes.txt"}, "confirm": true}
+	result := tool.Execute(ctx, commit)
+	if result.IsError || !strings.Contains(result.ForLLM, "/approve ") {
+		t.Fatalf("commit was not queued: %s", result.ForLLM)
+	}
+	if out := tool.Execute(ctx, map[string]any{"action": "log", "limit": float64(1)}).ForLLM; strings.Contains(out, "Add notes") {
+		t.Fatalf("commit ran before approval:\n%s", out)
+	}
+	code := result.ForLLM[strings.LastIndex(result.ForLLM, "/approve ")+len("/approve "):]
+	code = strings.Fields(code)[0]
+	if pending := tool.Pending("chat-1"); !strings.HasPrefix(pending, code+": commit in project") || !strings.Contains(pending, "git add -- notes.txt") {
+		t.Errorf("Pending = %q", pending)
+	}
+
+	if pending := tool.Pending("chat-2"); pending != "" {
+		t.Errorf("Pending in another session = %q", pending)
+	}
+
+	if _, err := tool.Approve(ctx, "chat-1", "wrong"); err == nil {
+		t.Error("unknown code approved")
+	}
+	if _, err := tool.Approve(ctx, "chat-2", code); err == nil {
+		t.Error("code approved from another session")
+	}
+	if _, err := tool.Approve(ctx, "chat-1", code); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if out := tool.Execute(ctx, map[string]any{"action": "log", "limit": float64(1)}).ForLLM; !strings.Contains(out, "Add notes") {
+		t.Errorf("commit not in log:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hook-ran")); err == nil {
+		t.Error("pre-commit hook ran")
+	}
+	if _, err := tool.Approve(ctx, "chat-1", code); err == nil {
+		t.Error("code approved twice")
+	}
+	if pending := tool.Pending("chat-1"); pending != "" {
+		t.Errorf("Pending after approval = %q", pending)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "checkout", "branch": "-f"})
+	if !result.IsError {
+		t.Error("option accepted as a branch name")
+	}
+	result = tool.Execute(context.Background(), map[string]any{"action": "checkout", "branch": "next"})
+	if !result.IsError {
+		t.Error("change queued outside a session")
+	}
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file.
+type ignoreRule struct {
+	base     string // directory holding the .gitignore, "" for the walk root
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // matched against the path from base, not just the name
+}
+
+// gitignore collects .gitignore rules while a directory tree is walked.
+// Directories are visited before their contents, so rules from deeper
+// files come later and take precedence, as in git.
+type gitignore struct {
+	rules []ignoreRule
+}
+
+// load reads dir/.gitignore from fsys, if present.
+func (g *gitignore) load(fsys fs.FS, dir string) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	base := dir
+	if base == "." {
+		base = ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		g.rules = append(g.rules, rule)
+	}
+}
+
+// loadParents reads the .gitignore files of dir's ancestors within fsys,
+// outermost first, so that searching a subdirectory honours them.
+func (g *gitignore) loadParents(fsys fs.FS, dir string) {
+	if dir == "." || dir == "" {
+		return
+	}
+	parts := strings.Split(dir, "/")
+	g.load(fsys, ".")
+	for i := 1; i < len(parts); i++ {
+		g.load(fsys, path.Join(parts[:i]...))
+	}
+}
+
+// ignored reports whether the slash-separated path p is ignored.
+func (g *gitignore) ignored(p string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		rel := p
+		if rule.base != "" {
+			var ok bool
+			rel, ok = strings.CutPrefix(p, rule.base+"/")
+			if !ok {
+				continue
+			}
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		var match bool
+		if rule.anchored {
+			match = matchPathGlob(rule.pattern, rel)
+		} else {
+			match, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchPathGlob matches a slash-separated path against a glob pattern in
+// which "**" stands for any number of directories.
+func matchPathGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,415 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTTPSecret is a credential that http_request substitutes for a
+// {{secret:NAME}} placeholder when it sends a request. The model only
+// ever sees the placeholder.
+type HTTPSecret struct {
+	Value string
+	Hosts []string // Hosts the secret may be sent to ("*.example.com" matches subdomains); empty means any
+}
+
+var secretPlaceholder = regexp.MustCompile(`\{\{\s*secret:([A-Za-z0-9_.-]+)\s*\}\}`)
+
+type httpSecret struct {
+	name  string
+	value string
+	hosts []egressRule
+}
+
+// HTTPRequestTool sends arbitrary HTTP requests, typically to REST APIs.
+// It goes through the same egress guard as web_fetch.
+type HTTPRequestTool struct {
+	maxChars int
+	proxy    string
+	timeout  time.Duration
+	guard    *EgressGuard
+	secrets  map[string]httpSecret
+}
+
+// NewHTTPRequestTool creates the tool. Secrets are keyed by the name used
+// in placeholders.
+func NewHTTPRequestTool(proxy string, timeout time.Duration, secrets map[string]HTTPSecret) (*HTTPRequestTool, error) {
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	t := &HTTPRequestTool{
+		maxChars: 50000,
+		proxy:    proxy,
+		timeout:  timeout,
+		guard:    defaultEgressGuard(),
+		secrets:  make(map[string]httpSecret, len(secrets)),
+	}
+	for name, s := range secrets {
+		if s.Value == "" {
+			return nil, fmt.Errorf("secret %s has no value", name)
+		}
+		hosts, err := parseEgressRules(s.Hosts)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s hosts: %w", name, err)
+		}
+		t.secrets[name] = httpSecret{name: name, value: s.Value, hosts: hosts}
+	}
+	return t, nil
+}
+
+// SetEgressGuard replaces the default guard, which blocks private,
+// loopback, link-local and cloud metadata addresses.
+func (t *HTTPRequestTool) SetEgressGuard(guard *EgressGuard) {
+	t.guard = guard
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+func (t *HTTPRequestTool) Description() string {
+	desc := "Send an HTTP request to an API and return the status, content type and body. " +
+		"Use select to extract part of a JSON response with a JSONPath like $.items[0].name or $.items[*].id."
+	if len(t.secrets) == 0 {
+		return desc
+	}
+	names := make([]string, 0, len(t.secrets))
+	for name := range t.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return desc + " Credentials are referenced by placeholder in the URL, headers, query or body, " +
+		"e.g. \"Authorization\": \"Bearer {{secret:NAME}}\"; their values are never shown. " +
+		"Available secrets: " + strings.Join(names, ", ") + "."
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"method": map[string]any{
+				"type":        "string",
+				"enum":        []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
+				"description": "HTTP method (default GET)",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Request URL (http or https)",
+			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"description":          "Request headers",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"query": map[string]any{
+				"type":        "object",
+				"description": "Query parameters added to the URL",
+			},
+			"json": map[string]any{
+				"description": "JSON request body",
+			},
+			"form": map[string]any{
+				"type":        "object",
+				"description": "Form request body (application/x-www-form-urlencoded)",
+			},
+			"select": map[string]any{
+				"type":        "string",
+				"description": "JSONPath selecting part of a JSON response, e.g. $.data[*].name",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	result := t.execute(ctx, args)
+	result.ForLLM = t.redact(result.ForLLM)
+	result.ForUser = t.redact(result.ForUser)
+	return result
+}
+
+func (t *HTTPRequestTool) execute(ctx context.Context, args map[string]any) *ToolResult {
+	rawURL, _ := args["url"].(string)
+	if rawURL == "" {
+		return ErrorResult("url is required")
+	}
+	method := "GET"
+	if m, ok := args["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+	if !slices.Contains([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}, method) {
+		return ErrorResult(fmt.Sprintf("unsupported method %q", method))
+	}
+
+	used := make(map[string]bool)
+	sub := func(s string) (string, error) { return t.substitute(s, used) }
+
+	urlStr, err := sub(rawURL)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrorResult("only http/https URLs are allowed")
+	}
+	if u.Host == "" {
+		return ErrorResult("missing domain in URL")
+	}
+
+	if query, ok := args["query"].(map[string]any); ok {
+		values := u.Query()
+		for key, v := range query {
+			s, err := sub(scalarString(v))
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			values.Set(key, s)
+		}
+		u.RawQuery = values.Encode()
+	}
+
+	var body io.Reader
+	var contentType string
+	jsonBody, hasJSON := args["json"]
+	form, hasForm := args["form"].(map[string]any)
+	switch {
+	case hasJSON && hasForm:
+		return ErrorResult("json and form are mutually exclusive")
+	case hasJSON && jsonBody != nil:
+		jsonBody, err = substituteJSON(jsonBody, sub)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		data, err := json.Marshal(jsonBody)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to encode JSON body: %v", err))
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	case hasForm:
+		values := url.Values{}
+		for key, v := range form {
+			s, err := sub(scalarString(v))
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			values.Set(key, s)
+		}
+		body, contentType = strings.NewReader(values.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := args["headers"].(map[string]any); ok {
+		for key, v := range headers {
+			s, err := sub(scalarString(v))
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			req.Header.Set(key, s)
+		}
+	}
+
+	if err := t.checkSecretHosts(used, u.Hostname()); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	client, err := createHTTPClient(t.proxy, t.timeout)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create HTTP client: %v", err))
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("stopped after 5 redirects")
+		}
+		// Headers carrying secrets are forwarded on redirect.
+		return t.checkSecretHosts(used, req.URL.Hostname())
+	}
+	t.guard.protect(client)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var blocked *EgressError
+		if errors.As(err, &blocked) {
+			return ErrorResult(blocked.Error())
+		}
+		return ErrorResult(fmt.Sprintf("request failed: %v", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes+1))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+	}
+	bodyTruncated := len(data) > maxFetchBytes
+	if bodyTruncated {
+		data = data[:maxFetchBytes]
+	}
+
+	text := string(data)
+	if sel, _ := args["select"].(string); sel != "" {
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return ErrorResult(fmt.Sprintf("HTTP %s: select needs a JSON response: %v", resp.Status, err))
+		}
+		selected, err := evalJSONPath(doc, sel)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("HTTP %s: %v", resp.Status, err))
+		}
+		out, _ := json.MarshalIndent(selected, "", "  ")
+		text = string(out)
+	}
+
+	var llm strings.Builder
+	fmt.Fprintf(&llm, "HTTP %s\n", resp.Status)
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		fmt.Fprintf(&llm, "Content-Type: %s\n", ct)
+	}
+	if loc := resp.Header.Get("Location"); loc != "" {
+		fmt.Fprintf(&llm, "Location: %s\n", loc)
+	}
+	if runes := []rune(text); len(runes) > t.maxChars {
+		fmt.Fprintf(&llm, "\n%s\n\n[Truncated to %d of %d characters; use select to narrow the response.]",
+			string(runes[:t.maxChars]), t.maxChars, len(runes))
+	} else if text != "" {
+		fmt.Fprintf(&llm, "\n%s", text)
+	}
+	if bodyTruncated {
+		fmt.Fprintf(&llm, "\n\n[The response was larger than %d MB; only the beginning was read.]",
+			maxFetchBytes>>20)
+	}
+	return SilentResult(llm.String())
+}
+
+// substitute replaces secret placeholders in s, recording the secrets
+// used. A secret without hosts is never substituted, since it could be sent
+// anywhere.
+func (t *HTTPRequestTool) substitute(s string, used map[string]bool) (string, error) {
+	var missing, unbound string
+	out := secretPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := secretPlaceholder.FindStringSubmatch(m)[1]
+		secret, ok := t.secrets[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return m
+		}
+		if len(secret.hosts) == 0 {
+			if unbound == "" {
+				unbound = name
+			}
+			return m
+		}
+		used[name] = true
+		return secret.value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("unknown secret %q", missing)
+	}
+	if unbound != "" {
+		return "", fmt.Errorf("secret %s has no allowed hosts; add hosts to its configuration", unbound)
+	}
+	return out, nil
+}
+
+// checkSecretHosts refuses to send secrets to hosts they are not meant for.
+func (t *HTTPRequestTool) checkSecretHosts(used map[string]bool, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for name := range used {
+		secret := t.secrets[name]
+		if matchHostRules(secret.hosts, host) {
+			continue
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err != nil || !matchAddrRules(secret.hosts, addr.Unmap()) {
+			return fmt.Errorf("secret %s may not be sent to %s", name, host)
+		}
+	}
+	return nil
+}
+
+// redact replaces secret values, which an API may echo back or an error
+// may include, with their placeholders.
+func (t *HTTPRequestTool) redact(s string) string {
+	if s == "" || len(t.secrets) == 0 {
+		return s
+	}
+	// Longer values first, so that a secret containing another is
+	// replaced whole.
+	secrets := slices.Collect(maps.Values(t.secrets))
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i].value) > len(secrets[j].value) })
+	var pairs []string
+	for _, secret := range secrets {
+		name := secret.name
+		placeholder := "{{secret:" + name + "}}"
+		pairs = append(pairs, secret.value, placeholder)
+		if escaped := url.QueryEscape(secret.value); escaped != secret.value {
+			pairs = append(pairs, escaped, placeholder)
+		}
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// substituteJSON replaces secret placeholders in the strings of a decoded
+// JSON value.
+func substituteJSON(v any, sub func(string) (string, error)) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return sub(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, child := range v {
+			s, err := substituteJSON(child, sub)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = s
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			s, err := substituteJSON(child, sub)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = s
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// scalarString formats a query, form or header value given as a JSON
+// scalar.
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
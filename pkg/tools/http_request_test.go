@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoopbackHTTPRequestTool(t *testing.T, secrets map[string]HTTPSecret) *HTTPRequestTool {
+	t.Helper()
+	tool, err := NewHTTPRequestTool("", 0, secrets)
+	require.NoError(t, err)
+	guard, err := NewEgressGuard(EgressPolicy{Allow: []string{"127.0.0.1"}})
+	require.NoError(t, err)
+	tool.SetEgressGuard(guard)
+	return tool
+}
+
+func TestHTTPRequest_InjectsSecretsAtSendTime(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		// Echo the credential back, as some APIs do.
+		fmt.Fprintf(w, `{"auth":%q,"tasks":[{"id":1,"content":"milk"},{"id":2,"content":"eggs"}]}`,
+			r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	tool := newLoopbackHTTPRequestTool(t, map[string]HTTPSecret{
+		"TODOIST": {Value: "tok-123 secret", Hosts: []string{"127.0.0.1"}},
+	})
+	assert.Contains(t, tool.Description(), "Available secrets: TODOIST.")
+	assert.NotContains(t, tool.Description(), "tok-123")
+
+	result := tool.Execute(t.Context(), map[string]any{
+		"method":  "post",
+		"url":     server.URL + "/tasks",
+		"headers": map[string]any{"Authorization": "Bearer {{secret:TODOIST}}"},
+		"query":   map[string]any{"key": "{{ secret:TODOIST }}", "limit": 5.0},
+		"json":    map[string]any{"content": "buy milk", "token": []any{"{{secret:TODOIST}}"}},
+	})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.True(t, result.Silent)
+
+	assert.Equal(t, "POST", got.Method)
+	assert.Equal(t, "Bearer tok-123 secret", got.Header.Get("Authorization"))
+	assert.Equal(t, "tok-123 secret", got.URL.Query().Get("key"))
+	assert.Equal(t, "5", got.URL.Query().Get("limit"))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal([]byte(gotBody), &body))
+	assert.Equal(t, []any{"tok-123 secret"}, body["token"])
+
+	assert.Contains(t, result.ForLLM, "HTTP 200 OK\nContent-Type: application/json")
+	assert.Contains(t, result.ForLLM, `"auth":"Bearer {{secret:TODOIST}}"`)
+	assert.NotContains(t, result.ForLLM, "tok-123")
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"url":    server.URL + "/tasks",
+		"select": "$.tasks[*].content",
+	})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "[\n  \"milk\",\n  \"eggs\"\n]")
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"method": "PUT",
+		"url":    server.URL + "/tasks",
+		"form":   map[string]any{"content": "milk & eggs", "done": true},
+	})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Equal(t, "application/x-www-form-urlencoded", got.Header.Get("Content-Type"))
+	assert.Equal(t, "content=milk+%26+eggs&done=true", gotBody)
+}
+
+func TestHTTPRequest_SecretRestrictions(t *testing.T) {
+	var hits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, r.URL.Path)
+		if r.URL.Path == "/away" {
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/landing", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	tool := newLoopbackHTTPRequestTool(t, map[string]HTTPSecret{
+		"API":  {Value: "s3cr3t", Hosts: []string{"127.0.0.1"}},
+		"ANY":  {Value: "open", Hosts: []string{"127.0.0.1"}},
+		"OPEN": {Value: "unbound"},
+	})
+
+	result := tool.Execute(t.Context(), map[string]any{"url": server.URL + "/?k={{secret:NOPE}}"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, `unknown secret "NOPE"`)
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"url":     "https://api.example.com/",
+		"headers": map[string]any{"X-Key": "{{secret:API}}"},
+	})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "secret API may not be sent to api.example.com")
+
+	// A secret without hosts is sent nowhere, not even to a listed host.
+	for _, target := range []string{"https://attacker.example.com/", server.URL + "/"} {
+		result = tool.Execute(t.Context(), map[string]any{
+			"url":     target,
+			"headers": map[string]any{"X-Key": "{{secret:OPEN}}"},
+		})
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.ForLLM, "secret OPEN has no allowed hosts")
+	}
+	assert.Empty(t, hits)
+
+	// A redirect must not carry the secret to another host.
+	result = tool.Execute(t.Context(), map[string]any{
+		"url":     server.URL + "/away",
+		"headers": map[string]any{"X-Key": "{{secret:API}}"},
+	})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "secret API may not be sent to localhost")
+	assert.Equal(t, []string{"/away"}, hits)
+
+	// Errors that include the URL are redacted.
+	result = tool.Execute(t.Context(), map[string]any{"url": "http://127.0.0.1:1/{{secret:ANY}}"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "{{secret:ANY}}")
+	assert.NotContains(t, result.ForLLM, "open")
+
+	_, err := NewHTTPRequestTool("", 0, map[string]HTTPSecret{"EMPTY": {}})
+	assert.ErrorContains(t, err, "secret EMPTY has no value")
+}
+
+func TestHTTPRequest_BlockedByEgressPolicy(t *testing.T) {
+	tool, err := NewHTTPRequestTool("", 0, nil)
+	require.NoError(t, err)
+	result := tool.Execute(t.Context(), map[string]any{"url": "http://127.0.0.1:8080/admin"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "loopback address")
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	var doc any
+	require.NoError(t, json.Unmarshal([]byte(`{"a":{"b c":[1,2,3]},"items":[{"id":"x"},{"id":"y"}]}`), &doc))
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"$", doc},
+		{"$.a['b c'][0]", 1.0},
+		{"$.a[\"b c\"][-1]", 3.0},
+		{"items[1].id", "y"},
+		{"$.items[*].id", []any{"x", "y"}},
+		{"$.a.*", []any{[]any{1.0, 2.0, 3.0}}},
+		{"$.missing[*]", []any{}},
+	}
+	for _, tt := range tests {
+		got, err := evalJSONPath(doc, tt.path)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+
+	_, err := evalJSONPath(doc, "$.a.nope")
+	assert.ErrorContains(t, err, "matched nothing")
+	_, err = evalJSONPath(doc, "$.items[x]")
+	assert.ErrorContains(t, err, "bad index")
+}
@@ -55,6 +55,12 @@ func (t *MessageTool) SetContext(channel, chatID string) {
 	t.sentInRound = false // Reset send tracking for new processing round
 }
 
+// ForSession returns a message tool with the same send callback and no
+// context of its own yet.
+func (t *MessageTool) ForSession(string) Tool {
+	return &MessageTool{sendCallback: t.sendCallback}
+}
+
 // HasSentInRound returns true if the message tool sent a message during the current round.
 func (t *MessageTool) HasSentInRound() bool {
 	return t.sentInRound
@@ -0,0 +1,829 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatchTool applies a unified diff or "*** Begin Patch" block to one
+// or more files. Either every file is changed or none is.
+type ApplyPatchTool struct {
+	fs fileSystem
+}
+
+func NewApplyPatchTool(workspace string, restrict bool) *ApplyPatchTool {
+	var fs fileSystem
+	if restrict {
+		fs = &sandboxFs{workspace: workspace}
+	} else {
+		fs = &hostFs{}
+	}
+	return &ApplyPatchTool{fs: fs}
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a patch that edits, creates, deletes or renames several files at once. Accepts a unified " +
+		"diff (diff -u / git diff) or a block of the form:\n" +
+		"*** Begin Patch\n*** Update File: path\n@@ optional anchor line\n context\n-old line\n+new line\n" +
+		"*** Add File: path\n+content\n*** Delete File: path\n*** End Patch\n" +
+		"Hunks are located by their context, tolerating shifted lines and whitespace differences. " +
+		"If any hunk fails, no file is changed. Use dry_run to check a patch first."
+}
+
+func (t *ApplyPatchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "The patch text",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "Report how each hunk would apply without changing any file",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	text, ok := args["patch"].(string)
+	if !ok || strings.TrimSpace(text) == "" {
+		return ErrorResult("patch is required")
+	}
+	dryRun, _ := args["dry_run"].(bool)
+
+	files, err := parsePatch(text)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid patch: %v", err))
+	}
+
+	set := newPatchSet(t.fs)
+	var report strings.Builder
+	ok = true
+	for _, fp := range files {
+		if !set.apply(fp, &report) {
+			ok = false
+		}
+	}
+	if !ok {
+		return ErrorResult("Patch not applied; no files were changed. Lines marked ! failed:\n\n" + report.String())
+	}
+	if dryRun {
+		return NewToolResult(fmt.Sprintf("Dry run: patch applies cleanly to %d files; nothing was written.\n\n%s",
+			len(files), report.String()))
+	}
+	if err := set.commit(); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write patch: %v", err))
+	}
+	return NewToolResult(fmt.Sprintf("Patch applied to %d files.\n\n%s", len(files), report.String()))
+}
+
+// maxPatchFuzz is how many context lines at each end of a hunk may be
+// ignored when the hunk does not apply as written, like patch's -F.
+const maxPatchFuzz = 2
+
+type patchOp int
+
+const (
+	patchModify patchOp = iota
+	patchCreate
+	patchDelete
+)
+
+type hunkLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+type patchHunk struct {
+	header   string // as shown in reports
+	oldStart int    // 1-based line from a unified diff header, 0 when unknown
+	anchor   string // "@@ text" line of the simple format, matched before the hunk
+	atEOF    bool   // the hunk must match at the end of the file
+	lines    []hunkLine
+	oldNoEOL bool
+	newNoEOL bool
+}
+
+type filePatch struct {
+	op      patchOp
+	path    string
+	newPath string // rename target, "" when not renamed
+	hunks   []*patchHunk
+	binary  bool
+	fromGit bool // started by a "diff --git" line
+	headers bool // the --- / +++ lines have been read
+}
+
+// parsePatch reads either a unified diff (as produced by diff -u or git
+// diff) or the simpler "*** Begin Patch" format.
+func parsePatch(text string) ([]*filePatch, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	var files []*filePatch
+	var err error
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "*** Begin Patch" {
+			files, err = parseSimplePatch(lines[i+1:])
+			break
+		}
+	}
+	if files == nil && err == nil {
+		files, err = parseUnifiedDiff(lines)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found; expected a unified diff or a *** Begin Patch block")
+	}
+	for _, f := range files {
+		if f.binary {
+			return nil, fmt.Errorf("%s: binary patches are not supported", f.path)
+		}
+		if f.path == "" {
+			return nil, fmt.Errorf("patch is missing a file name")
+		}
+		if f.op == patchModify && f.newPath == "" && len(f.hunks) == 0 {
+			return nil, fmt.Errorf("%s: no hunks to apply", f.path)
+		}
+	}
+	return files, nil
+}
+
+// parseSimplePatch parses the body of a "*** Begin Patch" block:
+//
+//	*** Update File: path
+//	*** Move to: new/path
+//	@@ optional line to anchor the hunk, e.g. a function signature
+//	 context
+//	-removed
+//	+added
+//	*** Add File: path
+//	+content
+//	*** Delete File: path
+//	*** End Patch
+func parseSimplePatch(lines []string) ([]*filePatch, error) {
+	var files []*filePatch
+	var cur *filePatch
+	var hunk *patchHunk
+	for n, line := range lines {
+		lineNo := n + 2 // after the Begin Patch line
+		marker := strings.TrimRight(line, " \t")
+		if rest, ok := strings.CutPrefix(marker, "*** "); ok {
+			var path string
+			switch {
+			case rest == "End Patch":
+				return files, nil
+			case rest == "End of File":
+				if hunk != nil {
+					hunk.atEOF = true
+				}
+				continue
+			case strings.HasPrefix(rest, "Add File:"):
+				path = strings.TrimSpace(strings.TrimPrefix(rest, "Add File:"))
+				hunk = &patchHunk{header: "content"}
+				cur = &filePatch{op: patchCreate, path: path, hunks: []*patchHunk{hunk}}
+			case strings.HasPrefix(rest, "Delete File:"):
+				path = strings.TrimSpace(strings.TrimPrefix(rest, "Delete File:"))
+				cur, hunk = &filePatch{op: patchDelete, path: path}, nil
+			case strings.HasPrefix(rest, "Update File:"):
+				path = strings.TrimSpace(strings.TrimPrefix(rest, "Update File:"))
+				cur, hunk = &filePatch{op: patchModify, path: path}, nil
+			case strings.HasPrefix(rest, "Move to:"):
+				if cur == nil || cur.op != patchModify {
+					return nil, fmt.Errorf("line %d: *** Move to must follow *** Update File", lineNo)
+				}
+				cur.newPath = strings.TrimSpace(strings.TrimPrefix(rest, "Move to:"))
+				continue
+			default:
+				return nil, fmt.Errorf("line %d: unknown marker %q", lineNo, line)
+			}
+			files = append(files, cur)
+			continue
+		}
+
+		if cur == nil {
+			if marker == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %q is outside a file section", lineNo, line)
+		}
+		if strings.HasPrefix(line, "@@") {
+			if cur.op != patchModify {
+				return nil, fmt.Errorf("line %d: @@ is only allowed in *** Update File sections", lineNo)
+			}
+			anchor := strings.TrimSpace(strings.Trim(strings.TrimSpace(line), "@"))
+			hunk = &patchHunk{header: strings.TrimSpace(line), anchor: anchor}
+			cur.hunks = append(cur.hunks, hunk)
+			continue
+		}
+
+		kind, text := byte(' '), ""
+		switch {
+		case line != "":
+			kind, text = line[0], line[1:]
+		case cur.op == patchCreate:
+			kind = '+'
+		}
+		switch {
+		case cur.op == patchDelete:
+			if marker == "" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: *** Delete File takes no content", lineNo)
+		case cur.op == patchCreate && kind != '+':
+			return nil, fmt.Errorf("line %d: every line of an added file must start with '+'", lineNo)
+		case kind != ' ' && kind != '-' && kind != '+':
+			return nil, fmt.Errorf("line %d: hunk lines must start with ' ', '-' or '+', got %q", lineNo, line)
+		}
+		if hunk == nil {
+			hunk = &patchHunk{header: "@@"}
+			cur.hunks = append(cur.hunks, hunk)
+		}
+		hunk.lines = append(hunk.lines, hunkLine{kind, text})
+	}
+	return files, nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff parses diff -u and git diff output. Hunk line counts are
+// used when present; hunks without them end at the first line that is not
+// part of a hunk, which tolerates hand-written diffs.
+func parseUnifiedDiff(lines []string) ([]*filePatch, error) {
+	var files []*filePatch
+	var cur *filePatch
+	newFile := func(fromGit bool) {
+		cur = &filePatch{fromGit: fromGit}
+		files = append(files, cur)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			newFile(true)
+			if idx := strings.LastIndex(line, " b/"); idx > 0 {
+				cur.path = strings.TrimPrefix(line[len("diff --git "):idx], "a/")
+				if newPath := line[idx+3:]; newPath != cur.path {
+					cur.newPath = newPath
+				}
+			}
+		case cur != nil && cur.fromGit && len(cur.hunks) == 0 && strings.HasPrefix(line, "rename from "):
+			cur.path = strings.TrimPrefix(line, "rename from ")
+		case cur != nil && cur.fromGit && len(cur.hunks) == 0 && strings.HasPrefix(line, "rename to "):
+			cur.newPath = strings.TrimPrefix(line, "rename to ")
+		case cur != nil && cur.fromGit && strings.HasPrefix(line, "new file mode"):
+			cur.op = patchCreate
+		case cur != nil && cur.fromGit && strings.HasPrefix(line, "deleted file mode"):
+			cur.op = patchDelete
+		case cur != nil && (line == "GIT binary patch" || strings.HasPrefix(line, "Binary files ")):
+			cur.binary = true
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldName, err := diffFileName(line[4:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			newName, err := diffFileName(lines[i+1][4:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+2, err)
+			}
+			i++
+			if (oldName == "/dev/null" || strings.HasPrefix(oldName, "a/")) &&
+				(newName == "/dev/null" || strings.HasPrefix(newName, "b/")) {
+				oldName = strings.TrimPrefix(oldName, "a/")
+				newName = strings.TrimPrefix(newName, "b/")
+			}
+			// The --- / +++ lines belong to the preceding "diff --git" line
+			// unless they name other files.
+			if cur == nil || !cur.fromGit || cur.headers ||
+				(oldName != cur.path && newName != cur.path && newName != cur.newPath) {
+				newFile(false)
+			}
+			cur.headers = true
+			cur.path, cur.newPath = oldName, ""
+			switch {
+			case oldName == "/dev/null":
+				cur.op, cur.path = patchCreate, newName
+			case newName == "/dev/null":
+				cur.op = patchDelete
+			case newName != oldName:
+				cur.newPath = newName
+			}
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any --- / +++ file header", i+1)
+			}
+			hunk, next := parseUnifiedHunk(lines, i)
+			cur.hunks = append(cur.hunks, hunk)
+			i = next - 1
+		}
+	}
+	return files, nil
+}
+
+func parseUnifiedHunk(lines []string, start int) (*patchHunk, int) {
+	header := lines[start]
+	hunk := &patchHunk{header: header}
+	oldCount, newCount := -1, -1
+	if m := hunkHeader.FindStringSubmatch(header); m != nil {
+		hunk.header = strings.TrimSpace(m[0])
+		hunk.oldStart, _ = strconv.Atoi(m[1])
+		oldCount, newCount = 1, 1
+		if m[2] != "" {
+			oldCount, _ = strconv.Atoi(m[2])
+		}
+		if m[4] != "" {
+			newCount, _ = strconv.Atoi(m[4])
+		}
+		if oldCount == 0 {
+			// "-N,0" inserts after line N.
+			hunk.oldStart++
+		}
+	}
+	counted := oldCount >= 0
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if counted && oldCount <= 0 && newCount <= 0 {
+			if strings.HasPrefix(line, `\`) {
+				markNoEOL(hunk)
+				continue
+			}
+			break
+		}
+		kind, text := byte(' '), ""
+		if line != "" {
+			kind, text = line[0], line[1:]
+		}
+		if !counted && (strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") ||
+			(strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "))) {
+			break
+		}
+		switch kind {
+		case ' ':
+			oldCount--
+			newCount--
+		case '-':
+			oldCount--
+		case '+':
+			newCount--
+		case '\\':
+			markNoEOL(hunk)
+			continue
+		default:
+			return hunk, i
+		}
+		hunk.lines = append(hunk.lines, hunkLine{kind, text})
+	}
+	return hunk, i
+}
+
+// markNoEOL records a "\ No newline at end of file" marker, which applies
+// to the hunk line before it.
+func markNoEOL(hunk *patchHunk) {
+	if len(hunk.lines) == 0 {
+		return
+	}
+	switch hunk.lines[len(hunk.lines)-1].kind {
+	case '-':
+		hunk.oldNoEOL = true
+	case '+':
+		hunk.newNoEOL = true
+	default:
+		hunk.oldNoEOL, hunk.newNoEOL = true, true
+	}
+}
+
+// diffFileName extracts the file name from a ---/+++ line, dropping the
+// timestamp diff -u appends and unquoting git's quoted names.
+func diffFileName(s string) (string, error) {
+	if name, _, ok := strings.Cut(s, "\t"); ok {
+		s = name
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		name, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted file name %s", s)
+		}
+		s = name
+	}
+	return s, nil
+}
+
+// textFile is a file split into lines, remembering its line ending style.
+type textFile struct {
+	lines        []string
+	crlf         bool
+	finalNewline bool
+}
+
+func splitText(content []byte) *textFile {
+	f := &textFile{finalNewline: true}
+	if len(content) == 0 {
+		return f
+	}
+	s := string(content)
+	f.crlf = strings.Contains(s, "\r\n")
+	if f.crlf {
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	f.finalNewline = strings.HasSuffix(s, "\n")
+	f.lines = strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return f
+}
+
+func (f *textFile) bytes() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	eol := "\n"
+	if f.crlf {
+		eol = "\r\n"
+	}
+	s := strings.Join(f.lines, eol)
+	if f.finalNewline {
+		s += eol
+	}
+	return []byte(s)
+}
+
+// Line comparisons from strictest to loosest.
+var lineMatchers = []struct {
+	name  string
+	equal func(a, b string) bool
+}{
+	{"", func(a, b string) bool { return a == b }},
+	{"ignoring trailing whitespace", func(a, b string) bool {
+		return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t")
+	}},
+	{"ignoring whitespace", func(a, b string) bool {
+		return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+	}},
+}
+
+// findBlock returns the index of the occurrence of block in lines at or
+// after start that is closest to expected, or -1.
+func findBlock(lines, block []string, start, expected int, atEOF bool, equal func(a, b string) bool) int {
+	best := -1
+	for i := start; i+len(block) <= len(lines); i++ {
+		if atEOF && i+len(block) != len(lines) {
+			continue
+		}
+		match := true
+		for j, want := range block {
+			if !equal(lines[i+j], want) {
+				match = false
+				break
+			}
+		}
+		if match && (best < 0 || abs(i-expected) < abs(best-expected)) {
+			best = i
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// hunkApplier applies the hunks of one file in order, tracking how far
+// earlier hunks moved later line numbers.
+type hunkApplier struct {
+	file   *textFile
+	cursor int // hunks may not match before this line
+	delta  int // lines added minus lines removed so far
+}
+
+// apply applies hunk and returns a description of where it matched.
+func (a *hunkApplier) apply(hunk *patchHunk) (string, error) {
+	lines := a.file.lines
+	expected := a.cursor
+	if hunk.oldStart > 0 {
+		expected = max(hunk.oldStart-1+a.delta, a.cursor)
+	}
+	start := a.cursor
+	if hunk.anchor != "" {
+		found := -1
+		for i := a.cursor; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == hunk.anchor || strings.Contains(lines[i], hunk.anchor) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return "", fmt.Errorf("anchor %q not found", hunk.anchor)
+		}
+		start, expected = found+1, found+1
+	}
+
+	leading, trailing := 0, 0
+	for leading < len(hunk.lines) && hunk.lines[leading].kind == ' ' {
+		leading++
+	}
+	for trailing < len(hunk.lines)-leading && hunk.lines[len(hunk.lines)-1-trailing].kind == ' ' {
+		trailing++
+	}
+
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		front, back := min(fuzz, leading), min(fuzz, trailing)
+		if fuzz > 0 && front == min(fuzz-1, leading) && back == min(fuzz-1, trailing) {
+			break // no more context to ignore
+		}
+		body := hunk.lines[front : len(hunk.lines)-back]
+		var old []string
+		for _, l := range body {
+			if l.kind != '+' {
+				old = append(old, l.text)
+			}
+		}
+		if fuzz > 0 && len(old) == 0 {
+			break // ignoring all context would insert anywhere
+		}
+
+		if len(old) == 0 {
+			// A pure insertion: at the stated line, after the anchor, or at
+			// the end of the file.
+			pos := len(lines)
+			if hunk.oldStart > 0 || hunk.anchor != "" {
+				pos = min(max(expected, start), len(lines))
+			}
+			a.replace(pos, 0, body, hunk)
+			return fmt.Sprintf("inserted at line %d", pos+1), nil
+		}
+
+		for _, matcher := range lineMatchers {
+			pos := findBlock(lines, old, start, expected, hunk.atEOF, matcher.equal)
+			if pos < 0 {
+				continue
+			}
+			a.replace(pos, len(old), body, hunk)
+			var notes []string
+			if hunk.oldStart > 0 && pos-front != hunk.oldStart-1 {
+				notes = append(notes, fmt.Sprintf("offset %+d lines", pos-front-(hunk.oldStart-1)))
+			}
+			if matcher.name != "" {
+				notes = append(notes, matcher.name)
+			}
+			if fuzz > 0 {
+				notes = append(notes, fmt.Sprintf("fuzz %d", fuzz))
+			}
+			desc := fmt.Sprintf("applied at line %d", pos+1)
+			if len(notes) > 0 {
+				desc += " (" + strings.Join(notes, ", ") + ")"
+			}
+			return desc, nil
+		}
+	}
+	return "", errors.New("context not found")
+}
+
+// replace swaps the n file lines at pos for the new side of body. Context
+// lines keep the file's text, so whitespace-insensitive matches do not
+// rewrite them.
+func (a *hunkApplier) replace(pos, n int, body []hunkLine, hunk *patchHunk) {
+	lines := a.file.lines
+	atEnd := pos+n == len(lines)
+	var out []string
+	k := pos
+	for _, l := range body {
+		switch l.kind {
+		case ' ':
+			out = append(out, lines[k])
+			k++
+		case '-':
+			k++
+		case '+':
+			out = append(out, l.text)
+		}
+	}
+	replaced := make([]string, 0, len(lines)-n+len(out))
+	replaced = append(replaced, lines[:pos]...)
+	replaced = append(replaced, out...)
+	replaced = append(replaced, lines[pos+n:]...)
+	a.file.lines = replaced
+	a.cursor = pos + len(out)
+	a.delta += len(out) - n
+
+	if atEnd {
+		if hunk.newNoEOL {
+			a.file.finalNewline = false
+		} else if hunk.oldNoEOL {
+			a.file.finalNewline = true
+		}
+	}
+}
+
+// patchedFile is the pending state of one file touched by a patch.
+type patchedFile struct {
+	path     string
+	original []byte
+	existed  bool
+	mode     fs.FileMode // permission bits of the file on disk, or of the file it was renamed from
+	content  []byte
+	exists   bool
+}
+
+// patchSet applies file patches in memory and then writes all of them, or
+// none: if a write fails, the files already written are restored.
+type patchSet struct {
+	fs    fileSystem
+	files map[string]*patchedFile
+	order []*patchedFile
+}
+
+func newPatchSet(sysFs fileSystem) *patchSet {
+	return &patchSet{fs: sysFs, files: make(map[string]*patchedFile)}
+}
+
+func (s *patchSet) load(path string) (*patchedFile, error) {
+	key := filepath.Clean(path)
+	if f := s.files[key]; f != nil {
+		return f, nil
+	}
+	f := &patchedFile{path: key}
+	data, err := s.fs.ReadFile(key)
+	switch {
+	case err == nil:
+		f.original, f.existed = data, true
+		if f.mode, err = s.fileMode(key); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	f.content, f.exists = f.original, f.existed
+	s.files[key] = f
+	s.order = append(s.order, f)
+	return f, nil
+}
+
+func (s *patchSet) fileMode(path string) (fs.FileMode, error) {
+	file, err := s.fs.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Mode().Perm(), nil
+}
+
+// apply applies fp in memory, appending per-hunk results to report.
+func (s *patchSet) apply(fp *filePatch, report *strings.Builder) bool {
+	f, err := s.load(fp.path)
+	if err != nil {
+		fmt.Fprintf(report, "! %s: %v\n", fp.path, err)
+		return false
+	}
+
+	switch fp.op {
+	case patchCreate:
+		if f.exists {
+			fmt.Fprintf(report, "! A %s: file already exists\n", fp.path)
+			return false
+		}
+		text := &textFile{finalNewline: true}
+		for _, h := range fp.hunks {
+			for _, l := range h.lines {
+				if l.kind == '+' {
+					text.lines = append(text.lines, l.text)
+				}
+			}
+			if h.newNoEOL {
+				text.finalNewline = false
+			}
+		}
+		f.content, f.exists = text.bytes(), true
+		fmt.Fprintf(report, "A %s (%d lines)\n", fp.path, len(text.lines))
+		return true
+	case patchDelete:
+		if !f.exists {
+			fmt.Fprintf(report, "! D %s: file not found\n", fp.path)
+			return false
+		}
+		f.content, f.exists = nil, false
+		fmt.Fprintf(report, "D %s\n", fp.path)
+		return true
+	}
+
+	if !f.exists {
+		fmt.Fprintf(report, "! M %s: file not found\n", fp.path)
+		return false
+	}
+	ok := true
+	var hunkReport strings.Builder
+	applier := &hunkApplier{file: splitText(f.content)}
+	for n, h := range fp.hunks {
+		desc, err := applier.apply(h)
+		if err != nil {
+			ok = false
+			fmt.Fprintf(&hunkReport, "  ! hunk %d (%s): FAILED, %v\n", n+1, h.header, err)
+			writeExpected(&hunkReport, h)
+			continue
+		}
+		fmt.Fprintf(&hunkReport, "  hunk %d (%s): %s\n", n+1, h.header, desc)
+	}
+	content := applier.file.bytes()
+
+	if fp.newPath == "" {
+		fmt.Fprintf(report, "M %s\n%s", fp.path, hunkReport.String())
+		f.content = content
+		return ok
+	}
+
+	fmt.Fprintf(report, "R %s -> %s\n%s", fp.path, fp.newPath, hunkReport.String())
+	target, err := s.load(fp.newPath)
+	if err != nil {
+		fmt.Fprintf(report, "  ! %s: %v\n", fp.newPath, err)
+		return false
+	}
+	if target.exists {
+		fmt.Fprintf(report, "  ! %s already exists\n", fp.newPath)
+		return false
+	}
+	f.content, f.exists = nil, false
+	target.content, target.exists, target.mode = content, true, f.mode
+	return ok
+}
+
+// writeExpected shows the lines a failed hunk was looking for.
+func writeExpected(report *strings.Builder, h *patchHunk) {
+	shown := 0
+	for _, l := range h.lines {
+		if l.kind == '+' {
+			continue
+		}
+		if shown == 3 {
+			report.WriteString("      ...\n")
+			break
+		}
+		fmt.Fprintf(report, "      expected: %q\n", l.text)
+		shown++
+	}
+}
+
+// commit writes every changed file, restoring the earlier ones if a write
+// fails.
+func (s *patchSet) commit() error {
+	var written []*patchedFile
+	for _, f := range s.order {
+		if f.exists == f.existed && bytes.Equal(f.content, f.original) {
+			continue
+		}
+		var err error
+		if f.exists {
+			err = s.write(f.path, f.content, f.mode)
+		} else {
+			err = s.fs.Remove(f.path)
+		}
+		if err != nil {
+			for i := len(written) - 1; i >= 0; i-- {
+				w := written[i]
+				if w.existed {
+					s.write(w.path, w.original, w.mode)
+				} else {
+					s.fs.Remove(w.path)
+				}
+			}
+			return fmt.Errorf("%s: %w; changes to other files were rolled back", f.path, err)
+		}
+		written = append(written, f)
+	}
+	return nil
+}
+
+// write writes data to path and gives it mode, if known. WriteFile
+// replaces the file, which would otherwise reset it to 0644.
+func (s *patchSet) write(path string, data []byte, mode fs.FileMode) error {
+	if err := s.fs.WriteFile(path, data); err != nil {
+		return err
+	}
+	if mode == 0 || mode == 0o644 {
+		return nil
+	}
+	return s.fs.Chmod(path, mode)
+}
@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTree(t *testing.T, root string, names ...string) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err == nil {
+			out[name] = string(data)
+		}
+	}
+	return out
+}
+
+func TestApplyPatchTool_UnifiedDiff(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"main.go": "package main\n\nimport \"fmt\"\n\n// added above, shifting the hunk\n\nfunc main() {\n" +
+			"\tfmt.Println(\"hello\")\n}\n",
+		"old.txt":  "to be renamed\n",
+		"gone.txt": "bye\n",
+	})
+
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,5 +3,5 @@ import "fmt"
+
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
+ }
+diff --git a/old.txt b/new.txt
+similarity index 100%
+rename from old.txt
+rename to new.txt
+--- /dev/null
++++ b/added.txt
+@@ -0,0 +1,2 @@
++first
++second
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	tool := NewApplyPatchTool(workspace, true)
+	result := tool.Execute(t.Context(), map[string]any{"patch": patch})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "hunk 1 (@@ -3,5 +3,5 @@): applied at line 6 (offset +3 lines)")
+	assert.Contains(t, result.ForLLM, "R old.txt -> new.txt")
+	assert.Contains(t, result.ForLLM, "A added.txt (2 lines)")
+	assert.Contains(t, result.ForLLM, "D gone.txt")
+
+	files := readTree(t, workspace, "main.go", "old.txt", "new.txt", "added.txt", "gone.txt")
+	assert.Contains(t, files["main.go"], "fmt.Println(\"hello, world\")")
+	assert.Equal(t, "to be renamed\n", files["new.txt"])
+	assert.Equal(t, "first\nsecond\n", files["added.txt"])
+	assert.NotContains(t, files, "old.txt")
+	assert.NotContains(t, files, "gone.txt")
+}
+
+func TestApplyPatchTool_KeepsFileMode(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"run.sh": "#!/bin/sh\necho hi\n", "build.sh": "#!/bin/sh\nmake\n"})
+	for _, name := range []string{"run.sh", "build.sh"} {
+		require.NoError(t, os.Chmod(filepath.Join(workspace, name), 0o755))
+	}
+
+	patch := `diff --git a/run.sh b/start.sh
+similarity index 100%
+rename from run.sh
+rename to start.sh
+--- a/build.sh
++++ b/build.sh
+@@ -1,2 +1,2 @@
+ #!/bin/sh
+-make
++make all
+`
+	tool := NewApplyPatchTool(workspace, true)
+	result := tool.Execute(t.Context(), map[string]any{"patch": patch})
+	require.False(t, result.IsError, result.ForLLM)
+
+	for _, name := range []string{"start.sh", "build.sh"} {
+		info, err := os.Stat(filepath.Join(workspace, name))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), name)
+	}
+}
+
+func TestApplyPatchTool_SimpleFormatWithFuzz(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"app.py":   "def a():\n    return 1\n\ndef b():\n    x = 1   \n    return x\n",
+		"crlf.txt": "one\r\ntwo\r\n",
+	})
+
+	patch := `*** Begin Patch
+*** Update File: app.py
+@@ def b():
+-  x = 1
++    x = 2
+     return x
+*** Update File: crlf.txt
+*** Move to: moved.txt
+ one
+-two
++TWO
+*** Add File: docs/readme.md
++# Title
+*** End Patch`
+	tool := NewApplyPatchTool(workspace, true)
+	result := tool.Execute(t.Context(), map[string]any{"patch": patch})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "applied at line 5 (ignoring whitespace)")
+
+	files := readTree(t, workspace, "app.py", "moved.txt", "docs/readme.md")
+	assert.Equal(t, "def a():\n    return 1\n\ndef b():\n    x = 2\n    return x\n", files["app.py"])
+	assert.Equal(t, "one\r\nTWO\r\n", files["moved.txt"])
+	assert.Equal(t, "# Title\n", files["docs/readme.md"])
+}
+
+func TestApplyPatchTool_AllOrNothing(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"})
+	tool := NewApplyPatchTool(workspace, true)
+
+	patch := `*** Begin Patch
+*** Update File: a.txt
+-alpha
++ALPHA
+*** Update File: b.txt
+-gamma
++GAMMA
+*** End Patch`
+	result := tool.Execute(t.Context(), map[string]any{"patch": patch})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "no files were changed")
+	assert.Contains(t, result.ForLLM, "hunk 1 (@@): applied at line 1")
+	assert.Contains(t, result.ForLLM, `! hunk 1 (@@): FAILED, context not found`)
+	assert.Contains(t, result.ForLLM, `expected: "gamma"`)
+	assert.Equal(t, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"}, readTree(t, workspace, "a.txt", "b.txt"))
+
+	// A dry run reports success but writes nothing.
+	fixed := strings.Replace(strings.Replace(patch, "gamma", "beta", 1), "GAMMA", "BETA", 1)
+	result = tool.Execute(t.Context(), map[string]any{"patch": fixed, "dry_run": true})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "Dry run")
+	assert.Equal(t, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"}, readTree(t, workspace, "a.txt", "b.txt"))
+
+	result = tool.Execute(t.Context(), map[string]any{"patch": fixed})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Equal(t, map[string]string{"a.txt": "ALPHA\n", "b.txt": "BETA\n"}, readTree(t, workspace, "a.txt", "b.txt"))
+}
+
+func TestApplyPatchTool_StaysInWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewApplyPatchTool(workspace, true)
+	for _, patch := range []string{
+		"*** Begin Patch\n*** Add File: ../escape.txt\n+x\n*** End Patch",
+		"--- /dev/null\n+++ b/../../escape.txt\n@@ -0,0 +1 @@\n+x\n",
+	} {
+		result := tool.Execute(t.Context(), map[string]any{"patch": patch})
+		assert.True(t, result.IsError, result.ForLLM)
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(workspace), "escape.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParsePatch_NoNewlineAtEOF(t *testing.T) {
+	content := []byte("a\nb")
+	patch := "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"
+	files, err := parsePatch(patch)
+	require.NoError(t, err)
+	applier := &hunkApplier{file: splitText(content)}
+	_, err = applier.apply(files[0].hunks[0])
+	require.NoError(t, err)
+	assert.Equal(t, "a\nc\n", string(applier.file.bytes()))
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/plan"
+)
+
+// PlanTool maintains the checklist of the current session. The agent loop
+// selects the session on the store before each message, and the context
+// builder shows the open plan to the model on every iteration.
+type PlanTool struct {
+	store   *plan.Store
+	session string // Fixed session of a ForSession copy; "" follows the store
+}
+
+func NewPlanTool(store *plan.Store) *PlanTool {
+	return &PlanTool{store: store}
+}
+
+// ForSession returns a plan tool that acts on the plan of sessionKey,
+// whatever session the store has selected.
+func (t *PlanTool) ForSession(sessionKey string) Tool {
+	return &PlanTool{store: t.store, session: sessionKey}
+}
+
+func (t *PlanTool) Name() string {
+	return "plan"
+}
+
+func (t *PlanTool) Description() string {
+	return "Track a multi-step task as a checklist that stays visible to you across iterations. " +
+		"create a plan before starting a request with several steps, update each item as you work on it " +
+		"(in_progress, then done or skipped), add notes for facts you will need later, " +
+		"and complete the plan when everything is finished. The user can see it with /plan."
+}
+
+func (t *PlanTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"create", "update", "note", "complete"},
+				"description": "create replaces the session's plan; update changes an item; note records a note; complete closes the plan",
+			},
+			"goal": map[string]any{
+				"type":        "string",
+				"description": "create: what the plan achieves",
+			},
+			"items": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "create: the steps in order; update: steps to append",
+			},
+			"item": map[string]any{
+				"type":        "integer",
+				"description": "update: number of the item to change; note: attach the note to this item instead of the plan",
+			},
+			"status": map[string]any{
+				"type":        "string",
+				"enum":        plan.Statuses,
+				"description": "update: new status of the item",
+			},
+			"note": map[string]any{
+				"type":        "string",
+				"description": "note, update and complete: text to record",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *PlanTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	sessionKey := t.session
+	if sessionKey == "" {
+		sessionKey = t.store.Session()
+	}
+	if sessionKey == "" {
+		return ErrorResult("no active session for the plan")
+	}
+	action, _ := args["action"].(string)
+	note, _ := args["note"].(string)
+	note = strings.TrimSpace(note)
+
+	if action == "create" {
+		goal, _ := args["goal"].(string)
+		items := stringList(args["items"])
+		if strings.TrimSpace(goal) == "" || len(items) == 0 {
+			return ErrorResult("create needs a goal and at least one item")
+		}
+		p := plan.New(strings.TrimSpace(goal), items)
+		return t.save(sessionKey, p, "Plan created")
+	}
+
+	p, err := t.store.Get(sessionKey)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if p == nil {
+		return ErrorResult("this session has no plan; use action create first")
+	}
+
+	switch action {
+	case "update":
+		added := stringList(args["items"])
+		id := intArg(args, "item", 0, 1<<30)
+		status, _ := args["status"].(string)
+		if id == 0 && len(added) == 0 {
+			return ErrorResult("update needs an item number or items to append")
+		}
+		if id > 0 {
+			item, ok := p.Item(id)
+			if !ok {
+				return ErrorResult(fmt.Sprintf("plan has no item %d", id))
+			}
+			if status != "" {
+				if !slices.Contains(plan.Statuses, status) {
+					return ErrorResult(fmt.Sprintf("invalid status %q (use %s)", status, strings.Join(plan.Statuses, ", ")))
+				}
+				item.Status = status
+			}
+			if note != "" {
+				item.Note = note
+			}
+		}
+		for _, text := range added {
+			p.AddItem(text)
+		}
+		p.Completed = false
+		return t.save(sessionKey, p, "Plan updated")
+
+	case "note":
+		if note == "" {
+			return ErrorResult("note needs a note")
+		}
+		if id := intArg(args, "item", 0, 1<<30); id > 0 {
+			item, ok := p.Item(id)
+			if !ok {
+				return ErrorResult(fmt.Sprintf("plan has no item %d", id))
+			}
+			item.Note = note
+		} else {
+			p.Notes = append(p.Notes, note)
+		}
+		return t.save(sessionKey, p, "Note added")
+
+	case "complete":
+		if note != "" {
+			p.Notes = append(p.Notes, note)
+		}
+		p.Completed = true
+		return t.save(sessionKey, p, "Plan completed")
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q", action))
+	}
+}
+
+func (t *PlanTool) save(sessionKey string, p *plan.Plan, done string) *ToolResult {
+	if err := t.store.Save(sessionKey, p); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(done + ":\n" + p.Format())
+}
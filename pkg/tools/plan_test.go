@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/plan"
+)
+
+func TestPlanTool_Actions(t *testing.T) {
+	store := plan.NewStore(t.TempDir())
+	tool := NewPlanTool(store)
+	ctx := context.Background()
+
+	if result := tool.Execute(ctx, map[string]any{"action": "create", "goal": "x", "items": []any{"a"}}); !result.IsError {
+		t.Error("plan tool should fail without an active session")
+	}
+	store.SetSession("s1")
+
+	if result := tool.Execute(ctx, map[string]any{"action": "update", "item": float64(1)}); !result.IsError {
+		t.Error("update should fail before a plan exists")
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "create", "goal": "Deploy"}); !result.IsError {
+		t.Error("create without items should fail")
+	}
+
+	result := tool.Execute(ctx, map[string]any{"action": "create", "goal": "Deploy", "items": "build, test"})
+	if result.IsError || !strings.Contains(result.ForLLM, "[ ] 2. test") {
+		t.Fatalf("create: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "update", "item": float64(3), "status": "done"}); !result.IsError {
+		t.Error("update of a missing item should fail")
+	}
+	if result := tool.Execute(ctx, map[string]any{"action": "update", "item": float64(1), "status": "finished"}); !result.IsError {
+		t.Error("update with an invalid status should fail")
+	}
+
+	tool.Execute(ctx, map[string]any{"action": "update", "item": float64(1), "status": "done", "items": []any{"deploy"}})
+	tool.Execute(ctx, map[string]any{"action": "note", "note": "staging only"})
+	result = tool.Execute(ctx, map[string]any{"action": "complete"})
+	for _, want := range []string{"(completed, 1/3 done)", "[x] 1. build", "[ ] 3. deploy", "- staging only"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("complete result %q does not contain %q", result.ForLLM, want)
+		}
+	}
+
+	p, err := store.Get("s1")
+	if err != nil || p == nil || !p.Completed {
+		t.Fatalf("stored plan = %+v, %v", p, err)
+	}
+}
+
+func TestToolRegistry_ForSession(t *testing.T) {
+	store := plan.NewStore(t.TempDir())
+	store.SetSession("parent")
+	registry := NewToolRegistry()
+	registry.Register(NewPlanTool(store))
+	registry.Register(NewMessageTool())
+
+	task := registry.ForSession("task")
+	result := task.Execute(context.Background(), "plan", map[string]any{"action": "create", "goal": "Task", "items": "a"})
+	if result.IsError {
+		t.Fatalf("create: %s", result.ForLLM)
+	}
+	if p, _ := store.Get("task"); p == nil || p.Goal != "Task" {
+		t.Errorf("plan of the task session = %+v", p)
+	}
+	if p, _ := store.Get("parent"); p != nil {
+		t.Error("the parent's plan should be untouched")
+	}
+	if store.Session() != "parent" {
+		t.Errorf("store session changed to %q", store.Session())
+	}
+
+	original, _ := registry.Get("message")
+	copied, _ := task.Get("message")
+	if original == copied {
+		t.Error("the message tool keeps per-turn state and should be copied")
+	}
+}
@@ -0,0 +1,711 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/sandbox"
+)
+
+const (
+	defaultMaxProcesses   = 8
+	defaultProcessBuffer  = 1 << 20
+	defaultReadOutputSize = 10000
+	exitNoticeTail        = 2000
+)
+
+// Metadata keys of process exit notifications, which name the agent and
+// session that started the process.
+const (
+	ProcessMetaID      = "process_id"
+	ProcessMetaAgent   = "process_agent_id"
+	ProcessMetaSession = "process_session_key"
+)
+
+// ProcessManager runs long-lived commands in the background for one agent.
+// Commands go through the same policy, workspace restriction and sandbox as
+// the exec tool. Combined stdout and stderr is kept in a fixed-size ring
+// file per process so it can be read incrementally.
+type ProcessManager struct {
+	agentID      string
+	exec         *ExecTool
+	bus          *bus.MessageBus
+	maxProcesses int
+	bufferSize   int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	dir    string
+	procs  map[string]*backgroundProcess
+	nextID int
+	redact func(string) string
+}
+
+type backgroundProcess struct {
+	id        string
+	label     string
+	command   string
+	started   time.Time
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	output    *ringFile
+	notify    bool
+	channel   string
+	chatID    string
+	session   string
+	done      chan struct{}
+	readPos   int64
+	exitCode  int
+	exitErr   error
+	exited    time.Time
+	signalled string
+}
+
+// NewProcessManager creates a manager that starts commands through
+// execTool for the agent agentID. Exit notifications are published to
+// msgBus when it is set.
+func NewProcessManager(agentID string, execTool *ExecTool, msgBus *bus.MessageBus) *ProcessManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ProcessManager{
+		agentID:      agentID,
+		exec:         execTool,
+		bus:          msgBus,
+		maxProcesses: defaultMaxProcesses,
+		bufferSize:   defaultProcessBuffer,
+		ctx:          ctx,
+		cancel:       cancel,
+		procs:        make(map[string]*backgroundProcess),
+		nextID:       1,
+	}
+}
+
+// SetRedactor filters the content of exit notifications, which include
+// the tail of the process output.
+func (m *ProcessManager) SetRedactor(fn func(string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redact = fn
+}
+
+// SetLimits sets how many processes are tracked and how much output is
+// kept for each. Non-positive values keep the defaults.
+func (m *ProcessManager) SetLimits(maxProcesses int, bufferBytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if maxProcesses > 0 {
+		m.maxProcesses = maxProcesses
+	}
+	if bufferBytes > 0 {
+		m.bufferSize = bufferBytes
+	}
+}
+
+// Start runs command in the background. When notify is set, an inbound
+// system message addressed to channel and chatID is published once the
+// process exits, for the session that started it.
+func (m *ProcessManager) Start(
+	command, workingDir, label string,
+	notify bool,
+	channel, chatID, session string,
+) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.makeRoom(); err != nil {
+		return "", err
+	}
+	if m.dir == "" {
+		dir, err := os.MkdirTemp("", "picoclaw-processes-")
+		if err != nil {
+			return "", fmt.Errorf("creating output directory: %w", err)
+		}
+		m.dir = dir
+	}
+
+	cmd, policy, cleanup, blocked := m.exec.prepareCommand(m.ctx, command, workingDir)
+	if blocked != nil {
+		return "", errors.New(blocked.ForLLM)
+	}
+
+	id := fmt.Sprintf("proc-%d", m.nextID)
+	output, err := newRingFile(filepath.Join(m.dir, id+".log"), m.bufferSize)
+	if err != nil {
+		cleanup()
+		return "", err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cleanup()
+		output.Close()
+		return "", err
+	}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Processes are stopped explicitly; don't let exec kill only the shell.
+	cmd.Cancel = func() error { return terminateProcessTree(cmd) }
+
+	if err := cmd.Start(); err != nil {
+		cleanup()
+		output.Close()
+		return "", fmt.Errorf("failed to start command: %w", sandbox.StartError(policy, err))
+	}
+	m.nextID++
+
+	proc := &backgroundProcess{
+		id:      id,
+		label:   label,
+		command: command,
+		started: time.Now(),
+		cmd:     cmd,
+		stdin:   stdin,
+		output:  output,
+		notify:  notify,
+		channel: channel,
+		chatID:  chatID,
+		session: session,
+		done:    make(chan struct{}),
+	}
+	m.procs[id] = proc
+	go m.wait(proc, cleanup)
+	return id, nil
+}
+
+// makeRoom drops the oldest finished process when the limit is reached.
+// The caller holds m.mu.
+func (m *ProcessManager) makeRoom() error {
+	if len(m.procs) < m.maxProcesses {
+		return nil
+	}
+	var oldest *backgroundProcess
+	for _, p := range m.procs {
+		if p.running() {
+			continue
+		}
+		if oldest == nil || p.exited.Before(oldest.exited) {
+			oldest = p
+		}
+	}
+	if oldest == nil {
+		return fmt.Errorf("too many running processes (limit %d); kill one before starting another", m.maxProcesses)
+	}
+	m.remove(oldest)
+	return nil
+}
+
+func (m *ProcessManager) remove(p *backgroundProcess) {
+	delete(m.procs, p.id)
+	p.output.Close()
+	os.Remove(p.output.path)
+}
+
+func (m *ProcessManager) wait(p *backgroundProcess, cleanup func()) {
+	err := p.cmd.Wait()
+	cleanup()
+
+	m.mu.Lock()
+	p.exitErr = err
+	p.exitCode = p.cmd.ProcessState.ExitCode()
+	p.exited = time.Now()
+	close(p.done)
+	notify := p.notify && m.bus != nil && m.ctx.Err() == nil
+	var content string
+	if notify {
+		content = fmt.Sprintf("Process %s completed: %s.\n\nResult:\n%s",
+			p.name(), m.describeExit(p), p.output.Tail(exitNoticeTail))
+		if m.redact != nil {
+			content = m.redact(content)
+		}
+	}
+	m.mu.Unlock()
+
+	if notify {
+		m.bus.PublishInbound(bus.InboundMessage{
+			Channel:  "system",
+			SenderID: "process:" + p.id,
+			ChatID:   fmt.Sprintf("%s:%s", p.channel, p.chatID),
+			Content:  content,
+			Metadata: map[string]string{
+				ProcessMetaID:      p.id,
+				ProcessMetaAgent:   m.agentID,
+				ProcessMetaSession: p.session,
+			},
+		})
+	}
+}
+
+// describeExit summarises how p finished. The caller holds m.mu.
+func (m *ProcessManager) describeExit(p *backgroundProcess) string {
+	runtime := p.exited.Sub(p.started).Round(time.Second)
+	if p.signalled != "" {
+		return fmt.Sprintf("stopped by %s after %v", p.signalled, runtime)
+	}
+	if p.exitCode < 0 && p.exitErr != nil {
+		return fmt.Sprintf("terminated (%v) after %v", p.exitErr, runtime)
+	}
+	return fmt.Sprintf("exited with code %d after %v", p.exitCode, runtime)
+}
+
+func (m *ProcessManager) get(id string) (*backgroundProcess, error) {
+	p, ok := m.procs[id]
+	if !ok {
+		return nil, fmt.Errorf("no process with id %q; use action=list to see processes", id)
+	}
+	return p, nil
+}
+
+// List describes the tracked processes, oldest first.
+func (m *ProcessManager) List() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.procs) == 0 {
+		return "No background processes."
+	}
+	procs := make([]*backgroundProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].started.Before(procs[j].started) })
+
+	var sb strings.Builder
+	for _, p := range procs {
+		status := fmt.Sprintf("running for %v, pid %d", time.Since(p.started).Round(time.Second), p.cmd.Process.Pid)
+		if !p.running() {
+			status = m.describeExit(p)
+		}
+		fmt.Fprintf(&sb, "- %s: %s, %d bytes of output\n  $ %s\n",
+			p.name(), status, p.output.Written(), truncateCommand(p.command))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// ReadOutput returns output starting at offset, or where the previous read
+// stopped when offset is negative, along with the offset to continue from.
+func (m *ProcessManager) ReadOutput(id string, offset int64, maxBytes int) (string, error) {
+	m.mu.Lock()
+	p, err := m.get(id)
+	if err != nil {
+		m.mu.Unlock()
+		return "", err
+	}
+	if offset < 0 {
+		offset = p.readPos
+	}
+	running := p.running()
+	status := "running"
+	if !running {
+		status = m.describeExit(p)
+	}
+	m.mu.Unlock()
+
+	data, start, err := p.output.ReadAt(offset, maxBytes)
+	if err != nil {
+		return "", err
+	}
+	next := start + int64(len(data))
+
+	m.mu.Lock()
+	p.readPos = next
+	m.mu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Process %s (%s). Output bytes %d-%d of %d; next offset %d.\n",
+		p.name(), status, start, next, p.output.Written(), next)
+	if start > offset {
+		fmt.Fprintf(&sb, "(bytes %d-%d were dropped from the output buffer)\n", offset, start)
+	}
+	if len(data) == 0 {
+		sb.WriteString("(no new output)")
+	} else {
+		sb.Write(data)
+	}
+	return sb.String(), nil
+}
+
+// WriteStdin writes input to the process and closes stdin when eof is set.
+func (m *ProcessManager) WriteStdin(id, input string, eof bool) error {
+	m.mu.Lock()
+	p, err := m.get(id)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !p.running() {
+		return fmt.Errorf("process %s has exited", id)
+	}
+	if input != "" {
+		if _, err := io.WriteString(p.stdin, input); err != nil {
+			return fmt.Errorf("writing to stdin: %w", err)
+		}
+	}
+	if eof {
+		return p.stdin.Close()
+	}
+	return nil
+}
+
+// Signal sends a signal such as TERM or INT to the process group.
+func (m *ProcessManager) Signal(id, signal string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	if !p.running() {
+		return fmt.Errorf("process %s has already exited", id)
+	}
+	signal = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if err := signalProcessTree(p.cmd, signal); err != nil {
+		return err
+	}
+	if signal != "STOP" && signal != "CONT" {
+		p.signalled = "SIG" + signal
+	}
+	return nil
+}
+
+// Kill stops the process and everything it started, waiting briefly for
+// it to exit.
+func (m *ProcessManager) Kill(id string) error {
+	m.mu.Lock()
+	p, err := m.get(id)
+	if err == nil && p.running() {
+		p.signalled = "SIGKILL"
+		_ = terminateProcessTree(p.cmd)
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("process %s did not exit after SIGKILL", id)
+	}
+	return nil
+}
+
+// Close kills all processes and removes their output.
+func (m *ProcessManager) Close() {
+	m.cancel()
+	m.mu.Lock()
+	procs := make([]*backgroundProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+		if p.running() {
+			_ = terminateProcessTree(p.cmd)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range procs {
+		select {
+		case <-p.done:
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range procs {
+		m.remove(p)
+	}
+	if m.dir != "" {
+		os.RemoveAll(m.dir)
+		m.dir = ""
+	}
+}
+
+func (p *backgroundProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *backgroundProcess) name() string {
+	if p.label == "" {
+		return p.id
+	}
+	return fmt.Sprintf("%s '%s'", p.id, p.label)
+}
+
+func truncateCommand(command string) string {
+	command = strings.Join(strings.Fields(command), " ")
+	if len(command) > 100 {
+		return command[:97] + "..."
+	}
+	return command
+}
+
+// ProcessTool exposes a ProcessManager to the agent.
+type ProcessTool struct {
+	manager *ProcessManager
+	channel string
+	chatID  string
+}
+
+func NewProcessTool(manager *ProcessManager) *ProcessTool {
+	return &ProcessTool{manager: manager}
+}
+
+func (t *ProcessTool) Name() string {
+	return "process"
+}
+
+func (t *ProcessTool) Description() string {
+	return "Run long-lived commands in the background (builds, dev servers, tail -f) and check on them later. " +
+		"Actions: start, list, read_output, write_stdin, signal, kill. Commands are subject to the same " +
+		"restrictions as exec. Set notify on start to get a message when the process exits."
+}
+
+func (t *ProcessTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"start", "list", "read_output", "write_stdin", "signal", "kill"},
+				"description": "What to do",
+			},
+			"command": map[string]any{
+				"type":        "string",
+				"description": "Shell command to run (start)",
+			},
+			"working_dir": map[string]any{
+				"type":        "string",
+				"description": "Optional working directory (start)",
+			},
+			"label": map[string]any{
+				"type":        "string",
+				"description": "Optional short name for the process (start)",
+			},
+			"notify": map[string]any{
+				"type":        "boolean",
+				"description": "Send a message with the last output when the process exits (start)",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Process ID returned by start (read_output, write_stdin, signal, kill)",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Byte offset to read from; defaults to where the previous read stopped (read_output)",
+			},
+			"max_bytes": map[string]any{
+				"type":        "integer",
+				"description": "Maximum bytes to return, default 10000 (read_output)",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "Text to write; include \\n to send a line (write_stdin)",
+			},
+			"eof": map[string]any{
+				"type":        "boolean",
+				"description": "Close stdin after writing (write_stdin)",
+			},
+			"signal": map[string]any{
+				"type":        "string",
+				"description": "Signal name: TERM, INT, HUP, QUIT, KILL, USR1, USR2, STOP or CONT (signal)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ProcessTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ProcessTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	id, _ := args["id"].(string)
+
+	switch action {
+	case "start":
+		command, _ := args["command"].(string)
+		if strings.TrimSpace(command) == "" {
+			return ErrorResult("command is required for start")
+		}
+		workingDir, _ := args["working_dir"].(string)
+		label, _ := args["label"].(string)
+		notify, _ := args["notify"].(bool)
+		if notify && (t.channel == "" || t.chatID == "") {
+			notify = false
+		}
+		id, err := t.manager.Start(command, workingDir, label, notify, t.channel, t.chatID, SessionKey(ctx))
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		msg := fmt.Sprintf("Started %s. Use action=read_output with id=%s to see its output.", id, id)
+		if notify {
+			msg += " You will be notified when it exits."
+		}
+		return NewToolResult(msg)
+
+	case "list":
+		return NewToolResult(t.manager.List())
+
+	case "read_output":
+		if id == "" {
+			return ErrorResult("id is required for read_output")
+		}
+		offset := int64(-1)
+		if v, ok := args["offset"].(float64); ok {
+			offset = max(int64(v), 0)
+		}
+		maxBytes := defaultReadOutputSize
+		if v, ok := args["max_bytes"].(float64); ok && v > 0 {
+			maxBytes = int(v)
+		}
+		out, err := t.manager.ReadOutput(id, offset, maxBytes)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return NewToolResult(out)
+
+	case "write_stdin":
+		if id == "" {
+			return ErrorResult("id is required for write_stdin")
+		}
+		input, _ := args["input"].(string)
+		eof, _ := args["eof"].(bool)
+		if input == "" && !eof {
+			return ErrorResult("input or eof is required for write_stdin")
+		}
+		if err := t.manager.WriteStdin(id, input, eof); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Wrote %d bytes to %s", len(input), id))
+
+	case "signal":
+		signal, _ := args["signal"].(string)
+		if id == "" || signal == "" {
+			return ErrorResult("id and signal are required for signal")
+		}
+		if err := t.manager.Signal(id, signal); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Sent %s to %s", strings.ToUpper(signal), id))
+
+	case "kill":
+		if id == "" {
+			return ErrorResult("id is required for kill")
+		}
+		if err := t.manager.Kill(id); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Killed %s", id))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q; use start, list, read_output, write_stdin, signal or kill", action))
+	}
+}
+
+// ringFile is a fixed-size file that keeps the most recent output. Offsets
+// count every byte ever written, so readers can tell what was dropped.
+type ringFile struct {
+	path    string
+	size    int64
+	mu      sync.Mutex
+	f       *os.File
+	written int64
+}
+
+func newRingFile(path string, size int64) (*ringFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("creating output file: %w", err)
+	}
+	return &ringFile{path: path, size: size, f: f}, nil
+}
+
+func (r *ringFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	n := len(p)
+	if int64(len(p)) > r.size {
+		r.written += int64(len(p)) - r.size
+		p = p[int64(len(p))-r.size:]
+	}
+	for len(p) > 0 {
+		pos := r.written % r.size
+		chunk := min(int64(len(p)), r.size-pos)
+		if _, err := r.f.WriteAt(p[:chunk], pos); err != nil {
+			return 0, err
+		}
+		r.written += chunk
+		p = p[chunk:]
+	}
+	return n, nil
+}
+
+// Written returns the total number of bytes written.
+func (r *ringFile) Written() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.written
+}
+
+// ReadAt returns up to limit bytes starting at offset, or at the oldest
+// byte still kept when offset has been overwritten, with the actual start.
+func (r *ringFile) ReadAt(offset int64, limit int) ([]byte, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil, offset, os.ErrClosed
+	}
+	start := max(offset, r.written-r.size, 0)
+	if start > r.written {
+		start = r.written
+	}
+	n := min(r.written-start, int64(limit))
+	buf := make([]byte, n)
+	for read := int64(0); read < n; {
+		pos := (start + read) % r.size
+		chunk := min(n-read, r.size-pos)
+		if _, err := r.f.ReadAt(buf[read:read+chunk], pos); err != nil {
+			return nil, start, err
+		}
+		read += chunk
+	}
+	return buf, start, nil
+}
+
+// Tail returns the last n bytes kept.
+func (r *ringFile) Tail(n int) string {
+	data, _, err := r.ReadAt(max(r.Written()-int64(n), 0), n)
+	if err != nil || len(data) == 0 {
+		return "(no output)"
+	}
+	return string(data)
+}
+
+func (r *ringFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
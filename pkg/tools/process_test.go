@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRingFile_KeepsMostRecentOutput(t *testing.T) {
+	r, err := newRingFile(filepath.Join(t.TempDir(), "out.log"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("abcdef"))
+	data, start, _ := r.ReadAt(0, 100)
+	if string(data) != "abcdef" || start != 0 {
+		t.Fatalf("read = %q from %d", data, start)
+	}
+
+	r.Write([]byte("ghijk")) // wraps around
+	data, start, _ = r.ReadAt(0, 100)
+	if string(data) != "defghijk" || start != 3 {
+		t.Errorf("after wrap: read = %q from %d, want defghijk from 3", data, start)
+	}
+	data, start, _ = r.ReadAt(6, 3)
+	if string(data) != "ghi" || start != 6 {
+		t.Errorf("partial read = %q from %d", data, start)
+	}
+
+	r.Write([]byte("0123456789ABC")) // larger than the buffer
+	if r.Written() != 24 {
+		t.Errorf("written = %d, want 24", r.Written())
+	}
+	if tail := r.Tail(4); tail != "9ABC" {
+		t.Errorf("tail = %q", tail)
+	}
+	data, _, _ = r.ReadAt(24, 10)
+	if len(data) != 0 {
+		t.Errorf("read at end = %q", data)
+	}
+}
+
+func TestProcessTool_Validation(t *testing.T) {
+	manager := NewProcessManager("main", NewExecTool(t.TempDir(), true), nil)
+	defer manager.Close()
+	tool := NewProcessTool(manager)
+
+	cases := []map[string]any{
+		{"action": "start"},
+		{"action": "read_output"},
+		{"action": "read_output", "id": "proc-9"},
+		{"action": "signal", "id": "proc-1"},
+		{"action": "bogus"},
+		{"action": "start", "command": "cat /etc/passwd"},
+	}
+	for _, args := range cases {
+		if r := tool.Execute(t.Context(), args); !r.IsError {
+			t.Errorf("%v: expected error, got %q", args, r.ForLLM)
+		}
+	}
+	r := tool.Execute(t.Context(), map[string]any{"action": "start", "command": "sudo ls"})
+	if !strings.Contains(r.ForLLM, "privilege escalation") {
+		t.Errorf("policy message = %q", r.ForLLM)
+	}
+}
@@ -0,0 +1,139 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func waitForOutput(t *testing.T, tool *ProcessTool, id, want string) string {
+	t.Helper()
+	var all strings.Builder
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r := tool.Execute(context.Background(), map[string]any{"action": "read_output", "id": id})
+		if r.IsError {
+			t.Fatalf("read_output: %s", r.ForLLM)
+		}
+		_, out, _ := strings.Cut(r.ForLLM, "\n")
+		if out != "(no new output)" {
+			all.WriteString(out)
+		}
+		if strings.Contains(all.String(), want) {
+			return all.String()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("output of %s never contained %q; got %q", id, want, all.String())
+	return ""
+}
+
+func TestProcessTool_StdinAndIncrementalOutput(t *testing.T) {
+	manager := NewProcessManager("main", NewExecTool(t.TempDir(), false), nil)
+	defer manager.Close()
+	tool := NewProcessTool(manager)
+
+	r := tool.Execute(t.Context(), map[string]any{
+		"action": "start", "command": "echo ready; while read line; do echo got:$line; done", "label": "echoer",
+	})
+	if r.IsError || !strings.Contains(r.ForLLM, "proc-1") {
+		t.Fatalf("start: %s", r.ForLLM)
+	}
+	waitForOutput(t, tool, "proc-1", "ready")
+
+	tool.Execute(t.Context(), map[string]any{"action": "write_stdin", "id": "proc-1", "input": "hello\n"})
+	out := waitForOutput(t, tool, "proc-1", "got:hello")
+	if strings.Contains(out, "ready") {
+		t.Errorf("incremental read repeated earlier output: %q", out)
+	}
+
+	// An explicit offset rereads from there.
+	r = tool.Execute(t.Context(), map[string]any{"action": "read_output", "id": "proc-1", "offset": 0.0})
+	if !strings.Contains(r.ForLLM, "ready\ngot:hello") {
+		t.Errorf("read from 0 = %q", r.ForLLM)
+	}
+
+	r = tool.Execute(t.Context(), map[string]any{"action": "list"})
+	if !strings.Contains(r.ForLLM, "proc-1 'echoer': running") {
+		t.Errorf("list = %q", r.ForLLM)
+	}
+
+	tool.Execute(t.Context(), map[string]any{"action": "write_stdin", "id": "proc-1", "eof": true})
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(tool.Execute(t.Context(), map[string]any{"action": "list"}).ForLLM, "exited with code 0") {
+		if time.Now().After(deadline) {
+			t.Fatal("process did not exit after stdin was closed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProcessTool_SignalKillAndNotify(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	manager := NewProcessManager("main", NewExecTool(t.TempDir(), false), msgBus)
+	defer manager.Close()
+	tool := NewProcessTool(manager)
+	tool.SetContext("telegram", "42")
+
+	r := tool.Execute(WithSessionKey(t.Context(), "agent:main:telegram:42"), map[string]any{
+		"action": "start", "command": "trap 'echo bye; exit 3' TERM; echo up; while true; do sleep 0.1; done",
+		"notify": true,
+	})
+	if r.IsError {
+		t.Fatalf("start: %s", r.ForLLM)
+	}
+	waitForOutput(t, tool, "proc-1", "up")
+
+	if r := tool.Execute(t.Context(), map[string]any{"action": "signal", "id": "proc-1", "signal": "term"}); r.IsError {
+		t.Fatalf("signal: %s", r.ForLLM)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no exit notification")
+	}
+	if msg.Channel != "system" || msg.ChatID != "telegram:42" || msg.SenderID != "process:proc-1" {
+		t.Errorf("notification routing = %+v", msg)
+	}
+	if msg.Metadata[ProcessMetaID] != "proc-1" || msg.Metadata[ProcessMetaAgent] != "main" ||
+		msg.Metadata[ProcessMetaSession] != "agent:main:telegram:42" {
+		t.Errorf("notification metadata = %v", msg.Metadata)
+	}
+	if !strings.Contains(msg.Content, "stopped by SIGTERM") || !strings.Contains(msg.Content, "bye") {
+		t.Errorf("notification content = %q", msg.Content)
+	}
+
+	tool.Execute(t.Context(), map[string]any{"action": "start", "command": "sleep 60"})
+	if r := tool.Execute(t.Context(), map[string]any{"action": "kill", "id": "proc-2"}); r.IsError {
+		t.Fatalf("kill: %s", r.ForLLM)
+	}
+	if list := tool.Execute(t.Context(), map[string]any{"action": "list"}).ForLLM; !strings.Contains(list, "stopped by SIGKILL") {
+		t.Errorf("list after kill = %q", list)
+	}
+}
+
+func TestProcessManager_Limit(t *testing.T) {
+	manager := NewProcessManager("main", NewExecTool(t.TempDir(), false), nil)
+	defer manager.Close()
+	manager.SetLimits(1, 0)
+
+	if _, err := manager.Start("sleep 60", "", "", false, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Start("sleep 60", "", "", false, "", "", ""); err == nil ||
+		!strings.Contains(err.Error(), "too many running processes") {
+		t.Fatalf("second start err = %v", err)
+	}
+	manager.Kill("proc-1")
+	// A finished process makes room for a new one.
+	if _, err := manager.Start("true", "", "", false, "", "", ""); err != nil {
+		t.Fatalf("start after kill: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	defaultReadLines  = 2000
+	maxReadBytes      = 100 << 10
+	maxReadLineLength = 2000
+	maxDecodeBytes    = 16 << 20
+	hexdumpBytes      = 256
+)
+
+// readOptions selects the part of a file read_file returns. offset is the
+// 1-based first line; tail, when set, overrides offset with the last lines.
+type readOptions struct {
+	offset      int
+	limit       int
+	tail        int
+	lineNumbers bool
+}
+
+func (o readOptions) paged() bool {
+	return o.offset > 1 || o.limit > 0 || o.tail > 0 || o.lineNumbers
+}
+
+type textEncoding int
+
+const (
+	encodingUTF8 textEncoding = iota
+	encodingUTF8BOM
+	encodingUTF16LE
+	encodingUTF16BE
+	encodingLatin1
+	encodingBinary
+)
+
+func (e textEncoding) String() string {
+	switch e {
+	case encodingUTF8BOM:
+		return "UTF-8 with BOM"
+	case encodingUTF16LE:
+		return "UTF-16LE"
+	case encodingUTF16BE:
+		return "UTF-16BE"
+	case encodingLatin1:
+		return "Latin-1"
+	case encodingBinary:
+		return "binary"
+	}
+	return "UTF-8"
+}
+
+// detectEncoding guesses the encoding of a file from its first bytes.
+// Files with a UTF-16 byte order mark are text; other files containing NUL
+// bytes or mostly control characters are binary. Text that is not valid
+// UTF-8 is treated as Latin-1.
+func detectEncoding(head []byte, complete bool) textEncoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return encodingUTF8BOM
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return encodingUTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return encodingUTF16BE
+	case bytes.IndexByte(head, 0) >= 0:
+		return encodingBinary
+	}
+
+	valid := head
+	if !complete {
+		// The sample may end in the middle of a multi-byte character.
+		for i := 0; i < utf8.UTFMax-1 && len(valid) > 0 && !utf8.Valid(valid); i++ {
+			valid = valid[:len(valid)-1]
+		}
+	}
+	if utf8.Valid(valid) {
+		return encodingUTF8
+	}
+
+	control := 0
+	for _, b := range head {
+		if (b < 0x20 && !strings.ContainsRune("\t\n\r\f\v\x1b", rune(b))) || b == 0x7F {
+			control++
+		}
+	}
+	if control*10 > len(head) {
+		return encodingBinary
+	}
+	return encodingLatin1
+}
+
+// readFileContent reads path from sysFs according to opts, streaming the
+// file so that only the selected lines are held in memory.
+func readFileContent(sysFs fileSystem, path string, opts readOptions) (string, error) {
+	f, err := sysFs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", readError(err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("failed to read file: %s is a directory; use list_dir", path)
+	}
+
+	reader := bufio.NewReaderSize(f, 64<<10)
+	head, err := reader.Peek(binarySniffSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", readError(err)
+	}
+	encoding := detectEncoding(head, int64(len(head)) == info.Size())
+
+	switch encoding {
+	case encodingBinary:
+		return describeBinary(path, info, head), nil
+	case encodingUTF8:
+		if !opts.paged() && info.Size() <= maxReadBytes {
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return "", readError(err)
+			}
+			if bytes.Count(bytes.TrimSuffix(content, []byte("\n")), []byte("\n")) < defaultReadLines {
+				return string(content), nil
+			}
+			reader = bufio.NewReader(bytes.NewReader(content))
+		}
+	case encodingUTF8BOM:
+		reader.Discard(3)
+	case encodingUTF16LE, encodingUTF16BE:
+		if info.Size() > maxDecodeBytes {
+			return "", fmt.Errorf("failed to read file: %s is %s and larger than %d MB", path, encoding, maxDecodeBytes>>20)
+		}
+		decoded, err := decodeUTF16(reader, encoding == encodingUTF16BE)
+		if err != nil {
+			return "", readError(err)
+		}
+		reader = bufio.NewReader(strings.NewReader(decoded))
+	}
+
+	content, err := readLines(reader, opts, encoding == encodingLatin1)
+	if err != nil {
+		return "", err
+	}
+	if encoding != encodingUTF8 {
+		content = fmt.Sprintf("[Decoded from %s]\n", encoding) + content
+	}
+	return content, nil
+}
+
+// readLines returns the lines selected by opts, followed by a note saying
+// which lines were shown whenever part of the file was left out.
+func readLines(reader *bufio.Reader, opts readOptions, latin1 bool) (string, error) {
+	first := max(opts.offset, 1)
+	limit := opts.limit
+	if limit <= 0 {
+		limit = defaultReadLines
+	}
+
+	type line struct {
+		num  int
+		text string
+	}
+	var selected []line
+	size, total := 0, 0
+	capped := false
+
+	for {
+		text, dropped, err := readLine(reader, maxReadLineLength)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", readError(err)
+		}
+		total++
+
+		if opts.tail > 0 {
+			if len(selected) == opts.tail {
+				size -= len(selected[0].text) + 1
+				selected = selected[1:]
+			}
+		} else if total < first || len(selected) >= limit || capped {
+			continue
+		}
+
+		s := string(text)
+		if latin1 && !utf8.Valid(text) {
+			runes := make([]rune, len(text))
+			for i, b := range text {
+				runes[i] = rune(b)
+			}
+			s = string(runes)
+		}
+		if dropped > 0 {
+			s += fmt.Sprintf("... [line truncated, %d more bytes]", dropped)
+		}
+		if opts.tail == 0 && len(selected) > 0 && size+len(s) > maxReadBytes {
+			capped = true
+			continue
+		}
+		selected = append(selected, line{total, s})
+		size += len(s) + 1
+	}
+
+	// A tail may still be over the byte budget; drop its oldest lines.
+	for opts.tail > 0 && len(selected) > 1 && size > maxReadBytes {
+		size -= len(selected[0].text) + 1
+		selected = selected[1:]
+		capped = true
+	}
+
+	if len(selected) == 0 {
+		if total == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("offset %d is past the end of the file (%d lines)", first, total)
+	}
+
+	var out strings.Builder
+	for _, l := range selected {
+		if opts.lineNumbers {
+			fmt.Fprintf(&out, "%6d\t", l.num)
+		}
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+	}
+
+	start, end := selected[0].num, selected[len(selected)-1].num
+	if start == 1 && end == total {
+		return out.String(), nil
+	}
+	fmt.Fprintf(&out, "\n[Showing lines %d-%d of %d", start, end, total)
+	if capped {
+		fmt.Fprintf(&out, "; output capped at %d KB", maxReadBytes>>10)
+	}
+	switch {
+	case end < total:
+		fmt.Fprintf(&out, ". Truncated, %d more lines; continue with offset=%d.]", total-end, end+1)
+	default:
+		fmt.Fprintf(&out, ". %d earlier lines; read them with offset and limit.]", start-1)
+	}
+	return out.String(), nil
+}
+
+// readLine reads one line without its line ending, keeping at most maxLen
+// bytes of it and reporting how many bytes were dropped.
+func readLine(reader *bufio.Reader, maxLen int) ([]byte, int, error) {
+	var line []byte
+	dropped := 0
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) && (line != nil || dropped > 0) {
+				return line, dropped, nil
+			}
+			return nil, 0, err
+		}
+		keep := min(len(chunk), maxLen-len(line))
+		if line == nil {
+			line = make([]byte, 0, keep)
+		}
+		line = append(line, chunk[:keep]...)
+		dropped += len(chunk) - keep
+		if !isPrefix {
+			return line, dropped, nil
+		}
+	}
+}
+
+func decodeUTF16(r io.Reader, bigEndian bool) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 2; i+1 < len(data); i += 2 { // skip the byte order mark
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// describeBinary summarizes a binary file instead of returning its bytes.
+func describeBinary(path string, info fs.FileInfo, head []byte) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Binary file: %s\n", path)
+	fmt.Fprintf(&out, "Size: %d bytes\n", info.Size())
+	fmt.Fprintf(&out, "Type: %s\n", http.DetectContentType(head))
+	fmt.Fprintf(&out, "Modified: %s\n", info.ModTime().Format("2006-01-02 15:04:05"))
+	n := min(len(head), hexdumpBytes)
+	fmt.Fprintf(&out, "\nFirst %d bytes:\n%s", n, hex.Dump(head[:n]))
+	return out.String()
+}
@@ -12,8 +12,9 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools  map[string]Tool
+	redact func(string) string
+	mu     sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -28,6 +29,70 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// Unregister removes a tool by name. It is a no-op if the tool is not registered.
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+}
+
+// SetRedactor filters the ForLLM and ForUser text of every tool result,
+// e.g. to mask secrets. nil disables filtering.
+func (r *ToolRegistry) SetRedactor(fn func(string) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redact = fn
+}
+
+func (r *ToolRegistry) redactResult(result *ToolResult) *ToolResult {
+	r.mu.RLock()
+	redact := r.redact
+	r.mu.RUnlock()
+	if redact != nil && result != nil {
+		result.ForLLM = redact(result.ForLLM)
+		result.ForUser = redact(result.ForUser)
+	}
+	return result
+}
+
+// WithSnapshotter returns a registry with the same tools and redactor, in
+// which the tools that change files record them in s. The registry itself
+// is left unchanged, so turns that overlap can each group their own
+// changes.
+func (r *ToolRegistry) WithSnapshotter(s Snapshotter) *ToolRegistry {
+	return r.clone(func(tool Tool) Tool {
+		if st, ok := tool.(SnapshottingTool); ok {
+			return st.WithSnapshotter(s)
+		}
+		return tool
+	})
+}
+
+// ForSession returns a registry with the same tools and redactor for a turn
+// of its own in sessionKey, such as a subagent task. SessionTools are
+// replaced by their copies for the session, so the turn neither sees nor
+// changes the per-turn state of the agent's other turns.
+func (r *ToolRegistry) ForSession(sessionKey string) *ToolRegistry {
+	return r.clone(func(tool Tool) Tool {
+		if st, ok := tool.(SessionTool); ok {
+			return st.ForSession(sessionKey)
+		}
+		return tool
+	})
+}
+
+// clone returns a registry with the redactor of r and each of its tools
+// passed through fn.
+func (r *ToolRegistry) clone(fn func(Tool) Tool) *ToolRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &ToolRegistry{tools: make(map[string]Tool, len(r.tools)), redact: r.redact}
+	for name, tool := range r.tools {
+		c.tools[name] = fn(tool)
+	}
+	return c
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -71,7 +136,9 @@ func (r *ToolRegistry) ExecuteWithContext(
 
 	// If tool implements AsyncTool and callback is provided, set callback
 	if asyncTool, ok := tool.(AsyncTool); ok && asyncCallback != nil {
-		asyncTool.SetCallback(asyncCallback)
+		asyncTool.SetCallback(func(ctx context.Context, result *ToolResult) {
+			asyncCallback(ctx, r.redactResult(result))
+		})
 		logger.DebugCF("tool", "Async callback injected",
 			map[string]any{
 				"tool": name,
@@ -79,7 +146,7 @@ func (r *ToolRegistry) ExecuteWithContext(
 	}
 
 	start := time.Now()
-	result := tool.Execute(ctx, args)
+	result := r.redactResult(tool.Execute(ctx, args))
 	duration := time.Since(start)
 
 	// Log based on result type
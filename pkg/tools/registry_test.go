@@ -192,6 +192,33 @@ func TestToolRegistry_ExecuteWithContext_AsyncCallback(t *testing.T) {
 	}
 }
 
+func TestToolRegistry_SetRedactor(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(&mockRegistryTool{
+		name:   "leaky",
+		params: map[string]any{},
+		result: &ToolResult{ForLLM: "TOKEN=s3cret", ForUser: "user s3cret"},
+	})
+	at := &mockAsyncRegistryTool{mockRegistryTool: *newMockTool("async_tool", "async work")}
+	at.result = AsyncResult("started")
+	r.Register(at)
+	r.SetRedactor(func(s string) string { return strings.ReplaceAll(s, "s3cret", "[REDACTED:TOKEN]") })
+
+	result := r.Execute(context.Background(), "leaky", nil)
+	if result.ForLLM != "TOKEN=[REDACTED:TOKEN]" || result.ForUser != "user [REDACTED:TOKEN]" {
+		t.Errorf("expected redacted result, got %q / %q", result.ForLLM, result.ForUser)
+	}
+
+	var got string
+	r.ExecuteWithContext(context.Background(), "async_tool", nil, "", "", func(_ context.Context, res *ToolResult) {
+		got = res.ForLLM
+	})
+	at.cb(context.Background(), SilentResult("done: s3cret"))
+	if got != "done: [REDACTED:TOKEN]" {
+		t.Errorf("expected redacted async result, got %q", got)
+	}
+}
+
 func TestToolRegistry_GetDefinitions(t *testing.T) {
 	r := NewToolRegistry()
 	r.Register(newMockTool("alpha", "tool A"))
@@ -0,0 +1,411 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultSearchResults = 100
+	maxSearchResults     = 1000
+	defaultGlobResults   = 200
+	maxSearchContext     = 10
+	maxSearchFileSize    = 10 << 20
+	maxSearchLineLength  = 300
+	binarySniffSize      = 8000
+)
+
+// walkFiles calls fn for every file under dir in fsys that is not excluded
+// by .gitignore. rel is the file's path in fsys and below its path relative
+// to dir, which globs are matched against; shown is how the model should
+// refer to it: base (the path it asked for) joined with below.
+func walkFiles(
+	ctx context.Context,
+	fsys fs.FS,
+	dir, base string,
+	fn func(rel, below, shown string) error,
+) error {
+	var ignore gitignore
+	ignore.loadParents(fsys, dir)
+
+	return fs.WalkDir(fsys, dir, func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if rel == dir {
+				return err
+			}
+			return nil // unreadable entries are skipped
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if rel != dir && (d.Name() == ".git" || ignore.ignored(rel, true)) {
+				return fs.SkipDir
+			}
+			ignore.load(fsys, rel)
+			return nil
+		}
+		if !d.Type().IsRegular() || (rel != dir && ignore.ignored(rel, false)) {
+			return nil
+		}
+		below := strings.TrimPrefix(strings.TrimPrefix(rel, dir), "/")
+		switch {
+		case dir == ".":
+			below = rel
+		case rel == dir: // a single file was asked for
+			return fn(rel, path.Base(rel), base)
+		}
+		return fn(rel, below, filepath.Join(base, filepath.FromSlash(below)))
+	})
+}
+
+// stringList reads a parameter given either as an array of strings or as
+// a single comma-separated string.
+func stringList(v any) []string {
+	var out []string
+	switch v := v.(type) {
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// matchAnyGlob matches patterns without a slash against the file name and
+// the others against the whole path.
+func matchAnyGlob(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+			continue
+		}
+		if matchPathGlob(strings.TrimPrefix(pattern, "./"), p) {
+			return true
+		}
+	}
+	return false
+}
+
+func intArg(args map[string]any, key string, def, limit int) int {
+	v, ok := args[key].(float64)
+	if !ok || v < 0 {
+		return def
+	}
+	return min(int(v), limit)
+}
+
+type SearchFilesTool struct {
+	fs fileSystem
+}
+
+func NewSearchFilesTool(workspace string, restrict bool) *SearchFilesTool {
+	var fs fileSystem
+	if restrict {
+		fs = &sandboxFs{workspace: workspace}
+	} else {
+		fs = &hostFs{}
+	}
+	return &SearchFilesTool{fs: fs}
+}
+
+func (t *SearchFilesTool) Name() string {
+	return "search_files"
+}
+
+func (t *SearchFilesTool) Description() string {
+	return "Search file contents with a regular expression, like grep -rn. Skips binary files and " +
+		"anything excluded by .gitignore. Prefer this over running grep through exec."
+}
+
+func (t *SearchFilesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Regular expression (RE2 syntax) to search for",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File or directory to search (default: current directory)",
+			},
+			"include": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Only search files matching these globs, e.g. [\"*.go\", \"docs/**/*.md\"]",
+			},
+			"exclude": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Skip files matching these globs",
+			},
+			"ignore_case": map[string]any{
+				"type":        "boolean",
+				"description": "Match case-insensitively",
+			},
+			"context_lines": map[string]any{
+				"type":        "integer",
+				"description": "Lines of context to show before and after each match (max 10)",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": "Maximum matching lines to return (default 100)",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	root, _ := args["path"].(string)
+	if root == "" {
+		root = "."
+	}
+	include := stringList(args["include"])
+	exclude := stringList(args["exclude"])
+	contextLines := intArg(args, "context_lines", 0, maxSearchContext)
+	maxResults := intArg(args, "max_results", defaultSearchResults, maxSearchResults)
+	if maxResults == 0 {
+		maxResults = defaultSearchResults
+	}
+
+	var out strings.Builder
+	matches, files := 0, 0
+	errLimit := errors.New("limit reached")
+	err = t.fs.WithFS(root, func(fsys fs.FS, dir string) error {
+		return walkFiles(ctx, fsys, dir, root, func(rel, below, shown string) error {
+			if len(include) > 0 && !matchAnyGlob(include, below) {
+				return nil
+			}
+			if matchAnyGlob(exclude, below) {
+				return nil
+			}
+			n, err := searchFile(fsys, rel, filepath.ToSlash(shown), re, contextLines, maxResults-matches, &out)
+			if err != nil {
+				return nil // unreadable files are skipped
+			}
+			if n > 0 {
+				matches += n
+				files++
+			}
+			if matches >= maxResults {
+				return errLimit
+			}
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return ErrorResult(fmt.Sprintf("search failed: %v", err))
+	}
+
+	if matches == 0 {
+		return NewToolResult(fmt.Sprintf("No matches for %q in %s", args["pattern"], root))
+	}
+	summary := fmt.Sprintf("\n%d matches in %d files", matches, files)
+	if errors.Is(err, errLimit) {
+		summary += fmt.Sprintf(" (stopped at max_results=%d; narrow the search to see more)", maxResults)
+	}
+	return NewToolResult(strings.TrimRight(out.String(), "\n") + "\n" + summary)
+}
+
+// searchFile writes up to limit matching lines of name, with context, in
+// grep's "file:line:text" format and returns how many matched. Binary and
+// very large files are skipped.
+func searchFile(
+	fsys fs.FS,
+	name, shown string,
+	re *regexp.Regexp,
+	contextLines, limit int,
+	out *strings.Builder,
+) (int, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || info.Size() > maxSearchFileSize {
+		return 0, err
+	}
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	head, _ := reader.Peek(binarySniffSize)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return 0, nil
+	}
+
+	type line struct {
+		num  int
+		text string
+	}
+	var before []line
+	matches, after, lastPrinted := 0, 0, 0
+	printLine := func(num int, text string, sep byte) {
+		if lastPrinted > 0 && num > lastPrinted+1 && contextLines > 0 {
+			out.WriteString("--\n")
+		}
+		if len(text) > maxSearchLineLength {
+			text = text[:maxSearchLineLength] + "..."
+		}
+		fmt.Fprintf(out, "%s%c%d%c%s\n", shown, sep, num, sep, text)
+		lastPrinted = num
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxSearchFileSize)
+	for num := 1; scanner.Scan(); num++ {
+		text := scanner.Text()
+		if matches < limit && re.MatchString(text) {
+			for _, l := range before {
+				if l.num > lastPrinted {
+					printLine(l.num, l.text, '-')
+				}
+			}
+			before = before[:0]
+			printLine(num, text, ':')
+			matches++
+			after = contextLines
+			continue
+		}
+		if after > 0 {
+			printLine(num, text, '-')
+			after--
+			continue
+		}
+		if matches >= limit {
+			break
+		}
+		if contextLines > 0 {
+			if len(before) == contextLines {
+				before = slices.Delete(before, 0, 1)
+			}
+			before = append(before, line{num, text})
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) && matches == 0 {
+		return 0, err
+	}
+	return matches, nil
+}
+
+type GlobFilesTool struct {
+	fs fileSystem
+}
+
+func NewGlobFilesTool(workspace string, restrict bool) *GlobFilesTool {
+	var fs fileSystem
+	if restrict {
+		fs = &sandboxFs{workspace: workspace}
+	} else {
+		fs = &hostFs{}
+	}
+	return &GlobFilesTool{fs: fs}
+}
+
+func (t *GlobFilesTool) Name() string {
+	return "glob_files"
+}
+
+func (t *GlobFilesTool) Description() string {
+	return "Find files by name pattern, like find. \"**\" matches any number of directories, e.g. " +
+		"\"**/*.go\" or \"src/**/test_*.py\". Files excluded by .gitignore are skipped."
+}
+
+func (t *GlobFilesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Glob pattern relative to path; a pattern without \"/\" matches file names at any depth",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to search (default: current directory)",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": "Maximum files to return (default 200)",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GlobFilesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	root, _ := args["path"].(string)
+	if root == "" {
+		root = "."
+	}
+	maxResults := intArg(args, "max_results", defaultGlobResults, maxSearchResults)
+	if maxResults == 0 {
+		maxResults = defaultGlobResults
+	}
+
+	var found []string
+	truncated := false
+	errLimit := errors.New("limit reached")
+	err := t.fs.WithFS(root, func(fsys fs.FS, dir string) error {
+		return walkFiles(ctx, fsys, dir, root, func(_, below, shown string) error {
+			if !matchAnyGlob([]string{pattern}, below) {
+				return nil
+			}
+			if len(found) == maxResults {
+				truncated = true
+				return errLimit
+			}
+			found = append(found, shown)
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return ErrorResult(fmt.Sprintf("glob failed: %v", err))
+	}
+	if len(found) == 0 {
+		return NewToolResult(fmt.Sprintf("No files match %q in %s", pattern, root))
+	}
+	result := strings.Join(found, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n(stopped at max_results=%d; use a narrower pattern to see more)", maxResults)
+	}
+	return NewToolResult(result)
+}
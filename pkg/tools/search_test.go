@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchFilesTool_RespectsGitignore(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		".gitignore":         "*.log\nbuild/\n!keep.log\n",
+		"main.go":            "needle in main\n",
+		"debug.log":          "needle in log\n",
+		"keep.log":           "needle kept\n",
+		"build/out.go":       "needle in build\n",
+		"sub/.gitignore":     "generated.go\n",
+		"sub/generated.go":   "needle generated\n",
+		"sub/handwritten.go": "needle handwritten\n",
+		".git/config":        "needle in git\n",
+		"blob.bin":           "needle\x00binary\n",
+	})
+
+	tool := NewSearchFilesTool(workspace, true)
+	result := tool.Execute(t.Context(), map[string]any{"pattern": "needle"})
+	if result.IsError {
+		t.Fatalf("search failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{"main.go:1:needle in main", "keep.log:1:", "sub/handwritten.go:1:"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("missing %q in:\n%s", want, result.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"debug.log", "build/", "generated.go", "in git", "blob.bin"} {
+		if strings.Contains(result.ForLLM, unwanted) {
+			t.Errorf("unexpected %q in:\n%s", unwanted, result.ForLLM)
+		}
+	}
+
+	// Searching a subdirectory still honours the root .gitignore.
+	result = tool.Execute(t.Context(), map[string]any{"pattern": "needle", "path": "build"})
+	if !strings.Contains(result.ForLLM, "build/out.go:1:") {
+		t.Errorf("explicitly searched ignored dir should be searched: %s", result.ForLLM)
+	}
+}
+
+func TestSearchFilesTool_ContextAndLimits(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		"a.txt":      "one\ntwo\nmatch here\nthree\nfour\nfive\nsix\nMATCH again\nseven\n",
+		"docs/b.md":  "match in docs\n",
+		"docs/c.txt": "match in txt\n",
+	})
+	tool := NewSearchFilesTool(workspace, true)
+
+	result := tool.Execute(t.Context(), map[string]any{
+		"pattern": "match", "path": "a.txt", "context_lines": 1.0, "ignore_case": true,
+	})
+	want := "a.txt-2-two\na.txt:3:match here\na.txt-4-three\n--\n" +
+		"a.txt-7-six\na.txt:8:MATCH again\na.txt-9-seven\n"
+	if !strings.HasPrefix(result.ForLLM, want) {
+		t.Errorf("context output:\n%s\nwant prefix:\n%s", result.ForLLM, want)
+	}
+
+	result = tool.Execute(t.Context(), map[string]any{
+		"pattern": "match", "include": []any{"docs/**"}, "exclude": "*.txt",
+	})
+	if !strings.Contains(result.ForLLM, "docs/b.md:1:") || strings.Contains(result.ForLLM, "c.txt") ||
+		strings.Contains(result.ForLLM, "a.txt") {
+		t.Errorf("include/exclude output:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(t.Context(), map[string]any{"pattern": "match", "max_results": 2.0})
+	if !strings.Contains(result.ForLLM, "2 matches") || !strings.Contains(result.ForLLM, "max_results=2") {
+		t.Errorf("limited output:\n%s", result.ForLLM)
+	}
+
+	if r := tool.Execute(t.Context(), map[string]any{"pattern": "("}); !r.IsError {
+		t.Errorf("invalid regex should fail: %s", r.ForLLM)
+	}
+}
+
+func TestSearchFilesTool_StaysInWorkspace(t *testing.T) {
+	outside := t.TempDir()
+	writeTree(t, outside, map[string]string{"secret.txt": "needle outside\n"})
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"inside.txt": "needle inside\n"})
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape")); err != nil {
+		t.Skipf("symlink not supported in this environment: %v", err)
+	}
+
+	tool := NewSearchFilesTool(workspace, true)
+	result := tool.Execute(t.Context(), map[string]any{"pattern": "needle"})
+	if strings.Contains(result.ForLLM, "outside") {
+		t.Errorf("search followed symlink out of workspace:\n%s", result.ForLLM)
+	}
+	for _, p := range []string{"../", "escape", outside} {
+		r := tool.Execute(t.Context(), map[string]any{"pattern": "needle", "path": p})
+		if !r.IsError {
+			t.Errorf("path %q should be rejected, got:\n%s", p, r.ForLLM)
+		}
+	}
+}
+
+func TestGlobFilesTool(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{
+		".gitignore":          "vendor/\n",
+		"main.go":             "",
+		"pkg/a/a.go":          "",
+		"pkg/a/a_test.go":     "",
+		"pkg/b/readme.md":     "",
+		"vendor/dep/dep.go":   "",
+		"pkg/a/testdata/x.go": "",
+	})
+	tool := NewGlobFilesTool(workspace, true)
+
+	result := tool.Execute(t.Context(), map[string]any{"pattern": "**/*.go"})
+	want := "main.go\npkg/a/a.go\npkg/a/a_test.go\npkg/a/testdata/x.go"
+	if result.ForLLM != want {
+		t.Errorf("glob **/*.go = %q, want %q", result.ForLLM, want)
+	}
+
+	result = tool.Execute(t.Context(), map[string]any{"pattern": "a/*_test.go", "path": "pkg"})
+	if result.ForLLM != filepath.Join("pkg", "a", "a_test.go") {
+		t.Errorf("glob in subdir = %q", result.ForLLM)
+	}
+
+	result = tool.Execute(t.Context(), map[string]any{"pattern": "*.go", "max_results": 1.0})
+	if !strings.Contains(result.ForLLM, "max_results=1") {
+		t.Errorf("limited glob = %q", result.ForLLM)
+	}
+
+	if r := tool.Execute(t.Context(), map[string]any{"pattern": "*.go", "path": "../"}); !r.IsError {
+		t.Errorf("glob outside workspace should fail: %s", r.ForLLM)
+	}
+}
@@ -10,63 +10,26 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/sandbox"
+	"github.com/sipeed/picoclaw/pkg/secrets"
+	"github.com/sipeed/picoclaw/pkg/skills"
 )
 
 type ExecTool struct {
 	workingDir          string
 	timeout             time.Duration
+	policy              *commandPolicy
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
-}
-
-var defaultDenyPatterns = []*regexp.Regexp{
-	regexp.MustCompile(`\brm\s+-[rf]{1,2}\b`),
-	regexp.MustCompile(`\bdel\s+/[fq]\b`),
-	regexp.MustCompile(`\brmdir\s+/s\b`),
-	regexp.MustCompile(`\b(format|mkfs|diskpart)\b\s`), // Match disk wiping commands (must be followed by space/args)
-	regexp.MustCompile(`\bdd\s+if=`),
-	regexp.MustCompile(`>\s*/dev/sd[a-z]\b`), // Block writes to disk devices (but allow /dev/null)
-	regexp.MustCompile(`\b(shutdown|reboot|poweroff)\b`),
-	regexp.MustCompile(`:\(\)\s*\{.*\};\s*:`),
-	regexp.MustCompile(`\$\([^)]+\)`),
-	regexp.MustCompile(`\$\{[^}]+\}`),
-	regexp.MustCompile("`[^`]+`"),
-	regexp.MustCompile(`\|\s*sh\b`),
-	regexp.MustCompile(`\|\s*bash\b`),
-	regexp.MustCompile(`;\s*rm\s+-[rf]`),
-	regexp.MustCompile(`&&\s*rm\s+-[rf]`),
-	regexp.MustCompile(`\|\|\s*rm\s+-[rf]`),
-	regexp.MustCompile(`>\s*/dev/null\s*>&?\s*\d?`),
-	regexp.MustCompile(`<<\s*EOF`),
-	regexp.MustCompile(`\$\(\s*cat\s+`),
-	regexp.MustCompile(`\$\(\s*curl\s+`),
-	regexp.MustCompile(`\$\(\s*wget\s+`),
-	regexp.MustCompile(`\$\(\s*which\s+`),
-	regexp.MustCompile(`\bsudo\b`),
-	regexp.MustCompile(`\bchmod\s+[0-7]{3,4}\b`),
-	regexp.MustCompile(`\bchown\b`),
-	regexp.MustCompile(`\bpkill\b`),
-	regexp.MustCompile(`\bkillall\b`),
-	regexp.MustCompile(`\bkill\s+-[9]\b`),
-	regexp.MustCompile(`\bcurl\b.*\|\s*(sh|bash)`),
-	regexp.MustCompile(`\bwget\b.*\|\s*(sh|bash)`),
-	regexp.MustCompile(`\bnpm\s+install\s+-g\b`),
-	regexp.MustCompile(`\bpip\s+install\s+--user\b`),
-	regexp.MustCompile(`\bapt\s+(install|remove|purge)\b`),
-	regexp.MustCompile(`\byum\s+(install|remove)\b`),
-	regexp.MustCompile(`\bdnf\s+(install|remove)\b`),
-	regexp.MustCompile(`\bdocker\s+run\b`),
-	regexp.MustCompile(`\bdocker\s+exec\b`),
-	regexp.MustCompile(`\bgit\s+push\b`),
-	regexp.MustCompile(`\bgit\s+force\b`),
-	regexp.MustCompile(`\bssh\b.*@`),
-	regexp.MustCompile(`\beval\b`),
-	regexp.MustCompile(`\bsource\s+.*\.sh\b`),
+	sandbox             *sandbox.Policy
+	secrets             *secrets.Vault
+	skills              func() []skills.SkillInfo
 }
 
 var bearerEnvHeaderSingleQuotePattern = regexp.MustCompile(`'Authorization:\s*Bearer\s*\$[A-Za-z_][A-Za-z0-9_]*'`)
@@ -80,12 +43,12 @@ func NewExecTool(workingDir string, restrict bool) *ExecTool {
 
 func NewExecToolWithConfig(workingDir string, restrict bool, config *config.Config) *ExecTool {
 	denyPatterns := make([]*regexp.Regexp, 0)
+	policy := newCommandPolicy(configExecPolicy(config), true)
 
 	if config != nil {
 		execConfig := config.Tools.Exec
 		enableDenyPatterns := execConfig.EnableDenyPatterns
 		if enableDenyPatterns {
-			denyPatterns = append(denyPatterns, defaultDenyPatterns...)
 			if len(execConfig.CustomDenyPatterns) > 0 {
 				fmt.Printf("Using custom deny patterns: %v\n", execConfig.CustomDenyPatterns)
 				for _, pattern := range execConfig.CustomDenyPatterns {
@@ -98,20 +61,42 @@ func NewExecToolWithConfig(workingDir string, restrict bool, config *config.Conf
 				}
 			}
 		} else {
-			// If deny patterns are disabled, we won't add any patterns, allowing all commands.
-			fmt.Println("Warning: deny patterns are disabled. All commands will be allowed.")
+			// Without the default rules only the explicit tools.exec.policy applies.
+			fmt.Println("Warning: default exec command rules are disabled. Only tools.exec.policy is enforced.")
+			policy = newCommandPolicy(execConfig.Policy, false)
 		}
-	} else {
-		denyPatterns = append(denyPatterns, defaultDenyPatterns...)
 	}
 
-	return &ExecTool{
+	tool := &ExecTool{
 		workingDir:          workingDir,
 		timeout:             60 * time.Second,
+		policy:              policy,
 		denyPatterns:        denyPatterns,
 		allowPatterns:       nil,
 		restrictToWorkspace: restrict,
 	}
+	if config != nil && config.Tools.Exec.Sandbox.Enabled {
+		tool.sandbox = sandboxPolicyFromConfig(config.Tools.Exec.Sandbox)
+	}
+	return tool
+}
+
+func configExecPolicy(cfg *config.Config) config.ExecPolicyConfig {
+	if cfg == nil {
+		return config.ExecPolicyConfig{}
+	}
+	return cfg.Tools.Exec.Policy
+}
+
+func sandboxPolicyFromConfig(cfg config.ExecSandboxConfig) *sandbox.Policy {
+	return &sandbox.Policy{
+		ReadOnly:     append(slices.Clone(sandbox.DefaultReadOnlyPaths), cfg.ReadOnlyPaths...),
+		ReadWrite:    slices.Clone(cfg.WritablePaths),
+		DenyNetwork:  cfg.DenyNetwork,
+		CPUSeconds:   cfg.CPUSeconds,
+		MemoryBytes:  int64(cfg.MemoryMB) << 20,
+		MaxProcesses: cfg.MaxProcesses,
+	}
 }
 
 func (t *ExecTool) Name() string {
@@ -144,33 +129,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	if !ok {
 		return ErrorResult("command is required")
 	}
-	command = stripProtectedEnvOverrides(command)
-	command = normalizeBearerEnvHeaderQuotes(command)
-	command = normalizeLegacyGitSummaryCommand(command)
-
-	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		if t.restrictToWorkspace && t.workingDir != "" {
-			resolvedWD, err := validatePath(wd, t.workingDir, true)
-			if err != nil {
-				return ErrorResult("Command blocked by safety guard (" + err.Error() + ")")
-			}
-			cwd = resolvedWD
-		} else {
-			cwd = wd
-		}
-	}
-
-	if cwd == "" {
-		wd, err := os.Getwd()
-		if err == nil {
-			cwd = wd
-		}
-	}
-
-	if guardError := t.guardCommand(command, cwd); guardError != "" {
-		return ErrorResult(guardError)
-	}
+	workingDir, _ := args["working_dir"].(string)
 
 	// timeout == 0 means no timeout
 	var cmdCtx context.Context
@@ -182,24 +141,18 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 	defer cancel()
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
-	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+	cmd, policy, cleanup, blocked := t.prepareCommand(cmdCtx, command, workingDir)
+	if blocked != nil {
+		return blocked
 	}
-	if cwd != "" {
-		cmd.Dir = cwd
-	}
-
-	prepareCommandForTermination(cmd)
+	defer cleanup()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	if err := cmd.Start(); err != nil {
-		return ErrorResult(fmt.Sprintf("failed to start command: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to start command: %v", sandbox.StartError(policy, err)))
 	}
 
 	done := make(chan error, 1)
@@ -263,6 +216,70 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	}
 }
 
+// prepareCommand applies the command policy, working directory
+// restriction and sandbox to command and returns the process to start,
+// with the secrets allowed for exec, or for the skill whose script it runs,
+// in its environment.
+// A non-nil result explains why the command may not run. cleanup must be
+// called once the process has exited.
+func (t *ExecTool) prepareCommand(
+	ctx context.Context,
+	command, workingDir string,
+) (*exec.Cmd, sandbox.Policy, func(), *ToolResult) {
+	var policy sandbox.Policy
+	command = stripProtectedEnvOverrides(command)
+	command = normalizeBearerEnvHeaderQuotes(command)
+	command = normalizeLegacyGitSummaryCommand(command)
+
+	cwd := t.workingDir
+	if workingDir != "" {
+		if t.restrictToWorkspace && t.workingDir != "" {
+			resolvedWD, err := validatePath(workingDir, t.workingDir, true)
+			if err != nil {
+				return nil, policy, nil, ErrorResult("Command blocked by safety guard (" + err.Error() + ")")
+			}
+			cwd = resolvedWD
+		} else {
+			cwd = workingDir
+		}
+	}
+
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			cwd = wd
+		}
+	}
+
+	if guardError := t.guardCommand(command, cwd); guardError != "" {
+		return nil, policy, nil, ErrorResult(guardError)
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	if cwd != "" {
+		cmd.Dir = cwd
+	}
+	cmd.Env = t.environ(t.skillForCommand(command, cwd))
+
+	prepareCommandForTermination(cmd)
+
+	cleanup := func() {}
+	if t.sandbox != nil {
+		var err error
+		policy, cleanup, err = t.wrapSandbox(cmd, cwd)
+		if err != nil {
+			return nil, policy, nil,
+				ErrorResult(fmt.Sprintf("Command blocked: exec sandbox unavailable: %v", err)).WithError(err)
+		}
+	}
+	return cmd, policy, cleanup, nil
+}
+
 func normalizeBearerEnvHeaderQuotes(command string) string {
 	return bearerEnvHeaderSingleQuotePattern.ReplaceAllStringFunc(command, func(segment string) string {
 		if len(segment) < 2 {
@@ -376,7 +393,7 @@ while IFS= read -r repo; do
 
   echo "=== Skip: $repo (neither local git path nor owner/repo) ==="
 done < "$TMP_REPOS"
-rm -f "$TMP_REPOS"
+rm -f -- "$TMP_REPOS"
 echo "TOTAL_COMMITS=$TOTAL_COMMITS"`
 }
 
@@ -386,7 +403,7 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 
 	for _, pattern := range t.denyPatterns {
 		if pattern.MatchString(lower) {
-			return "Command blocked by safety guard (dangerous pattern detected)"
+			return fmt.Sprintf("Command blocked by safety guard (matches custom deny pattern %q)", pattern.String())
 		}
 	}
 
@@ -403,39 +420,150 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 		}
 	}
 
+	var workspace string
 	if t.restrictToWorkspace {
-		if strings.Contains(cmd, "..\\") || strings.Contains(cmd, "../") {
-			return "Command blocked by safety guard (path traversal detected)"
+		workspace = t.workingDir
+		if workspace == "" {
+			workspace = cwd
 		}
-
-		cwdPath, err := filepath.Abs(cwd)
-		if err != nil {
-			return ""
+		if abs, err := filepath.Abs(workspace); err == nil {
+			workspace = abs
+		}
+		if abs, err := filepath.Abs(cwd); err == nil {
+			cwd = abs
 		}
+	}
+	if workspace == "" && (t.policy == nil || t.policy.empty()) {
+		return ""
+	}
+	if err := checkShellCommand(cmd, t.policy, cwd, workspace); err != nil {
+		return err.Error()
+	}
+	return ""
+}
 
-		pathPattern := regexp.MustCompile(`[A-Za-z]:\\[^\\\"']+|/[^\s\"']+`)
-		matches := pathPattern.FindAllString(cmd, -1)
+// wrapSandbox confines cmd to the workspace (or cwd when there is none) and
+// a private temporary directory that is removed by the returned cleanup.
+func (t *ExecTool) wrapSandbox(cmd *exec.Cmd, cwd string) (sandbox.Policy, func(), error) {
+	policy := *t.sandbox
+	root := t.workingDir
+	if root == "" {
+		root = cwd
+	}
 
-		for _, raw := range matches {
-			p, err := filepath.Abs(raw)
-			if err != nil {
-				continue
-			}
+	tmpDir, err := os.MkdirTemp("", "picoclaw-exec-")
+	if err != nil {
+		return policy, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
 
-			rel, err := filepath.Rel(cwdPath, p)
-			if err != nil {
-				continue
-			}
+	policy.ReadWrite = append([]string{root, tmpDir}, policy.ReadWrite...)
+	cmd.Env = append(cmd.Env, "TMPDIR="+tmpDir)
+	if err := sandbox.Wrap(cmd, policy); err != nil {
+		cleanup()
+		return policy, nil, err
+	}
+	return policy, cleanup, nil
+}
 
-			if strings.HasPrefix(rel, "..") {
-				return "Command blocked by safety guard (path outside working dir)"
-			}
+// SetSecrets makes the vault secrets allowed for exec, or for the skill
+// whose script a command runs, available to commands as environment
+// variables. Other vault secrets are removed from the inherited
+// environment, so that "env" does not reveal them.
+func (t *ExecTool) SetSecrets(vault *secrets.Vault) {
+	t.secrets = vault
+}
+
+// SetSkills sets the installed skills, whose directories decide which
+// commands run a skill's script.
+func (t *ExecTool) SetSkills(list func() []skills.SkillInfo) {
+	t.skills = list
+}
+
+// skillScriptInterpreters may run a skill script on its behalf.
+var skillScriptInterpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "python": true, "python3": true,
+	"node": true, "deno": true, "bun": true, "ruby": true, "perl": true, "php": true,
+}
+
+// skillShellSyntax lists the characters that would let a command do more
+// than run one script: separators, pipes, redirections, expansions,
+// substitutions and globs.
+const skillShellSyntax = ";&|$`<>(){}[]*?!~#\\\n\r"
+
+// skillForCommand returns the skill whose script command runs, or "". The
+// scope of skill secrets comes from the command itself, never from what the
+// model declares: the command must be a single invocation of a file inside
+// the skill's directory, directly or through an interpreter, with no shell
+// syntax that could run anything else.
+func (t *ExecTool) skillForCommand(command, cwd string) string {
+	if t.skills == nil || strings.ContainsAny(command, skillShellSyntax) {
+		return ""
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	script := fields[0]
+	if skillScriptInterpreters[script] {
+		if len(fields) < 2 {
+			return ""
+		}
+		script = fields[1]
+	} else if !strings.Contains(script, "/") {
+		// Without a slash the shell looks the name up in PATH.
+		return ""
+	}
+	if strings.ContainsAny(script, `'"`) || strings.HasPrefix(script, "-") {
+		return ""
+	}
+	if !filepath.IsAbs(script) {
+		script = filepath.Join(cwd, script)
+	}
+	real, err := filepath.EvalSymlinks(script)
+	if err != nil {
+		return ""
+	}
+	if info, err := os.Stat(real); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	for _, skill := range t.skills() {
+		dir, err := filepath.EvalSymlinks(filepath.Dir(skill.Path))
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, real); err == nil && filepath.IsLocal(rel) {
+			return skill.Name
 		}
 	}
-
 	return ""
 }
 
+// environ returns the environment of a command, with the secrets of skill
+// if it runs that skill's script.
+func (t *ExecTool) environ(skill string) []string {
+	env := os.Environ()
+	if t.secrets == nil {
+		return env
+	}
+	list := t.secrets.List()
+	env = slices.DeleteFunc(env, func(kv string) bool {
+		name, _, _ := strings.Cut(kv, "=")
+		return slices.ContainsFunc(list, func(s secrets.Secret) bool { return s.Name == name })
+	})
+	for _, s := range list {
+		if s.AllowsTool(t.Name()) || s.AllowsSkill(skill) {
+			env = append(env, s.Name+"="+s.Value)
+		}
+	}
+	return env
+}
+
+// SetSandbox runs commands under policy, or unconfined when policy is nil.
+func (t *ExecTool) SetSandbox(policy *sandbox.Policy) {
+	t.sandbox = policy
+}
+
 func (t *ExecTool) SetTimeout(timeout time.Duration) {
 	t.timeout = timeout
 }
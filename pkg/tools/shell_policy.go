@@ -0,0 +1,1457 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// commandPolicy decides which commands the exec tool may run. It is
+// evaluated against the parsed shell syntax tree rather than the command
+// text, so quoting, absolute paths and wrappers such as env or xargs do
+// not hide the command that actually runs.
+type commandPolicy struct {
+	allowed       []string
+	denied        []deniedCommand
+	rules         []argumentRule
+	denySubshells bool
+	denyPipelines bool
+	// structural enables the built-in checks that are not about a single
+	// command: piping into a shell, fork bombs and writes to devices.
+	structural bool
+}
+
+type deniedCommand struct {
+	pattern string
+	reason  string
+}
+
+type argumentRule struct {
+	command    string
+	subcommand []string
+	flags      []string
+	argPattern *regexp.Regexp
+	reason     string
+}
+
+var defaultDeniedCommands = []deniedCommand{
+	{"sudo", "privilege escalation is not allowed"},
+	{"su", "privilege escalation is not allowed"},
+	{"doas", "privilege escalation is not allowed"},
+	{"shutdown", "powering off or rebooting the host is not allowed"},
+	{"reboot", "powering off or rebooting the host is not allowed"},
+	{"poweroff", "powering off or rebooting the host is not allowed"},
+	{"halt", "powering off or rebooting the host is not allowed"},
+	{"mkfs*", "formatting disks is not allowed"},
+	{"format", "formatting disks is not allowed"},
+	{"diskpart", "formatting disks is not allowed"},
+	{"dd", "raw device copies are not allowed; use cp to copy files"},
+	{"chown", "changing file ownership is not allowed"},
+	{"pkill", "killing processes by name is not allowed; use kill with a specific PID"},
+	{"killall", "killing processes by name is not allowed; use kill with a specific PID"},
+	{"eval", "eval runs code built at run time; write the command out directly"},
+}
+
+var defaultArgumentRules = slices.Concat(
+	[]argumentRule{
+		{
+			command: "rm", flags: []string{"-r", "-R", "--recursive", "--no-preserve-root"},
+			reason: "recursive deletion is not allowed; remove specific files by name",
+		},
+		{
+			command: "del", argPattern: regexp.MustCompile(`(?i)^/[fqs]$`),
+			reason: "forced or recursive deletion is not allowed; remove specific files by name",
+		},
+		{
+			command: "rmdir", argPattern: regexp.MustCompile(`(?i)^/s$`),
+			reason: "recursive deletion is not allowed; remove specific files by name",
+		},
+		{
+			command: "kill", argPattern: regexp.MustCompile(`(?i)^(-9|-?(sig)?kill)$`),
+			reason: "SIGKILL is not allowed; use kill <pid> with the default TERM signal",
+		},
+		{
+			command: "chmod", argPattern: regexp.MustCompile(`^[0-7]{3,4}$`),
+			reason: "numeric modes are not allowed; use a symbolic mode such as u+x or go-w",
+		},
+		{
+			command: "git", subcommand: []string{"push"},
+			reason: "pushing to remotes is not allowed; commit locally and ask the user to push",
+		},
+		{
+			command: "ssh", argPattern: regexp.MustCompile(`@`),
+			reason: "connecting to remote hosts over ssh is not allowed",
+		},
+		{
+			command: "source", argPattern: regexp.MustCompile(`\.sh$`),
+			reason: "sourcing shell scripts runs them unchecked; run the commands directly",
+		},
+		{
+			command: ".", argPattern: regexp.MustCompile(`\.sh$`),
+			reason: "sourcing shell scripts runs them unchecked; run the commands directly",
+		},
+	},
+	subcommandRules([]string{"npm"}, []string{"install", "i", "add"}, []string{"-g", "--global"},
+		"global package installs are not allowed; install into the workspace instead"),
+	subcommandRules([]string{"pip", "pip3"}, []string{"install"}, []string{"--user"},
+		"user-wide package installs are not allowed; use a virtual environment in the workspace"),
+	subcommandRules([]string{"apt", "apt-get", "yum", "dnf"}, []string{"install", "remove", "purge"}, nil,
+		"installing or removing system packages is not allowed; ask the user to do it"),
+	subcommandRules([]string{"docker"}, []string{"run", "exec"}, nil,
+		"starting or entering containers is not allowed"),
+)
+
+func subcommandRules(commands, subcommands, flags []string, reason string) []argumentRule {
+	var rules []argumentRule
+	for _, command := range commands {
+		for _, sub := range subcommands {
+			rules = append(rules, argumentRule{
+				command: command, subcommand: []string{sub}, flags: flags, reason: reason,
+			})
+		}
+	}
+	return rules
+}
+
+// globalOptionsWithValue lists options that take a separate value before
+// the subcommand, so "git -C repo push" is still recognised as a push.
+var globalOptionsWithValue = map[string][]string{
+	"git":    {"-C", "-c", "--git-dir", "--work-tree", "--namespace", "--config-env"},
+	"docker": {"-H", "--host", "-c", "--context", "--config", "-l", "--log-level"},
+}
+
+// shellBuiltins only affect the shell itself and are always allowed.
+var shellBuiltins = map[string]bool{
+	"echo": true, "printf": true, "test": true, "[": true, "true": true, "false": true,
+	":": true, "pwd": true, "export": true, "set": true, "unset": true, "exit": true,
+	"return": true, "shift": true, "read": true, "local": true, "declare": true,
+	"wait": true, "break": true, "continue": true, "readonly": true, "typeset": true,
+	"getopts": true, "cd": true, "pushd": true, "popd": true,
+}
+
+// shells run the script given with -c, or read one from stdin.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "ash": true,
+}
+
+// commandWrappers run their operand as a command after their own options.
+// The values are the options that take a separate argument.
+var commandWrappers = map[string][]string{
+	"env":     {"-u", "--unset", "-C", "--chdir", "-S", "--split-string"},
+	"command": nil,
+	"builtin": nil,
+	"exec":    {"-a"},
+	"nohup":   nil,
+	"setsid":  nil,
+	"nice":    {"-n", "--adjustment"},
+	"ionice":  {"-c", "--class", "-n", "--classdata"},
+	"time":    {"-o", "--output", "-f", "--format"},
+	"timeout": {"-s", "--signal", "-k", "--kill-after"},
+	"stdbuf":  {"-i", "--input", "-o", "--output", "-e", "--error"},
+	"xargs": {
+		"-a", "--arg-file", "-d", "--delimiter", "-E", "-I", "-L", "--max-lines",
+		"-n", "--max-args", "-P", "--max-procs", "-s", "--max-chars",
+	},
+	"busybox": nil,
+}
+
+// safeDevices may be written to by redirections.
+var safeDevices = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/stdin", "/dev/tty", "/dev/zero"}
+
+const maxNestedShells = 4
+
+// newCommandPolicy builds the policy from cfg, on top of the default rules
+// when defaults is set. Invalid argument patterns are reported and skipped,
+// like invalid custom deny patterns.
+func newCommandPolicy(cfg config.ExecPolicyConfig, defaults bool) *commandPolicy {
+	p := &commandPolicy{
+		allowed:       slices.Clone(cfg.AllowedCommands),
+		denySubshells: cfg.DenySubshells,
+		denyPipelines: cfg.DenyPipelines,
+		structural:    defaults,
+	}
+	if defaults {
+		p.denied = slices.Clone(defaultDeniedCommands)
+		p.rules = slices.Clone(defaultArgumentRules)
+	}
+	for _, name := range cfg.DeniedCommands {
+		p.denied = append(p.denied, deniedCommand{name, "it is listed in tools.exec.policy.denied_commands"})
+	}
+	for _, rule := range cfg.ArgumentRules {
+		r := argumentRule{
+			command:    rule.Command,
+			subcommand: rule.Subcommand,
+			flags:      rule.Flags,
+			reason:     rule.Reason,
+		}
+		if rule.ArgPattern != "" {
+			re, err := regexp.Compile(rule.ArgPattern)
+			if err != nil {
+				fmt.Printf("Invalid exec argument rule pattern %q: %v\n", rule.ArgPattern, err)
+				continue
+			}
+			r.argPattern = re
+		}
+		if r.reason == "" {
+			r.reason = "it matches a rule in tools.exec.policy.argument_rules"
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p
+}
+
+// empty reports whether the policy has nothing to enforce.
+func (p *commandPolicy) empty() bool {
+	return len(p.allowed) == 0 && len(p.denied) == 0 && len(p.rules) == 0 &&
+		!p.denySubshells && !p.denyPipelines && !p.structural
+}
+
+// checkCommand returns why the command name with args is not allowed, or "".
+func (p *commandPolicy) checkCommand(name string, args []shellArg) string {
+	if len(p.allowed) > 0 && !slices.ContainsFunc(p.allowed, func(pattern string) bool {
+		return matchCommandName(pattern, name)
+	}) {
+		return fmt.Sprintf("%s is not an allowed command (allowed: %s)", name, strings.Join(p.allowed, ", "))
+	}
+	for _, d := range p.denied {
+		if matchCommandName(d.pattern, name) {
+			return fmt.Sprintf("%s is blocked: %s", name, d.reason)
+		}
+	}
+	for _, rule := range p.rules {
+		if reason := rule.check(name, args); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func matchCommandName(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+type ruleMatch int
+
+const (
+	ruleNoMatch ruleMatch = iota
+	ruleMatches
+	ruleUnknown // an argument is built at run time
+)
+
+// check returns why args break the rule, or "".
+func (r argumentRule) check(name string, args []shellArg) string {
+	if !matchCommandName(r.command, name) {
+		return ""
+	}
+	rest := args[1:]
+	results := make([]ruleMatch, 0, 3)
+
+	if len(r.subcommand) > 0 {
+		m, after := r.matchSubcommand(name, rest)
+		results = append(results, m)
+		rest = after
+	}
+	if len(r.flags) > 0 {
+		results = append(results, r.matchFlags(rest))
+	}
+	if r.argPattern != nil {
+		results = append(results, r.matchPattern(rest))
+	}
+
+	if slices.Contains(results, ruleNoMatch) {
+		return ""
+	}
+	display := strings.Join(append([]string{name}, r.subcommand...), " ")
+	if slices.Contains(results, ruleUnknown) {
+		return fmt.Sprintf("the arguments to %s are built at run time, so they cannot be checked against "+
+			"the rule %q; write them out literally (values after -- are not treated as flags)", display, r.reason)
+	}
+	return fmt.Sprintf("%s is blocked: %s", display, r.reason)
+}
+
+func (r argumentRule) matchSubcommand(name string, args []shellArg) (ruleMatch, []shellArg) {
+	valueOptions := globalOptionsWithValue[name]
+	matched := 0
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !a.literal {
+			return ruleUnknown, args[i+1:]
+		}
+		if strings.HasPrefix(a.value, "-") {
+			if slices.Contains(valueOptions, a.value) {
+				i++
+			}
+			continue
+		}
+		if a.value != r.subcommand[matched] {
+			return ruleNoMatch, nil
+		}
+		matched++
+		if matched == len(r.subcommand) {
+			return ruleMatches, args[i+1:]
+		}
+	}
+	return ruleNoMatch, nil
+}
+
+func (r argumentRule) matchFlags(args []shellArg) ruleMatch {
+	result := ruleNoMatch
+	for _, a := range args {
+		if !a.literal {
+			result = ruleUnknown
+			continue
+		}
+		if a.value == "--" {
+			break
+		}
+		for _, flag := range r.flags {
+			if hasFlag(a.value, flag) {
+				return ruleMatches
+			}
+		}
+	}
+	return result
+}
+
+func (r argumentRule) matchPattern(args []shellArg) ruleMatch {
+	result := ruleNoMatch
+	for _, a := range args {
+		if !a.literal {
+			result = ruleUnknown
+			continue
+		}
+		if r.argPattern.MatchString(a.value) {
+			return ruleMatches
+		}
+	}
+	return result
+}
+
+// hasFlag reports whether arg is flag, a long flag with an attached value,
+// or a group of short flags such as -rf that includes it.
+func hasFlag(arg, flag string) bool {
+	if arg == flag {
+		return true
+	}
+	if strings.HasPrefix(flag, "--") {
+		return strings.HasPrefix(arg, flag+"=")
+	}
+	if len(flag) != 2 || flag[0] != '-' || !isASCIILetter(flag[1]) {
+		return false
+	}
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+		return false
+	}
+	for i := 1; i < len(arg); i++ {
+		if !isASCIILetter(arg[i]) {
+			return false
+		}
+	}
+	return strings.IndexByte(arg[1:], flag[1]) >= 0
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// shellArg is a command word after quote removal and expansion. Words
+// whose value depends on running something are not literal.
+type shellArg struct {
+	value     string
+	literal   bool
+	procSubst bool // the word is a <(...) process substitution
+}
+
+// policyViolation explains why a command was blocked, in terms the model
+// can act on.
+type policyViolation struct {
+	command string
+	reason  string
+}
+
+func (v *policyViolation) Error() string {
+	if v.command == "" {
+		return "Command blocked by safety guard: " + v.reason
+	}
+	return fmt.Sprintf("Command blocked by safety guard: %s (in `%s`)", v.reason, v.command)
+}
+
+// shellVar is a variable assigned by the script itself.
+type shellVar struct {
+	value string
+	known bool
+}
+
+// policyCheck walks one command's syntax tree. When workspace is set,
+// path arguments are resolved against every directory the shell may be in
+// and must stay inside it.
+type policyCheck struct {
+	policy    *commandPolicy
+	workspace string
+	cwds      []string
+	seen      []string
+	vars      map[string]shellVar
+	funcs     map[string]bool
+	depth     int
+}
+
+// checkShellCommand parses command and returns a violation describing the
+// first construct that policy or the workspace restriction rejects.
+func checkShellCommand(command string, policy *commandPolicy, cwd, workspace string) error {
+	if policy == nil {
+		policy = &commandPolicy{}
+	}
+	c := &policyCheck{
+		policy:    policy,
+		workspace: workspace,
+		cwds:      []string{cwd},
+		seen:      []string{cwd},
+		vars:      map[string]shellVar{},
+		funcs:     map[string]bool{},
+	}
+	return c.script(command)
+}
+
+func (c *policyCheck) script(src string) error {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(src), "")
+	if err != nil {
+		return &policyViolation{reason: fmt.Sprintf(
+			"the command could not be parsed as shell syntax (%v); rewrite it as plain POSIX shell", err)}
+	}
+	return c.stmts(file.Stmts)
+}
+
+// scoped runs fn with the working directory and variables restored
+// afterwards, as for subshells.
+func (c *policyCheck) scoped(fn func() error) error {
+	cwds, seen, vars := c.cwds, c.seen, c.vars
+	c.vars = make(map[string]shellVar, len(vars))
+	for k, v := range vars {
+		c.vars[k] = v
+	}
+	err := fn()
+	c.cwds, c.seen, c.vars = cwds, seen, vars
+	return err
+}
+
+// stmts checks a statement list. Any statement may fail part way, so the
+// directories before it stay possible afterwards.
+func (c *policyCheck) stmts(list []*syntax.Stmt) error {
+	for _, stmt := range list {
+		before := c.cwds
+		if err := c.stmt(stmt); err != nil {
+			return err
+		}
+		c.cwds = unionDirs(before, c.cwds)
+	}
+	return nil
+}
+
+func (c *policyCheck) stmt(stmt *syntax.Stmt) error {
+	if stmt.Coprocess && c.policy.denySubshells {
+		return &policyViolation{command: c.source(stmt), reason: "coprocesses are not allowed by tools.exec.policy"}
+	}
+	for _, redir := range stmt.Redirs {
+		if err := c.redirect(redir); err != nil {
+			return err
+		}
+	}
+	if stmt.Cmd == nil {
+		return nil
+	}
+	if err := c.stdinScript(stmt); err != nil {
+		return err
+	}
+	if c.policy.structural {
+		if name, ok := c.procSubstShell(stmt); ok {
+			return &policyViolation{command: c.source(stmt), reason: fmt.Sprintf(
+				"feeding a process substitution into %s runs code that cannot be reviewed; save it to a file "+
+					"in the workspace and inspect it first", name)}
+		}
+	}
+	if stmt.Background || stmt.Coprocess {
+		return c.scoped(func() error { return c.command(stmt) })
+	}
+	return c.command(stmt)
+}
+
+func (c *policyCheck) command(stmt *syntax.Stmt) error {
+	switch cmd := stmt.Cmd.(type) {
+	case *syntax.CallExpr:
+		return c.call(cmd)
+	case *syntax.BinaryCmd:
+		return c.binary(cmd)
+	case *syntax.Subshell:
+		if c.policy.denySubshells {
+			return &policyViolation{command: c.source(stmt), reason: "subshells are not allowed by tools.exec.policy"}
+		}
+		return c.scoped(func() error { return c.stmts(cmd.Stmts) })
+	case *syntax.Block:
+		return c.stmts(cmd.Stmts)
+	case *syntax.IfClause:
+		return c.ifClause(cmd)
+	case *syntax.WhileClause:
+		if err := c.stmts(cmd.Cond); err != nil {
+			return err
+		}
+		return c.loopBody(cmd.Do)
+	case *syntax.ForClause:
+		if iter, ok := cmd.Loop.(*syntax.WordIter); ok {
+			for _, word := range iter.Items {
+				if err := c.nested(word); err != nil {
+					return err
+				}
+				for _, arg := range c.expandWord(word) {
+					if err := c.checkPath(arg, iter.Name.Value); err != nil {
+						return err
+					}
+				}
+			}
+			c.vars[iter.Name.Value] = shellVar{}
+		} else if err := c.nested(cmd.Loop); err != nil {
+			return err
+		}
+		return c.loopBody(cmd.Do)
+	case *syntax.CaseClause:
+		if err := c.nested(cmd.Word); err != nil {
+			return err
+		}
+		start, result := c.cwds, c.cwds
+		for _, item := range cmd.Items {
+			for _, pattern := range item.Patterns {
+				if err := c.nested(pattern); err != nil {
+					return err
+				}
+			}
+			c.cwds = start
+			if err := c.stmts(item.Stmts); err != nil {
+				return err
+			}
+			result = unionDirs(result, c.cwds)
+		}
+		c.cwds = result
+		return nil
+	case *syntax.FuncDecl:
+		return c.funcDecl(cmd)
+	case *syntax.DeclClause:
+		for _, assign := range cmd.Args {
+			if err := c.assign(assign); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *syntax.TimeClause:
+		if cmd.Stmt == nil {
+			return nil
+		}
+		return c.stmt(cmd.Stmt)
+	case *syntax.CoprocClause:
+		return c.scoped(func() error { return c.stmt(cmd.Stmt) })
+	default:
+		// Arithmetic, [[ ]] tests and let only matter for the command
+		// substitutions they contain.
+		return c.nested(cmd)
+	}
+}
+
+func (c *policyCheck) ifClause(clause *syntax.IfClause) error {
+	if err := c.stmts(clause.Cond); err != nil {
+		return err
+	}
+	afterCond := c.cwds
+	if err := c.stmts(clause.Then); err != nil {
+		return err
+	}
+	then := c.cwds
+	c.cwds = afterCond
+	if clause.Else != nil {
+		if err := c.ifClause(clause.Else); err != nil {
+			return err
+		}
+	}
+	c.cwds = unionDirs(then, c.cwds)
+	return nil
+}
+
+// loopBody checks a loop body twice so that directory changes made by one
+// iteration are seen by the next.
+func (c *policyCheck) loopBody(body []*syntax.Stmt) error {
+	for range 2 {
+		if err := c.stmts(body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *policyCheck) binary(cmd *syntax.BinaryCmd) error {
+	switch cmd.Op {
+	case syntax.Pipe, syntax.PipeAll:
+		if c.policy.denyPipelines {
+			return &policyViolation{command: c.source(cmd), reason: "pipelines are not allowed by tools.exec.policy"}
+		}
+		if c.policy.structural {
+			if name, ok := c.stdinShell(cmd.Y); ok {
+				return &policyViolation{command: c.source(cmd), reason: fmt.Sprintf(
+					"piping into %s runs code that cannot be reviewed; save it to a file in the workspace "+
+						"and inspect it first", name)}
+			}
+		}
+		// Each side of a pipeline runs in its own subshell.
+		if err := c.scoped(func() error { return c.stmt(cmd.X) }); err != nil {
+			return err
+		}
+		return c.scoped(func() error { return c.stmt(cmd.Y) })
+	case syntax.AndStmt:
+		if err := c.stmt(cmd.X); err != nil {
+			return err
+		}
+		return c.stmt(cmd.Y)
+	default: // OrStmt
+		before := c.cwds
+		if err := c.stmt(cmd.X); err != nil {
+			return err
+		}
+		left := c.cwds
+		c.cwds = unionDirs(before, left)
+		if err := c.stmt(cmd.Y); err != nil {
+			return err
+		}
+		c.cwds = unionDirs(left, c.cwds)
+		return nil
+	}
+}
+
+// stdinShell reports whether stmt runs a shell that reads its script from
+// standard input.
+func (c *policyCheck) stdinShell(stmt *syntax.Stmt) (string, bool) {
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	args := c.expandArgs(call.Args)
+	for len(args) > 0 && args[0].literal {
+		name := commandBaseName(args[0].value)
+		if shells[name] {
+			for _, a := range args[1:] {
+				if !a.literal || a.value == "-" || a.value == "-s" {
+					break
+				}
+				if strings.HasPrefix(a.value, "-") && !strings.HasPrefix(a.value, "--") &&
+					strings.Contains(a.value[1:], "c") {
+					return "", false
+				}
+				if !strings.HasPrefix(a.value, "-") && !strings.HasPrefix(a.value, "+") {
+					return "", false // script file operand
+				}
+			}
+			return name, true
+		}
+		if _, ok := commandWrappers[name]; !ok {
+			return "", false
+		}
+		args = unwrapCommand(name, args)
+	}
+	return "", false
+}
+
+// stdinScript checks the heredoc or here-string that stmt feeds to a shell
+// reading its script from stdin, like the script of sh -c.
+func (c *policyCheck) stdinScript(stmt *syntax.Stmt) error {
+	var body *syntax.Word
+	heredoc := false
+	for _, redir := range stmt.Redirs {
+		switch redir.Op {
+		case syntax.Hdoc, syntax.DashHdoc:
+			body, heredoc = redir.Hdoc, true
+		case syntax.WordHdoc:
+			body, heredoc = redir.Word, false
+		case syntax.RdrIn:
+			body = nil // the last redirection of stdin wins
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	name, ok := c.stdinShell(stmt)
+	if !ok {
+		return nil
+	}
+	value, known := c.wordValue(body)
+	if heredoc && !slices.ContainsFunc(body.Parts, func(p syntax.WordPart) bool {
+		_, lit := p.(*syntax.Lit)
+		return !lit
+	}) {
+		// Heredoc text keeps its backslashes, unlike an unquoted word.
+		var sb strings.Builder
+		for _, p := range body.Parts {
+			sb.WriteString(p.(*syntax.Lit).Value)
+		}
+		value, known = sb.String(), true
+	}
+	return c.inlineScript(name, shellArg{value: value, literal: known}, c.source(stmt))
+}
+
+// procSubstShell reports whether stmt runs a shell, source or . on the
+// output of a process substitution, as in bash <(curl ...) or
+// bash < <(curl ...).
+func (c *policyCheck) procSubstShell(stmt *syntax.Stmt) (string, bool) {
+	for _, redir := range stmt.Redirs {
+		if redir.Op == syntax.RdrIn && isProcSubstIn(redir.Word) {
+			if name, ok := c.stdinShell(stmt); ok {
+				return name, true
+			}
+		}
+	}
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	args := c.expandArgs(call.Args)
+	for len(args) > 0 && args[0].literal {
+		name := commandBaseName(args[0].value)
+		if shells[name] || name == "source" || name == "." {
+			for _, a := range args[1:] {
+				if a.procSubst {
+					return name, true
+				}
+				if !a.literal {
+					return "", false
+				}
+				if shells[name] && strings.HasPrefix(a.value, "-") && !strings.HasPrefix(a.value, "--") &&
+					strings.Contains(a.value[1:], "c") {
+					return "", false // later operands are positional parameters
+				}
+				if !strings.HasPrefix(a.value, "-") && !strings.HasPrefix(a.value, "+") {
+					return "", false // script file operand
+				}
+			}
+			return "", false
+		}
+		if _, ok := commandWrappers[name]; !ok {
+			return "", false
+		}
+		args = unwrapCommand(name, args)
+	}
+	return "", false
+}
+
+func isProcSubstIn(word *syntax.Word) bool {
+	if word == nil || len(word.Parts) != 1 {
+		return false
+	}
+	ps, ok := word.Parts[0].(*syntax.ProcSubst)
+	return ok && ps.Op == syntax.CmdIn
+}
+
+func (c *policyCheck) funcDecl(decl *syntax.FuncDecl) error {
+	if decl.Name == nil {
+		return nil
+	}
+	name := decl.Name.Value
+	if c.policy.structural && callsItself(decl.Body, name) {
+		return &policyViolation{command: c.source(decl), reason: fmt.Sprintf(
+			"function %s calls itself, which is how fork bombs work; write a loop instead", name)}
+	}
+	c.funcs[name] = true
+	return c.scoped(func() error { return c.stmt(decl.Body) })
+}
+
+func callsItself(body *syntax.Stmt, name string) bool {
+	found := false
+	syntax.Walk(body, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok && len(call.Args) > 0 && call.Args[0].Lit() == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func (c *policyCheck) assign(assign *syntax.Assign) error {
+	if assign.Value != nil {
+		if err := c.nested(assign.Value); err != nil {
+			return err
+		}
+	}
+	if assign.Array != nil {
+		if err := c.nested(assign.Array); err != nil {
+			return err
+		}
+	}
+	if assign.Name == nil {
+		return nil
+	}
+	name := assign.Name.Value
+	switch {
+	case assign.Naked:
+		if _, ok := c.vars[name]; !ok {
+			if _, set := os.LookupEnv(name); !set {
+				c.vars[name] = shellVar{}
+			}
+		}
+	case assign.Value == nil || assign.Append || assign.Index != nil:
+		c.vars[name] = shellVar{}
+	default:
+		value, ok := c.wordValue(assign.Value)
+		c.vars[name] = shellVar{value: value, known: ok}
+	}
+	return nil
+}
+
+func (c *policyCheck) redirect(redir *syntax.Redirect) error {
+	if redir.Hdoc != nil {
+		if err := c.nested(redir.Hdoc); err != nil {
+			return err
+		}
+	}
+	if redir.Word == nil {
+		return nil
+	}
+	if err := c.nested(redir.Word); err != nil {
+		return err
+	}
+	switch redir.Op {
+	case syntax.Hdoc, syntax.DashHdoc, syntax.WordHdoc, syntax.DplIn, syntax.DplOut:
+		return nil
+	}
+	target, ok := c.wordValue(redir.Word)
+	if !ok {
+		return nil
+	}
+	if c.policy.structural && redir.Op != syntax.RdrIn && strings.HasPrefix(path.Clean(target), "/dev/") &&
+		!isSafeDevice(target) {
+		return &policyViolation{command: c.source(redir), reason: fmt.Sprintf(
+			"writing to the device %s is not allowed", target)}
+	}
+	return c.checkPath(shellArg{value: target, literal: true}, c.source(redir))
+}
+
+func isSafeDevice(p string) bool {
+	p = path.Clean(p)
+	return slices.Contains(safeDevices, p) || strings.HasPrefix(p, "/dev/fd/")
+}
+
+// nested checks the command and process substitutions inside node, which
+// run in subshells.
+func (c *policyCheck) nested(node syntax.Node) error {
+	var err error
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if err != nil {
+			return false
+		}
+		var stmts []*syntax.Stmt
+		switch sub := n.(type) {
+		case *syntax.CmdSubst:
+			stmts = sub.Stmts
+		case *syntax.ProcSubst:
+			stmts = sub.Stmts
+		default:
+			return true
+		}
+		if c.policy.denySubshells {
+			err = &policyViolation{command: c.source(n),
+				reason: "command and process substitutions are not allowed by tools.exec.policy"}
+			return false
+		}
+		err = c.scoped(func() error { return c.stmts(stmts) })
+		return false
+	})
+	return err
+}
+
+func (c *policyCheck) call(call *syntax.CallExpr) error {
+	for _, assign := range call.Assigns {
+		if len(call.Args) == 0 {
+			if err := c.assign(assign); err != nil {
+				return err
+			}
+		} else if assign.Value != nil {
+			if err := c.nested(assign.Value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, word := range call.Args {
+		if err := c.nested(word); err != nil {
+			return err
+		}
+	}
+	if len(call.Args) == 0 {
+		return nil
+	}
+	args := c.expandArgs(call.Args)
+	if len(args) == 0 {
+		return nil
+	}
+	return c.run(args)
+}
+
+// run checks one resolved command line, following wrappers such as env or
+// xargs and the scripts given to sh -c.
+func (c *policyCheck) run(args []shellArg) error {
+	display := displayArgs(args)
+	first := args[0]
+	if !first.literal || first.value == "" ||
+		(first.value != "[" && strings.ContainsAny(first.value, "*?[ \t\n")) {
+		return &policyViolation{command: display, reason: "the command name is built at run time; " +
+			"write the command name out literally so it can be checked"}
+	}
+	name := commandBaseName(first.value)
+
+	if name == "cd" || name == "pushd" || name == "popd" {
+		return c.changeDir(name, args, display)
+	}
+	for _, arg := range args[1:] {
+		if err := c.checkPath(arg, display); err != nil {
+			return err
+		}
+	}
+	if !c.funcs[name] {
+		c.setByCommand(name, args)
+	}
+	if c.funcs[name] || shellBuiltins[name] {
+		return nil
+	}
+	if reason := c.policy.checkCommand(name, args); reason != "" {
+		return &policyViolation{command: display, reason: reason}
+	}
+
+	switch {
+	case shells[name]:
+		return c.shellScript(name, args, display)
+	case name == "eval" || name == "trap":
+		return c.evalScript(name, args, display)
+	case name == "find":
+		return c.findExec(args)
+	}
+	if _, ok := commandWrappers[name]; ok {
+		if script, ok := envSplitString(name, args); ok {
+			return c.inlineScript(name, script, display)
+		}
+		if inner := unwrapCommand(name, args); len(inner) > 0 {
+			if name == "xargs" {
+				inner = xargsShellInput(args, inner)
+			}
+			return c.run(inner)
+		}
+	}
+	return nil
+}
+
+// varOptions lists, for the builtins that set variables from their input,
+// the options that take a value and the option whose value is a variable
+// name.
+var varOptions = map[string]struct {
+	withValue []string
+	names     string
+}{
+	"read":      {withValue: []string{"-a", "-d", "-i", "-n", "-N", "-p", "-t", "-u"}, names: "-a"},
+	"mapfile":   {withValue: []string{"-d", "-n", "-O", "-s", "-u", "-C", "-c"}},
+	"readarray": {withValue: []string{"-d", "-n", "-O", "-s", "-u", "-C", "-c"}},
+	"getopts":   {},
+	"printf":    {withValue: []string{"-v"}, names: "-v"},
+}
+
+// setByCommand marks the variables that read, mapfile, getopts or
+// printf -v assign as unknown: their values come from input or from
+// formatting at run time, not from the environment.
+func (c *policyCheck) setByCommand(name string, args []shellArg) {
+	opts, ok := varOptions[name]
+	if !ok {
+		return
+	}
+	unknown := func(a shellArg) {
+		if a.literal {
+			c.vars[a.value] = shellVar{}
+		}
+	}
+	switch name {
+	case "read":
+		unknown(shellArg{value: "REPLY", literal: true})
+	case "mapfile", "readarray":
+		unknown(shellArg{value: "MAPFILE", literal: true})
+	case "getopts":
+		unknown(shellArg{value: "OPTARG", literal: true})
+		unknown(shellArg{value: "OPTIND", literal: true})
+	}
+	operands := 0
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a.literal && strings.HasPrefix(a.value, "-") && a.value != "-" {
+			if a.value == opts.names && i+1 < len(args) {
+				unknown(args[i+1])
+			}
+			if slices.Contains(opts.withValue, a.value) {
+				i++
+			}
+			continue
+		}
+		operands++
+		switch name {
+		case "printf":
+			return // the format and its arguments
+		case "getopts":
+			if operands == 2 {
+				unknown(a)
+			}
+		default:
+			unknown(a)
+		}
+	}
+}
+
+// xargsShellInput returns the command line of a shell run by xargs with
+// the arguments xargs fills in from its input marked as unknown: an -I
+// replacement string, or the script of a -c that xargs appends. inner is
+// the part of args after the xargs options.
+func xargsShellInput(args, inner []shellArg) []shellArg {
+	if !inner[0].literal || !shells[commandBaseName(inner[0].value)] {
+		return inner
+	}
+	replace := ""
+	for i, a := range args[1 : len(args)-len(inner)] {
+		switch {
+		case !a.literal:
+		case a.value == "-I" && i+2 < len(args):
+			replace = args[i+2].value
+		case strings.HasPrefix(a.value, "-I") && len(a.value) > 2:
+			replace = a.value[2:]
+		case a.value == "-i" || a.value == "--replace":
+			replace = "{}"
+		case strings.HasPrefix(a.value, "-i") || strings.HasPrefix(a.value, "--replace="):
+			replace = a.value[strings.IndexAny(a.value, "i=")+1:]
+		}
+	}
+	out := slices.Clone(inner)
+	for i, a := range out[1:] {
+		if replace != "" && a.literal && strings.Contains(a.value, replace) {
+			out[i+1] = shellArg{}
+		}
+	}
+	if last := out[len(out)-1]; last.literal && strings.HasPrefix(last.value, "-") &&
+		!strings.HasPrefix(last.value, "--") && strings.Contains(last.value[1:], "c") {
+		out = append(out, shellArg{}) // the script comes from the input
+	}
+	return out
+}
+
+// unwrapCommand returns the command run by a wrapper such as env, nice or
+// xargs, or nil when there is none.
+func unwrapCommand(name string, args []shellArg) []shellArg {
+	valueOptions := commandWrappers[name]
+	i := 1
+	for i < len(args) {
+		a := args[i]
+		if !a.literal {
+			break
+		}
+		if a.value == "--" {
+			i++
+			break
+		}
+		if name == "env" && strings.Contains(a.value, "=") && !strings.HasPrefix(a.value, "-") {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(a.value, "-") || a.value == "-" {
+			break
+		}
+		if name == "command" && (a.value == "-v" || a.value == "-V") {
+			return nil // only looks the command up
+		}
+		i++
+		if slices.Contains(valueOptions, a.value) {
+			i++
+		}
+	}
+	if name == "timeout" && i < len(args) {
+		i++ // duration
+	}
+	if i >= len(args) {
+		return nil
+	}
+	return args[i:]
+}
+
+// envSplitString returns the command line given to env -S.
+func envSplitString(name string, args []shellArg) (shellArg, bool) {
+	if name != "env" {
+		return shellArg{}, false
+	}
+	for i, a := range args[1:] {
+		if a.literal && (a.value == "-S" || a.value == "--split-string") && i+2 < len(args) {
+			return args[i+2], true
+		}
+		if a.literal && strings.HasPrefix(a.value, "--split-string=") {
+			return shellArg{value: strings.TrimPrefix(a.value, "--split-string="), literal: true}, true
+		}
+	}
+	return shellArg{}, false
+}
+
+func (c *policyCheck) shellScript(name string, args []shellArg, display string) error {
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if !a.literal || !strings.HasPrefix(a.value, "-") || a.value == "--" {
+			return nil // script file or stdin; its contents are not known here
+		}
+		if a.value == "-o" {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(a.value, "--") && strings.Contains(a.value[1:], "c") {
+			if i+1 >= len(args) {
+				return nil
+			}
+			return c.inlineScript(name, args[i+1], display)
+		}
+	}
+	return nil
+}
+
+func (c *policyCheck) evalScript(name string, args []shellArg, display string) error {
+	if len(args) < 2 {
+		return nil
+	}
+	if name == "trap" {
+		if args[1].literal && (args[1].value == "-" || strings.HasPrefix(args[1].value, "-") || args[1].value == "") {
+			return nil
+		}
+		return c.inlineScript(name, args[1], display)
+	}
+	parts := make([]string, 0, len(args)-1)
+	for _, a := range args[1:] {
+		if !a.literal {
+			return c.inlineScript(name, a, display)
+		}
+		parts = append(parts, a.value)
+	}
+	return c.inlineScript(name, shellArg{value: strings.Join(parts, " "), literal: true}, display)
+}
+
+// inlineScript checks shell code passed as an argument to name.
+func (c *policyCheck) inlineScript(name string, script shellArg, display string) error {
+	if !script.literal {
+		return &policyViolation{command: display, reason: fmt.Sprintf(
+			"the script passed to %s is built at run time; write it out literally so it can be checked", name)}
+	}
+	if c.policy.denySubshells {
+		return &policyViolation{command: display, reason: "nested shells are not allowed by tools.exec.policy"}
+	}
+	if c.depth >= maxNestedShells {
+		return &policyViolation{command: display, reason: "shells are nested too deeply; run the command directly"}
+	}
+	return c.scoped(func() error {
+		c.depth++
+		defer func() { c.depth-- }()
+		funcs := c.funcs
+		c.funcs = map[string]bool{}
+		defer func() { c.funcs = funcs }()
+		return c.script(script.value)
+	})
+}
+
+// findExec checks the commands run by find -exec and its variants.
+func (c *policyCheck) findExec(args []shellArg) error {
+	for i := 1; i < len(args); i++ {
+		switch args[i].value {
+		case "-exec", "-execdir", "-ok", "-okdir":
+		default:
+			continue
+		}
+		j := i + 1
+		for j < len(args) && args[j].value != ";" && args[j].value != "+" {
+			j++
+		}
+		if j > i+1 {
+			if err := c.run(args[i+1 : j]); err != nil {
+				return err
+			}
+		}
+		i = j
+	}
+	return nil
+}
+
+func (c *policyCheck) changeDir(name string, args []shellArg, display string) error {
+	if c.workspace == "" {
+		return nil
+	}
+	if name == "popd" {
+		c.cwds = c.seen
+		return nil
+	}
+	var target *shellArg
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a.literal && strings.HasPrefix(a.value, "-") && a.value != "-" {
+			continue
+		}
+		target = &args[i]
+		break
+	}
+	if target == nil {
+		home, _ := os.UserHomeDir()
+		target = &shellArg{value: home, literal: home != ""}
+	}
+	if !target.literal || target.value == "-" {
+		return &policyViolation{command: display, reason: "the cd target is only known at run time; " +
+			"cd to a literal path inside the workspace " + c.workspace}
+	}
+	next := make([]string, 0, len(c.cwds))
+	for _, dir := range c.cwds {
+		p := c.resolve(dir, target.value)
+		if !isWithinWorkspace(p, c.workspace) {
+			return &policyViolation{command: display, reason: fmt.Sprintf(
+				"%s %s leaves the workspace %s; stay inside it", name, target.value, c.workspace)}
+		}
+		next = append(next, p)
+	}
+	c.cwds = unionDirs(nil, next)
+	c.seen = unionDirs(c.seen, next)
+	return nil
+}
+
+// checkPath rejects a path-like argument that resolves outside the
+// workspace from any directory the shell may be in.
+func (c *policyCheck) checkPath(arg shellArg, display string) error {
+	if c.workspace == "" || !arg.literal {
+		return nil
+	}
+	value := arg.value
+	if strings.HasPrefix(value, "-") {
+		_, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil
+		}
+		value = v
+	}
+	if !looksLikePath(value) {
+		return nil
+	}
+	for _, dir := range c.cwds {
+		p := c.resolve(dir, value)
+		if !isWithinWorkspace(p, c.workspace) && !isSafeDevice(p) {
+			return &policyViolation{command: display, reason: fmt.Sprintf(
+				"path %s resolves to %s, outside the workspace %s; use paths inside the workspace",
+				value, p, c.workspace)}
+		}
+	}
+	return nil
+}
+
+func (c *policyCheck) resolve(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(dir, p)
+}
+
+func looksLikePath(v string) bool {
+	if v == "" || strings.Contains(v, "://") {
+		return false
+	}
+	return v == ".." || strings.ContainsAny(v, `/\`)
+}
+
+// expandArgs resolves command words, applying brace expansion.
+func (c *policyCheck) expandArgs(words []*syntax.Word) []shellArg {
+	args := make([]shellArg, 0, len(words))
+	for _, word := range words {
+		args = append(args, c.expandWord(word)...)
+	}
+	return args
+}
+
+func (c *policyCheck) expandWord(word *syntax.Word) []shellArg {
+	// SplitBraces rewrites the word in place; keep the parsed tree intact
+	// for the checks that walk it later.
+	split := *word
+	if !syntax.SplitBraces(&split) {
+		value, ok := c.wordValue(word)
+		return []shellArg{{value: value, literal: ok, procSubst: isProcSubstIn(word)}}
+	}
+	word = &split
+	for _, part := range word.Parts {
+		// Don't materialise sequences such as {1..1000000}.
+		if brace, ok := part.(*syntax.BraceExp); ok && brace.Sequence {
+			return []shellArg{{}}
+		}
+	}
+	var args []shellArg
+	for _, w := range expand.Braces(word) {
+		value, ok := c.wordValue(w)
+		args = append(args, shellArg{value: value, literal: ok})
+	}
+	return args
+}
+
+// wordValue returns the value of word after quote removal, tilde and
+// variable expansion, and whether it could be known without running
+// anything.
+func (c *policyCheck) wordValue(word *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for i, part := range word.Parts {
+		if !c.appendPart(&sb, part, i == 0, false) {
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+func (c *policyCheck) appendPart(sb *strings.Builder, part syntax.WordPart, first, quoted bool) bool {
+	switch p := part.(type) {
+	case *syntax.Lit:
+		value := p.Value
+		if quoted {
+			sb.WriteString(unescapeDoubleQuoted(value))
+			return true
+		}
+		if first && (value == "~" || strings.HasPrefix(value, "~/")) {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return false
+			}
+			value = home + value[1:]
+		}
+		sb.WriteString(unescapeUnquoted(value))
+	case *syntax.SglQuoted:
+		if p.Dollar {
+			return false
+		}
+		sb.WriteString(p.Value)
+	case *syntax.DblQuoted:
+		if p.Dollar {
+			return false
+		}
+		for _, inner := range p.Parts {
+			if !c.appendPart(sb, inner, false, true) {
+				return false
+			}
+		}
+	case *syntax.ParamExp:
+		if !simpleParam(p) {
+			return false
+		}
+		value, ok := c.lookupVar(p.Param.Value)
+		// Unquoted values are split into fields and globbed.
+		if !ok || (!quoted && strings.ContainsAny(value, " \t\n*?[")) {
+			return false
+		}
+		sb.WriteString(value)
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *policyCheck) lookupVar(name string) (string, bool) {
+	if v, ok := c.vars[name]; ok {
+		return v.value, v.known
+	}
+	if name == "" || !(name[0] == '_' || isASCIILetter(name[0])) {
+		return "", false // positional and special parameters
+	}
+	return os.Getenv(name), true
+}
+
+func simpleParam(p *syntax.ParamExp) bool {
+	return p.Param != nil && p.Flags == nil && !p.Excl && !p.Length && !p.Width && !p.IsSet &&
+		p.NestedParam == nil && p.Index == nil && len(p.Modifiers) == 0 && p.Slice == nil &&
+		p.Repl == nil && p.Names == 0 && p.Exp == nil
+}
+
+func unescapeUnquoted(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func unescapeDoubleQuoted(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// commandBaseName strips the directory so /bin/rm is checked as rm.
+func commandBaseName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".exe")
+}
+
+func displayArgs(args []shellArg) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		if a.literal {
+			parts[i] = a.value
+		} else {
+			parts[i] = "<dynamic>"
+		}
+	}
+	s := strings.Join(parts, " ")
+	if len(s) > 120 {
+		s = s[:117] + "..."
+	}
+	return s
+}
+
+func (c *policyCheck) source(node syntax.Node) string {
+	var sb strings.Builder
+	if err := syntax.NewPrinter(syntax.SingleLine(true)).Print(&sb, node); err != nil {
+		return ""
+	}
+	s := strings.TrimSpace(sb.String())
+	if len(s) > 120 {
+		s = s[:117] + "..."
+	}
+	return s
+}
+
+// unionDirs returns the directories in a or b, without duplicates.
+func unionDirs(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, dir := range b {
+		if !slices.Contains(out, dir) {
+			out = append(out, dir)
+		}
+	}
+	return out
+}
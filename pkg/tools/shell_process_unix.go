@@ -3,6 +3,7 @@
 package tools
 
 import (
+	"fmt"
 	"os/exec"
 	"syscall"
 )
@@ -30,3 +31,28 @@ func terminateProcessTree(cmd *exec.Cmd) error {
 	_ = cmd.Process.Kill()
 	return nil
 }
+
+var processSignals = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"INT":  syscall.SIGINT,
+	"HUP":  syscall.SIGHUP,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
+// signalProcessTree sends the named signal to the process group started
+// for cmd.
+func signalProcessTree(cmd *exec.Cmd, name string) error {
+	sig, ok := processSignals[name]
+	if !ok {
+		return fmt.Errorf("unsupported signal %q", name)
+	}
+	if cmd == nil || cmd.Process == nil || cmd.Process.Pid <= 0 {
+		return fmt.Errorf("process is not running")
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
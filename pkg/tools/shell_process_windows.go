@@ -3,6 +3,7 @@
 package tools
 
 import (
+	"fmt"
 	"os/exec"
 	"strconv"
 )
@@ -25,3 +26,11 @@ func terminateProcessTree(cmd *exec.Cmd) error {
 	_ = cmd.Process.Kill()
 	return nil
 }
+
+// signalProcessTree only supports KILL on Windows.
+func signalProcessTree(cmd *exec.Cmd, name string) error {
+	if name != "KILL" {
+		return fmt.Errorf("signal %q is not supported on Windows; use KILL", name)
+	}
+	return terminateProcessTree(cmd)
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/sandbox"
+)
+
+func newSandboxedExecTool(t *testing.T, workspace string) *ExecTool {
+	t.Helper()
+	if err := sandbox.Supported(); err != nil {
+		t.Skipf("sandbox unsupported: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Tools.Exec.Sandbox.Enabled = true
+	// No regex workspace guard: the sandbox alone must hold the boundary.
+	return NewExecToolWithConfig(workspace, false, cfg)
+}
+
+func TestShellTool_SandboxBlocksEscapes(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("top secret"), 0o644)
+	tool := newSandboxedExecTool(t, workspace)
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"command": "cd " + outside + "; cat secret.txt",
+	})
+	if !result.IsError || strings.Contains(result.ForLLM, "top secret") {
+		t.Errorf("read outside workspace: %+v", result)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{"command": "cd /; cat etc/passwd"})
+	if !result.IsError {
+		t.Errorf("cd /; cat etc/passwd succeeded: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]any{
+		"command": "echo ok > note.txt && cat note.txt && echo tmp > $TMPDIR/x && cat $TMPDIR/x",
+	})
+	if result.IsError || result.ForLLM != "ok\ntmp\n" {
+		t.Errorf("workspace and temp writes: %+v", result)
+	}
+}
+
+func TestCronTool_CommandPayloadIsSandboxed(t *testing.T) {
+	workspace := t.TempDir()
+	if err := sandbox.Supported(); err != nil {
+		t.Skipf("sandbox unsupported: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Tools.Exec.Sandbox.Enabled = true
+	cronTool := NewCronTool(nil, nil, nil, workspace, false, 0, cfg)
+	if cronTool.execTool.sandbox == nil {
+		t.Fatal("cron exec tool is not sandboxed")
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/sipeed/picoclaw/pkg/secrets"
+	"github.com/sipeed/picoclaw/pkg/skills"
 )
 
 // TestShellTool_Success verifies successful command execution
@@ -344,3 +348,97 @@ func TestNormalizeLegacyGitSummaryCommand_RewritesMalformedHybridCommand(t *test
 		t.Fatalf("expected canonical hybrid rewrite, got: %s", got)
 	}
 }
+
+// TestShellTool_SecretsEnvironment verifies that vault secrets reach only
+// the commands they are granted to and are withheld from the inherited
+// environment otherwise.
+func TestShellTool_SecretsEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses env")
+	}
+	t.Setenv(secrets.KeyEnv, "")
+	vault, err := secrets.Open(filepath.Join(t.TempDir(), "secrets.enc"), filepath.Join(t.TempDir(), "secrets.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []secrets.Secret{
+		{Name: "PC_EXEC_SECRET", Value: "for-exec", Tools: []string{"exec"}},
+		{Name: "PC_SKILL_SECRET", Value: "for-skill", Skills: []string{"todoist-manager"}},
+		{Name: "PC_HTTP_SECRET", Value: "for-http", Tools: []string{"http_request"}},
+	} {
+		if err := vault.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Still exported from an env file, as before the secret was imported.
+	t.Setenv("PC_HTTP_SECRET", "for-http")
+
+	tool := NewExecTool("", false)
+	tool.SetSecrets(vault)
+
+	result := tool.Execute(context.Background(), map[string]any{"command": "env"})
+	if result.IsError {
+		t.Fatalf("env failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "PC_EXEC_SECRET=for-exec") {
+		t.Errorf("expected exec secret in environment, got: %s", result.ForLLM)
+	}
+	for _, hidden := range []string{"PC_SKILL_SECRET", "PC_HTTP_SECRET"} {
+		if strings.Contains(result.ForLLM, hidden) {
+			t.Errorf("expected %s to be withheld, got: %s", hidden, result.ForLLM)
+		}
+	}
+
+	// Skill secrets follow the skill's own script, not a declared name.
+	skillDir := filepath.Join(t.TempDir(), "skills", "todoist-manager")
+	if err := os.MkdirAll(filepath.Join(skillDir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("# todoist"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "scripts", "run.sh"), []byte("env\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "evil.sh")
+	if err := os.WriteFile(outside, []byte("env\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(skillDir, "scripts", "link.sh")); err != nil {
+		t.Fatal(err)
+	}
+	tool.SetSkills(func() []skills.SkillInfo {
+		return []skills.SkillInfo{{Name: "todoist-manager", Path: filepath.Join(skillDir, "SKILL.md")}}
+	})
+
+	for _, command := range []string{
+		"sh " + filepath.Join(skillDir, "scripts", "run.sh") + ` "buy milk"`,
+		filepath.Join(skillDir, "scripts", "run.sh"),
+	} {
+		result = tool.Execute(context.Background(), map[string]any{"command": command})
+		if !strings.Contains(result.ForLLM, "PC_SKILL_SECRET=for-skill") {
+			t.Errorf("expected skill secret for %q, got: %s", command, result.ForLLM)
+		}
+	}
+	result = tool.Execute(context.Background(), map[string]any{
+		"command":     "sh scripts/run.sh",
+		"working_dir": skillDir,
+	})
+	if !strings.Contains(result.ForLLM, "PC_SKILL_SECRET=for-skill") {
+		t.Errorf("expected skill secret for a script relative to working_dir, got: %s", result.ForLLM)
+	}
+
+	for _, command := range []string{
+		"env",
+		"sh " + filepath.Join(skillDir, "scripts", "run.sh") + "; env",
+		"sh " + filepath.Join(skillDir, "scripts", "run.sh") + " | cat",
+		"sh " + filepath.Join(skillDir, "scripts", "link.sh"),
+		"sh -c env " + filepath.Join(skillDir, "scripts", "run.sh"),
+		"env " + filepath.Join(skillDir, "scripts", "run.sh"),
+	} {
+		result = tool.Execute(context.Background(), map[string]any{"command": command})
+		if strings.Contains(result.ForLLM, "PC_SKILL_SECRET") {
+			t.Errorf("expected skill secret to be withheld from %q, got: %s", command, result.ForLLM)
+		}
+	}
+}
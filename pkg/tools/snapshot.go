@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Snapshotter records a file's content before a tool changes it, so that
+// the change can be undone. existed is false when the file is created.
+type Snapshotter interface {
+	Snapshot(path string, content []byte, existed bool) error
+}
+
+// SnapshottingTool is implemented by tools that change files and can
+// record them in a Snapshotter first. WithSnapshotter returns a copy of the
+// tool that records in s instead, for a turn that groups its changes on
+// its own.
+type SnapshottingTool interface {
+	Tool
+	SetSnapshotter(s Snapshotter)
+	WithSnapshotter(s Snapshotter) Tool
+}
+
+// snapshotFs wraps a fileSystem, recording each file before it is written
+// or removed.
+type snapshotFs struct {
+	fileSystem
+	snapshots Snapshotter
+	workspace string // resolves relative paths for the sandbox; "" uses the working directory
+}
+
+func withSnapshots(sysFs fileSystem, s Snapshotter) fileSystem {
+	if inner, ok := sysFs.(*snapshotFs); ok {
+		sysFs = inner.fileSystem
+	}
+	workspace := ""
+	if sandbox, ok := sysFs.(*sandboxFs); ok {
+		workspace = sandbox.workspace
+	}
+	return &snapshotFs{fileSystem: sysFs, snapshots: s, workspace: workspace}
+}
+
+func (s *snapshotFs) WriteFile(path string, data []byte) error {
+	s.record(path)
+	return s.fileSystem.WriteFile(path, data)
+}
+
+func (s *snapshotFs) Remove(path string) error {
+	s.record(path)
+	return s.fileSystem.Remove(path)
+}
+
+// record snapshots path. A failure is logged rather than returned: losing
+// the ability to undo should not stop the agent from working.
+func (s *snapshotFs) record(path string) {
+	content, err := s.fileSystem.ReadFile(path)
+	existed := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return // the write will fail the same way
+	}
+
+	abs := path
+	if !filepath.IsAbs(abs) && s.workspace != "" {
+		abs = filepath.Join(s.workspace, abs)
+	}
+	if err := s.snapshots.Snapshot(abs, content, existed); err != nil {
+		logger.WarnCF("tools", "Failed to snapshot file before changing it",
+			map[string]any{"path": path, "error": err.Error()})
+	}
+}
+
+func (t *WriteFileTool) SetSnapshotter(s Snapshotter) {
+	t.fs = withSnapshots(t.fs, s)
+}
+
+func (t *EditFileTool) SetSnapshotter(s Snapshotter) {
+	t.fs = withSnapshots(t.fs, s)
+}
+
+func (t *AppendFileTool) SetSnapshotter(s Snapshotter) {
+	t.fs = withSnapshots(t.fs, s)
+}
+
+func (t *ApplyPatchTool) SetSnapshotter(s Snapshotter) {
+	t.fs = withSnapshots(t.fs, s)
+}
+
+func (t *WriteFileTool) WithSnapshotter(s Snapshotter) Tool {
+	return &WriteFileTool{fs: withSnapshots(t.fs, s)}
+}
+
+func (t *EditFileTool) WithSnapshotter(s Snapshotter) Tool {
+	return &EditFileTool{fs: withSnapshots(t.fs, s)}
+}
+
+func (t *AppendFileTool) WithSnapshotter(s Snapshotter) Tool {
+	return &AppendFileTool{fs: withSnapshots(t.fs, s)}
+}
+
+func (t *ApplyPatchTool) WithSnapshotter(s Snapshotter) Tool {
+	return &ApplyPatchTool{fs: withSnapshots(t.fs, s)}
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSnapshot struct {
+	path    string
+	content string
+	existed bool
+}
+
+type fakeSnapshotter struct {
+	snapshots []recordedSnapshot
+}
+
+func (f *fakeSnapshotter) Snapshot(path string, content []byte, existed bool) error {
+	f.snapshots = append(f.snapshots, recordedSnapshot{path, string(content), existed})
+	return nil
+}
+
+func TestSnapshottingTools_RecordBeforeChanging(t *testing.T) {
+	workspace := t.TempDir()
+	writeTree(t, workspace, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"})
+	snaps := &fakeSnapshotter{}
+
+	write := NewWriteFileTool(workspace, true)
+	edit := NewEditFileTool(workspace, true)
+	patch := NewApplyPatchTool(workspace, true)
+	for _, tool := range []SnapshottingTool{write, edit, patch} {
+		tool.SetSnapshotter(snaps)
+		tool.SetSnapshotter(snaps) // setting it again must not snapshot twice
+	}
+
+	results := []*ToolResult{
+		write.Execute(t.Context(), map[string]any{"path": "new.txt", "content": "fresh"}),
+		edit.Execute(t.Context(), map[string]any{"path": "a.txt", "old_text": "alpha", "new_text": "ALPHA"}),
+		patch.Execute(t.Context(), map[string]any{"patch": "*** Begin Patch\n*** Delete File: b.txt\n*** End Patch"}),
+	}
+	for _, result := range results {
+		require.False(t, result.IsError, result.ForLLM)
+	}
+
+	assert.Equal(t, []recordedSnapshot{
+		{filepath.Join(workspace, "new.txt"), "", false},
+		{filepath.Join(workspace, "a.txt"), "alpha\n", true},
+		{filepath.Join(workspace, "b.txt"), "beta\n", true},
+	}, snaps.snapshots)
+}
+
+func TestToolRegistry_WithSnapshotter(t *testing.T) {
+	workspace := t.TempDir()
+	shared := &fakeSnapshotter{}
+	write := NewWriteFileTool(workspace, true)
+	write.SetSnapshotter(shared)
+	registry := NewToolRegistry()
+	registry.Register(write)
+	registry.Register(NewReadFileTool(workspace, true))
+
+	turn := &fakeSnapshotter{}
+	scoped := registry.WithSnapshotter(turn)
+	assert.Equal(t, registry.List(), scoped.List())
+
+	result := scoped.Execute(t.Context(), "write_file", map[string]any{"path": "a.txt", "content": "x"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Len(t, turn.snapshots, 1)
+	assert.Empty(t, shared.snapshots, "the original registry keeps its own snapshotter")
+
+	result = registry.Execute(t.Context(), "write_file", map[string]any{"path": "b.txt", "content": "y"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Len(t, shared.snapshots, 1)
+	assert.Len(t, turn.snapshots, 1)
+}
@@ -61,6 +61,13 @@ func (t *SpawnTool) SetContext(channel, chatID string) {
 	t.originChatID = chatID
 }
 
+// ForSession returns a spawn tool with the same manager and allowlist.
+func (t *SpawnTool) ForSession(string) Tool {
+	c := NewSpawnTool(t.manager)
+	c.allowlistCheck = t.allowlistCheck
+	return c
+}
+
 func (t *SpawnTool) SetAllowlistChecker(check func(targetAgentID string) bool) {
 	t.allowlistCheck = check
 }
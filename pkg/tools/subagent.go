@@ -2,24 +2,85 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/providers"
 )
 
+// Subagent task statuses. A task that was running when PicoClaw stopped is
+// interrupted.
+const (
+	SubagentRunning     = "running"
+	SubagentCompleted   = "completed"
+	SubagentFailed      = "failed"
+	SubagentCanceled    = "canceled"
+	SubagentTimedOut    = "timed_out"
+	SubagentInterrupted = "interrupted"
+)
+
 type SubagentTask struct {
-	ID            string
-	Task          string
-	Label         string
-	AgentID       string
-	OriginChannel string
-	OriginChatID  string
-	Status        string
-	Result        string
-	Created       int64
+	ID            string `json:"id"`
+	Task          string `json:"task"`
+	Label         string `json:"label,omitempty"`
+	AgentID       string `json:"agent_id,omitempty"`
+	OriginChannel string `json:"origin_channel"`
+	OriginChatID  string `json:"origin_chat_id"`
+	Background    bool   `json:"background,omitempty"` // Started by spawn rather than waited for
+	Status        string `json:"status"`
+	Result        string `json:"result,omitempty"`
+	Iterations    int    `json:"iterations,omitempty"`
+	Created       int64  `json:"created"`
+	Finished      int64  `json:"finished,omitempty"`
+}
+
+// SubagentRunner runs task as one turn of the agent task.AgentID, or of
+// the agent that owns the manager when AgentID is empty, and returns its
+// final answer.
+type SubagentRunner func(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error)
+
+// Default limits on subagent nesting and on the number of subagents a
+// manager runs at once.
+const (
+	DefaultSubagentMaxDepth      = 2
+	DefaultSubagentMaxConcurrent = 4
+)
+
+// DefaultSubagentTimeout is the default wall-clock limit of a subagent task.
+const DefaultSubagentTimeout = 30 * time.Minute
+
+// maxFinishedSubagentTasks is how many finished tasks are kept; older ones
+// are dropped.
+const maxFinishedSubagentTasks = 50
+
+// Metadata keys of the system message that reports a spawned subagent's
+// result to the agent that spawned it.
+const (
+	SubagentMetaTaskID = "subagent_task_id"
+	SubagentMetaLabel  = "subagent_label"
+	SubagentMetaAgent  = "subagent_agent_id"
+	SubagentMetaParent = "subagent_parent_id"
+	SubagentMetaStatus = "subagent_status"
+)
+
+type subagentDepthKey struct{}
+
+// SubagentDepth returns how many subagents deep ctx is: 0 in a turn
+// started by a user message, 1 inside a subagent, and so on.
+func SubagentDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(subagentDepthKey{}).(int)
+	return depth
 }
 
 type SubagentManager struct {
@@ -30,6 +91,15 @@ type SubagentManager struct {
 	bus            *bus.MessageBus
 	workspace      string
 	tools          *ToolRegistry
+	runner         SubagentRunner
+	parentID       string
+	maxDepth       int
+	maxConcurrent  int
+	running        int
+	timeout        time.Duration
+	cancels        map[string]context.CancelFunc
+	storePath      string // tasks.json; empty when tasks are not persisted
+	orphans        []*SubagentTask
 	maxIterations  int
 	maxTokens      int
 	temperature    float64
@@ -50,6 +120,10 @@ func NewSubagentManager(
 		bus:           bus,
 		workspace:     workspace,
 		tools:         NewToolRegistry(),
+		maxDepth:      DefaultSubagentMaxDepth,
+		maxConcurrent: DefaultSubagentMaxConcurrent,
+		timeout:       DefaultSubagentTimeout,
+		cancels:       make(map[string]context.CancelFunc),
 		maxIterations: 10,
 		nextID:        1,
 	}
@@ -80,14 +154,164 @@ func (sm *SubagentManager) RegisterTool(tool Tool) {
 	sm.tools.Register(tool)
 }
 
+// SetRunner makes subagents run as turns of configured agents, with their
+// own model, tools, skills and workspace, instead of a bare tool loop on
+// the manager's provider. parentID names the agent that owns the manager
+// and is reported with spawned results.
+func (sm *SubagentManager) SetRunner(parentID string, runner SubagentRunner) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.parentID = parentID
+	sm.runner = runner
+}
+
+// SetLimits sets how deep subagents may nest and how many may run at once.
+// Values of zero or less keep the current limit.
+func (sm *SubagentManager) SetLimits(maxDepth, maxConcurrent int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if maxDepth > 0 {
+		sm.maxDepth = maxDepth
+	}
+	if maxConcurrent > 0 {
+		sm.maxConcurrent = maxConcurrent
+	}
+}
+
+// SetTimeout sets the wall-clock limit of each task. Values of zero or less
+// keep the current limit.
+func (sm *SubagentManager) SetTimeout(timeout time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if timeout > 0 {
+		sm.timeout = timeout
+	}
+}
+
+// EnablePersistence keeps task records in <workspace>/subagents/tasks.json
+// and loads the records of earlier runs. Tasks that were still running are
+// marked interrupted and reported by ReportOrphans.
+func (sm *SubagentManager) EnablePersistence() error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.storePath = filepath.Join(sm.workspace, "subagents", "tasks.json")
+
+	data, err := os.ReadFile(sm.storePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read subagent tasks: %w", err)
+	}
+	var tasks []*SubagentTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("failed to parse subagent tasks: %w", err)
+	}
+	for _, task := range tasks {
+		if n, err := strconv.Atoi(strings.TrimPrefix(task.ID, "subagent-")); err == nil && n >= sm.nextID {
+			sm.nextID = n + 1
+		}
+		if task.Status == SubagentRunning {
+			task.Status = SubagentInterrupted
+			task.Result = "Interrupted by a restart before it finished"
+			task.Finished = time.Now().UnixMilli()
+			sm.orphans = append(sm.orphans, task)
+		}
+		sm.tasks[task.ID] = task
+	}
+	if len(sm.orphans) > 0 {
+		return sm.saveLocked()
+	}
+	return nil
+}
+
+// ReportOrphans publishes a system message for each task interrupted by
+// the last restart, so that the agent that started it can tell the user or
+// start it again. It returns the number of tasks reported.
+func (sm *SubagentManager) ReportOrphans() int {
+	sm.mu.Lock()
+	orphans := sm.orphans
+	sm.orphans = nil
+	sm.mu.Unlock()
+	for _, task := range orphans {
+		sm.publishResult(task)
+	}
+	return len(orphans)
+}
+
+// Cancel stops a running task.
+func (sm *SubagentManager) Cancel(taskID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	task, ok := sm.tasks[taskID]
+	if !ok {
+		return fmt.Errorf("task %s not found", taskID)
+	}
+	cancel, ok := sm.cancels[taskID]
+	if !ok {
+		return fmt.Errorf("task %s is not running (%s)", taskID, task.Status)
+	}
+	cancel()
+	return nil
+}
+
+// Spawn starts task in the background. When it finishes, callback is
+// called and the result is published to the bus as a system message.
 func (sm *SubagentManager) Spawn(
 	ctx context.Context,
 	task, label, agentID, originChannel, originChatID string,
 	callback AsyncCallback,
 ) (string, error) {
+	subagentTask, taskCtx, err := sm.start(ctx, task, label, agentID, originChannel, originChatID, true)
+	if err != nil {
+		return "", err
+	}
+
+	// Start task in background with context cancellation support
+	go sm.runTask(taskCtx, subagentTask, callback)
+
+	if label != "" {
+		return fmt.Sprintf("Spawned subagent '%s' for task: %s", label, task), nil
+	}
+	return fmt.Sprintf("Spawned subagent for task: %s", task), nil
+}
+
+// Run runs task and waits for it to finish. The finished task is returned
+// even when it failed.
+func (sm *SubagentManager) Run(
+	ctx context.Context,
+	task, label, agentID, originChannel, originChatID string,
+) (*SubagentTask, error) {
+	subagentTask, taskCtx, err := sm.start(ctx, task, label, agentID, originChannel, originChatID, false)
+	if err != nil {
+		return nil, err
+	}
+	err = sm.execute(taskCtx, subagentTask)
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	finished := *subagentTask
+	return &finished, err
+}
+
+// start checks the depth and concurrency limits and records a new running
+// task. The task runs under the returned context, which is canceled by
+// Cancel and at the manager's timeout.
+func (sm *SubagentManager) start(
+	ctx context.Context,
+	task, label, agentID, originChannel, originChatID string,
+	background bool,
+) (*SubagentTask, context.Context, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	if depth := SubagentDepth(ctx); depth >= sm.maxDepth {
+		return nil, nil, fmt.Errorf("subagents may only nest %d level(s) deep; do this task yourself", sm.maxDepth)
+	}
+	if sm.running >= sm.maxConcurrent {
+		return nil, nil, fmt.Errorf("%d subagents are already running; wait for one to finish", sm.running)
+	}
+	sm.running++
+
 	taskID := fmt.Sprintf("subagent-%d", sm.nextID)
 	sm.nextID++
 
@@ -98,24 +322,73 @@ func (sm *SubagentManager) Spawn(
 		AgentID:       agentID,
 		OriginChannel: originChannel,
 		OriginChatID:  originChatID,
-		Status:        "running",
+		Background:    background,
+		Status:        SubagentRunning,
 		Created:       time.Now().UnixMilli(),
 	}
 	sm.tasks[taskID] = subagentTask
+	taskCtx, cancel := context.WithTimeout(ctx, sm.timeout)
+	sm.cancels[taskID] = cancel
+	if err := sm.saveLocked(); err != nil {
+		logger.WarnCF("subagent", "Failed to save subagent tasks", map[string]any{"error": err.Error()})
+	}
+	return subagentTask, taskCtx, nil
+}
 
-	// Start task in background with context cancellation support
-	go sm.runTask(ctx, subagentTask, callback)
+// execute runs a started task one level deeper than ctx and records its
+// outcome.
+func (sm *SubagentManager) execute(ctx context.Context, task *SubagentTask) error {
+	var loopResult *ToolLoopResult
+	err := ctx.Err()
+	if err == nil {
+		sm.mu.RLock()
+		runner := sm.runner
+		sm.mu.RUnlock()
+
+		runCtx := context.WithValue(ctx, subagentDepthKey{}, SubagentDepth(ctx)+1)
+		if runner != nil {
+			loopResult, err = runner(runCtx, task)
+		} else {
+			loopResult, err = sm.runToolLoop(runCtx, task)
+		}
+	}
 
-	if label != "" {
-		return fmt.Sprintf("Spawned subagent '%s' for task: %s", label, task), nil
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.running--
+	if cancel, ok := sm.cancels[task.ID]; ok {
+		cancel()
+		delete(sm.cancels, task.ID)
 	}
-	return fmt.Sprintf("Spawned subagent for task: %s", task), nil
-}
+	task.Finished = time.Now().UnixMilli()
+	defer func() {
+		if err := sm.saveLocked(); err != nil {
+			logger.WarnCF("subagent", "Failed to save subagent tasks", map[string]any{"error": err.Error()})
+		}
+	}()
 
-func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, callback AsyncCallback) {
-	task.Status = "running"
-	task.Created = time.Now().UnixMilli()
+	switch {
+	case err == nil:
+		task.Status = SubagentCompleted
+		task.Result = loopResult.Content
+		task.Iterations = loopResult.Iterations
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		task.Status = SubagentTimedOut
+		task.Result = fmt.Sprintf("Task stopped after the %s time limit", sm.timeout)
+	case ctx.Err() != nil:
+		task.Status = SubagentCanceled
+		task.Result = "Task canceled during execution"
+	default:
+		task.Status = SubagentFailed
+		task.Result = fmt.Sprintf("Error: %v", err)
+	}
+	return err
+}
 
+// runToolLoop runs task on the manager's provider and tools. It is used
+// when no runner is set.
+func (sm *SubagentManager) runToolLoop(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error) {
 	// Build system prompt for subagent
 	systemPrompt := `You are a subagent. Complete the given task independently and report the result.
 You have access to tools - use them as needed to complete your task.
@@ -132,17 +405,6 @@ After completing the task, provide a clear summary of what was done.`
 		},
 	}
 
-	// Check if context is already canceled before starting
-	select {
-	case <-ctx.Done():
-		sm.mu.Lock()
-		task.Status = "canceled"
-		task.Result = "Task canceled before execution"
-		sm.mu.Unlock()
-		return
-	default:
-	}
-
 	// Run tool loop with access to tools
 	sm.mu.RLock()
 	tools := sm.tools
@@ -164,34 +426,26 @@ After completing the task, provide a clear summary of what was done.`
 		}
 	}
 
-	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
+	return RunToolLoop(ctx, ToolLoopConfig{
 		Provider:      sm.provider,
 		Model:         sm.defaultModel,
 		Tools:         tools,
 		MaxIterations: maxIter,
 		LLMOptions:    llmOptions,
 	}, messages, task.OriginChannel, task.OriginChatID)
+}
 
-	sm.mu.Lock()
-	var result *ToolResult
-	defer func() {
-		sm.mu.Unlock()
-		// Call callback if provided and result is set
-		if callback != nil && result != nil {
-			callback(ctx, result)
-		}
-	}()
+func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, callback AsyncCallback) {
+	err := sm.execute(ctx, task)
+
+	sm.mu.RLock()
+	finished := *task
+	sm.mu.RUnlock()
 
+	var result *ToolResult
 	if err != nil {
-		task.Status = "failed"
-		task.Result = fmt.Sprintf("Error: %v", err)
-		// Check if it was canceled
-		if ctx.Err() != nil {
-			task.Status = "canceled"
-			task.Result = "Task canceled during execution"
-		}
 		result = &ToolResult{
-			ForLLM:  task.Result,
+			ForLLM:  finished.Result,
 			ForUser: "",
 			Silent:  false,
 			IsError: true,
@@ -199,49 +453,123 @@ After completing the task, provide a clear summary of what was done.`
 			Err:     err,
 		}
 	} else {
-		task.Status = "completed"
-		task.Result = loopResult.Content
 		result = &ToolResult{
 			ForLLM: fmt.Sprintf(
 				"Subagent '%s' completed (iterations: %d): %s",
-				task.Label,
-				loopResult.Iterations,
-				loopResult.Content,
+				finished.Label,
+				finished.Iterations,
+				finished.Result,
 			),
-			ForUser: loopResult.Content,
+			ForUser: finished.Result,
 			Silent:  false,
 			IsError: false,
 			Async:   false,
 		}
 	}
+	// Call callback if provided
+	if callback != nil {
+		callback(ctx, result)
+	}
+
+	sm.publishResult(&finished)
+}
+
+// publishResult sends a finished task back to the main agent; the metadata
+// says which task it belongs to, so the content is only the result.
+func (sm *SubagentManager) publishResult(task *SubagentTask) {
+	if sm.bus == nil {
+		return
+	}
+	sm.mu.RLock()
+	parentID := sm.parentID
+	sm.mu.RUnlock()
+	sm.bus.PublishInbound(bus.InboundMessage{
+		Channel:  "system",
+		SenderID: fmt.Sprintf("subagent:%s", task.ID),
+		// Format: "original_channel:original_chat_id" for routing back
+		ChatID:  fmt.Sprintf("%s:%s", task.OriginChannel, task.OriginChatID),
+		Content: task.Result,
+		Metadata: map[string]string{
+			SubagentMetaTaskID: task.ID,
+			SubagentMetaLabel:  task.Label,
+			SubagentMetaAgent:  task.AgentID,
+			SubagentMetaParent: parentID,
+			SubagentMetaStatus: task.Status,
+		},
+	})
+}
+
+// saveLocked writes the task records, dropping the oldest finished tasks
+// beyond maxFinishedSubagentTasks. sm.mu must be held.
+func (sm *SubagentManager) saveLocked() error {
+	tasks := sm.sortedTasksLocked()
+	finished := 0
+	for i := len(tasks) - 1; i >= 0; i-- {
+		if tasks[i].Status == SubagentRunning {
+			continue
+		}
+		if finished++; finished > maxFinishedSubagentTasks {
+			delete(sm.tasks, tasks[i].ID)
+		}
+	}
+	if sm.storePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(sm.sortedTasksLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(sm.storePath), 0o755); err != nil {
+		return err
+	}
+	tmp := sm.storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, sm.storePath)
+}
 
-	// Send announce message back to main agent
-	if sm.bus != nil {
-		announceContent := fmt.Sprintf("Task '%s' completed.\n\nResult:\n%s", task.Label, task.Result)
-		sm.bus.PublishInbound(bus.InboundMessage{
-			Channel:  "system",
-			SenderID: fmt.Sprintf("subagent:%s", task.ID),
-			// Format: "original_channel:original_chat_id" for routing back
-			ChatID:  fmt.Sprintf("%s:%s", task.OriginChannel, task.OriginChatID),
-			Content: announceContent,
-		})
+// sortedTasksLocked returns the tasks oldest first. sm.mu must be held.
+func (sm *SubagentManager) sortedTasksLocked() []*SubagentTask {
+	tasks := make([]*SubagentTask, 0, len(sm.tasks))
+	for _, task := range sm.tasks {
+		tasks = append(tasks, task)
 	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Created != tasks[j].Created {
+			return tasks[i].Created < tasks[j].Created
+		}
+		return taskNumber(tasks[i].ID) < taskNumber(tasks[j].ID)
+	})
+	return tasks
 }
 
+func taskNumber(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "subagent-"))
+	return n
+}
+
+// GetTask returns a copy of the record of taskID.
 func (sm *SubagentManager) GetTask(taskID string) (*SubagentTask, bool) {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 	task, ok := sm.tasks[taskID]
-	return task, ok
+	if !ok {
+		return nil, false
+	}
+	copied := *task
+	return &copied, true
 }
 
+// ListTasks returns copies of the task records, oldest first.
 func (sm *SubagentManager) ListTasks() []*SubagentTask {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	tasks := make([]*SubagentTask, 0, len(sm.tasks))
-	for _, task := range sm.tasks {
-		tasks = append(tasks, task)
+	tasks := sm.sortedTasksLocked()
+	for i, task := range tasks {
+		copied := *task
+		tasks[i] = &copied
 	}
 	return tasks
 }
@@ -250,9 +578,10 @@ func (sm *SubagentManager) ListTasks() []*SubagentTask {
 // Unlike SpawnTool which runs tasks asynchronously, SubagentTool waits for completion
 // and returns the result directly in the ToolResult.
 type SubagentTool struct {
-	manager       *SubagentManager
-	originChannel string
-	originChatID  string
+	manager        *SubagentManager
+	originChannel  string
+	originChatID   string
+	allowlistCheck func(targetAgentID string) bool
 }
 
 func NewSubagentTool(manager *SubagentManager) *SubagentTool {
@@ -268,7 +597,7 @@ func (t *SubagentTool) Name() string {
 }
 
 func (t *SubagentTool) Description() string {
-	return "Execute a subagent task synchronously and return the result. Use this for delegating specific tasks to an independent agent instance. Returns execution summary to user and full details to LLM."
+	return "Execute a subagent task synchronously and return the result. Use this for delegating specific tasks to an independent agent instance, optionally another configured agent with its own model and tools. Returns execution summary to user and full details to LLM."
 }
 
 func (t *SubagentTool) Parameters() map[string]any {
@@ -283,6 +612,10 @@ func (t *SubagentTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional short label for the task (for display)",
 			},
+			"agent_id": map[string]any{
+				"type":        "string",
+				"description": "Optional target agent ID to delegate the task to",
+			},
 		},
 		"required": []string{"task"},
 	}
@@ -293,6 +626,17 @@ func (t *SubagentTool) SetContext(channel, chatID string) {
 	t.originChatID = chatID
 }
 
+// ForSession returns a subagent tool with the same manager and allowlist.
+func (t *SubagentTool) ForSession(string) Tool {
+	c := NewSubagentTool(t.manager)
+	c.allowlistCheck = t.allowlistCheck
+	return c
+}
+
+func (t *SubagentTool) SetAllowlistChecker(check func(targetAgentID string) bool) {
+	t.allowlistCheck = check
+}
+
 func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	task, ok := args["task"].(string)
 	if !ok {
@@ -305,53 +649,18 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("Subagent manager not configured").WithError(fmt.Errorf("manager is nil"))
 	}
 
-	// Build messages for subagent
-	messages := []providers.Message{
-		{
-			Role:    "system",
-			Content: "You are a subagent. Complete the given task independently and provide a clear, concise result.",
-		},
-		{
-			Role:    "user",
-			Content: task,
-		},
+	agentID, _ := args["agent_id"].(string)
+	if agentID != "" && t.allowlistCheck != nil && !t.allowlistCheck(agentID) {
+		return ErrorResult(fmt.Sprintf("not allowed to delegate to agent '%s'", agentID))
 	}
 
-	// Use RunToolLoop to execute with tools (same as async SpawnTool)
-	sm := t.manager
-	sm.mu.RLock()
-	tools := sm.tools
-	maxIter := sm.maxIterations
-	maxTokens := sm.maxTokens
-	temperature := sm.temperature
-	hasMaxTokens := sm.hasMaxTokens
-	hasTemperature := sm.hasTemperature
-	sm.mu.RUnlock()
-
-	var llmOptions map[string]any
-	if hasMaxTokens || hasTemperature {
-		llmOptions = map[string]any{}
-		if hasMaxTokens {
-			llmOptions["max_tokens"] = maxTokens
-		}
-		if hasTemperature {
-			llmOptions["temperature"] = temperature
-		}
-	}
-
-	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
-		Provider:      sm.provider,
-		Model:         sm.defaultModel,
-		Tools:         tools,
-		MaxIterations: maxIter,
-		LLMOptions:    llmOptions,
-	}, messages, t.originChannel, t.originChatID)
+	finished, err := t.manager.Run(ctx, task, label, agentID, t.originChannel, t.originChatID)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
 	}
 
 	// ForUser: Brief summary for user (truncated if too long)
-	userContent := loopResult.Content
+	userContent := finished.Result
 	maxUserLen := 500
 	if len(userContent) > maxUserLen {
 		userContent = userContent[:maxUserLen] + "..."
@@ -363,7 +672,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		labelStr = "(unnamed)"
 	}
 	llmContent := fmt.Sprintf("Subagent task completed:\nLabel: %s\nIterations: %d\nResult: %s",
-		labelStr, loopResult.Iterations, loopResult.Content)
+		labelStr, finished.Iterations, finished.Result)
 
 	return &ToolResult{
 		ForLLM:  llmContent,
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+// FormatSubagentTask renders one line about task for task lists.
+func FormatSubagentTask(task *SubagentTask) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s [%s]", task.ID, task.Status)
+	if task.Label != "" {
+		sb.WriteString(" " + task.Label)
+	}
+	if task.AgentID != "" {
+		sb.WriteString(" (agent " + task.AgentID + ")")
+	}
+	started := time.UnixMilli(task.Created)
+	if task.Finished > 0 {
+		fmt.Fprintf(&sb, ", ran %s", time.UnixMilli(task.Finished).Sub(started).Round(time.Second))
+	} else {
+		fmt.Fprintf(&sb, ", running for %s", time.Since(started).Round(time.Second))
+	}
+	sb.WriteString(": " + utils.Truncate(strings.Join(strings.Fields(task.Task), " "), 80))
+	return sb.String()
+}
+
+// SubagentStatusTool reports the subagent tasks of an agent.
+type SubagentStatusTool struct {
+	manager *SubagentManager
+}
+
+func NewSubagentStatusTool(manager *SubagentManager) *SubagentStatusTool {
+	return &SubagentStatusTool{manager: manager}
+}
+
+func (t *SubagentStatusTool) Name() string {
+	return "subagent_status"
+}
+
+func (t *SubagentStatusTool) Description() string {
+	return "List subagent tasks started with spawn or subagent, or show one task's status and result."
+}
+
+func (t *SubagentStatusTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"task_id": map[string]any{
+				"type":        "string",
+				"description": "Task to show, e.g. subagent-3; omit to list all tasks",
+			},
+		},
+	}
+}
+
+func (t *SubagentStatusTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	if taskID, _ := args["task_id"].(string); taskID != "" {
+		task, ok := t.manager.GetTask(taskID)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("task %s not found", taskID))
+		}
+		text := FormatSubagentTask(task)
+		if task.Result != "" {
+			text += "\nResult:\n" + task.Result
+		}
+		return SilentResult(text)
+	}
+
+	tasks := t.manager.ListTasks()
+	if len(tasks) == 0 {
+		return SilentResult("No subagent tasks.")
+	}
+	lines := make([]string, len(tasks))
+	for i, task := range tasks {
+		lines[i] = FormatSubagentTask(task)
+	}
+	return SilentResult(strings.Join(lines, "\n"))
+}
+
+// SubagentCancelTool stops a running subagent task.
+type SubagentCancelTool struct {
+	manager *SubagentManager
+}
+
+func NewSubagentCancelTool(manager *SubagentManager) *SubagentCancelTool {
+	return &SubagentCancelTool{manager: manager}
+}
+
+func (t *SubagentCancelTool) Name() string {
+	return "subagent_cancel"
+}
+
+func (t *SubagentCancelTool) Description() string {
+	return "Stop a running subagent task, e.g. one that is no longer needed or is taking too long."
+}
+
+func (t *SubagentCancelTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"task_id": map[string]any{
+				"type":        "string",
+				"description": "Task to stop, e.g. subagent-3",
+			},
+		},
+		"required": []string{"task_id"},
+	}
+}
+
+func (t *SubagentCancelTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	taskID, _ := args["task_id"].(string)
+	if taskID == "" {
+		return ErrorResult("task_id is required")
+	}
+	if err := t.manager.Cancel(taskID); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(fmt.Sprintf("Canceling %s.", taskID))
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestSubagentManager_CancelAndTimeout(t *testing.T) {
+	manager := NewSubagentManager(&MockLLMProvider{}, "test-model", t.TempDir(), nil)
+	manager.SetTimeout(50 * time.Millisecond)
+	manager.SetRunner("main", func(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	timedOut, err := manager.Run(context.Background(), "loop forever", "", "", "cli", "direct")
+	if err == nil || timedOut.Status != SubagentTimedOut {
+		t.Errorf("task should time out, got %+v, %v", timedOut, err)
+	}
+
+	manager.SetTimeout(time.Minute)
+	done := make(chan *ToolResult, 1)
+	if _, err := manager.Spawn(context.Background(), "research", "r", "", "cli", "direct",
+		func(ctx context.Context, result *ToolResult) { done <- result }); err != nil {
+		t.Fatal(err)
+	}
+	status := NewSubagentStatusTool(manager).Execute(context.Background(), map[string]any{})
+	if !strings.Contains(status.ForLLM, "subagent-2 [running] r") {
+		t.Errorf("status should list the running task:\n%s", status.ForLLM)
+	}
+
+	cancel := NewSubagentCancelTool(manager)
+	if result := cancel.Execute(context.Background(), map[string]any{"task_id": "subagent-2"}); result.IsError {
+		t.Fatalf("cancel failed: %s", result.ForLLM)
+	}
+	if result := <-done; !result.IsError {
+		t.Error("a canceled task should report an error result")
+	}
+	task, _ := manager.GetTask("subagent-2")
+	if task.Status != SubagentCanceled {
+		t.Errorf("status = %s, want canceled", task.Status)
+	}
+	if result := cancel.Execute(context.Background(), map[string]any{"task_id": "subagent-2"}); !result.IsError {
+		t.Error("canceling a finished task should fail")
+	}
+}
+
+func TestSubagentManager_PersistsAndReportsOrphans(t *testing.T) {
+	workspace := t.TempDir()
+	first := NewSubagentManager(&MockLLMProvider{}, "test-model", workspace, nil)
+	if err := first.EnablePersistence(); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	stopped := make(chan struct{})
+	defer func() {
+		close(release)
+		<-stopped
+	}()
+	first.SetRunner("main", func(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error) {
+		if task.Label == "stuck" {
+			<-release
+		}
+		return &ToolLoopResult{Content: "ok"}, nil
+	})
+	if _, err := first.Run(context.Background(), "quick", "", "", "cli", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Spawn(context.Background(), "slow research", "stuck", "", "telegram", "42",
+		func(ctx context.Context, result *ToolResult) { close(stopped) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new manager on the same workspace sees the state a restart would.
+	msgBus := bus.NewMessageBus()
+	second := NewSubagentManager(&MockLLMProvider{}, "test-model", workspace, msgBus)
+	second.SetRunner("main", nil)
+	if err := second.EnablePersistence(); err != nil {
+		t.Fatal(err)
+	}
+	tasks := second.ListTasks()
+	if len(tasks) != 2 || tasks[0].Status != SubagentCompleted || tasks[1].Status != SubagentInterrupted {
+		t.Fatalf("reloaded tasks = %+v", tasks)
+	}
+	if n := second.ReportOrphans(); n != 1 {
+		t.Fatalf("ReportOrphans = %d, want 1", n)
+	}
+	msg, _ := msgBus.ConsumeInbound(context.Background())
+	if msg.Metadata[SubagentMetaStatus] != SubagentInterrupted || msg.ChatID != "telegram:42" ||
+		msg.Metadata[SubagentMetaTaskID] != "subagent-2" {
+		t.Errorf("unexpected orphan report %+v", msg)
+	}
+	if n := second.ReportOrphans(); n != 0 {
+		t.Errorf("orphans should be reported once, got %d", n)
+	}
+
+	if _, err := second.Run(context.Background(), "next", "", "", "cli", "direct"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := second.GetTask("subagent-3"); !ok {
+		t.Error("task IDs should continue after the reloaded ones")
+	}
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -348,3 +349,77 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+func TestSubagentManager_RunnerAndLimits(t *testing.T) {
+	manager := NewSubagentManager(&MockLLMProvider{}, "test-model", "/tmp/test", nil)
+	manager.SetLimits(1, 1)
+
+	release := make(chan struct{})
+	manager.SetRunner("main", func(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error) {
+		if task.Label == "slow" {
+			<-release
+			return &ToolLoopResult{Content: "slow done"}, nil
+		}
+		// A subagent may not start another one beyond the depth limit.
+		_, err := manager.Run(ctx, "nested", "", "", "cli", "direct")
+		return &ToolLoopResult{
+			Content:    fmt.Sprintf("ran %s on %s at depth %d, nested: %v", task.Task, task.AgentID, SubagentDepth(ctx), err),
+			Iterations: 2,
+		}, nil
+	})
+
+	finished, err := manager.Run(context.Background(), "audit", "", "research", "cli", "direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished.Status != "completed" || finished.Iterations != 2 ||
+		!strings.HasPrefix(finished.Result, "ran audit on research at depth 1, nested: subagents may only nest 1 level") {
+		t.Errorf("unexpected finished task %+v", finished)
+	}
+
+	done := make(chan struct{})
+	callback := func(ctx context.Context, result *ToolResult) { close(done) }
+	if _, err := manager.Spawn(context.Background(), "long", "slow", "", "cli", "direct", callback); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Run(context.Background(), "more", "", "", "cli", "direct"); err == nil ||
+		!strings.Contains(err.Error(), "already running") {
+		t.Errorf("expected the concurrency limit to refuse a second subagent, got %v", err)
+	}
+	close(release)
+	<-done
+}
+
+func TestSubagentManager_SpawnPublishesStructuredResult(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	manager := NewSubagentManager(&MockLLMProvider{}, "test-model", "/tmp/test", msgBus)
+	manager.SetRunner("main", func(ctx context.Context, task *SubagentTask) (*ToolLoopResult, error) {
+		return &ToolLoopResult{Content: "Result:\nnot a header", Iterations: 1}, nil
+	})
+
+	if _, err := manager.Spawn(context.Background(), "summarize", "sum", "writer", "telegram", "42", nil); err != nil {
+		t.Fatal(err)
+	}
+	msg, ok := msgBus.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("no result published")
+	}
+	if msg.Content != "Result:\nnot a header" {
+		t.Errorf("content should be the bare result, got %q", msg.Content)
+	}
+	want := map[string]string{
+		SubagentMetaTaskID: "subagent-1",
+		SubagentMetaLabel:  "sum",
+		SubagentMetaAgent:  "writer",
+		SubagentMetaParent: "main",
+		SubagentMetaStatus: "completed",
+	}
+	for key, value := range want {
+		if msg.Metadata[key] != value {
+			t.Errorf("metadata %s = %q, want %q", key, msg.Metadata[key], value)
+		}
+	}
+	if msg.ChatID != "telegram:42" {
+		t.Errorf("chat ID = %q", msg.ChatID)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -498,6 +500,7 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]any) *ToolR
 type WebFetchTool struct {
 	maxChars int
 	proxy    string
+	guard    *EgressGuard
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
@@ -506,6 +509,7 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 	}
 	return &WebFetchTool{
 		maxChars: maxChars,
+		guard:    defaultEgressGuard(),
 	}
 }
 
@@ -516,15 +520,25 @@ func NewWebFetchToolWithProxy(maxChars int, proxy string) *WebFetchTool {
 	return &WebFetchTool{
 		maxChars: maxChars,
 		proxy:    proxy,
+		guard:    defaultEgressGuard(),
 	}
 }
 
+// SetEgressGuard replaces the default guard, which blocks private,
+// loopback, link-local and cloud metadata addresses.
+func (t *WebFetchTool) SetEgressGuard(guard *EgressGuard) {
+	t.guard = guard
+}
+
 func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract its readable content. HTML pages are reduced to the main article and " +
+		"converted to Markdown (keeping headings, links and tables), PDFs to text and JSON is pretty-printed. " +
+		"Long content is returned in pages; continue with start_index. Use this to get weather info, news, " +
+		"articles, documentation or any web content."
 }
 
 func (t *WebFetchTool) Parameters() map[string]any {
@@ -535,6 +549,17 @@ func (t *WebFetchTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "URL to fetch",
 			},
+			"format": map[string]any{
+				"type": "string",
+				"enum": []string{"markdown", "text", "raw"},
+				"description": "markdown (default): main content as Markdown; text: main content as plain text; " +
+					"raw: the response body unchanged",
+			},
+			"start_index": map[string]any{
+				"type":        "integer",
+				"description": "Character offset to start from, to read past the end of a truncated result",
+				"minimum":     0.0,
+			},
 			"maxChars": map[string]any{
 				"type":        "integer",
 				"description": "Maximum characters to extract",
@@ -564,12 +589,21 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("missing domain in URL")
 	}
 
+	format := "markdown"
+	if f, ok := args["format"].(string); ok && f != "" {
+		format = strings.ToLower(f)
+	}
+	if format != "markdown" && format != "text" && format != "raw" {
+		return ErrorResult(fmt.Sprintf("unknown format %q: use markdown, text or raw", format))
+	}
+
 	maxChars := t.maxChars
 	if mc, ok := args["maxChars"].(float64); ok {
 		if int(mc) > 100 {
 			maxChars = int(mc)
 		}
 	}
+	startIndex := intArg(args, "start_index", 0, math.MaxInt)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
@@ -590,45 +624,41 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		}
 		return nil
 	}
+	t.guard.protect(client)
 
 	resp, err := client.Do(req)
 	if err != nil {
+		var blocked *EgressError
+		if errors.As(err, &blocked) {
+			return ErrorResult(blocked.Error())
+		}
 		return ErrorResult(fmt.Sprintf("request failed: %v", err))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes+1))
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
 	}
+	bodyTruncated := len(body) > maxFetchBytes
+	if bodyTruncated {
+		body = body[:maxFetchBytes]
+	}
 
-	contentType := resp.Header.Get("Content-Type")
-
-	var text, extractor string
-
-	if strings.Contains(contentType, "application/json") {
-		var jsonData any
-		if err := json.Unmarshal(body, &jsonData); err == nil {
-			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
-			text = string(formatted)
-			extractor = "json"
-		} else {
-			text = string(body)
-			extractor = "raw"
-		}
-	} else if strings.Contains(contentType, "text/html") || len(body) > 0 &&
-		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")) {
-		text = t.extractText(string(body))
-		extractor = "text"
-	} else {
-		text = string(body)
-		extractor = "raw"
+	text, extractor, err := t.convert(body, resp.Header.Get("Content-Type"), resp.Request.URL, format)
+	if err != nil {
+		return ErrorResult(err.Error())
 	}
 
-	truncated := len(text) > maxChars
-	if truncated {
-		text = text[:maxChars]
+	runes := []rune(text)
+	total := len(runes)
+	if startIndex > total {
+		return ErrorResult(fmt.Sprintf("start_index %d is past the end of the content (%d characters)",
+			startIndex, total))
 	}
+	end := min(startIndex+maxChars, total)
+	text = string(runes[startIndex:end])
+	truncated := end < total
 
 	result := map[string]any{
 		"url":       urlStr,
@@ -638,44 +668,73 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		"length":    len(text),
 		"text":      text,
 	}
+	if startIndex > 0 || truncated {
+		result["start_index"] = startIndex
+		result["total_length"] = total
+	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 
+	var llm strings.Builder
+	fmt.Fprintf(&llm, "Fetched %d bytes from %s (extractor: %s, truncated: %v)\n\n%s",
+		len(text), urlStr, extractor, truncated, text)
+	if truncated {
+		fmt.Fprintf(&llm, "\n\n[Showing characters %d-%d of %d. Continue with start_index=%d.]",
+			startIndex, end, total, end)
+	}
+	if bodyTruncated {
+		fmt.Fprintf(&llm, "\n\n[The response was larger than %d MB; only the beginning was read.]",
+			maxFetchBytes>>20)
+	}
+
 	return &ToolResult{
-		ForLLM: fmt.Sprintf(
-			"Fetched %d bytes from %s (extractor: %s, truncated: %v)",
-			len(text),
-			urlStr,
-			extractor,
-			truncated,
-		),
+		ForLLM:  llm.String(),
 		ForUser: string(resultJSON),
 	}
 }
 
-func (t *WebFetchTool) extractText(htmlContent string) string {
-	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
-	result := re.ReplaceAllLiteralString(htmlContent, "")
-	re = regexp.MustCompile(`<style[\s\S]*?</style>`)
-	result = re.ReplaceAllLiteralString(result, "")
-	re = regexp.MustCompile(`<[^>]+>`)
-	result = re.ReplaceAllLiteralString(result, "")
-
-	result = strings.TrimSpace(result)
-
-	re = regexp.MustCompile(`[^\S\n]+`)
-	result = re.ReplaceAllString(result, " ")
-	re = regexp.MustCompile(`\n{3,}`)
-	result = re.ReplaceAllString(result, "\n\n")
-
-	lines := strings.Split(result, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
+// maxFetchBytes limits how much of a response web_fetch reads.
+const maxFetchBytes = 10 << 20
+
+// convert turns a response body into text in format and names the
+// extractor used.
+func (t *WebFetchTool) convert(body []byte, contentType string, base *url.URL, format string) (string, string, error) {
+	contentType = strings.ToLower(contentType)
+	isPDF := strings.Contains(contentType, "application/pdf") || bytes.HasPrefix(body, []byte("%PDF-"))
+	isHTML := strings.Contains(contentType, "text/html") || strings.Contains(contentType, "xhtml") ||
+		len(body) > 0 && (strings.HasPrefix(string(body), "<!DOCTYPE") ||
+			strings.HasPrefix(strings.ToLower(string(body)), "<html"))
+
+	switch {
+	case isPDF:
+		// A PDF's bytes are of no use to the model, even in raw format.
+		text, pages, err := extractPDF(body)
+		if err != nil {
+			return "", "", err
+		}
+		if strings.TrimSpace(text) == "" {
+			text = fmt.Sprintf("[PDF with %d page(s) but no text layer; it may be scanned images.]", pages)
+		}
+		return text, "pdf", nil
+	case format == "raw":
+		return string(body), "raw", nil
+	case strings.Contains(contentType, "json"):
+		var jsonData any
+		if err := json.Unmarshal(body, &jsonData); err == nil {
+			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
+			return string(formatted), "json", nil
 		}
+		return string(body), "raw", nil
+	case isHTML:
+		plain := format == "text"
+		title, content := extractHTML(string(body), base, plain)
+		return formatTitle(title, content, plain), "readability", nil
 	}
+	return string(body), "raw", nil
+}
 
-	return strings.Join(cleanLines, "\n")
+// extractText returns the main content of an HTML page as plain text.
+func (t *WebFetchTool) extractText(htmlContent string) string {
+	_, content := extractHTML(htmlContent, nil, true)
+	return content
 }
@@ -0,0 +1,738 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Readability-style main content extraction: boilerplate (navigation,
+// banners, sidebars, hidden elements) is removed, paragraphs are scored
+// by their length and commas, the scores are propagated to ancestors and
+// the best-scoring container, discounted by its link density, is taken as
+// the article together with related siblings.
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|banner|breadcrumb|combx|comment|community|consent|` +
+		`cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|\bnav|newsletter|popup|related|remark|` +
+		`replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|ad-break|` +
+		`agegate|pagination|pager|promo|signup`)
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|` +
+		`blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|` +
+		`contact|cookie|consent|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|` +
+		`scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	hiddenStyle = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+	spaceRun    = regexp.MustCompile(`\s+`)
+)
+
+// removedElements never contain readable content.
+var removedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Iframe: true,
+	atom.Svg: true, atom.Canvas: true, atom.Object: true, atom.Embed: true, atom.Button: true,
+	atom.Input: true, atom.Select: true, atom.Textarea: true, atom.Head: true, atom.Link: true,
+	atom.Meta: true, atom.Nav: true, atom.Aside: true, atom.Dialog: true,
+}
+
+// boilerplateRoles mark page furniture rather than content.
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"dialog": true, "alertdialog": true, "menu": true, "menubar": true, "search": true,
+}
+
+// blockElements end a run of inline content.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Dd: true, atom.Details: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Summary: true,
+	atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Tfoot: true, atom.Th: true,
+	atom.Thead: true, atom.Tr: true, atom.Ul: true,
+}
+
+// extractHTML returns the title and main content of an HTML page, as
+// Markdown or, with plain set, as text. Relative links are resolved
+// against base, which may be nil.
+func extractHTML(page string, base *url.URL, plain bool) (title, content string) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", ""
+	}
+	title = documentTitle(doc)
+
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	pruneBoilerplate(body)
+
+	r := &markdownRenderer{base: base, plain: plain}
+	nodes := articleNodes(body)
+	var parts []string
+	for _, n := range nodes {
+		parts = append(parts, r.render(n))
+	}
+	content = cleanRendered(strings.Join(parts, "\n\n"))
+
+	// Too little was kept: the page is not article-shaped, so use it all.
+	if n := utf8.RuneCountInString(content); n < 500 && len(nodes) > 0 && nodes[0] != body {
+		if all := cleanRendered(r.render(body)); utf8.RuneCountInString(all) > 4*n {
+			content = all
+		}
+	}
+	return title, content
+}
+
+func documentTitle(doc *html.Node) string {
+	if t := findElement(doc, atom.Title); t != nil {
+		if title := strings.TrimSpace(spaceRun.ReplaceAllString(textContent(t), " ")); title != "" {
+			return title
+		}
+	}
+	if h := findElement(doc, atom.H1); h != nil {
+		return strings.TrimSpace(spaceRun.ReplaceAllString(textContent(h), " "))
+	}
+	return ""
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// innerTextLength counts the non-space characters of n.
+func innerTextLength(n *html.Node) int {
+	return utf8.RuneCountInString(strings.TrimSpace(spaceRun.ReplaceAllString(textContent(n), " ")))
+}
+
+// linkDensity is the share of n's text that is link text.
+func linkDensity(n *html.Node) float64 {
+	total := innerTextLength(n)
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += innerTextLength(n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+// pruneBoilerplate removes elements that are never part of the content.
+func pruneBoilerplate(root *html.Node) {
+	var walk func(*html.Node, bool)
+	walk = func(n *html.Node, inArticle bool) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.CommentNode {
+				n.RemoveChild(c)
+			} else if c.Type == html.ElementNode {
+				if isBoilerplate(c, inArticle) {
+					n.RemoveChild(c)
+				} else {
+					walk(c, inArticle || c.DataAtom == atom.Article || c.DataAtom == atom.Main)
+				}
+			}
+			c = next
+		}
+	}
+	walk(root, false)
+}
+
+func isBoilerplate(n *html.Node, inArticle bool) bool {
+	if removedElements[n.DataAtom] {
+		return true
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" || hiddenStyle.MatchString(attr(n, "style")) {
+		return true
+	}
+	if boilerplateRoles[attr(n, "role")] {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main, atom.A, atom.Table, atom.Tbody, atom.Tr, atom.Td, atom.Th,
+		atom.Pre, atom.Code:
+		return false
+	case atom.Header, atom.Footer:
+		// An article's own header carries its title and byline.
+		return !inArticle
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, s := range []string{attr(n, "class"), attr(n, "id")} {
+		if s == "" {
+			continue
+		}
+		if negativeWeight.MatchString(s) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(s) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		return 10
+	case atom.Div:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+// isParagraphLike reports whether n is scored as a paragraph: p, pre and
+// td elements, and divs or sections holding only inline content.
+func isParagraphLike(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td:
+		return true
+	case atom.Div, atom.Section:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockElements[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// articleNodes returns the nodes holding root's main content in document
+// order, or root itself if nothing stands out.
+func articleNodes(root *html.Node) []*html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			scores[n] = tagWeight(n) + classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if isParagraphLike(c) {
+				text := strings.TrimSpace(spaceRun.ReplaceAllString(textContent(c), " "))
+				if length := utf8.RuneCountInString(text); length >= 25 {
+					score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")) +
+						math.Min(float64(length)/100, 3)
+					level := 0
+					for a := c.Parent; a != nil && a.Type == html.ElementNode && level < 5; a = a.Parent {
+						divider := 1.0
+						if level == 1 {
+							divider = 2
+						} else if level > 1 {
+							divider = float64(level) * 3
+						}
+						addScore(a, score/divider)
+						if a == root {
+							break
+						}
+						level++
+					}
+				}
+				if c.DataAtom != atom.Div && c.DataAtom != atom.Section {
+					continue
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var top *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+	if top == nil || top == root || scores[top] <= 0 {
+		return []*html.Node{root}
+	}
+
+	// Related content split across siblings, such as a lead paragraph
+	// outside the article body, is kept too.
+	parent := top.Parent
+	if parent == nil {
+		return []*html.Node{top}
+	}
+	threshold := math.Max(10, scores[top]*0.2)
+	var nodes []*html.Node
+	for s := parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		keep := s == top
+		if score, ok := scores[s]; ok && score >= threshold {
+			keep = true
+		} else if s.DataAtom == atom.P {
+			length := innerTextLength(s)
+			density := linkDensity(s)
+			text := textContent(s)
+			keep = (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.Contains(text, ". "))
+		}
+		if keep {
+			nodes = append(nodes, s)
+		}
+	}
+	return nodes
+}
+
+// markdownRenderer converts HTML to Markdown, or to plain text that keeps
+// the paragraph and list structure.
+type markdownRenderer struct {
+	base  *url.URL
+	plain bool
+}
+
+func (r *markdownRenderer) render(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRun.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return r.children(n)
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := inlineText(r.children(n))
+		if r.plain || text == "" {
+			return block(text)
+		}
+		level := int(n.Data[1] - '0')
+		return block(strings.Repeat("#", level) + " " + text)
+	case atom.Br:
+		return "\n"
+	case atom.Hr:
+		if r.plain {
+			return "\n\n"
+		}
+		return block("---")
+	case atom.A:
+		return r.link(n)
+	case atom.Img:
+		return r.image(n)
+	case atom.Strong, atom.B:
+		return r.emphasis(n, "**")
+	case atom.Em, atom.I:
+		return r.emphasis(n, "*")
+	case atom.Del, atom.S, atom.Strike:
+		return r.emphasis(n, "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return r.inlineCode(n)
+	case atom.Pre:
+		return r.codeBlock(n)
+	case atom.Ul, atom.Ol:
+		return r.list(n)
+	case atom.Li:
+		return block(r.children(n))
+	case atom.Blockquote:
+		return r.blockquote(n)
+	case atom.Table:
+		return r.table(n)
+	case atom.Dt:
+		text := inlineText(r.children(n))
+		if !r.plain && text != "" {
+			text = "**" + text + "**"
+		}
+		return block(text)
+	}
+	if removedElements[n.DataAtom] {
+		return ""
+	}
+	if blockElements[n.DataAtom] {
+		return block(r.children(n))
+	}
+	return r.children(n)
+}
+
+// children renders n's children, dropping the spaces left at the edges
+// of lines by whitespace between tags.
+func (r *markdownRenderer) children(n *html.Node) string {
+	var out []byte
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s := r.render(c)
+		if s == "" {
+			continue
+		}
+		if len(out) == 0 || out[len(out)-1] == '\n' {
+			s = strings.TrimLeft(s, " ")
+		}
+		if strings.HasPrefix(s, "\n") {
+			for len(out) > 0 && out[len(out)-1] == ' ' {
+				out = out[:len(out)-1]
+			}
+		}
+		out = append(out, s...)
+	}
+	return string(out)
+}
+
+func block(s string) string {
+	s = strings.Trim(s, " \n")
+	if s == "" {
+		return ""
+	}
+	return "\n\n" + s + "\n\n"
+}
+
+// inlineText joins the lines of s for headings and table cells.
+func inlineText(s string) string {
+	return strings.TrimSpace(spaceRun.ReplaceAllString(s, " "))
+}
+
+func (r *markdownRenderer) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if r.base == nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return r.base.ResolveReference(u).String()
+}
+
+func (r *markdownRenderer) link(n *html.Node) string {
+	text := r.children(n)
+	href := attr(n, "href")
+	trimmed := strings.TrimSpace(text)
+	lower := strings.ToLower(strings.TrimSpace(href))
+	if r.plain || trimmed == "" || href == "" || strings.HasPrefix(lower, "#") ||
+		strings.HasPrefix(lower, "javascript:") {
+		return text
+	}
+	lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	trail := text[len(strings.TrimRight(text, " ")):]
+	trimmed = strings.ReplaceAll(strings.ReplaceAll(trimmed, "[", `\[`), "]", `\]`)
+	target := strings.ReplaceAll(strings.ReplaceAll(r.resolve(href), "(", "%28"), ")", "%29")
+	return lead + "[" + trimmed + "](" + target + ")" + trail
+}
+
+func (r *markdownRenderer) image(n *html.Node) string {
+	alt := inlineText(attr(n, "alt"))
+	src := attr(n, "src")
+	if r.plain || src == "" || strings.HasPrefix(src, "data:") {
+		return alt
+	}
+	return "![" + alt + "](" + r.resolve(src) + ")"
+}
+
+func (r *markdownRenderer) emphasis(n *html.Node, marker string) string {
+	text := r.children(n)
+	trimmed := strings.TrimSpace(text)
+	if r.plain || trimmed == "" || strings.Contains(trimmed, "\n") {
+		return text
+	}
+	lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	trail := text[len(strings.TrimRight(text, " ")):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func (r *markdownRenderer) inlineCode(n *html.Node) string {
+	text := spaceRun.ReplaceAllString(textContent(n), " ")
+	if r.plain || strings.TrimSpace(text) == "" {
+		return text
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+func (r *markdownRenderer) codeBlock(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			sb.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	code := strings.Trim(sb.String(), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	if r.plain {
+		return "\n\n" + code + "\n\n"
+	}
+
+	lang := ""
+	for _, el := range []*html.Node{n, n.FirstChild} {
+		if el == nil || el.Type != html.ElementNode {
+			continue
+		}
+		for _, class := range strings.Fields(attr(el, "class")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = l
+			} else if l, ok := strings.CutPrefix(class, "lang-"); ok {
+				lang = l
+			}
+		}
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return "\n\n" + fence + lang + "\n" + code + "\n" + fence + "\n\n"
+}
+
+func (r *markdownRenderer) list(n *html.Node) string {
+	index := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		index = start
+	}
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		content := strings.Trim(r.children(c), " \n")
+		if c.DataAtom != atom.Li {
+			// Lists nested directly in lists, as some editors produce.
+			if content != "" {
+				items = append(items, indentLines(content, "  "))
+			}
+			continue
+		}
+		if content == "" {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+		items = append(items, marker+indentLines(content, strings.Repeat(" ", len(marker)))[len(marker):])
+	}
+	return block(strings.Join(items, "\n"))
+}
+
+func (r *markdownRenderer) blockquote(n *html.Node) string {
+	content := strings.Trim(r.children(n), " \n")
+	if r.plain || content == "" {
+		return block(content)
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return block(strings.Join(lines, "\n"))
+}
+
+// table renders a data table as a GFM table. Layout tables, with a single
+// column or nested tables, are rendered as their content.
+func (r *markdownRenderer) table(n *html.Node) string {
+	var rows [][]*html.Node
+	nested := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Tr:
+				var cells []*html.Node
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						cells = append(cells, cell)
+						if findElement(cell, atom.Table) != nil {
+							nested = true
+						}
+					}
+				}
+				rows = append(rows, cells)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if nested || columns < 2 {
+		return block(r.children(n))
+	}
+
+	var caption string
+	if c := findElement(n, atom.Caption); c != nil {
+		caption = inlineText(r.children(c))
+	}
+	lines := make([]string, 0, len(rows)+2)
+	for i, row := range rows {
+		cells := make([]string, columns)
+		for j, cell := range row {
+			text := inlineText(r.children(cell))
+			if !r.plain {
+				text = strings.ReplaceAll(text, "|", `\|`)
+			}
+			cells[j] = text
+		}
+		if r.plain {
+			lines = append(lines, strings.TrimRight(strings.Join(cells, " | "), " |"))
+			continue
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	if caption != "" {
+		lines = append([]string{caption, ""}, lines...)
+	}
+	return block(strings.Join(lines, "\n"))
+}
+
+func indentLines(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanRendered trims trailing spaces and collapses blank lines outside
+// fenced code blocks.
+func cleanRendered(s string) string {
+	lines := strings.Split(s, "\n")
+	var out []string
+	fence := ""
+	blank := 0
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence == "" && strings.HasPrefix(trimmed, "```") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, "`"))]
+		} else if fence != "" && strings.TrimSpace(line) == fence {
+			fence = ""
+		} else if fence != "" {
+			out = append(out, line)
+			continue
+		}
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n")
+}
+
+// formatTitle puts the page title above content unless it already starts
+// with it.
+func formatTitle(title, content string, plain bool) string {
+	if title == "" {
+		return content
+	}
+	first, _, _ := strings.Cut(content, "\n")
+	if heading := strings.TrimSpace(strings.TrimLeft(first, "#")); heading != "" &&
+		(strings.Contains(title, heading) || strings.Contains(heading, title)) {
+		return content
+	}
+	if plain {
+		return fmt.Sprintf("%s\n\n%s", title, content)
+	}
+	return fmt.Sprintf("# %s\n\n%s", title, content)
+}
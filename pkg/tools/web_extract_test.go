@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html><head><title>Understanding Go Channels - The Gopher Blog</title><script>track()</script></head>
+<body>
+<header class="site-header"><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<nav><ul><li><a href="/about">About</a></li></ul></nav>
+<div id="cookie-banner">We use cookies to improve your experience. <button>Accept</button></div>
+<div class="layout">
+  <div class="sidebar"><h3>Popular posts</h3><ul><li><a href="/p1">Post one</a></li></ul></div>
+  <article class="post">
+    <header><h1>Understanding Go Channels</h1></header>
+    <p>Channels are the pipes that connect concurrent goroutines. You can send values into channels from
+    one goroutine and receive them in another, which makes them <strong>central</strong> to Go's concurrency.</p>
+    <h2>Creating a channel</h2>
+    <p>Create a channel with <code>make(chan T)</code>. Channels are typed by the values they convey,
+    see <a href="/spec#Channel_types">the spec</a> for details, edge cases, and more.</p>
+    <pre><code class="language-go">ch := make(chan string)
+
+msg := &lt;-ch</code></pre>
+    <table><thead><tr><th>Kind</th><th>Blocks when</th></tr></thead>
+    <tbody><tr><td>Unbuffered</td><td>no receiver is ready</td></tr>
+    <tr><td>Buffered</td><td>the buffer is full | empty</td></tr></tbody></table>
+    <ol><li>Send blocks until received.</li><li>Receive blocks until sent.</li></ol>
+    <div style="display: none">Hidden tracking text</div>
+  </article>
+</div>
+<footer>© 2026 Gopher Blog. <a href="/privacy">Privacy</a></footer>
+</body></html>`
+
+func TestExtractHTML_Markdown(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/posts/channels")
+	title, content := extractHTML(articlePage, base, false)
+	assert.Equal(t, "Understanding Go Channels - The Gopher Blog", title)
+
+	for _, want := range []string{
+		"# Understanding Go Channels\n\nChannels are the pipes",
+		"**central**",
+		"## Creating a channel",
+		"`make(chan T)`",
+		"[the spec](https://blog.example.com/spec#Channel_types)",
+		"```go\nch := make(chan string)\n\nmsg := <-ch\n```",
+		"| Kind | Blocks when |\n| --- | --- |\n| Unbuffered | no receiver is ready |",
+		`the buffer is full \| empty`,
+		"1. Send blocks until received.\n2. Receive blocks until sent.",
+	} {
+		assert.Contains(t, content, want)
+	}
+	for _, unwanted := range []string{"cookies", "Popular posts", "About", "Privacy", "Hidden", "track()", "Home"} {
+		assert.NotContains(t, content, unwanted)
+	}
+
+	// The article's own heading already matches the title.
+	assert.True(t, strings.HasPrefix(formatTitle(title, content, false), "# Understanding Go Channels\n"))
+	assert.Equal(t, "# Page\n\nbody", formatTitle("Page", "body", false))
+}
+
+func TestExtractHTML_Text(t *testing.T) {
+	_, content := extractHTML(articlePage, nil, true)
+	assert.Contains(t, content, "Understanding Go Channels\n\nChannels are the pipes")
+	assert.Contains(t, content, "see the spec for details")
+	assert.Contains(t, content, "Unbuffered | no receiver is ready")
+	assert.NotContains(t, content, "**")
+	assert.NotContains(t, content, "](")
+	assert.NotContains(t, content, "cookies")
+}
+
+func TestExtractHTML_NonArticlePageKeepsEverything(t *testing.T) {
+	page := `<html><body><div><h1>Status</h1><ul><li>API: up</li><li>DB: up</li></ul>` +
+		`<p>Updated hourly.</p></div></body></html>`
+	_, content := extractHTML(page, nil, false)
+	assert.Equal(t, "# Status\n\n- API: up\n- DB: up\n\nUpdated hourly.", content)
+}
+
+// buildPDF writes a minimal PDF with one page per entry of pages, each
+// showing its text in Helvetica.
+func buildPDF(pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] "+
+				"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestWebFetch_FormatsAndPaging(t *testing.T) {
+	long := strings.Repeat("0123456789", 30)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, articlePage)
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(buildPDF("Quarterly report", "Revenue grew"))
+		case "/data":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"b":[1,2],"a":"x"}`)
+		case "/long":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, long)
+		}
+	}))
+	defer server.Close()
+	tool := newLoopbackWebFetchTool(t, 50000)
+
+	fetch := func(args map[string]any) *ToolResult {
+		t.Helper()
+		result := tool.Execute(t.Context(), args)
+		require.False(t, result.IsError, result.ForLLM)
+		return result
+	}
+
+	result := fetch(map[string]any{"url": server.URL + "/article"})
+	assert.Contains(t, result.ForLLM, "extractor: readability")
+	assert.Contains(t, result.ForLLM, "## Creating a channel")
+	assert.Contains(t, result.ForLLM, "[the spec]("+server.URL+"/spec#Channel_types)")
+
+	result = fetch(map[string]any{"url": server.URL + "/article", "format": "raw"})
+	assert.Contains(t, result.ForLLM, "<nav>")
+
+	result = fetch(map[string]any{"url": server.URL + "/doc.pdf"})
+	assert.Contains(t, result.ForLLM, "extractor: pdf")
+	assert.Contains(t, result.ForLLM, "--- Page 1 ---\nQuarterly report\n\n--- Page 2 ---\nRevenue grew")
+
+	result = fetch(map[string]any{"url": server.URL + "/data"})
+	assert.Contains(t, result.ForLLM, "{\n  \"a\": \"x\",\n  \"b\": [\n    1,")
+
+	result = fetch(map[string]any{"url": server.URL + "/long", "maxChars": 120.0})
+	assert.Contains(t, result.ForLLM, long[:120]+"\n\n[Showing characters 0-120 of 300. Continue with start_index=120.]")
+	result = fetch(map[string]any{"url": server.URL + "/long", "maxChars": 120.0, "start_index": 240.0})
+	assert.Contains(t, result.ForLLM, "truncated: false)\n\n"+long[240:])
+
+	result = tool.Execute(t.Context(), map[string]any{"url": server.URL + "/long", "start_index": 400.0})
+	assert.True(t, result.IsError)
+	result = tool.Execute(t.Context(), map[string]any{"url": server.URL + "/long", "format": "html"})
+	assert.True(t, result.IsError)
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// extractPDF returns the text of a PDF document, page by page. Scanned
+// pages without a text layer come out empty.
+func extractPDF(data []byte) (text string, pages int, err error) {
+	defer func() {
+		// The parser panics on some malformed files.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	pages = reader.NumPage()
+	fonts := make(map[string]*pdf.Font)
+	var sb strings.Builder
+	for i := 1; i <= pages; i++ {
+		page := reader.Page(i)
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", pages, fmt.Errorf("failed to read PDF page %d: %w", i, err)
+		}
+		pageText = cleanPDFText(pageText)
+		if pageText == "" {
+			continue
+		}
+		if pages > 1 {
+			fmt.Fprintf(&sb, "--- Page %d ---\n", i)
+		}
+		sb.WriteString(pageText)
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), pages, nil
+}
+
+// cleanPDFText trims the lines of extracted text and collapses blank lines.
+func cleanPDFText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r", "\n"), "\n")
+	var out []string
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+		if line == "" {
+			blank = len(out) > 0
+			continue
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
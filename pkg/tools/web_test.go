@@ -10,6 +10,19 @@ import (
 	"time"
 )
 
+// newLoopbackWebFetchTool returns a web_fetch tool that may reach the
+// httptest servers on 127.0.0.1, which the default egress guard blocks.
+func newLoopbackWebFetchTool(t *testing.T, maxChars int) *WebFetchTool {
+	t.Helper()
+	guard, err := NewEgressGuard(EgressPolicy{Allow: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatalf("NewEgressGuard() error: %v", err)
+	}
+	tool := NewWebFetchTool(maxChars)
+	tool.SetEgressGuard(guard)
+	return tool
+}
+
 // TestWebTool_WebFetch_Success verifies successful URL fetching
 func TestWebTool_WebFetch_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +32,7 @@ func TestWebTool_WebFetch_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLoopbackWebFetchTool(t, 50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -55,7 +68,7 @@ func TestWebTool_WebFetch_JSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLoopbackWebFetchTool(t, 50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -146,7 +159,7 @@ func TestWebTool_WebFetch_Truncation(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(1000) // Limit to 1000 chars
+	tool := newLoopbackWebFetchTool(t, 1000) // Limit to 1000 chars
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
@@ -215,7 +228,7 @@ func TestWebTool_WebFetch_HTMLExtraction(t *testing.T) {
 	}))
 	defer server.Close()
 
-	tool := NewWebFetchTool(50000)
+	tool := newLoopbackWebFetchTool(t, 50000)
 	ctx := context.Background()
 	args := map[string]any{
 		"url": server.URL,
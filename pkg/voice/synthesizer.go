@@ -0,0 +1,264 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// DefaultTTSMaxChars is the longest reply spoken when voice.tts.max_chars
+// is not set. Longer replies are sent as text.
+const DefaultTTSMaxChars = 1500
+
+// Synthesizer turns reply text into speech. Synthesize writes the audio to a
+// temporary file and returns its path; the caller removes the file once it
+// has been sent.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) (string, error)
+	IsAvailable() bool
+}
+
+// NewSynthesizer creates the synthesizer selected by cfg. It returns nil
+// when no provider is configured.
+func NewSynthesizer(cfg config.TTSConfig) (Synthesizer, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		return NewOpenAISynthesizer(cfg.APIKey, cfg.APIBase, cfg.Model, cfg.Voice, cfg.Format), nil
+	case "command":
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("voice.tts.command is required for the command provider")
+		}
+		return NewCommandSynthesizer(cfg.Command, cfg.Format), nil
+	default:
+		return nil, fmt.Errorf("unknown TTS provider %q (use openai or command)", cfg.Provider)
+	}
+}
+
+const defaultSpeechAPIBase = "https://api.openai.com/v1"
+
+// OpenAISynthesizer calls an OpenAI-compatible /audio/speech endpoint.
+type OpenAISynthesizer struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	voice      string
+	format     string
+	httpClient *http.Client
+}
+
+// NewOpenAISynthesizer creates a synthesizer for apiBase (default
+// https://api.openai.com/v1). Empty model, voice and format select tts-1,
+// alloy and opus; opus is what Telegram plays as a voice note.
+func NewOpenAISynthesizer(apiKey, apiBase, model, voiceName, format string) *OpenAISynthesizer {
+	logger.DebugCF("voice", "Creating OpenAI-compatible synthesizer", map[string]any{
+		"has_api_key": apiKey != "",
+		"api_base":    apiBase,
+	})
+
+	if apiBase == "" {
+		apiBase = defaultSpeechAPIBase
+	}
+	if model == "" {
+		model = "tts-1"
+	}
+	if voiceName == "" {
+		voiceName = "alloy"
+	}
+	if format == "" {
+		format = "opus"
+	}
+	return &OpenAISynthesizer{
+		apiKey:  apiKey,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		model:   model,
+		voice:   voiceName,
+		format:  format,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (s *OpenAISynthesizer) Synthesize(ctx context.Context, text string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":           s.model,
+		"input":           text,
+		"voice":           s.voice,
+		"response_format": s.format,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := s.apiBase + "/audio/speech"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	logger.DebugCF("voice", "Sending speech request", map[string]any{
+		"url":         url,
+		"model":       s.model,
+		"text_length": len(text),
+	})
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		logger.ErrorCF("voice", "Speech API error", map[string]any{
+			"status_code": resp.StatusCode,
+			"response":    string(msg),
+		})
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(msg))
+	}
+
+	return writeAudio(resp.Body, audioExt(s.format))
+}
+
+// IsAvailable reports whether the synthesizer can be used: OpenAI needs a
+// key, while a self-hosted server set through apiBase may not.
+func (s *OpenAISynthesizer) IsAvailable() bool {
+	return s.apiKey != "" || s.apiBase != defaultSpeechAPIBase
+}
+
+// CommandSynthesizer runs a local text-to-speech program such as piper. The
+// text is written to its standard input. If an argument contains {output},
+// the placeholder is replaced with the path of the audio file the program
+// must write; otherwise the audio is read from its standard output.
+type CommandSynthesizer struct {
+	command []string
+	ext     string
+	timeout time.Duration
+}
+
+// NewCommandSynthesizer creates a synthesizer for command. format is the
+// extension of the audio the command produces (default wav).
+func NewCommandSynthesizer(command []string, format string) *CommandSynthesizer {
+	if format == "" {
+		format = "wav"
+	}
+	return &CommandSynthesizer{
+		command: command,
+		ext:     audioExt(format),
+		timeout: 60 * time.Second,
+	}
+}
+
+func (s *CommandSynthesizer) Synthesize(ctx context.Context, text string) (string, error) {
+	if len(s.command) == 0 {
+		return "", fmt.Errorf("no TTS command configured")
+	}
+
+	out, err := os.CreateTemp("", "picoclaw-tts-*"+s.ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio file: %w", err)
+	}
+	path := out.Name()
+
+	args := make([]string, len(s.command))
+	toFile := false
+	for i, arg := range s.command {
+		if strings.Contains(arg, "{output}") {
+			toFile = true
+			arg = strings.ReplaceAll(arg, "{output}", path)
+		}
+		args[i] = arg
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if !toFile {
+		cmd.Stdout = out
+	}
+
+	err = cmd.Run()
+	out.Close()
+	if err != nil {
+		os.Remove(path)
+		logger.ErrorCF("voice", "TTS command failed", map[string]any{
+			"command": args[0],
+			"error":   err.Error(),
+			"stderr":  strings.TrimSpace(stderr.String()),
+		})
+		return "", fmt.Errorf("TTS command failed: %w", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		os.Remove(path)
+		return "", fmt.Errorf("TTS command produced no audio")
+	}
+	return path, nil
+}
+
+func (s *CommandSynthesizer) IsAvailable() bool {
+	if len(s.command) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(s.command[0])
+	return err == nil
+}
+
+// writeAudio copies r to a new temporary file with extension ext.
+func writeAudio(r io.Reader, ext string) (string, error) {
+	f, err := os.CreateTemp("", "picoclaw-tts-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create audio file: %w", err)
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write audio: %w", err)
+	}
+	if n == 0 {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("empty audio response")
+	}
+	return f.Name(), nil
+}
+
+// audioExt maps a response format to a file extension. Opus is stored in an
+// Ogg container, which is what voice notes use.
+func audioExt(format string) string {
+	switch format {
+	case "opus", "ogg":
+		return ".ogg"
+	default:
+		return "." + strings.TrimPrefix(format, ".")
+	}
+}
+
+// IsVoiceNote reports whether path holds Ogg/Opus audio, which chat apps
+// can play as a voice message rather than an audio file.
+func IsVoiceNote(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".ogg") || strings.HasSuffix(lower, ".opus")
+}
@@ -0,0 +1,110 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestOpenAISynthesizer_WritesAudio(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer key" {
+			t.Errorf("unexpected Authorization %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte("OggS audio"))
+	}))
+	defer server.Close()
+
+	s := NewOpenAISynthesizer("key", server.URL+"/v1/", "", "nova", "")
+	path, err := s.Synthesize(context.Background(), "Hello there")
+	if err != nil {
+		t.Fatalf("Synthesize failed: %v", err)
+	}
+	defer os.Remove(path)
+
+	if filepath.Ext(path) != ".ogg" || !IsVoiceNote(path) {
+		t.Errorf("expected an .ogg voice note, got %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "OggS audio" {
+		t.Errorf("unexpected audio %q", data)
+	}
+	if got["input"] != "Hello there" || got["voice"] != "nova" || got["model"] != "tts-1" || got["response_format"] != "opus" {
+		t.Errorf("unexpected request %v", got)
+	}
+}
+
+func TestOpenAISynthesizer_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad voice", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	s := NewOpenAISynthesizer("", server.URL, "", "", "mp3")
+	if !s.IsAvailable() {
+		t.Error("expected a self-hosted endpoint to be available without a key")
+	}
+	if _, err := s.Synthesize(context.Background(), "hi"); err == nil {
+		t.Fatal("expected an error for a failed request")
+	}
+}
+
+func TestCommandSynthesizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		command []string
+	}{
+		{"stdout", []string{"cat"}},
+		{"output placeholder", []string{"sh", "-c", "cat > {output}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCommandSynthesizer(tt.command, "")
+			if !s.IsAvailable() {
+				t.Skipf("%s not available", tt.command[0])
+			}
+			path, err := s.Synthesize(context.Background(), "spoken text")
+			if err != nil {
+				t.Fatalf("Synthesize failed: %v", err)
+			}
+			defer os.Remove(path)
+
+			if filepath.Ext(path) != ".wav" {
+				t.Errorf("expected a .wav file, got %s", path)
+			}
+			if data, _ := os.ReadFile(path); string(data) != "spoken text" {
+				t.Errorf("unexpected audio %q", data)
+			}
+		})
+	}
+
+	if _, err := NewCommandSynthesizer([]string{"true"}, "").Synthesize(context.Background(), "x"); err == nil {
+		t.Error("expected an error when the command writes no audio")
+	}
+}
+
+func TestNewSynthesizer(t *testing.T) {
+	if s, err := NewSynthesizer(config.TTSConfig{}); s != nil || err != nil {
+		t.Errorf("expected no synthesizer without a provider, got %v, %v", s, err)
+	}
+	if _, err := NewSynthesizer(config.TTSConfig{Provider: "command"}); err == nil {
+		t.Error("expected an error for the command provider without a command")
+	}
+	if _, err := NewSynthesizer(config.TTSConfig{Provider: "espeak"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+	if s, err := NewSynthesizer(config.TTSConfig{Provider: "openai", APIKey: "k"}); err != nil || s == nil {
+		t.Errorf("expected an openai synthesizer, got %v, %v", s, err)
+	}
+}
@@ -9,17 +9,37 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/utils"
 )
 
-type GroqTranscriber struct {
-	apiKey     string
-	apiBase    string
-	httpClient *http.Client
+// Transcriber turns an audio file into text. Channels that receive voice
+// messages use it to pass the spoken text to the agent.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error)
+	IsAvailable() bool
+}
+
+const (
+	groqAPIBase   = "https://api.groq.com/openai/v1"
+	openAIAPIBase = "https://api.openai.com/v1"
+)
+
+// OpenAITranscriber calls an OpenAI-compatible /audio/transcriptions
+// endpoint: Groq, OpenAI, or a self-hosted server such as faster-whisper
+// server or LocalAI.
+type OpenAITranscriber struct {
+	apiKey      string
+	apiBase     string
+	model       string
+	keyRequired bool
+	httpClient  *http.Client
 }
 
 type TranscriptionResponse struct {
@@ -28,20 +48,38 @@ type TranscriptionResponse struct {
 	Duration float64 `json:"duration,omitempty"`
 }
 
-func NewGroqTranscriber(apiKey string) *GroqTranscriber {
+// NewGroqTranscriber creates a transcriber for Groq's Whisper API.
+func NewGroqTranscriber(apiKey string) *OpenAITranscriber {
 	logger.DebugCF("voice", "Creating Groq transcriber", map[string]any{"has_api_key": apiKey != ""})
 
-	apiBase := "https://api.groq.com/openai/v1"
-	return &GroqTranscriber{
-		apiKey:  apiKey,
-		apiBase: apiBase,
+	t := NewOpenAITranscriber(apiKey, groqAPIBase, "whisper-large-v3")
+	t.keyRequired = true
+	return t
+}
+
+// NewOpenAITranscriber creates a transcriber for apiBase (default
+// https://api.openai.com/v1, model whisper-1). A self-hosted endpoint set
+// through apiBase can be used without a key.
+func NewOpenAITranscriber(apiKey, apiBase, model string) *OpenAITranscriber {
+	keyRequired := apiBase == ""
+	if apiBase == "" {
+		apiBase = openAIAPIBase
+	}
+	if model == "" {
+		model = "whisper-1"
+	}
+	return &OpenAITranscriber{
+		apiKey:      apiKey,
+		apiBase:     strings.TrimRight(apiBase, "/"),
+		model:       model,
+		keyRequired: keyRequired,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
 }
 
-func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
 	logger.InfoCF("voice", "Starting transcription", map[string]any{"audio_file": audioFilePath})
 
 	audioFile, err := os.Open(audioFilePath)
@@ -79,7 +117,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 
 	logger.DebugCF("voice", "File copied to request", map[string]any{"bytes_copied": copied})
 
-	if err = writer.WriteField("model", "whisper-large-v3"); err != nil {
+	if err = writer.WriteField("model", t.model); err != nil {
 		logger.ErrorCF("voice", "Failed to write model field", map[string]any{"error": err})
 		return nil, fmt.Errorf("failed to write model field: %w", err)
 	}
@@ -102,9 +140,11 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
 
-	logger.DebugCF("voice", "Sending transcription request to Groq API", map[string]any{
+	logger.DebugCF("voice", "Sending transcription request", map[string]any{
 		"url":                url,
 		"request_size_bytes": requestBody.Len(),
 		"file_size_bytes":    fileInfo.Size(),
@@ -131,7 +171,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	logger.DebugCF("voice", "Received response from Groq API", map[string]any{
+	logger.DebugCF("voice", "Received transcription response", map[string]any{
 		"status_code":         resp.StatusCode,
 		"response_size_bytes": len(body),
 	})
@@ -152,8 +192,131 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	return &result, nil
 }
 
-func (t *GroqTranscriber) IsAvailable() bool {
-	available := t.apiKey != ""
+func (t *OpenAITranscriber) IsAvailable() bool {
+	available := t.apiKey != "" || !t.keyRequired
 	logger.DebugCF("voice", "Checking transcriber availability", map[string]any{"available": available})
 	return available
 }
+
+// NewTranscriber creates the transcriber selected by cfg. It returns nil
+// when no provider is configured.
+func NewTranscriber(cfg config.TranscriptionConfig) (Transcriber, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "groq":
+		t := NewGroqTranscriber(cfg.APIKey)
+		if cfg.APIBase != "" {
+			t.apiBase = strings.TrimRight(cfg.APIBase, "/")
+		}
+		if cfg.Model != "" {
+			t.model = cfg.Model
+		}
+		return t, nil
+	case "openai":
+		return NewOpenAITranscriber(cfg.APIKey, cfg.APIBase, cfg.Model), nil
+	case "command":
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("voice.transcription.command is required for the command provider")
+		}
+		return NewCommandTranscriber(cfg.Command), nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider %q (use groq, openai or command)", cfg.Provider)
+	}
+}
+
+// CommandTranscriber runs a local speech-to-text program such as
+// whisper.cpp and reads the transcript from its standard output. {input} in
+// an argument is replaced with the audio file. {input_wav} is replaced with
+// a 16 kHz mono WAV copy made with ffmpeg, the format whisper.cpp expects.
+type CommandTranscriber struct {
+	command []string
+	timeout time.Duration
+}
+
+func NewCommandTranscriber(command []string) *CommandTranscriber {
+	return &CommandTranscriber{
+		command: command,
+		timeout: 2 * time.Minute,
+	}
+}
+
+func (t *CommandTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+	if len(t.command) == 0 {
+		return nil, fmt.Errorf("no transcription command configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	wavPath := ""
+	for _, arg := range t.command {
+		if strings.Contains(arg, "{input_wav}") {
+			var err error
+			if wavPath, err = convertToWAV(ctx, audioFilePath); err != nil {
+				return nil, err
+			}
+			defer os.Remove(wavPath)
+			break
+		}
+	}
+
+	args := make([]string, len(t.command))
+	for i, arg := range t.command {
+		arg = strings.ReplaceAll(arg, "{input_wav}", wavPath)
+		args[i] = strings.ReplaceAll(arg, "{input}", audioFilePath)
+	}
+
+	logger.InfoCF("voice", "Starting transcription", map[string]any{
+		"audio_file": audioFilePath,
+		"command":    args[0],
+	})
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		logger.ErrorCF("voice", "Transcription command failed", map[string]any{
+			"command": args[0],
+			"error":   err.Error(),
+			"stderr":  utils.Truncate(strings.TrimSpace(stderr.String()), 500),
+		})
+		return nil, fmt.Errorf("transcription command failed: %w", err)
+	}
+
+	text := strings.Join(strings.Fields(stdout.String()), " ")
+	if text == "" {
+		return nil, fmt.Errorf("transcription command produced no text")
+	}
+
+	logger.InfoCF("voice", "Transcription completed successfully", map[string]any{
+		"text_length":           len(text),
+		"transcription_preview": utils.Truncate(text, 50),
+	})
+	return &TranscriptionResponse{Text: text}, nil
+}
+
+func (t *CommandTranscriber) IsAvailable() bool {
+	if len(t.command) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(t.command[0])
+	return err == nil
+}
+
+// convertToWAV converts path to a temporary 16 kHz mono WAV file.
+func convertToWAV(ctx context.Context, path string) (string, error) {
+	f, err := os.CreateTemp("", "picoclaw-stt-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	f.Close()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-loglevel", "error", "-y", "-i", path, "-ar", "16000", "-ac", "1", f.Name())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("ffmpeg conversion failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return f.Name(), nil
+}
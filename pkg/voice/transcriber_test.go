@@ -0,0 +1,112 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func writeAudioFixture(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "voice.ogg")
+	if err := os.WriteFile(path, []byte("OggS audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenAITranscriber_SelfHosted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no Authorization header, got %q", auth)
+		}
+		if model := r.FormValue("model"); model != "Systran/faster-whisper-small" {
+			t.Errorf("unexpected model %q", model)
+		}
+		if _, header, err := r.FormFile("file"); err != nil || header.Filename != "voice.ogg" {
+			t.Errorf("unexpected file upload: %v", err)
+		}
+		json.NewEncoder(w).Encode(TranscriptionResponse{Text: "turn on the lights"})
+	}))
+	defer server.Close()
+
+	tr := NewOpenAITranscriber("", server.URL+"/v1", "Systran/faster-whisper-small")
+	if !tr.IsAvailable() {
+		t.Fatal("expected a self-hosted endpoint to be available without a key")
+	}
+	result, err := tr.Transcribe(context.Background(), writeAudioFixture(t))
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if result.Text != "turn on the lights" {
+		t.Errorf("unexpected text %q", result.Text)
+	}
+}
+
+func TestOpenAITranscriber_KeyRequired(t *testing.T) {
+	if NewGroqTranscriber("").IsAvailable() {
+		t.Error("expected Groq without a key to be unavailable")
+	}
+	if NewOpenAITranscriber("", "", "").IsAvailable() {
+		t.Error("expected OpenAI without a key to be unavailable")
+	}
+	if !NewGroqTranscriber("gsk").IsAvailable() {
+		t.Error("expected Groq with a key to be available")
+	}
+}
+
+func TestCommandTranscriber(t *testing.T) {
+	path := writeAudioFixture(t)
+
+	tr := NewCommandTranscriber([]string{"sh", "-c", `test -f "$1" && printf '  hello\n world \n'`, "sh", "{input}"})
+	if !tr.IsAvailable() {
+		t.Skip("sh not available")
+	}
+	result, err := tr.Transcribe(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Transcribe failed: %v", err)
+	}
+	if result.Text != "hello world" {
+		t.Errorf("unexpected text %q", result.Text)
+	}
+
+	if _, err := NewCommandTranscriber([]string{"true"}).Transcribe(context.Background(), path); err == nil {
+		t.Error("expected an error when the command prints nothing")
+	}
+	if _, err := NewCommandTranscriber([]string{"false"}).Transcribe(context.Background(), path); err == nil {
+		t.Error("expected an error when the command fails")
+	}
+	if NewCommandTranscriber([]string{"picoclaw-no-such-whisper"}).IsAvailable() {
+		t.Error("expected a missing command to be unavailable")
+	}
+}
+
+func TestNewTranscriber(t *testing.T) {
+	if tr, err := NewTranscriber(config.TranscriptionConfig{}); tr != nil || err != nil {
+		t.Errorf("expected no transcriber without a provider, got %v, %v", tr, err)
+	}
+	if _, err := NewTranscriber(config.TranscriptionConfig{Provider: "command"}); err == nil {
+		t.Error("expected an error for the command provider without a command")
+	}
+	if _, err := NewTranscriber(config.TranscriptionConfig{Provider: "vosk"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+
+	tr, err := NewTranscriber(config.TranscriptionConfig{Provider: "groq", APIKey: "gsk", Model: "whisper-large-v3-turbo"})
+	if err != nil {
+		t.Fatalf("NewTranscriber failed: %v", err)
+	}
+	groq := tr.(*OpenAITranscriber)
+	if groq.apiBase != groqAPIBase || groq.model != "whisper-large-v3-turbo" {
+		t.Errorf("unexpected groq transcriber %+v", groq)
+	}
+}
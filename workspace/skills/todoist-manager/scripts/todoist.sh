@@ -0,0 +1,50 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+usage() {
+  cat <<'USAGE'
+Usage: todoist.sh today
+       todoist.sh add "task content" ["due string"]
+       todoist.sh complete task-id
+
+Talk to the Todoist REST API with TODOIST_API_TOKEN.
+USAGE
+}
+
+if [ -z "${TODOIST_API_TOKEN:-}" ]; then
+  echo "TODOIST_API_TOKEN is not set. Store it with: picoclaw secrets import TODOIST_API_TOKEN --skill todoist-manager"
+  exit 0
+fi
+
+api="https://api.todoist.com/api/v1"
+
+case "${1:-}" in
+  today)
+    curl -s "$api/tasks?filter=today" \
+      -H "Authorization: Bearer $TODOIST_API_TOKEN" \
+      | jq -r 'if type=="object" and has("results") then .results[] else .[] end | "☐ [\(.id)] \(.content) (p\(.priority // 1))"'
+    ;;
+  add)
+    [ $# -ge 2 ] || { usage; exit 1; }
+    jq -n --arg content "$2" --arg due "${3:-today}" '{content: $content, due_string: $due}' \
+      | curl -s -X POST "$api/tasks" \
+          -H "Authorization: Bearer $TODOIST_API_TOKEN" \
+          -H "Content-Type: application/json" \
+          -d @-
+    ;;
+  complete)
+    [ $# -ge 2 ] || { usage; exit 1; }
+    case "$2" in
+      *[!A-Za-z0-9]*) echo "Invalid task id: $2"; exit 1 ;;
+    esac
+    curl -s -X POST "$api/tasks/$2/close" \
+      -H "Authorization: Bearer $TODOIST_API_TOKEN"
+    ;;
+  -h|--help|"")
+    usage
+    ;;
+  *)
+    usage
+    exit 1
+    ;;
+esac